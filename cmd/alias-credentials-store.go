@@ -0,0 +1,238 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/trinet2005/oss-mc/pkg/probe"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/term"
+)
+
+// The credential store keeps the keys of aliases encrypted with a
+// passphrase, instead of in plain text in the config file. The key is
+// derived from the passphrase with Argon2id, the store is sealed with
+// AES-256-GCM.
+const (
+	credentialStoreFile    = "credentials.enc"
+	credentialStoreVersion = "1"
+	credentialStoreKDF     = "argon2id"
+
+	// Environment variable holding the passphrase of the store.
+	credentialStorePassphraseEnv = "MC_CREDENTIALS_PASSPHRASE"
+)
+
+// credentialStoreEntry - keys of an alias.
+type credentialStoreEntry struct {
+	AccessKey    string `json:"accessKey"`
+	SecretKey    string `json:"secretKey"`
+	SessionToken string `json:"sessionToken,omitempty"`
+}
+
+// credentialStoreV1 - decrypted content of the store.
+type credentialStoreV1 struct {
+	Version string                          `json:"version"`
+	Aliases map[string]credentialStoreEntry `json:"aliases"`
+}
+
+// sealedCredentialStoreV1 - the store as written on disk.
+type sealedCredentialStoreV1 struct {
+	Version string `json:"version"`
+	KDF     string `json:"kdf"`
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"`
+}
+
+// Passphrase of the store, asked once.
+var credentialStorePassphrase struct {
+	sync.Mutex
+	value string
+}
+
+func getCredentialStorePath() (string, *probe.Error) {
+	dir, err := getMcConfigDir()
+	if err != nil {
+		return "", err.Trace()
+	}
+	return filepath.Join(dir, credentialStoreFile), nil
+}
+
+// getCredentialStorePassphrase returns the passphrase of the store from
+// MC_CREDENTIALS_PASSPHRASE, or asks for it on a terminal.
+func getCredentialStorePassphrase() (string, *probe.Error) {
+	if passphrase := os.Getenv(credentialStorePassphraseEnv); passphrase != "" {
+		return passphrase, nil
+	}
+	credentialStorePassphrase.Lock()
+	defer credentialStorePassphrase.Unlock()
+	if credentialStorePassphrase.value != "" {
+		return credentialStorePassphrase.value, nil
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return "", probe.NewError(fmt.Errorf("passphrase of the credential store required, set `%s`", credentialStorePassphraseEnv))
+	}
+	fmt.Fprint(os.Stderr, "Enter passphrase of the credential store: ")
+	passphrase, e := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if e != nil {
+		return "", probe.NewError(e)
+	}
+	if len(passphrase) == 0 {
+		return "", probe.NewError(errors.New("empty passphrase of the credential store"))
+	}
+	credentialStorePassphrase.value = string(passphrase)
+	return credentialStorePassphrase.value, nil
+}
+
+func credentialStoreAEAD(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key := argon2.IDKey([]byte(passphrase), salt, 1, 64*1024, 4, 32)
+	block, e := aes.NewCipher(key)
+	if e != nil {
+		return nil, e
+	}
+	return cipher.NewGCM(block)
+}
+
+// loadCredentialStore decrypts the credential store, a missing store
+// is empty.
+func loadCredentialStore() (*credentialStoreV1, *probe.Error) {
+	store := &credentialStoreV1{Version: credentialStoreVersion, Aliases: make(map[string]credentialStoreEntry)}
+	storePath, err := getCredentialStorePath()
+	if err != nil {
+		return nil, err.Trace()
+	}
+	data, e := os.ReadFile(storePath)
+	if e != nil {
+		if os.IsNotExist(e) {
+			return store, nil
+		}
+		return nil, probe.NewError(e).Trace(storePath)
+	}
+
+	var sealed sealedCredentialStoreV1
+	if e = json.Unmarshal(data, &sealed); e != nil {
+		return nil, probe.NewError(e).Trace(storePath)
+	}
+	if sealed.Version != credentialStoreVersion || sealed.KDF != credentialStoreKDF {
+		return nil, probe.NewError(fmt.Errorf("unsupported credential store version `%s`", sealed.Version)).Trace(storePath)
+	}
+	passphrase, err := getCredentialStorePassphrase()
+	if err != nil {
+		return nil, err.Trace(storePath)
+	}
+	aead, e := credentialStoreAEAD(passphrase, sealed.Salt)
+	if e != nil {
+		return nil, probe.NewError(e).Trace(storePath)
+	}
+	if len(sealed.Nonce) != aead.NonceSize() {
+		return nil, probe.NewError(errors.New("invalid credential store nonce")).Trace(storePath)
+	}
+	plaintext, e := aead.Open(nil, sealed.Nonce, sealed.Data, []byte(sealed.Version))
+	if e != nil {
+		return nil, probe.NewError(errors.New("unable to decrypt the credential store, wrong passphrase")).Trace(storePath)
+	}
+	if e = json.Unmarshal(plaintext, store); e != nil {
+		return nil, probe.NewError(e).Trace(storePath)
+	}
+	if store.Aliases == nil {
+		store.Aliases = make(map[string]credentialStoreEntry)
+	}
+	return store, nil
+}
+
+// saveCredentialStore encrypts and writes the credential store, with a
+// new salt and nonce every time.
+func saveCredentialStore(store *credentialStoreV1) *probe.Error {
+	storePath, err := getCredentialStorePath()
+	if err != nil {
+		return err.Trace()
+	}
+	passphrase, err := getCredentialStorePassphrase()
+	if err != nil {
+		return err.Trace(storePath)
+	}
+	plaintext, e := json.Marshal(store)
+	if e != nil {
+		return probe.NewError(e)
+	}
+
+	sealed := sealedCredentialStoreV1{
+		Version: credentialStoreVersion,
+		KDF:     credentialStoreKDF,
+		Salt:    make([]byte, 32),
+	}
+	if _, e = rand.Read(sealed.Salt); e != nil {
+		return probe.NewError(e)
+	}
+	aead, e := credentialStoreAEAD(passphrase, sealed.Salt)
+	if e != nil {
+		return probe.NewError(e)
+	}
+	sealed.Nonce = make([]byte, aead.NonceSize())
+	if _, e = rand.Read(sealed.Nonce); e != nil {
+		return probe.NewError(e)
+	}
+	sealed.Data = aead.Seal(nil, sealed.Nonce, plaintext, []byte(sealed.Version))
+
+	data, e := json.MarshalIndent(sealed, "", "\t")
+	if e != nil {
+		return probe.NewError(e)
+	}
+	tmpPath := storePath + ".tmp"
+	if e = os.WriteFile(tmpPath, data, 0o600); e != nil {
+		return probe.NewError(e).Trace(tmpPath)
+	}
+	if e = os.Rename(tmpPath, storePath); e != nil {
+		os.Remove(tmpPath)
+		return probe.NewError(e).Trace(storePath)
+	}
+	return nil
+}
+
+// setCredentialStoreEntry stores the keys of an alias.
+func setCredentialStoreEntry(alias string, entry credentialStoreEntry) *probe.Error {
+	store, err := loadCredentialStore()
+	if err != nil {
+		return err.Trace(alias)
+	}
+	store.Aliases[alias] = entry
+	return saveCredentialStore(store)
+}
+
+// removeCredentialStoreEntry removes the keys of an alias.
+func removeCredentialStoreEntry(alias string) *probe.Error {
+	store, err := loadCredentialStore()
+	if err != nil {
+		return err.Trace(alias)
+	}
+	if _, ok := store.Aliases[alias]; !ok {
+		return nil
+	}
+	delete(store.Aliases, alias)
+	return saveCredentialStore(store)
+}
@@ -0,0 +1,321 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/trinet2005/oss-go-sdk/pkg/credentials"
+	"github.com/trinet2005/oss-mc/pkg/probe"
+)
+
+// Credential providers of aliases.
+const (
+	credentialProcess = "process"
+	credentialFile    = "file"
+	credentialEnv     = "env"
+	credentialStore   = "store"
+)
+
+const (
+	// credentialProcessTimeout - how long a credential process may run.
+	credentialProcessTimeout = time.Minute
+	// credentialEnvPrefix - default prefix of the environment variables
+	// holding the credentials of an alias.
+	credentialEnvPrefix = "AWS_"
+)
+
+// String describes the source of the credentials, without secrets.
+func (c aliasCredentialsV10) String() string {
+	switch c.Provider {
+	case credentialProcess:
+		return c.Provider + ": " + c.Command
+	case credentialFile:
+		file, profile := c.File, c.Profile
+		if file == "" {
+			file = "~/.aws/credentials"
+		}
+		if profile == "" {
+			profile = "default"
+		}
+		return c.Provider + ": " + file + " [" + profile + "]"
+	case credentialEnv:
+		prefix := c.Prefix
+		if prefix == "" {
+			prefix = credentialEnvPrefix
+		}
+		return c.Provider + ": " + prefix + "*"
+	case stsLDAP:
+		return c.Provider + ": " + c.Username
+	case stsWebIdentity, stsClientGrants:
+		if c.TokenFile != "" {
+			return c.Provider + ": " + c.TokenFile
+		}
+		return c.Provider + ": " + c.Command
+	case stsClientCertificate:
+		return c.Provider + ": " + c.CertFile
+	}
+	return c.Provider
+}
+
+// validateAliasCredentials checks the provider of an alias is known and
+// configured.
+func validateAliasCredentials(c *aliasCredentialsV10) *probe.Error {
+	switch c.Provider {
+	case credentialProcess:
+		if strings.TrimSpace(c.Command) == "" {
+			return probe.NewError(errors.New("credential process command is empty"))
+		}
+	case credentialFile, credentialEnv, credentialStore:
+	case stsLDAP:
+		if c.Username == "" {
+			return probe.NewError(errors.New("LDAP username is empty"))
+		}
+	case stsWebIdentity, stsClientGrants:
+		if c.TokenFile == "" && c.Command == "" {
+			return probe.NewError(fmt.Errorf("a token file or command is required by `%s`", c.Provider))
+		}
+	case stsClientCertificate:
+		if c.CertFile == "" || c.KeyFile == "" {
+			return probe.NewError(errors.New("a certificate and its private key are required by `client-certificate`"))
+		}
+	default:
+		return probe.NewError(fmt.Errorf("unknown credential provider `%s`, valid options are "+
+			"`[process, file, env, store, ldap, web-identity, client-grants, client-certificate]`", c.Provider))
+	}
+	if c.Duration != "" {
+		if _, e := time.ParseDuration(c.Duration); e != nil {
+			return probe.NewError(e).Trace(c.Duration)
+		}
+	}
+	if c.Endpoint != "" && newClientURL(c.Endpoint).Type != objectStorage {
+		return probe.NewError(fmt.Errorf("invalid STS endpoint `%s`", c.Endpoint))
+	}
+	return nil
+}
+
+// newAliasCredentials returns the credentials of an alias from its
+// provider, they are retrieved again once expired. STS logins go to the
+// server of hostURL unless the provider has its own endpoint.
+func newAliasCredentials(alias, hostURL string, c *aliasCredentialsV10, signature string) (*credentials.Credentials, *probe.Error) {
+	if err := validateAliasCredentials(c); err != nil {
+		return nil, err.Trace(alias)
+	}
+	if isSTSProvider(c.Provider) {
+		return newSTSCredentials(alias, hostURL, c), nil
+	}
+	var provider credentials.Provider
+	switch c.Provider {
+	case credentialProcess:
+		provider = &processCredentials{command: c.Command}
+	case credentialFile:
+		provider = &credentials.FileAWSCredentials{Filename: c.File, Profile: c.Profile}
+	case credentialEnv:
+		prefix := c.Prefix
+		if prefix == "" {
+			prefix = credentialEnvPrefix
+		}
+		provider = &envCredentials{prefix: prefix}
+	case credentialStore:
+		provider = &storeCredentials{alias: alias}
+	}
+	signerType := credentials.SignatureV4
+	if strings.EqualFold(signature, "S3v2") {
+		signerType = credentials.SignatureV2
+	}
+	return credentials.New(signerProvider{Provider: provider, signerType: signerType}), nil
+}
+
+// credentialsValue retrieves the credentials of an alias once.
+func credentialsValue(alias, hostURL string, c *aliasCredentialsV10, signature string) (credentials.Value, error) {
+	creds, err := newAliasCredentials(alias, hostURL, c, signature)
+	if err != nil {
+		return credentials.Value{}, err.ToGoError()
+	}
+	return creds.Get()
+}
+
+// credentialsKey identifies the source of the credentials of a config,
+// clients are cached by credentials.
+func credentialsKey(c *aliasCredentialsV10) string {
+	if c == nil {
+		return ""
+	}
+	return strings.Join([]string{
+		c.Provider, c.Command, c.File, c.Profile, c.Prefix, c.Endpoint, c.Username,
+		c.TokenFile, c.CertFile, c.KeyFile, c.RoleARN, c.Duration,
+	}, "\x00")
+}
+
+// signerProvider signs with the signature of the alias.
+type signerProvider struct {
+	credentials.Provider
+	signerType credentials.SignatureType
+}
+
+func (p signerProvider) Retrieve() (credentials.Value, error) {
+	value, e := p.Provider.Retrieve()
+	if e != nil {
+		return value, e
+	}
+	value.SignerType = p.signerType
+	return value, nil
+}
+
+// processCredentialsOutput - output of a credential process, in the
+// format of the credential_process setting of the AWS CLI.
+type processCredentialsOutput struct {
+	Version         int       `json:"Version"`
+	AccessKeyID     string    `json:"AccessKeyId"`
+	SecretAccessKey string    `json:"SecretAccessKey"`
+	SessionToken    string    `json:"SessionToken"`
+	Expiration      time.Time `json:"Expiration"`
+}
+
+// processCredentials runs a command printing the credentials as JSON,
+// they are retrieved again before they expire. Credentials without an
+// expiration are retrieved once.
+type processCredentials struct {
+	credentials.Expiry
+	command   string
+	retrieved bool
+	expires   bool
+}
+
+// runCredentialCommand runs a command of the shell printing credentials
+// or a token on its output.
+func runCredentialCommand(command string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), credentialProcessTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, e := cmd.Output()
+	if e != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("credential process failed: %w: %s", e, msg)
+		}
+		return nil, fmt.Errorf("credential process failed: %w", e)
+	}
+	return out, nil
+}
+
+func (p *processCredentials) Retrieve() (credentials.Value, error) {
+	out, e := runCredentialCommand(p.command)
+	if e != nil {
+		return credentials.Value{}, e
+	}
+
+	var output processCredentialsOutput
+	if e = json.Unmarshal(out, &output); e != nil {
+		return credentials.Value{}, fmt.Errorf("invalid output of the credential process: %w", e)
+	}
+	if output.Version != 1 {
+		return credentials.Value{}, fmt.Errorf("unsupported version %d of the credential process output", output.Version)
+	}
+	if output.AccessKeyID == "" || output.SecretAccessKey == "" {
+		return credentials.Value{}, errors.New("credential process returned no keys")
+	}
+
+	p.retrieved = true
+	p.expires = !output.Expiration.IsZero()
+	if p.expires {
+		p.SetExpiration(output.Expiration, credentials.DefaultExpiryWindow)
+	}
+	return credentials.Value{
+		AccessKeyID:     output.AccessKeyID,
+		SecretAccessKey: output.SecretAccessKey,
+		SessionToken:    output.SessionToken,
+	}, nil
+}
+
+func (p *processCredentials) IsExpired() bool {
+	if !p.retrieved {
+		return true
+	}
+	return p.expires && p.Expiry.IsExpired()
+}
+
+// envCredentials reads the credentials from the variables
+// <prefix>ACCESS_KEY_ID, <prefix>SECRET_ACCESS_KEY and
+// <prefix>SESSION_TOKEN.
+type envCredentials struct {
+	prefix    string
+	retrieved bool
+}
+
+func (p *envCredentials) Retrieve() (credentials.Value, error) {
+	accessKey := os.Getenv(p.prefix + "ACCESS_KEY_ID")
+	secretKey := os.Getenv(p.prefix + "SECRET_ACCESS_KEY")
+	if accessKey == "" || secretKey == "" {
+		return credentials.Value{}, fmt.Errorf("`%sACCESS_KEY_ID` and `%sSECRET_ACCESS_KEY` must be set", p.prefix, p.prefix)
+	}
+	p.retrieved = true
+	return credentials.Value{
+		AccessKeyID:     accessKey,
+		SecretAccessKey: secretKey,
+		SessionToken:    os.Getenv(p.prefix + "SESSION_TOKEN"),
+	}, nil
+}
+
+func (p *envCredentials) IsExpired() bool {
+	return !p.retrieved
+}
+
+// storeCredentials reads the credentials of an alias from the
+// encrypted credential store.
+type storeCredentials struct {
+	alias     string
+	retrieved bool
+}
+
+func (p *storeCredentials) Retrieve() (credentials.Value, error) {
+	store, err := loadCredentialStore()
+	if err != nil {
+		return credentials.Value{}, err.ToGoError()
+	}
+	entry, ok := store.Aliases[p.alias]
+	if !ok {
+		return credentials.Value{}, fmt.Errorf("no credentials of `%s` in the credential store", p.alias)
+	}
+	p.retrieved = true
+	return credentials.Value{
+		AccessKeyID:     entry.AccessKey,
+		SecretAccessKey: entry.SecretKey,
+		SessionToken:    entry.SessionToken,
+	}, nil
+}
+
+func (p *storeCredentials) IsExpired() bool {
+	return !p.retrieved
+}
@@ -0,0 +1,194 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/trinet2005/oss-go-sdk/pkg/credentials"
+	. "gopkg.in/check.v1"
+)
+
+// credentialProcessScript writes a credential process printing keys
+// numbered by the times it ran, expiring at the given time.
+func credentialProcessScript(c *C, expiration string) string {
+	dir := c.MkDir()
+	script := filepath.Join(dir, "creds.sh")
+	counter := filepath.Join(dir, "count")
+	content := fmt.Sprintf(`#!/bin/sh
+n=$(($(cat %[1]s 2>/dev/null || echo 0) + 1))
+echo $n > %[1]s
+echo '{"Version": 1, "AccessKeyId": "access'$n'", "SecretAccessKey": "secret'$n'", "SessionToken": "token", "Expiration": "%[2]s"}'
+`, counter, expiration)
+	c.Assert(os.WriteFile(script, []byte(content), 0o700), IsNil)
+	return script
+}
+
+func (s *TestSuite) TestProcessCredentials(c *C) {
+	now := time.Now()
+	expiration := now.Add(time.Hour)
+	provider := &processCredentials{command: credentialProcessScript(c, expiration.Format(time.RFC3339))}
+	provider.CurrentTime = func() time.Time { return now }
+	creds := credentials.New(provider)
+
+	value, e := creds.Get()
+	c.Assert(e, IsNil)
+	c.Assert(value.AccessKeyID, Equals, "access1")
+	c.Assert(value.SecretAccessKey, Equals, "secret1")
+	c.Assert(value.SessionToken, Equals, "token")
+
+	// Still valid, the process is not run again.
+	now = now.Add(30 * time.Minute)
+	value, e = creds.Get()
+	c.Assert(e, IsNil)
+	c.Assert(value.AccessKeyID, Equals, "access1")
+
+	// Retrieved again before they expire.
+	now = expiration.Add(-time.Minute)
+	value, e = creds.Get()
+	c.Assert(e, IsNil)
+	c.Assert(value.AccessKeyID, Equals, "access2")
+
+	// Credentials without an expiration are retrieved once.
+	provider = &processCredentials{command: `echo '{"Version": 1, "AccessKeyId": "a", "SecretAccessKey": "b"}'`}
+	_, e = provider.Retrieve()
+	c.Assert(e, IsNil)
+	c.Assert(provider.IsExpired(), Equals, false)
+
+	for _, command := range []string{
+		`echo '{"Version": 2, "AccessKeyId": "a", "SecretAccessKey": "b"}'`,
+		`echo '{"Version": 1}'`,
+		`echo 'not json'`,
+		`echo 'denied' >&2; exit 1`,
+	} {
+		_, e = (&processCredentials{command: command}).Retrieve()
+		c.Assert(e, NotNil, Commentf("%s", command))
+	}
+	_, e = (&processCredentials{command: `echo 'denied' >&2; exit 1`}).Retrieve()
+	c.Assert(strings.Contains(e.Error(), "denied"), Equals, true)
+}
+
+func (s *TestSuite) TestEnvCredentials(c *C) {
+	os.Setenv("MC_TEST_CREDS_ACCESS_KEY_ID", "access")
+	os.Setenv("MC_TEST_CREDS_SECRET_ACCESS_KEY", "secret")
+	defer os.Unsetenv("MC_TEST_CREDS_ACCESS_KEY_ID")
+	defer os.Unsetenv("MC_TEST_CREDS_SECRET_ACCESS_KEY")
+
+	creds, err := newAliasCredentials("env", "", &aliasCredentialsV10{Provider: credentialEnv, Prefix: "MC_TEST_CREDS_"}, "S3v2")
+	c.Assert(err, IsNil)
+	value, e := creds.Get()
+	c.Assert(e, IsNil)
+	c.Assert(value.AccessKeyID, Equals, "access")
+	c.Assert(value.SecretAccessKey, Equals, "secret")
+	c.Assert(value.SignerType, Equals, credentials.SignatureV2)
+
+	_, e = (&envCredentials{prefix: "MC_TEST_CREDS_MISSING_"}).Retrieve()
+	c.Assert(e, NotNil)
+}
+
+func (s *TestSuite) TestValidateAliasCredentials(c *C) {
+	c.Assert(validateAliasCredentials(&aliasCredentialsV10{Provider: credentialFile}), IsNil)
+	c.Assert(validateAliasCredentials(&aliasCredentialsV10{Provider: credentialProcess}), NotNil)
+	c.Assert(validateAliasCredentials(&aliasCredentialsV10{Provider: "vault"}), NotNil)
+
+	c.Assert(aliasCredentialsV10{Provider: credentialFile, Profile: "prod"}.String(), Equals, "file: ~/.aws/credentials [prod]")
+	c.Assert(aliasCredentialsV10{Provider: credentialEnv}.String(), Equals, "env: AWS_*")
+
+	// Aliases of the store have their own keys.
+	store := &aliasConfigV10{Credentials: &aliasCredentialsV10{Provider: credentialStore}}
+	c.Assert(sameAliasCredentials(store, store), Equals, false)
+	process := &aliasConfigV10{Credentials: &aliasCredentialsV10{Provider: credentialProcess, Command: "creds"}}
+	c.Assert(sameAliasCredentials(process, process), Equals, true)
+	c.Assert(sameAliasCredentials(process, &aliasConfigV10{}), Equals, false)
+}
+
+func (s *TestSuite) TestCredentialStore(c *C) {
+	defer func(dir string) { mcCustomConfigDir = dir }(mcCustomConfigDir)
+	mcCustomConfigDir = c.MkDir()
+	os.Setenv(credentialStorePassphraseEnv, "correct horse")
+	defer os.Unsetenv(credentialStorePassphraseEnv)
+
+	// A missing store is empty.
+	store, err := loadCredentialStore()
+	c.Assert(err, IsNil)
+	c.Assert(store.Aliases, HasLen, 0)
+
+	c.Assert(setCredentialStoreEntry("first", credentialStoreEntry{AccessKey: "access", SecretKey: "very-secret-key"}), IsNil)
+	c.Assert(setCredentialStoreEntry("second", credentialStoreEntry{AccessKey: "other", SecretKey: "other-secret"}), IsNil)
+
+	data, e := os.ReadFile(filepath.Join(mcCustomConfigDir, credentialStoreFile))
+	c.Assert(e, IsNil)
+	c.Assert(strings.Contains(string(data), "very-secret-key"), Equals, false)
+	fi, e := os.Stat(filepath.Join(mcCustomConfigDir, credentialStoreFile))
+	c.Assert(e, IsNil)
+	c.Assert(fi.Mode().Perm(), Equals, os.FileMode(0o600))
+
+	value, e := (&storeCredentials{alias: "first"}).Retrieve()
+	c.Assert(e, IsNil)
+	c.Assert(value.AccessKeyID, Equals, "access")
+	c.Assert(value.SecretAccessKey, Equals, "very-secret-key")
+	_, e = (&storeCredentials{alias: "missing"}).Retrieve()
+	c.Assert(e, NotNil)
+
+	c.Assert(removeCredentialStoreEntry("first"), IsNil)
+	store, err = loadCredentialStore()
+	c.Assert(err, IsNil)
+	c.Assert(store.Aliases, HasLen, 1)
+
+	os.Setenv(credentialStorePassphraseEnv, "wrong")
+	_, err = loadCredentialStore()
+	c.Assert(err, NotNil)
+}
+
+func (s *TestSuite) TestS3ClientCredentialProcess(c *C) {
+	var authorizations []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has("location") {
+			w.Write([]byte(`<LocationConstraint xmlns="http://s3.amazonaws.com/doc/2006-03-01/">us-east-1</LocationConstraint>`))
+			return
+		}
+		authorizations = append(authorizations, r.Header.Get("Authorization"))
+		w.Header().Set("ETag", `"d41d8cd98f00b204e9800998ecf8427e"`)
+		w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+		w.Header().Set("Content-Length", "0")
+	}))
+	defer server.Close()
+
+	script := credentialProcessScript(c, time.Now().Add(time.Hour).Format(time.RFC3339))
+	aliasToConfigMap["creds-process"] = &aliasConfigV10{
+		URL:         server.URL,
+		API:         "S3v4",
+		Path:        "on",
+		Credentials: &aliasCredentialsV10{Provider: credentialProcess, Command: script},
+	}
+	defer delete(aliasToConfigMap, "creds-process")
+
+	clnt, err := newClient("creds-process/bucket/object")
+	c.Assert(err, IsNil)
+	_, err = clnt.Stat(context.Background(), StatOptions{})
+	c.Assert(err, IsNil)
+	c.Assert(len(authorizations) > 0, Equals, true)
+	c.Assert(strings.Contains(authorizations[len(authorizations)-1], "Credential=access1/"), Equals, true)
+}
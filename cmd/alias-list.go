@@ -75,6 +75,8 @@ func mainAliasList(ctx *cli.Context, deprecated bool) error {
 	console.SetColor("SecretKey", color.New(color.FgCyan))
 	console.SetColor("API", color.New(color.FgBlue))
 	console.SetColor("Path", color.New(color.FgCyan))
+	console.SetColor("Credentials", color.New(color.FgCyan))
+	console.SetColor("Scope", color.New(color.FgYellow))
 
 	alias := cleanAlias(ctx.Args().Get(0))
 
@@ -99,7 +101,7 @@ func printAliases(aliases ...aliasMessage) {
 			// Format properly for alignment based on alias length only in non json mode.
 			alias.Alias = fmt.Sprintf("%-*.*s", maxAlias, maxAlias, alias.Alias)
 		}
-		if alias.AccessKey == "" || alias.SecretKey == "" {
+		if (alias.AccessKey == "" || alias.SecretKey == "") && alias.Credentials == "" {
 			alias.AccessKey = ""
 			alias.SecretKey = ""
 			alias.API = ""
@@ -131,6 +133,10 @@ func listAliases(alias string, deprecated bool) (aliases []aliasMessage) {
 				SecretKey:   v.SecretKey,
 				API:         v.API,
 			}
+			if v.Credentials != nil {
+				aliasMsg.Credentials = v.Credentials.String()
+			}
+			aliasMsg.Bucket, aliasMsg.Prefix = v.Bucket, v.Prefix
 
 			if deprecated {
 				aliasMsg.Lookup = v.Path
@@ -152,6 +158,10 @@ func listAliases(alias string, deprecated bool) (aliases []aliasMessage) {
 			SecretKey:   v.SecretKey,
 			API:         v.API,
 		}
+		if v.Credentials != nil {
+			aliasMsg.Credentials = v.Credentials.String()
+		}
+		aliasMsg.Bucket, aliasMsg.Prefix = v.Bucket, v.Prefix
 
 		if deprecated {
 			aliasMsg.Lookup = v.Path
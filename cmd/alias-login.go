@@ -0,0 +1,102 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+
+	"github.com/fatih/color"
+	"github.com/minio/cli"
+	"github.com/trinet2005/oss-mc/pkg/probe"
+	"github.com/trinet2005/oss-pkg/console"
+)
+
+var aliasLoginCmd = cli.Command{
+	Name:  "login",
+	Usage: "login again to an alias using STS",
+	Action: func(ctx *cli.Context) error {
+		return mainAliasLogin(ctx)
+	},
+	Before:          setGlobalsFromContext,
+	Flags:           globalFlags,
+	HideHelpCommand: true,
+	OnUsageError:    onUsageError,
+	CustomHelpTemplate: `NAME:
+  {{.HelpName}} - {{.Usage}}
+
+USAGE:
+  {{.HelpName}} ALIAS
+
+FLAGS:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}
+EXAMPLES:
+  1. Login again to "myminio" alias, the LDAP password is asked for.
+     {{.Prompt}} {{.HelpName}} myminio
+     Enter LDAP password of alice:
+
+`,
+}
+
+// checkAliasLoginSyntax - verifies input arguments to 'alias login'.
+func checkAliasLoginSyntax(ctx *cli.Context) {
+	args := ctx.Args()
+
+	if len(ctx.Args()) != 1 {
+		fatalIf(errInvalidArgument().Trace(args...),
+			"Incorrect number of arguments for alias login command.")
+	}
+
+	alias := cleanAlias(args.Get(0))
+	if !isValidAlias(alias) {
+		fatalIf(errDummy().Trace(alias), "Invalid alias `"+alias+"`.")
+	}
+}
+
+// mainAliasLogin is the handle for "mc alias login" command.
+func mainAliasLogin(ctx *cli.Context) error {
+	checkAliasLoginSyntax(ctx)
+
+	console.SetColor("AliasMessage", color.New(color.FgGreen))
+
+	alias := cleanAlias(ctx.Args().Get(0))
+	aliasMsg := loginAlias(alias)
+	aliasMsg.op = "login"
+	printMsg(aliasMsg)
+	return nil
+}
+
+// loginAlias - obtains new temporary credentials for an alias.
+func loginAlias(alias string) aliasMessage {
+	aliasMustExist(alias)
+	hostCfg := mustGetHostConfig(alias)
+	if hostCfg.Credentials == nil || !isSTSProvider(hostCfg.Credentials.Provider) {
+		fatalIf(probe.NewError(errors.New("not an STS alias")).Trace(alias),
+			"Alias `"+alias+"` does not login with STS, use `mc alias set --sts`.")
+	}
+
+	session, err := stsLogin(alias, hostCfg.URL, hostCfg.Credentials, true)
+	fatalIf(err.Trace(alias), "Unable to login to `"+alias+"`.")
+
+	return aliasMessage{
+		Alias:       alias,
+		URL:         hostCfg.URL,
+		Credentials: hostCfg.Credentials.String(),
+		Expiration:  &session.Expiration,
+	}
+}
@@ -18,6 +18,9 @@
 package cmd
 
 import (
+	"strings"
+	"time"
+
 	"github.com/minio/cli"
 	json "github.com/minio/colorjson"
 	"github.com/trinet2005/oss-mc/pkg/probe"
@@ -42,6 +45,7 @@ var aliasSubcommands = []cli.Command{
 	aliasListCmd,
 	aliasRemoveCmd,
 	aliasImportCmd,
+	aliasLoginCmd,
 }
 
 var aliasCmd = cli.Command{
@@ -65,13 +69,17 @@ func mainAlias(ctx *cli.Context) error {
 type aliasMessage struct {
 	op          string
 	prettyPrint bool
-	Status      string `json:"status"`
-	Alias       string `json:"alias"`
-	URL         string `json:"URL"`
-	AccessKey   string `json:"accessKey,omitempty"`
-	SecretKey   string `json:"secretKey,omitempty"`
-	API         string `json:"api,omitempty"`
-	Path        string `json:"path,omitempty"`
+	Status      string     `json:"status"`
+	Alias       string     `json:"alias"`
+	URL         string     `json:"URL"`
+	AccessKey   string     `json:"accessKey,omitempty"`
+	SecretKey   string     `json:"secretKey,omitempty"`
+	API         string     `json:"api,omitempty"`
+	Path        string     `json:"path,omitempty"`
+	Bucket      string     `json:"bucket,omitempty"`
+	Prefix      string     `json:"prefix,omitempty"`
+	Credentials string     `json:"credentials,omitempty"`
+	Expiration  *time.Time `json:"expiration,omitempty"`
 	// Deprecated field, replaced by Path
 	Lookup string `json:"lookup,omitempty"`
 }
@@ -82,21 +90,31 @@ type aliasMessage struct {
 func (h aliasMessage) String() string {
 	switch h.op {
 	case "list":
-		// Create a new pretty table with cols configuration
-		t := newPrettyRecord(2,
-			Row{"Alias", "Alias"},
-			Row{"URL", "URL"},
-			Row{"AccessKey", "AccessKey"},
-			Row{"SecretKey", "SecretKey"},
-			Row{"API", "API"},
-			Row{"Path", "Path"},
-		)
 		// Handle deprecated lookup
 		path := h.Path
 		if path == "" {
 			path = h.Lookup
 		}
-		return t.buildRecord(h.Alias, h.URL, h.AccessKey, h.SecretKey, h.API, path)
+		rows := []Row{{"Alias", "Alias"}, {"URL", "URL"}}
+		contents := []string{h.Alias, h.URL}
+		if h.Bucket != "" {
+			// Scoped aliases show the prefix they are confined to.
+			rows = append(rows, Row{"Scope", "Scope"})
+			contents = append(contents, strings.TrimSuffix(h.Bucket+"/"+h.Prefix, "/"))
+		}
+		if h.Credentials != "" {
+			// Keys of credential providers are not in the config.
+			rows = append(rows, Row{"Credentials", "Credentials"})
+			contents = append(contents, h.Credentials)
+		} else {
+			rows = append(rows, Row{"AccessKey", "AccessKey"}, Row{"SecretKey", "SecretKey"})
+			contents = append(contents, h.AccessKey, h.SecretKey)
+		}
+		rows = append(rows, Row{"API", "API"}, Row{"Path", "Path"})
+		contents = append(contents, h.API, path)
+		// Create a new pretty table with cols configuration
+		t := newPrettyRecord(2, rows...)
+		return t.buildRecord(contents...)
 	case "remove":
 		return console.Colorize("AliasMessage", "Removed `"+h.Alias+"` successfully.")
 	case "add": // add is deprecated
@@ -105,6 +123,9 @@ func (h aliasMessage) String() string {
 		return console.Colorize("AliasMessage", "Added `"+h.Alias+"` successfully.")
 	case "import":
 		return console.Colorize("AliasMessage", "Imported `"+h.Alias+"` successfully.")
+	case "login":
+		return console.Colorize("AliasMessage", "Logged in `"+h.Alias+"` successfully, credentials expire at "+
+			h.Expiration.Local().Format(printDate)+".")
 	default:
 		return ""
 	}
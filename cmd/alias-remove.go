@@ -97,6 +97,18 @@ func removeAlias(alias string) aliasMessage {
 	// check if alias is valid
 	aliasMustExist(alias)
 
+	// Remove the keys of the alias from the credential store.
+	if creds := conf.Aliases[alias].Credentials; creds != nil && creds.Provider == credentialStore {
+		err = removeCredentialStoreEntry(alias)
+		fatalIf(err.Trace(alias), "Unable to remove the keys of `"+alias+"` from the credential store.")
+	}
+
+	// Remove the temporary credentials of STS logins.
+	if creds := conf.Aliases[alias].Credentials; creds != nil && isSTSProvider(creds.Provider) {
+		err = removeSTSSession(alias)
+		fatalIf(err.Trace(alias), "Unable to remove the temporary credentials of `"+alias+"`.")
+	}
+
 	// Remove the alias from the config.
 	delete(conf.Aliases, alias)
 
@@ -22,6 +22,7 @@ import (
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"errors"
 	"fmt"
 	"math/rand"
 	"net"
@@ -33,6 +34,7 @@ import (
 	"github.com/fatih/color"
 	"github.com/minio/cli"
 	minio "github.com/trinet2005/oss-go-sdk"
+	"github.com/trinet2005/oss-go-sdk/pkg/s3utils"
 	"github.com/trinet2005/oss-mc/pkg/probe"
 	"github.com/trinet2005/oss-pkg/console"
 	"golang.org/x/term"
@@ -48,7 +50,71 @@ var aliasSetFlags = []cli.Flag{
 	},
 	cli.StringFlag{
 		Name:  "api",
-		Usage: "API signature. Valid options are '[S3v4, S3v2]'",
+		Usage: "API signature. Valid options are '[S3v4, S3v2, sftp]'",
+	},
+	cli.StringFlag{
+		Name:  "bucket",
+		Usage: "confine the alias to a bucket, its paths are relative to the bucket",
+	},
+	cli.StringFlag{
+		Name:  "prefix",
+		Usage: "confine the alias to a prefix of '--bucket'",
+	},
+	cli.StringFlag{
+		Name:  "credential-process",
+		Usage: "command printing the credentials as JSON, run again when they expire",
+	},
+	cli.StringFlag{
+		Name:  "credential-file",
+		Usage: "shared credentials file holding the credentials, defaults to '~/.aws/credentials' with '--credential-profile'",
+	},
+	cli.StringFlag{
+		Name:  "credential-profile",
+		Usage: "profile of the shared credentials file",
+	},
+	cli.StringFlag{
+		Name:  "credential-env",
+		Usage: "prefix of the environment variables PREFIXACCESS_KEY_ID, PREFIXSECRET_ACCESS_KEY and PREFIXSESSION_TOKEN",
+	},
+	cli.BoolFlag{
+		Name:  "credential-store",
+		Usage: "store the keys in the credential store encrypted with a passphrase, instead of the config file",
+	},
+	cli.StringFlag{
+		Name:  "sts",
+		Usage: "login with STS for temporary credentials. Valid options are '[ldap, web-identity, client-grants, client-certificate]'",
+	},
+	cli.StringFlag{
+		Name:  "sts-endpoint",
+		Usage: "STS endpoint, defaults to the server of the alias",
+	},
+	cli.StringFlag{
+		Name:  "sts-username",
+		Usage: "LDAP username, the password is asked for or read from MC_STS_PASSWORD",
+	},
+	cli.StringFlag{
+		Name:  "sts-token-file",
+		Usage: "file holding the identity token of 'web-identity' and 'client-grants' logins",
+	},
+	cli.StringFlag{
+		Name:  "sts-token-command",
+		Usage: "command printing the identity token of 'web-identity' and 'client-grants' logins",
+	},
+	cli.StringFlag{
+		Name:  "sts-cert",
+		Usage: "client certificate of 'client-certificate' logins",
+	},
+	cli.StringFlag{
+		Name:  "sts-key",
+		Usage: "private key of the client certificate of 'client-certificate' logins",
+	},
+	cli.StringFlag{
+		Name:  "sts-role-arn",
+		Usage: "role of 'web-identity' logins",
+	},
+	cli.StringFlag{
+		Name:  "sts-duration",
+		Usage: "requested validity of the temporary credentials, e.g. '1h'",
 	},
 }
 
@@ -96,6 +162,33 @@ EXAMPLES:
      {{.Prompt}} echo -e "BKIKJAA5BMMU2RHO6IBB\nV8f1CwQqAcwo80UEIJEjc5gVQUSSx5ohQ9GSrr12" | \
                  {{.HelpName}} mys3 https://s3.amazonaws.com --api "s3v4" --path "off"
      {{.EnableHistory}}
+  6. Add the folder "/srv/data" of an SFTP server under "mysftp" alias, authenticating with the SSH agent
+     or the keys of the user. Pass the password as the secret key if required.
+     {{.Prompt}} {{.HelpName}} mysftp sftp://backup@sftp.example.com:2222/srv/data backup ""
+  7. Add Amazon S3 storage service under "mys3" alias, the credentials are printed by a command in the JSON
+     format of the AWS CLI 'credential_process' and retrieved again when they expire.
+     {{.Prompt}} {{.HelpName}} mys3 https://s3.amazonaws.com --credential-process "vault-s3-creds --role backup"
+  8. Add Amazon S3 storage service under "mys3" alias, with the credentials of the "prod" profile of
+     the shared credentials file '~/.aws/credentials'.
+     {{.Prompt}} {{.HelpName}} mys3 https://s3.amazonaws.com --credential-profile prod
+  9. Add MinIO service under "myminio" alias, with the credentials in the environment variables
+     MYMINIO_ACCESS_KEY_ID and MYMINIO_SECRET_ACCESS_KEY.
+     {{.Prompt}} {{.HelpName}} myminio http://localhost:9000 --credential-env MYMINIO_
+ 10. Add MinIO service under "myminio" alias keeping its keys in the credential store, encrypted with
+     the passphrase asked for or set in MC_CREDENTIALS_PASSPHRASE.
+     {{.Prompt}} {{.HelpName}} myminio http://localhost:9000 --credential-store
+     Enter Access Key: minio
+     Enter Secret Key: minio123
+     Enter passphrase of the credential store:
+ 11. Add MinIO service under "myminio" alias logging in with an LDAP account, the temporary credentials
+     are renewed when they expire and 'mc alias login myminio' asks for the password again.
+     {{.Prompt}} {{.HelpName}} myminio https://minio.example.com --sts ldap --sts-username alice
+     Enter LDAP password of alice:
+ 12. Add MinIO service under "myminio" alias logging in with the token of an identity provider.
+     {{.Prompt}} {{.HelpName}} myminio https://minio.example.com --sts web-identity \
+           --sts-token-command "oidc-token myidp" --sts-duration 12h
+ 13. Add the prefix "prod/2024" of the bucket "logs" under "logs" alias, 'mc ls logs/' lists the prefix.
+     {{.Prompt}} {{.HelpName}} logs https://minio.example.com --bucket logs --prefix prod/2024
 `,
 }
 
@@ -127,19 +220,54 @@ func checkAliasSetSyntax(ctx *cli.Context, accessKey, secretKey string, deprecat
 		fatalIf(errInvalidURL(url), "Invalid URL.")
 	}
 
-	if !isValidAccessKey(accessKey) {
-		fatalIf(errInvalidArgument().Trace(accessKey),
-			"Invalid access key `"+accessKey+"`.")
+	creds, err := aliasCredentialsFromContext(ctx)
+	fatalIf(err.Trace(alias), "Invalid credential provider.")
+	if creds != nil && creds.Provider != credentialStore && argsNr > 2 {
+		fatalIf(errInvalidArgument().Trace(ctx.Args().Tail()...),
+			"Access and secret keys cannot be set with a credential provider.")
+	}
+
+	if isSFTP := newClientURL(url).Type == sftpStorage; isSFTP || strings.EqualFold(api, sftpScheme) {
+		// SFTP aliases hold the user name and the password if any.
+		if !isSFTP || (api != "" && !strings.EqualFold(api, sftpScheme)) {
+			fatalIf(errInvalidArgument().Trace(url, api),
+				"SFTP aliases require an `sftp://` URL and the `sftp` API.")
+		}
+		if creds != nil {
+			fatalIf(errInvalidArgument().Trace(url),
+				"Credential providers are not supported by SFTP aliases.")
+		}
+	} else {
+		if !isValidAccessKey(accessKey) {
+			fatalIf(errInvalidArgument().Trace(accessKey),
+				"Invalid access key `"+accessKey+"`.")
+		}
+
+		if !isValidSecretKey(secretKey) {
+			fatalIf(errInvalidArgument().Trace(secretKey),
+				"Invalid secret key `"+secretKey+"`.")
+		}
 	}
 
-	if !isValidSecretKey(secretKey) {
-		fatalIf(errInvalidArgument().Trace(secretKey),
-			"Invalid secret key `"+secretKey+"`.")
+	if bucket, prefix := ctx.String("bucket"), ctx.String("prefix"); bucket != "" || prefix != "" {
+		if newClientURL(url).Type == sftpStorage {
+			fatalIf(errInvalidArgument().Trace(url),
+				"SFTP aliases cannot be confined to a bucket.")
+		}
+		if bucket == "" {
+			fatalIf(errInvalidArgument().Trace(prefix), "A prefix requires `--bucket`.")
+		}
+		if e := s3utils.CheckValidBucketName(bucket); e != nil {
+			fatalIf(probe.NewError(e).Trace(bucket), "Invalid bucket `"+bucket+"`.")
+		}
+		if isEscapingPath(prefix) {
+			fatalIf(errInvalidArgument().Trace(prefix), "Invalid prefix `"+prefix+"`.")
+		}
 	}
 
 	if api != "" && !isValidAPI(api) { // Empty value set to default "S3v4".
 		fatalIf(errInvalidArgument().Trace(api),
-			"Unrecognized API signature. Valid options are `[S3v4, S3v2]`.")
+			"Unrecognized API signature. Valid options are `[S3v4, S3v2, sftp]`.")
 	}
 
 	if deprecated {
@@ -160,6 +288,20 @@ func setAlias(alias string, aliasCfgV10 aliasConfigV10) aliasMessage {
 	mcCfgV10, err := loadMcConfig()
 	fatalIf(err.Trace(globalMCConfigVersion), "Unable to load config `"+mustGetMcConfigPath()+"`.")
 
+	// Keep the bandwidth limits, HTTP settings and endpoints, they are
+	// only set in the config file.
+	if prevCfg, ok := mcCfgV10.Aliases[alias]; ok {
+		if aliasCfgV10.Limit == nil {
+			aliasCfgV10.Limit = prevCfg.Limit
+		}
+		if aliasCfgV10.Transport == nil {
+			aliasCfgV10.Transport = prevCfg.Transport
+		}
+		if aliasCfgV10.Endpoints == nil && aliasCfgV10.URL == prevCfg.URL {
+			aliasCfgV10.Endpoints = prevCfg.Endpoints
+		}
+	}
+
 	// Add new host.
 	mcCfgV10.Aliases[alias] = aliasCfgV10
 
@@ -173,6 +315,8 @@ func setAlias(alias string, aliasCfgV10 aliasConfigV10) aliasMessage {
 		SecretKey: aliasCfgV10.SecretKey,
 		API:       aliasCfgV10.API,
 		Path:      aliasCfgV10.Path,
+		Bucket:    aliasCfgV10.Bucket,
+		Prefix:    aliasCfgV10.Prefix,
 	}
 }
 
@@ -264,6 +408,51 @@ func BuildS3Config(ctx context.Context, url, accessKey, secretKey, api, path str
 	return s3Config, nil
 }
 
+// aliasCredentialsFromContext returns the credential provider set on
+// the command line, if any.
+func aliasCredentialsFromContext(ctx *cli.Context) (*aliasCredentialsV10, *probe.Error) {
+	var providers []*aliasCredentialsV10
+	if command := ctx.String("credential-process"); command != "" {
+		providers = append(providers, &aliasCredentialsV10{Provider: credentialProcess, Command: command})
+	}
+	if file, profile := ctx.String("credential-file"), ctx.String("credential-profile"); file != "" || profile != "" {
+		providers = append(providers, &aliasCredentialsV10{Provider: credentialFile, File: file, Profile: profile})
+	}
+	if prefix := ctx.String("credential-env"); prefix != "" {
+		providers = append(providers, &aliasCredentialsV10{Provider: credentialEnv, Prefix: prefix})
+	}
+	if ctx.Bool("credential-store") {
+		providers = append(providers, &aliasCredentialsV10{Provider: credentialStore})
+	}
+	if provider := ctx.String("sts"); provider != "" {
+		if !isSTSProvider(provider) {
+			return nil, probe.NewError(fmt.Errorf("unknown STS provider `%s`, valid options are "+
+				"`[ldap, web-identity, client-grants, client-certificate]`", provider))
+		}
+		providers = append(providers, &aliasCredentialsV10{
+			Provider:  provider,
+			Endpoint:  ctx.String("sts-endpoint"),
+			Username:  ctx.String("sts-username"),
+			TokenFile: ctx.String("sts-token-file"),
+			Command:   ctx.String("sts-token-command"),
+			CertFile:  ctx.String("sts-cert"),
+			KeyFile:   ctx.String("sts-key"),
+			RoleARN:   ctx.String("sts-role-arn"),
+			Duration:  ctx.String("sts-duration"),
+		})
+	}
+	switch len(providers) {
+	case 0:
+		return nil, nil
+	case 1:
+		if err := validateAliasCredentials(providers[0]); err != nil {
+			return nil, err.Trace()
+		}
+		return providers[0], nil
+	}
+	return nil, probe.NewError(errors.New("only one credential provider can be set"))
+}
+
 // fetchAliasKeys - returns the user accessKey and secretKey
 func fetchAliasKeys(args cli.Args) (string, string) {
 	accessKey := ""
@@ -328,27 +517,80 @@ func mainAliasSet(cli *cli.Context, deprecated bool) error {
 		}
 	}
 
-	accessKey, secretKey := fetchAliasKeys(args)
+	// Keys of other credential providers are not asked for.
+	var accessKey, secretKey string
+	creds, _ := aliasCredentialsFromContext(cli)
+	if creds == nil || creds.Provider == credentialStore {
+		accessKey, secretKey = fetchAliasKeys(args)
+	}
 	checkAliasSetSyntax(cli, accessKey, secretKey, deprecated)
 
 	ctx, cancelAliasAdd := context.WithCancel(globalContext)
 	defer cancelAliasAdd()
 
-	if !globalInsecure && !globalJSON && term.IsTerminal(int(os.Stdout.Fd())) {
-		peerCert, err = promptTrustSelfSignedCert(ctx, url, alias)
+	var aliasCfg aliasConfigV10
+	if newClientURL(url).Type == sftpStorage {
+		aliasCfg = aliasConfigV10{
+			URL:       url,
+			AccessKey: accessKey,
+			SecretKey: secretKey,
+			API:       sftpScheme,
+		}
+		// Check the server accepts the credentials.
+		_, err = sftpNew(url, &aliasCfg)
+		fatalIf(err.Trace(alias, url, accessKey), "Unable to initialize new alias from the provided credentials.")
+	} else if creds != nil && creds.Provider != credentialStore {
+		// Check the credentials are provided, they are only retrieved
+		// when used later on.
+		if api == "" {
+			api = "S3v4"
+		}
+		if !globalInsecure && !globalJSON && term.IsTerminal(int(os.Stdout.Fd())) {
+			_, err = promptTrustSelfSignedCert(ctx, url, alias)
+			fatalIf(err.Trace(alias, url), "Unable to initialize new alias from the provided credentials.")
+		}
+		if isSTSProvider(creds.Provider) {
+			// Login again, the alias may have changed.
+			fatalIf(removeSTSSession(alias).Trace(alias), "Unable to remove the temporary credentials of the alias.")
+		}
+		_, e := credentialsValue(alias, url, creds, api)
+		fatalIf(probe.NewError(e).Trace(alias), "Unable to retrieve the credentials of the alias.")
+		aliasCfg = aliasConfigV10{
+			URL:         url,
+			API:         api,
+			Path:        path,
+			Credentials: creds,
+		}
+	} else {
+		if !globalInsecure && !globalJSON && term.IsTerminal(int(os.Stdout.Fd())) {
+			peerCert, err = promptTrustSelfSignedCert(ctx, url, alias)
+			fatalIf(err.Trace(alias, url, accessKey), "Unable to initialize new alias from the provided credentials.")
+		}
+
+		s3Config, err := BuildS3Config(ctx, url, accessKey, secretKey, api, path, peerCert)
 		fatalIf(err.Trace(alias, url, accessKey), "Unable to initialize new alias from the provided credentials.")
+
+		aliasCfg = aliasConfigV10{
+			URL:       s3Config.HostURL,
+			AccessKey: s3Config.AccessKey,
+			SecretKey: s3Config.SecretKey,
+			API:       s3Config.Signature,
+			Path:      path,
+		}
+		if creds != nil {
+			err = setCredentialStoreEntry(alias, credentialStoreEntry{AccessKey: accessKey, SecretKey: secretKey})
+			fatalIf(err.Trace(alias), "Unable to save the keys in the credential store.")
+			aliasCfg.AccessKey, aliasCfg.SecretKey = "", ""
+			aliasCfg.Credentials = creds
+		}
 	}
 
-	s3Config, err := BuildS3Config(ctx, url, accessKey, secretKey, api, path, peerCert)
-	fatalIf(err.Trace(alias, url, accessKey), "Unable to initialize new alias from the provided credentials.")
+	if bucket := cli.String("bucket"); bucket != "" {
+		aliasCfg.Bucket = bucket
+		aliasCfg.Prefix = strings.Trim(cli.String("prefix"), "/")
+	}
 
-	msg := setAlias(alias, aliasConfigV10{
-		URL:       s3Config.HostURL,
-		AccessKey: s3Config.AccessKey,
-		SecretKey: s3Config.SecretKey,
-		API:       s3Config.Signature,
-		Path:      path,
-	}) // Add an alias with specified credentials.
+	msg := setAlias(alias, aliasCfg) // Add an alias with specified credentials.
 
 	msg.op = "set"
 	if deprecated {
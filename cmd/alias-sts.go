@@ -0,0 +1,409 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/trinet2005/oss-go-sdk/pkg/credentials"
+	"github.com/trinet2005/oss-mc/pkg/probe"
+	"golang.org/x/term"
+)
+
+// STS providers obtain temporary credentials from the server, only the
+// way to log in is saved in the config file. The temporary credentials
+// are cached under `<configDir>/sts` until they expire, a new login is
+// done transparently when possible, `mc alias login` forces one.
+const (
+	stsLDAP              = "ldap"
+	stsWebIdentity       = "web-identity"
+	stsClientGrants      = "client-grants"
+	stsClientCertificate = "client-certificate"
+
+	// Environment variable holding the LDAP password.
+	stsPasswordEnv = "MC_STS_PASSWORD"
+
+	stsSessionDir = "sts"
+
+	// Validity assumed when the server does not send an expiration.
+	stsDefaultValidity = 15 * time.Minute
+
+	// Cached credentials expiring sooner are not used.
+	stsMinValidity = 2 * time.Minute
+)
+
+func isSTSProvider(provider string) bool {
+	switch provider {
+	case stsLDAP, stsWebIdentity, stsClientGrants, stsClientCertificate:
+		return true
+	}
+	return false
+}
+
+// stsSession - temporary credentials of an alias.
+type stsSession struct {
+	Source       string    `json:"source"`
+	AccessKey    string    `json:"accessKey"`
+	SecretKey    string    `json:"secretKey"`
+	SessionToken string    `json:"sessionToken"`
+	Expiration   time.Time `json:"expiration"`
+}
+
+// LDAP passwords entered on the terminal, asked once.
+var stsPasswords = struct {
+	sync.Mutex
+	values map[string]string
+}{values: make(map[string]string)}
+
+// Serializes logins so a single one is done when several clients
+// find the credentials expired at once.
+var stsLoginMutex sync.Mutex
+
+// stsSource identifies the login an STS session comes from, a session
+// is thrown away when the alias changes.
+func stsSource(endpoint string, c *aliasCredentialsV10) string {
+	sum := sha256.Sum256([]byte(endpoint + "\x00" + credentialsKey(c)))
+	return hex.EncodeToString(sum[:])
+}
+
+// stsEndpoint returns the STS endpoint of an alias, its server unless
+// the provider has its own.
+func stsEndpoint(hostURL string, c *aliasCredentialsV10) string {
+	if c.Endpoint != "" {
+		return strings.TrimSuffix(c.Endpoint, "/")
+	}
+	u := newClientURL(hostURL)
+	return u.Scheme + "://" + u.Host
+}
+
+func getSTSSessionPath(alias string) (string, *probe.Error) {
+	dir, err := getMcConfigDir()
+	if err != nil {
+		return "", err.Trace()
+	}
+	return filepath.Join(dir, stsSessionDir, alias+".json"), nil
+}
+
+// loadSTSSession reads the cached credentials of an alias, nil when
+// there are none.
+func loadSTSSession(alias string) (*stsSession, *probe.Error) {
+	sessionPath, err := getSTSSessionPath(alias)
+	if err != nil {
+		return nil, err.Trace(alias)
+	}
+	data, e := os.ReadFile(sessionPath)
+	if e != nil {
+		if os.IsNotExist(e) {
+			return nil, nil
+		}
+		return nil, probe.NewError(e).Trace(sessionPath)
+	}
+	session := &stsSession{}
+	if e = json.Unmarshal(data, session); e != nil {
+		return nil, probe.NewError(e).Trace(sessionPath)
+	}
+	return session, nil
+}
+
+func saveSTSSession(alias string, session *stsSession) *probe.Error {
+	sessionPath, err := getSTSSessionPath(alias)
+	if err != nil {
+		return err.Trace(alias)
+	}
+	if e := os.MkdirAll(filepath.Dir(sessionPath), 0o700); e != nil {
+		return probe.NewError(e).Trace(sessionPath)
+	}
+	data, e := json.Marshal(session)
+	if e != nil {
+		return probe.NewError(e)
+	}
+	tmpPath := sessionPath + ".tmp"
+	if e = os.WriteFile(tmpPath, data, 0o600); e != nil {
+		return probe.NewError(e).Trace(tmpPath)
+	}
+	if e = os.Rename(tmpPath, sessionPath); e != nil {
+		os.Remove(tmpPath)
+		return probe.NewError(e).Trace(sessionPath)
+	}
+	return nil
+}
+
+// removeSTSSession removes the cached credentials of an alias.
+func removeSTSSession(alias string) *probe.Error {
+	sessionPath, err := getSTSSessionPath(alias)
+	if err != nil {
+		return err.Trace(alias)
+	}
+	if e := os.Remove(sessionPath); e != nil && !os.IsNotExist(e) {
+		return probe.NewError(e).Trace(sessionPath)
+	}
+	return nil
+}
+
+var stsExpirationRegexp = regexp.MustCompile(`<Expiration>([^<]+)</Expiration>`)
+
+// stsResponseRecorder keeps the expiration found in STS responses,
+// not all STS providers of the SDK report it.
+type stsResponseRecorder struct {
+	transport  http.RoundTripper
+	expiration time.Time
+}
+
+func (r *stsResponseRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, e := r.transport.RoundTrip(req)
+	if e != nil || resp.StatusCode != http.StatusOK {
+		return resp, e
+	}
+	body, e := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if e != nil {
+		return nil, e
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if m := stsExpirationRegexp.FindSubmatch(body); m != nil {
+		if t, e := time.Parse(time.RFC3339, strings.TrimSpace(string(m[1]))); e == nil {
+			r.expiration = t
+		}
+	}
+	return resp, nil
+}
+
+// newSTSTransport returns the transport used to talk to the STS
+// endpoint, it uses the HTTP settings of the alias like its S3 clients.
+// The certificate of `client-certificate` logins replaces the client
+// certificate of the alias.
+func newSTSTransport(alias string, certificates []tls.Certificate) (http.RoundTripper, *probe.Error) {
+	config := &Config{
+		ConnReadDeadline:  globalConnReadDeadline,
+		ConnWriteDeadline: globalConnWriteDeadline,
+	}
+	if hostCfg := mustGetHostConfig(alias); hostCfg != nil {
+		config.AliasTransport = hostCfg.Transport
+	}
+	aliasTr, err := newAliasTransport(config)
+	if err != nil {
+		return nil, err.Trace(alias)
+	}
+	tlsConfig := aliasTr.tlsConfig(globalInsecure)
+	if len(certificates) > 0 {
+		tlsConfig.Certificates = certificates
+	}
+	return aliasTr.roundTripper(&http.Transport{
+		Proxy:               aliasTr.proxyFunc(http.ProxyFromEnvironment),
+		DialContext:         newCustomDialContext(aliasTr),
+		TLSHandshakeTimeout: 10 * time.Second,
+		TLSClientConfig:     tlsConfig,
+	}), nil
+}
+
+// getSTSPassword returns the LDAP password of an alias from
+// MC_STS_PASSWORD, or asks for it on a terminal. The password is
+// asked again when interactive is set.
+func getSTSPassword(alias, username string, interactive bool) (string, *probe.Error) {
+	isTerminal := term.IsTerminal(int(os.Stdin.Fd()))
+	if password := os.Getenv(stsPasswordEnv); password != "" && !(interactive && isTerminal) {
+		return password, nil
+	}
+	stsPasswords.Lock()
+	defer stsPasswords.Unlock()
+	if password, ok := stsPasswords.values[alias]; ok && !interactive {
+		return password, nil
+	}
+	if !isTerminal {
+		return "", probe.NewError(fmt.Errorf("LDAP password of `%s` required, run `mc alias login %s` or set `%s`",
+			alias, alias, stsPasswordEnv))
+	}
+	fmt.Fprintf(os.Stderr, "Enter LDAP password of %s: ", username)
+	password, e := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if e != nil {
+		return "", probe.NewError(e)
+	}
+	if len(password) == 0 {
+		return "", probe.NewError(errors.New("empty LDAP password"))
+	}
+	stsPasswords.values[alias] = string(password)
+	return string(password), nil
+}
+
+// readSTSToken returns the identity token of web-identity and
+// client-grants logins, from a file or printed by a command.
+func readSTSToken(c *aliasCredentialsV10) (string, error) {
+	var token []byte
+	var e error
+	if c.TokenFile != "" {
+		token, e = os.ReadFile(c.TokenFile)
+	} else {
+		token, e = runCredentialCommand(c.Command)
+	}
+	if e != nil {
+		return "", e
+	}
+	if len(bytes.TrimSpace(token)) == 0 {
+		return "", errors.New("empty identity token")
+	}
+	return string(bytes.TrimSpace(token)), nil
+}
+
+// stsLogin obtains new temporary credentials for an alias and caches
+// them, interactive asks for the LDAP password again.
+func stsLogin(alias, hostURL string, c *aliasCredentialsV10, interactive bool) (*stsSession, *probe.Error) {
+	var duration time.Duration
+	if c.Duration != "" {
+		var e error
+		if duration, e = time.ParseDuration(c.Duration); e != nil {
+			return nil, probe.NewError(e).Trace(c.Duration)
+		}
+	}
+	endpoint := stsEndpoint(hostURL, c)
+
+	var certificates []tls.Certificate
+	if c.Provider == stsClientCertificate {
+		certificate, e := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if e != nil {
+			return nil, probe.NewError(e).Trace(c.CertFile, c.KeyFile)
+		}
+		certificates = append(certificates, certificate)
+	}
+	transport, err := newSTSTransport(alias, certificates)
+	if err != nil {
+		return nil, err.Trace(alias)
+	}
+	recorder := &stsResponseRecorder{transport: transport}
+
+	var provider credentials.Provider
+	switch c.Provider {
+	case stsLDAP:
+		password, err := getSTSPassword(alias, c.Username, interactive)
+		if err != nil {
+			return nil, err.Trace(alias)
+		}
+		provider = &credentials.LDAPIdentity{
+			Client:          &http.Client{Transport: recorder},
+			STSEndpoint:     endpoint,
+			LDAPUsername:    c.Username,
+			LDAPPassword:    password,
+			RequestedExpiry: duration,
+		}
+	case stsWebIdentity:
+		provider = &credentials.STSWebIdentity{
+			Client:      &http.Client{Transport: recorder},
+			STSEndpoint: endpoint,
+			RoleARN:     c.RoleARN,
+			GetWebIDTokenExpiry: func() (*credentials.WebIdentityToken, error) {
+				token, e := readSTSToken(c)
+				if e != nil {
+					return nil, e
+				}
+				return &credentials.WebIdentityToken{Token: token, Expiry: int(duration.Seconds())}, nil
+			},
+		}
+	case stsClientGrants:
+		provider = &credentials.STSClientGrants{
+			Client:      &http.Client{Transport: recorder},
+			STSEndpoint: endpoint,
+			GetClientGrantsTokenExpiry: func() (*credentials.ClientGrantsToken, error) {
+				token, e := readSTSToken(c)
+				if e != nil {
+					return nil, e
+				}
+				return &credentials.ClientGrantsToken{Token: token, Expiry: int(duration.Seconds())}, nil
+			},
+		}
+	case stsClientCertificate:
+		provider = &credentials.STSCertificateIdentity{
+			Client:               http.Client{Transport: recorder},
+			STSEndpoint:          endpoint,
+			S3CredentialLivetime: duration,
+		}
+	default:
+		return nil, probe.NewError(fmt.Errorf("`%s` is not an STS provider", c.Provider))
+	}
+
+	value, e := provider.Retrieve()
+	if e != nil {
+		return nil, probe.NewError(fmt.Errorf("unable to login to `%s`: %w", endpoint, e)).Trace(alias)
+	}
+	session := &stsSession{
+		Source:       stsSource(endpoint, c),
+		AccessKey:    value.AccessKeyID,
+		SecretKey:    value.SecretAccessKey,
+		SessionToken: value.SessionToken,
+		Expiration:   recorder.expiration,
+	}
+	if session.Expiration.IsZero() {
+		validity := duration
+		if validity == 0 {
+			validity = stsDefaultValidity
+		}
+		session.Expiration = time.Now().Add(validity)
+	}
+	if err := saveSTSSession(alias, session); err != nil {
+		return nil, err.Trace(alias)
+	}
+	return session, nil
+}
+
+// stsCredentials - provider of the temporary credentials of an alias,
+// they are read from the cache or obtained by a new login once expired.
+type stsCredentials struct {
+	credentials.Expiry
+	alias   string
+	hostURL string
+	cfg     *aliasCredentialsV10
+}
+
+func newSTSCredentials(alias, hostURL string, c *aliasCredentialsV10) *credentials.Credentials {
+	return credentials.New(&stsCredentials{alias: alias, hostURL: hostURL, cfg: c})
+}
+
+func (s *stsCredentials) Retrieve() (credentials.Value, error) {
+	stsLoginMutex.Lock()
+	defer stsLoginMutex.Unlock()
+
+	session, err := loadSTSSession(s.alias)
+	if err != nil || session == nil ||
+		session.Source != stsSource(stsEndpoint(s.hostURL, s.cfg), s.cfg) ||
+		time.Until(session.Expiration) < stsMinValidity {
+		session, err = stsLogin(s.alias, s.hostURL, s.cfg, false)
+		if err != nil {
+			return credentials.Value{}, err.ToGoError()
+		}
+	}
+	s.SetExpiration(session.Expiration, credentials.DefaultExpiryWindow)
+	return credentials.Value{
+		AccessKeyID:     session.AccessKey,
+		SecretAccessKey: session.SecretKey,
+		SessionToken:    session.SessionToken,
+		SignerType:      credentials.SignatureV4,
+	}, nil
+}
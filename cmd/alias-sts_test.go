@@ -0,0 +1,172 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/trinet2005/oss-mc/pkg/probe"
+	. "gopkg.in/check.v1"
+)
+
+// newTestSTSServer answers STS logins with keys numbered by the times
+// it was called, valid for an hour. LDAP logins need the password
+// "secret", web identity logins the token "token".
+func newTestSTSServer(calls *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if e := r.ParseForm(); e != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		action := r.Form.Get("Action")
+		switch {
+		case action == "AssumeRoleWithLDAPIdentity" && r.Form.Get("LDAPPassword") == "secret":
+		case action == "AssumeRoleWithWebIdentity" && r.Form.Get("WebIdentityToken") == "token":
+		default:
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `<ErrorResponse><Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error></ErrorResponse>`)
+			return
+		}
+		n := atomic.AddInt32(calls, 1)
+		expiration := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+		fmt.Fprintf(w, `<%[1]sResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/"><%[1]sResult><Credentials><AccessKeyId>access%[2]d</AccessKeyId>`+
+			`<SecretAccessKey>secret%[2]d</SecretAccessKey><SessionToken>token%[2]d</SessionToken>`+
+			`<Expiration>%[3]s</Expiration></Credentials></%[1]sResult></%[1]sResponse>`, action, n, expiration)
+	}))
+}
+
+func (s *TestSuite) TestSTSCredentials(c *C) {
+	defer func(dir string) { mcCustomConfigDir = dir }(mcCustomConfigDir)
+	mcCustomConfigDir = c.MkDir()
+	defer func(load func() (*configV10, *probe.Error)) { loadMcConfig = load }(loadMcConfig)
+	loadMcConfig = func() (*configV10, *probe.Error) { return newMcConfig(), nil }
+	os.Setenv(stsPasswordEnv, "secret")
+	defer os.Unsetenv(stsPasswordEnv)
+
+	var calls int32
+	server := newTestSTSServer(&calls)
+	defer server.Close()
+
+	cfg := &aliasCredentialsV10{Provider: stsLDAP, Username: "alice"}
+	c.Assert(validateAliasCredentials(cfg), IsNil)
+
+	value, e := newSTSCredentials("ldap", server.URL, cfg).Get()
+	c.Assert(e, IsNil)
+	c.Assert(value.AccessKeyID, Equals, "access1")
+	c.Assert(value.SessionToken, Equals, "token1")
+
+	// The temporary credentials are cached with their expiration.
+	session, err := loadSTSSession("ldap")
+	c.Assert(err, IsNil)
+	c.Assert(session.AccessKey, Equals, "access1")
+	c.Assert(session.Expiration.After(time.Now().Add(50*time.Minute)), Equals, true)
+	fi, e := os.Stat(filepath.Join(mcCustomConfigDir, stsSessionDir, "ldap.json"))
+	c.Assert(e, IsNil)
+	c.Assert(fi.Mode().Perm(), Equals, os.FileMode(0o600))
+
+	value, e = newSTSCredentials("ldap", server.URL, cfg).Get()
+	c.Assert(e, IsNil)
+	c.Assert(value.AccessKeyID, Equals, "access1")
+	c.Assert(atomic.LoadInt32(&calls), Equals, int32(1))
+
+	// Expired credentials are renewed.
+	session.Expiration = time.Now().Add(-time.Minute)
+	c.Assert(saveSTSSession("ldap", session), IsNil)
+	value, e = newSTSCredentials("ldap", server.URL, cfg).Get()
+	c.Assert(e, IsNil)
+	c.Assert(value.AccessKeyID, Equals, "access2")
+
+	// So are the credentials of another login.
+	other := &aliasCredentialsV10{Provider: stsLDAP, Username: "bob"}
+	value, e = newSTSCredentials("ldap", server.URL, other).Get()
+	c.Assert(e, IsNil)
+	c.Assert(value.AccessKeyID, Equals, "access3")
+
+	// A forced login does not use the cache.
+	session, err = stsLogin("ldap", server.URL, cfg, true)
+	c.Assert(err, IsNil)
+	c.Assert(session.AccessKey, Equals, "access4")
+
+	c.Assert(removeSTSSession("ldap"), IsNil)
+	session, err = loadSTSSession("ldap")
+	c.Assert(err, IsNil)
+	c.Assert(session, IsNil)
+
+	os.Setenv(stsPasswordEnv, "wrong")
+	_, e = newSTSCredentials("ldap", server.URL, cfg).Get()
+	c.Assert(e, NotNil)
+}
+
+func (s *TestSuite) TestSTSWebIdentity(c *C) {
+	defer func(dir string) { mcCustomConfigDir = dir }(mcCustomConfigDir)
+	mcCustomConfigDir = c.MkDir()
+	defer func(load func() (*configV10, *probe.Error)) { loadMcConfig = load }(loadMcConfig)
+	loadMcConfig = func() (*configV10, *probe.Error) { return newMcConfig(), nil }
+
+	var calls int32
+	server := newTestSTSServer(&calls)
+	defer server.Close()
+
+	tokenFile := filepath.Join(c.MkDir(), "token")
+	c.Assert(os.WriteFile(tokenFile, []byte("token\n"), 0o600), IsNil)
+
+	cfg := &aliasCredentialsV10{Provider: stsWebIdentity, TokenFile: tokenFile, Endpoint: server.URL, Duration: "1h"}
+	c.Assert(validateAliasCredentials(cfg), IsNil)
+	value, e := newSTSCredentials("oidc", "https://unused.example.com", cfg).Get()
+	c.Assert(e, IsNil)
+	c.Assert(value.AccessKeyID, Equals, "access1")
+
+	cfg = &aliasCredentialsV10{Provider: stsWebIdentity, Command: "echo token", Endpoint: server.URL}
+	session, err := stsLogin("oidc", "", cfg, false)
+	c.Assert(err, IsNil)
+	c.Assert(session.AccessKey, Equals, "access2")
+
+	cfg = &aliasCredentialsV10{Provider: stsWebIdentity, Command: "echo invalid", Endpoint: server.URL}
+	_, err = stsLogin("oidc", "", cfg, false)
+	c.Assert(err, NotNil)
+}
+
+func (s *TestSuite) TestValidateSTSCredentials(c *C) {
+	testCases := []struct {
+		cfg   aliasCredentialsV10
+		valid bool
+	}{
+		{aliasCredentialsV10{Provider: stsLDAP, Username: "alice"}, true},
+		{aliasCredentialsV10{Provider: stsLDAP}, false},
+		{aliasCredentialsV10{Provider: stsWebIdentity, TokenFile: "/tmp/token"}, true},
+		{aliasCredentialsV10{Provider: stsClientGrants, Command: "get-token"}, true},
+		{aliasCredentialsV10{Provider: stsWebIdentity}, false},
+		{aliasCredentialsV10{Provider: stsClientCertificate, CertFile: "cert.pem", KeyFile: "key.pem"}, true},
+		{aliasCredentialsV10{Provider: stsClientCertificate, CertFile: "cert.pem"}, false},
+		{aliasCredentialsV10{Provider: stsLDAP, Username: "alice", Duration: "1h"}, true},
+		{aliasCredentialsV10{Provider: stsLDAP, Username: "alice", Duration: "an hour"}, false},
+		{aliasCredentialsV10{Provider: stsLDAP, Username: "alice", Endpoint: "https://sts.example.com"}, true},
+		{aliasCredentialsV10{Provider: stsLDAP, Username: "alice", Endpoint: "sts.example.com"}, false},
+	}
+	for i, testCase := range testCases {
+		cfg := testCase.cfg
+		err := validateAliasCredentials(&cfg)
+		c.Assert(err == nil, Equals, testCase.valid, Commentf("Test %d: %v", i+1, err))
+	}
+}
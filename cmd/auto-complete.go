@@ -240,6 +240,8 @@ var completeCmds = map[string]complete.Predictor{
 	"/diff":      complete.PredictOr(s3Completer, fsCompleter),
 	"/find":      complete.PredictOr(s3Completer, fsCompleter),
 	"/mirror":    complete.PredictOr(s3Completer, fsCompleter),
+	"/sync":      complete.PredictOr(s3Completer, fsCompleter),
+	"/serve":     complete.PredictOr(s3Completer, fsCompleter),
 	"/pipe":      complete.PredictOr(s3Completer, fsCompleter),
 	"/stat":      complete.PredictOr(s3Completer, fsCompleter),
 	"/watch":     complete.PredictOr(s3Completer, fsCompleter),
@@ -463,6 +465,17 @@ var completeCmds = map[string]complete.Predictor{
 	"/alias/list":   aliasCompleter,
 	"/alias/remove": aliasCompleter,
 	"/alias/import": nil,
+	"/alias/login":  aliasCompleter,
+
+	"/session/list":   nil,
+	"/session/resume": nil,
+	"/session/clear":  nil,
+
+	"/bucket/apply":  s3Complete{deepLevel: 2},
+	"/bucket/export": s3Complete{deepLevel: 2},
+
+	"/inventory/generate": complete.PredictOr(s3Completer, fsCompleter),
+	"/inventory/verify":   complete.PredictOr(s3Completer, fsCompleter),
 
 	"/support/callhome":     aliasCompleter,
 	"/support/register":     aliasCompleter,
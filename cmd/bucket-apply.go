@@ -0,0 +1,208 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/minio/cli"
+	"github.com/trinet2005/oss-mc/pkg/probe"
+	"github.com/trinet2005/oss-pkg/console"
+)
+
+var bucketApplyFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "file, f",
+		Usage: "path of the YAML or JSON bucket spec, '-' reads STDIN",
+	},
+	cli.BoolFlag{
+		Name:  "dry-run",
+		Usage: "only show the plan, do not change the bucket",
+	},
+}
+
+var bucketApplyCmd = cli.Command{
+	Name:         "apply",
+	Usage:        "apply a declarative configuration to a bucket",
+	Action:       mainBucketApply,
+	OnUsageError: onUsageError,
+	Before:       setGlobalsFromContext,
+	Flags:        append(bucketApplyFlags, globalFlags...),
+	CustomHelpTemplate: `NAME:
+  {{.HelpName}} - {{.Usage}}
+
+USAGE:
+  {{.HelpName}} [FLAGS] TARGET
+
+FLAGS:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}
+DESCRIPTION:
+  Compares the live configuration of a bucket with a spec, shows the plan
+  and applies only the differences. The bucket is created if it does not
+  exist. Sections missing from the spec are left untouched, empty sections
+  remove the configuration:
+
+    objectLock: true          # only when the bucket is created
+    versioning:
+      status: Enabled
+      excludedPrefixes: [tmp/]
+    retention:
+      mode: GOVERNANCE
+      validity: 30d
+    encryption:
+      algorithm: sse-kms
+      kmsKeyId: my-key
+    anonymous:
+      access: download        # private, download, upload, public or custom with a policy
+    tags:
+      team: analytics
+    quota:
+      size: 1TiB
+    events:
+      - arn: arn:minio:sqs::primary:webhook
+        events: [put, delete]
+        suffix: .csv
+    lifecycle: {}             # as exported by 'mc ilm rule export'
+    replication: {}           # as exported by 'mc replicate export'
+
+EXAMPLES:
+  1. Show the changes 'bucket.yaml' would make to 'mybucket'.
+     {{.Prompt}} {{.HelpName}} --dry-run -f bucket.yaml myminio/mybucket
+
+  2. Apply 'bucket.yaml' to 'mybucket'.
+     {{.Prompt}} {{.HelpName}} -f bucket.yaml myminio/mybucket
+
+  3. Copy the configuration of a bucket to another one.
+     {{.Prompt}} mc bucket export myminio/mybucket | {{.HelpName}} -f - myminio/newbucket
+`,
+}
+
+// bucketPlanMessage - the changes applied, or to apply, to a bucket.
+type bucketPlanMessage struct {
+	Status  string         `json:"status"`
+	URL     string         `json:"url"`
+	Create  bool           `json:"create,omitempty"`
+	Changes []bucketChange `json:"changes"`
+	DryRun  bool           `json:"dryRun,omitempty"`
+}
+
+func (b bucketPlanMessage) String() string {
+	if !b.Create && len(b.Changes) == 0 {
+		return console.Colorize("BucketApplyNoChange", fmt.Sprintf("`%s` is up to date.", b.URL))
+	}
+	var add, modify, remove int
+	var lines []string
+	if b.Create {
+		lines = append(lines, console.Colorize("BucketApplyAdd", fmt.Sprintf("+ bucket: %s", b.URL)))
+	}
+	for _, change := range b.Changes {
+		switch change.Action {
+		case bucketChangeAdd:
+			add++
+			lines = append(lines, console.Colorize("BucketApplyAdd", fmt.Sprintf("+ %s: %s", change.Section, change.To)))
+		case bucketChangeRemove:
+			remove++
+			lines = append(lines, console.Colorize("BucketApplyRemove", fmt.Sprintf("- %s: %s", change.Section, change.From)))
+		default:
+			modify++
+			lines = append(lines, console.Colorize("BucketApplyModify", fmt.Sprintf("~ %s: %s => %s", change.Section, change.From, change.To)))
+		}
+	}
+	summary := fmt.Sprintf("Applied %d additions, %d modifications and %d removals to `%s`.", add, modify, remove, b.URL)
+	if b.DryRun {
+		summary = fmt.Sprintf("Plan: %d to add, %d to modify and %d to remove on `%s`.", add, modify, remove, b.URL)
+	}
+	return strings.Join(lines, "\n") + "\n" + console.Colorize("BucketApplySummary", summary)
+}
+
+func (b bucketPlanMessage) JSON() string {
+	b.Status = "success"
+	if b.Changes == nil {
+		b.Changes = []bucketChange{}
+	}
+	buf, e := json.MarshalIndent(b, "", " ")
+	fatalIf(probe.NewError(e), "Unable to marshal into JSON.")
+	return string(buf)
+}
+
+// checkBucketApplySyntax - validate all the passed arguments
+func checkBucketApplySyntax(ctx *cli.Context) {
+	if len(ctx.Args()) != 1 || ctx.String("file") == "" {
+		showCommandHelpAndExit(ctx, 1) // last argument is exit code
+	}
+}
+
+func mainBucketApply(cliCtx *cli.Context) error {
+	ctx, cancelBucketApply := context.WithCancel(globalContext)
+	defer cancelBucketApply()
+
+	console.SetColor("BucketApplyAdd", color.New(color.FgGreen))
+	console.SetColor("BucketApplyModify", color.New(color.FgYellow))
+	console.SetColor("BucketApplyRemove", color.New(color.FgRed))
+	console.SetColor("BucketApplySummary", color.New(color.Bold))
+	console.SetColor("BucketApplyNoChange", color.New(color.FgGreen))
+
+	checkBucketApplySyntax(cliCtx)
+
+	aliasedURL := cliCtx.Args().Get(0)
+	dryRun := cliCtx.Bool("dry-run")
+
+	spec, err := readBucketSpec(cliCtx.String("file"))
+	fatalIf(err, "Unable to read the bucket spec.")
+
+	client, err := newClient(aliasedURL)
+	fatalIf(err, "Unable to initialize connection.")
+
+	msg := bucketPlanMessage{URL: aliasedURL, DryRun: dryRun}
+	_, err = client.Stat(ctx, StatOptions{})
+	if err != nil {
+		if _, ok := err.ToGoError().(BucketDoesNotExist); !ok {
+			fatalIf(err, "Unable to check the bucket.")
+		}
+		msg.Create = true
+	}
+
+	// A new bucket has no configuration yet.
+	live := &bucketSpec{}
+	if !msg.Create {
+		live, err = getBucketSpec(ctx, aliasedURL, client, spec)
+		fatalIf(err, "Unable to get the bucket configuration.")
+		if spec.ObjectLock && !live.ObjectLock {
+			fatalIf(errInvalidArgument().Trace(aliasedURL), "Object locking can only be enabled when the bucket is created.")
+		}
+	}
+	msg.Changes = diffBucketSpec(live, spec)
+
+	if !dryRun {
+		if msg.Create {
+			fatalIf(client.MakeBucket(ctx, "", false, spec.ObjectLock), "Unable to create the bucket.")
+			live, err = getBucketSpec(ctx, aliasedURL, client, spec)
+			fatalIf(err, "Unable to get the bucket configuration.")
+		}
+		for _, change := range msg.Changes {
+			fatalIf(applyBucketChange(ctx, aliasedURL, client, change, live, spec), "Unable to apply the "+change.Section+" configuration.")
+		}
+	}
+	printMsg(msg)
+	return nil
+}
@@ -0,0 +1,123 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/minio/cli"
+	"github.com/trinet2005/oss-mc/pkg/probe"
+)
+
+var bucketExportFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "format",
+		Usage: "format of the spec, 'yaml' or 'json'",
+		Value: "yaml",
+	},
+}
+
+var bucketExportCmd = cli.Command{
+	Name:         "export",
+	Usage:        "export the configuration of a bucket as a spec",
+	Action:       mainBucketExport,
+	OnUsageError: onUsageError,
+	Before:       setGlobalsFromContext,
+	Flags:        append(bucketExportFlags, globalFlags...),
+	CustomHelpTemplate: `NAME:
+  {{.HelpName}} - {{.Usage}}
+
+USAGE:
+  {{.HelpName}} [FLAGS] TARGET
+
+FLAGS:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}
+DESCRIPTION:
+  Exports versioning, default retention, encryption, anonymous access, tags,
+  quota, notifications, lifecycle and replication of a bucket to STDOUT, in
+  the format read by 'mc bucket apply'.
+
+EXAMPLES:
+  1. Export the configuration of 'mybucket' to 'bucket.yaml'.
+     {{.Prompt}} {{.HelpName}} myminio/mybucket > bucket.yaml
+
+  2. Export the configuration of 'mybucket' as JSON.
+     {{.Prompt}} {{.HelpName}} --format json myminio/mybucket
+`,
+}
+
+type bucketExportMessage struct {
+	Status string      `json:"status"`
+	URL    string      `json:"url"`
+	Spec   *bucketSpec `json:"spec"`
+	format string
+}
+
+func (b bucketExportMessage) String() string {
+	if b.format == "json" {
+		buf, e := json.MarshalIndent(b.Spec, "", " ")
+		fatalIf(probe.NewError(e), "Unable to marshal the bucket spec.")
+		return string(buf)
+	}
+	buf, e := marshalBucketSpecYAML(b.Spec)
+	fatalIf(probe.NewError(e), "Unable to marshal the bucket spec.")
+	return strings.TrimSuffix(string(buf), "\n")
+}
+
+func (b bucketExportMessage) JSON() string {
+	b.Status = "success"
+	buf, e := json.MarshalIndent(b, "", " ")
+	fatalIf(probe.NewError(e), "Unable to marshal into JSON.")
+	return string(buf)
+}
+
+// checkBucketSpecExportSyntax - validate all the passed arguments
+func checkBucketSpecExportSyntax(ctx *cli.Context) {
+	if len(ctx.Args()) != 1 {
+		showCommandHelpAndExit(ctx, 1) // last argument is exit code
+	}
+	switch ctx.String("format") {
+	case "yaml", "json":
+	default:
+		fatalIf(errInvalidArgument().Trace(ctx.String("format")), "Invalid --format, expected 'yaml' or 'json'.")
+	}
+}
+
+func mainBucketExport(cliCtx *cli.Context) error {
+	ctx, cancelBucketExport := context.WithCancel(globalContext)
+	defer cancelBucketExport()
+
+	checkBucketSpecExportSyntax(cliCtx)
+
+	aliasedURL := cliCtx.Args().Get(0)
+	client, err := newClient(aliasedURL)
+	fatalIf(err, "Unable to initialize connection.")
+
+	live, err := getBucketSpec(ctx, aliasedURL, client, nil)
+	fatalIf(err, "Unable to get the bucket configuration.")
+
+	printMsg(bucketExportMessage{
+		URL:    aliasedURL,
+		Spec:   exportBucketSpec(live),
+		format: cliCtx.String("format"),
+	})
+	return nil
+}
@@ -0,0 +1,766 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+	"github.com/trinet2005/oss-admin-go"
+	minio "github.com/trinet2005/oss-go-sdk"
+	"github.com/trinet2005/oss-go-sdk/pkg/lifecycle"
+	"github.com/trinet2005/oss-go-sdk/pkg/replication"
+	"github.com/trinet2005/oss-mc/pkg/probe"
+	"gopkg.in/yaml.v2"
+)
+
+// bucketSpec - declarative configuration of a bucket. Sections left out
+// of a spec are not managed, empty sections remove the configuration.
+type bucketSpec struct {
+	ObjectLock  bool                     `json:"objectLock,omitempty"`
+	Versioning  *bucketVersioningSpec    `json:"versioning,omitempty"`
+	Retention   *bucketRetentionSpec     `json:"retention,omitempty"`
+	Encryption  *bucketEncryptionSpec    `json:"encryption,omitempty"`
+	Anonymous   *bucketAnonymousSpec     `json:"anonymous,omitempty"`
+	Tags        *map[string]string       `json:"tags,omitempty"`
+	Quota       *bucketQuotaSpec         `json:"quota,omitempty"`
+	Events      *[]bucketEventSpec       `json:"events,omitempty"`
+	Lifecycle   *lifecycle.Configuration `json:"lifecycle,omitempty"`
+	Replication *replication.Config      `json:"replication,omitempty"`
+}
+
+type bucketVersioningSpec struct {
+	Status           string   `json:"status,omitempty"`
+	ExcludedPrefixes []string `json:"excludedPrefixes,omitempty"`
+	ExcludeFolders   bool     `json:"excludeFolders,omitempty"`
+}
+
+// bucketRetentionSpec - default retention of objects in a locked bucket.
+type bucketRetentionSpec struct {
+	Mode     string `json:"mode,omitempty"`
+	Validity string `json:"validity,omitempty"`
+}
+
+type bucketEncryptionSpec struct {
+	Algorithm string `json:"algorithm,omitempty"`
+	KMSKeyID  string `json:"kmsKeyId,omitempty"`
+}
+
+// bucketAnonymousSpec - anonymous access, a custom access has a policy.
+type bucketAnonymousSpec struct {
+	Access string          `json:"access,omitempty"`
+	Policy json.RawMessage `json:"policy,omitempty"`
+}
+
+type bucketQuotaSpec struct {
+	Size string `json:"size,omitempty"`
+}
+
+type bucketEventSpec struct {
+	ARN    string   `json:"arn"`
+	Events []string `json:"events"`
+	Prefix string   `json:"prefix,omitempty"`
+	Suffix string   `json:"suffix,omitempty"`
+}
+
+// Sections of a bucket spec, in the order they are applied.
+var bucketSpecSections = []string{
+	"versioning",
+	"retention",
+	"encryption",
+	"anonymous",
+	"tags",
+	"quota",
+	"events",
+	"lifecycle",
+	"replication",
+}
+
+// Short names of the notification events, as accepted by `mc event add`.
+var bucketEventNames = map[string]string{
+	"s3:ObjectCreated:*":      "put",
+	"s3:ObjectRemoved:*":      "delete",
+	"s3:ObjectAccessed:*":     "get",
+	"s3:Replication:*":        "replica",
+	"s3:ObjectRestore:*":      "ilm",
+	"s3:ObjectTransition:*":   "ilm",
+	"s3:Scanner:ManyVersions": "scanner",
+	"s3:Scanner:BigPrefix":    "scanner",
+}
+
+// parseBucketSpec parses a YAML or JSON bucket spec.
+func parseBucketSpec(data []byte) (*bucketSpec, error) {
+	var v interface{}
+	if e := yaml.Unmarshal(data, &v); e != nil {
+		return nil, e
+	}
+	v, e := yamlToJSONValue(v)
+	if e != nil {
+		return nil, e
+	}
+	if v == nil {
+		return nil, errors.New("empty bucket spec")
+	}
+	buf, e := json.Marshal(v)
+	if e != nil {
+		return nil, e
+	}
+	dec := json.NewDecoder(strings.NewReader(string(buf)))
+	dec.DisallowUnknownFields()
+	var spec bucketSpec
+	if e = dec.Decode(&spec); e != nil {
+		return nil, e
+	}
+	if e = spec.validate(); e != nil {
+		return nil, e
+	}
+	return &spec, nil
+}
+
+// readBucketSpec reads a bucket spec from a file, or STDIN for "-".
+func readBucketSpec(file string) (*bucketSpec, *probe.Error) {
+	var data []byte
+	var e error
+	if file == "-" {
+		data, e = io.ReadAll(os.Stdin)
+	} else {
+		data, e = os.ReadFile(file)
+	}
+	if e != nil {
+		return nil, probe.NewError(e).Trace(file)
+	}
+	spec, e := parseBucketSpec(data)
+	if e != nil {
+		return nil, probe.NewError(e).Trace(file)
+	}
+	return spec, nil
+}
+
+// yamlToJSONValue converts the maps decoded from YAML to JSON objects.
+func yamlToJSONValue(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			k, ok := key.(string)
+			if !ok {
+				k = fmt.Sprint(key)
+			}
+			value, e := yamlToJSONValue(value)
+			if e != nil {
+				return nil, e
+			}
+			m[k] = value
+		}
+		return m, nil
+	case []interface{}:
+		for i := range v {
+			value, e := yamlToJSONValue(v[i])
+			if e != nil {
+				return nil, e
+			}
+			v[i] = value
+		}
+	}
+	return v, nil
+}
+
+// marshalBucketSpecYAML marshals a spec to YAML, in the order of the
+// JSON fields.
+func marshalBucketSpecYAML(spec *bucketSpec) ([]byte, error) {
+	buf, e := json.Marshal(spec)
+	if e != nil {
+		return nil, e
+	}
+	var m yaml.MapSlice
+	if e = yaml.Unmarshal(buf, &m); e != nil {
+		return nil, e
+	}
+	return yaml.Marshal(m)
+}
+
+func (s *bucketSpec) validate() error {
+	if s.Versioning != nil {
+		switch strings.ToLower(s.Versioning.Status) {
+		case "enabled", "suspended":
+		default:
+			return fmt.Errorf("invalid versioning status `%s`, expected Enabled or Suspended", s.Versioning.Status)
+		}
+	}
+	if s.Retention != nil && (s.Retention.Mode != "" || s.Retention.Validity != "") {
+		if !minio.RetentionMode(strings.ToUpper(s.Retention.Mode)).IsValid() {
+			return fmt.Errorf("invalid retention mode `%s`", s.Retention.Mode)
+		}
+		if s.Retention.Validity == "" {
+			return errors.New("retention validity is missing")
+		}
+		if _, _, err := parseRetentionValidity(s.Retention.Validity); err != nil {
+			return fmt.Errorf("invalid retention validity `%s`", s.Retention.Validity)
+		}
+	}
+	if s.Encryption != nil {
+		switch strings.ToLower(s.Encryption.Algorithm) {
+		case "", "sse-s3":
+		case "sse-kms":
+			if s.Encryption.KMSKeyID == "" {
+				return errors.New("sse-kms encryption requires a kmsKeyId")
+			}
+		default:
+			return fmt.Errorf("invalid encryption algorithm `%s`", s.Encryption.Algorithm)
+		}
+	}
+	if s.Anonymous != nil {
+		switch accessPerms(strings.ToLower(s.Anonymous.Access)) {
+		case "", accessPrivate, accessNone, accessDownload, accessUpload, accessPublic:
+		case accessCustom:
+			if len(s.Anonymous.Policy) == 0 {
+				return errors.New("custom anonymous access requires a policy")
+			}
+		default:
+			return fmt.Errorf("invalid anonymous access `%s`", s.Anonymous.Access)
+		}
+	}
+	if s.Quota != nil && s.Quota.Size != "" {
+		if _, e := humanize.ParseBytes(s.Quota.Size); e != nil {
+			return fmt.Errorf("invalid quota size `%s`", s.Quota.Size)
+		}
+	}
+	if s.Events != nil {
+		for _, event := range *s.Events {
+			if event.ARN == "" || len(event.Events) == 0 {
+				return errors.New("events require an arn and a list of events")
+			}
+		}
+	}
+	return nil
+}
+
+// section returns the canonical JSON form of a section of the spec, empty
+// if the section is not configured, and whether the spec manages it.
+func (s *bucketSpec) section(name string) (string, bool) {
+	var v interface{}
+	managed := true
+	switch name {
+	case "versioning":
+		managed = s.Versioning != nil
+		if managed && s.Versioning.Status != "" {
+			prefixes := append([]string{}, s.Versioning.ExcludedPrefixes...)
+			sort.Strings(prefixes)
+			status := strings.ToLower(s.Versioning.Status)
+			v = bucketVersioningSpec{
+				Status:           strings.ToUpper(status[:1]) + status[1:],
+				ExcludedPrefixes: prefixes,
+				ExcludeFolders:   s.Versioning.ExcludeFolders,
+			}
+		}
+	case "retention":
+		managed = s.Retention != nil
+		if managed && s.Retention.Mode != "" {
+			validity, unit, _ := parseRetentionValidity(s.Retention.Validity)
+			v = bucketRetentionSpec{
+				Mode:     strings.ToUpper(s.Retention.Mode),
+				Validity: formatRetentionValidity(validity, unit),
+			}
+		}
+	case "encryption":
+		managed = s.Encryption != nil
+		if managed && s.Encryption.Algorithm != "" {
+			v = bucketEncryptionSpec{
+				Algorithm: strings.ToLower(s.Encryption.Algorithm),
+				KMSKeyID:  s.Encryption.KMSKeyID,
+			}
+		}
+	case "anonymous":
+		managed = s.Anonymous != nil
+		if managed {
+			switch access := accessPerms(strings.ToLower(s.Anonymous.Access)); access {
+			case "", accessPrivate, accessNone:
+			case accessCustom:
+				var policy interface{}
+				json.Unmarshal(s.Anonymous.Policy, &policy)
+				v = map[string]interface{}{"access": access, "policy": policy}
+			default:
+				v = map[string]interface{}{"access": access}
+			}
+		}
+	case "tags":
+		managed = s.Tags != nil
+		if managed && len(*s.Tags) > 0 {
+			v = *s.Tags
+		}
+	case "quota":
+		managed = s.Quota != nil
+		if managed && s.Quota.Size != "" {
+			if size, _ := humanize.ParseBytes(s.Quota.Size); size > 0 {
+				v = bucketQuotaSpec{Size: formatQuotaSize(size)}
+			}
+		}
+	case "events":
+		managed = s.Events != nil
+		if managed && len(*s.Events) > 0 {
+			v = canonicalBucketEvents(*s.Events)
+		}
+	case "lifecycle":
+		managed = s.Lifecycle != nil
+		if managed && len(s.Lifecycle.Rules) > 0 {
+			// Rule IDs are generated by the server when left out.
+			rules := make([]interface{}, 0, len(s.Lifecycle.Rules))
+			for _, rule := range s.Lifecycle.Rules {
+				rule.ID = ""
+				rules = append(rules, rule)
+			}
+			v = map[string]interface{}{"Rules": canonicalRules(rules)}
+		}
+	case "replication":
+		managed = s.Replication != nil
+		if managed && len(s.Replication.Rules) > 0 {
+			rules := make([]interface{}, 0, len(s.Replication.Rules))
+			for _, rule := range s.Replication.Rules {
+				rule.ID = ""
+				rules = append(rules, rule)
+			}
+			v = map[string]interface{}{"Role": s.Replication.Role, "Rules": canonicalRules(rules)}
+		}
+	}
+	if v == nil {
+		return "", managed
+	}
+	buf, _ := json.Marshal(v)
+	return string(buf), managed
+}
+
+// canonicalBucketEvents sorts notification configurations and their
+// events, described with short names.
+func canonicalBucketEvents(events []bucketEventSpec) []bucketEventSpec {
+	canonical := make([]bucketEventSpec, 0, len(events))
+	for _, event := range events {
+		names := make(map[string]struct{})
+		for _, name := range event.Events {
+			if short, ok := bucketEventNames[name]; ok {
+				name = short
+			}
+			names[strings.ToLower(name)] = struct{}{}
+		}
+		event.Events = make([]string, 0, len(names))
+		for name := range names {
+			event.Events = append(event.Events, name)
+		}
+		sort.Strings(event.Events)
+		canonical = append(canonical, event)
+	}
+	sort.Slice(canonical, func(i, j int) bool {
+		return canonical[i].key() < canonical[j].key()
+	})
+	return canonical
+}
+
+// canonicalRules returns the JSON forms of rules, sorted.
+func canonicalRules(rules []interface{}) []json.RawMessage {
+	canonical := make([]json.RawMessage, 0, len(rules))
+	for _, rule := range rules {
+		buf, _ := json.Marshal(rule)
+		canonical = append(canonical, buf)
+	}
+	sort.Slice(canonical, func(i, j int) bool {
+		return string(canonical[i]) < string(canonical[j])
+	})
+	return canonical
+}
+
+func (e bucketEventSpec) key() string {
+	return strings.Join([]string{e.ARN, strings.Join(e.Events, ","), e.Prefix, e.Suffix}, "\x00")
+}
+
+func formatRetentionValidity(validity uint64, unit minio.ValidityUnit) string {
+	switch unit {
+	case minio.Days:
+		return strconv.FormatUint(validity, 10) + "d"
+	case minio.Years:
+		return strconv.FormatUint(validity, 10) + "y"
+	}
+	return ""
+}
+
+// bucketChange - a difference between the live configuration of a
+// bucket and its spec.
+type bucketChange struct {
+	Section string `json:"section"`
+	Action  string `json:"action"`
+	From    string `json:"from,omitempty"`
+	To      string `json:"to,omitempty"`
+}
+
+// Actions of bucket changes.
+const (
+	bucketChangeAdd    = "add"
+	bucketChangeModify = "modify"
+	bucketChangeRemove = "remove"
+)
+
+// diffBucketSpec returns the changes turning the live configuration of a
+// bucket into the desired one, for the sections the spec manages.
+func diffBucketSpec(live, desired *bucketSpec) []bucketChange {
+	var changes []bucketChange
+	for _, name := range bucketSpecSections {
+		to, managed := desired.section(name)
+		if !managed {
+			continue
+		}
+		from, _ := live.section(name)
+		if from == to {
+			continue
+		}
+		change := bucketChange{Section: name, Action: bucketChangeModify, From: from, To: to}
+		switch {
+		case from == "":
+			change.Action = bucketChangeAdd
+		case to == "":
+			change.Action = bucketChangeRemove
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// bucketConfigMissing returns true if the error reports a configuration
+// which is not set, or not supported, on a bucket.
+func bucketConfigMissing(err *probe.Error) bool {
+	switch minio.ToErrorResponse(err.ToGoError()).Code {
+	case "NoSuchLifecycleConfiguration",
+		"ServerSideEncryptionConfigurationNotFoundError",
+		"ReplicationConfigurationNotFoundError",
+		"ObjectLockConfigurationNotFoundError",
+		"NoSuchTagSet",
+		"NoSuchBucketPolicy",
+		"NotImplemented":
+		return true
+	}
+	return false
+}
+
+// getBucketSpec reads the live configuration of a bucket, limited to
+// the sections managed by a spec if any.
+func getBucketSpec(ctx context.Context, aliasedURL string, clnt Client, managed *bucketSpec) (*bucketSpec, *probe.Error) {
+	live := &bucketSpec{}
+	want := func(name string) bool {
+		if managed == nil {
+			return true
+		}
+		_, ok := managed.section(name)
+		return ok
+	}
+	missing := func(err *probe.Error) *probe.Error {
+		if err == nil || bucketConfigMissing(err) {
+			return nil
+		}
+		return err.Trace(aliasedURL)
+	}
+
+	status, mode, validity, unit, err := clnt.GetObjectLockConfig(ctx)
+	if err = missing(err); err != nil {
+		return nil, err
+	}
+	live.ObjectLock = status == "Enabled"
+	if want("retention") {
+		live.Retention = &bucketRetentionSpec{Mode: string(mode), Validity: formatRetentionValidity(validity, unit)}
+	}
+
+	if want("versioning") {
+		config, err := clnt.GetVersion(ctx)
+		if err = missing(err); err != nil {
+			return nil, err
+		}
+		live.Versioning = &bucketVersioningSpec{Status: config.Status, ExcludeFolders: config.ExcludeFolders}
+		for _, prefix := range config.ExcludedPrefixes {
+			live.Versioning.ExcludedPrefixes = append(live.Versioning.ExcludedPrefixes, prefix.Prefix)
+		}
+	}
+
+	if want("encryption") {
+		algorithm, keyID, err := clnt.GetEncryption(ctx)
+		if err = missing(err); err != nil {
+			return nil, err
+		}
+		live.Encryption = &bucketEncryptionSpec{Algorithm: strings.ToLower(algorithm), KMSKeyID: keyID}
+		if live.Encryption.Algorithm == "aws:kms" {
+			live.Encryption.Algorithm = "sse-kms"
+		} else if live.Encryption.Algorithm == "aes256" {
+			live.Encryption.Algorithm = "sse-s3"
+		}
+	}
+
+	if want("anonymous") {
+		access, policy, err := clnt.GetAccess(ctx)
+		if err = missing(err); err != nil {
+			return nil, err
+		}
+		live.Anonymous = &bucketAnonymousSpec{Access: string(stringToAccessPerm(access))}
+		if live.Anonymous.Access == string(accessCustom) {
+			live.Anonymous.Policy = json.RawMessage(policy)
+		}
+	}
+
+	if want("tags") {
+		tags, err := clnt.GetTags(ctx, "")
+		if err = missing(err); err != nil {
+			return nil, err
+		}
+		if tags == nil {
+			tags = map[string]string{}
+		}
+		live.Tags = &tags
+	}
+
+	if want("quota") {
+		live.Quota = &bucketQuotaSpec{}
+		// Quotas are a MinIO extension, reported if available.
+		if admClnt, err := newAdminClient(aliasedURL); err == nil {
+			_, bucket := url2Alias(aliasedURL)
+			if quota, e := admClnt.GetBucketQuota(ctx, bucket); e == nil && quotaSize(quota) > 0 {
+				live.Quota.Size = formatQuotaSize(quotaSize(quota))
+			} else if e != nil && managed != nil {
+				return nil, probe.NewError(e).Trace(aliasedURL)
+			}
+		} else if managed != nil {
+			return nil, err.Trace(aliasedURL)
+		}
+	}
+
+	if want("events") {
+		events := []bucketEventSpec{}
+		if s3Clnt, ok := clnt.(*S3Client); ok {
+			configs, err := s3Clnt.ListNotificationConfigs(ctx, "")
+			if err = missing(err); err != nil {
+				return nil, err
+			}
+			for _, config := range configs {
+				events = append(events, bucketEventSpec{ARN: config.Arn, Events: config.Events, Prefix: config.Prefix, Suffix: config.Suffix})
+			}
+		}
+		events = canonicalBucketEvents(events)
+		live.Events = &events
+	}
+
+	if want("lifecycle") {
+		config, _, err := clnt.GetLifecycle(ctx)
+		if err = missing(err); err != nil {
+			return nil, err
+		}
+		if config == nil {
+			config = lifecycle.NewConfiguration()
+		}
+		live.Lifecycle = config
+	}
+
+	if want("replication") {
+		config, err := clnt.GetReplication(ctx)
+		if err = missing(err); err != nil {
+			return nil, err
+		}
+		live.Replication = &config
+	}
+	return live, nil
+}
+
+// quotaSize returns the size limit of a bucket quota.
+func quotaSize(quota madmin.BucketQuota) uint64 {
+	if quota.Size > 0 {
+		return quota.Size
+	}
+	return quota.Quota
+}
+
+// formatQuotaSize formats a quota in IEC units if exact, in bytes otherwise.
+func formatQuotaSize(size uint64) string {
+	s := humanize.IBytes(size)
+	if parsed, e := humanize.ParseBytes(s); e == nil && parsed == size {
+		return s
+	}
+	return strconv.FormatUint(size, 10)
+}
+
+// exportBucketSpec returns the live configuration of a bucket as a spec,
+// leaving out the sections which are not configured.
+func exportBucketSpec(live *bucketSpec) *bucketSpec {
+	spec := &bucketSpec{ObjectLock: live.ObjectLock}
+	configured := func(name string) bool {
+		v, _ := live.section(name)
+		return v != ""
+	}
+	if configured("versioning") {
+		spec.Versioning = live.Versioning
+	}
+	if configured("retention") {
+		spec.Retention = live.Retention
+	}
+	if configured("encryption") {
+		spec.Encryption = live.Encryption
+	}
+	if configured("anonymous") {
+		spec.Anonymous = live.Anonymous
+	}
+	if configured("tags") {
+		spec.Tags = live.Tags
+	}
+	if configured("quota") {
+		spec.Quota = live.Quota
+	}
+	if configured("events") {
+		spec.Events = live.Events
+	}
+	if configured("lifecycle") {
+		spec.Lifecycle = live.Lifecycle
+	}
+	if configured("replication") {
+		spec.Replication = live.Replication
+	}
+	return spec
+}
+
+// applyBucketChange applies the desired configuration of a section.
+func applyBucketChange(ctx context.Context, aliasedURL string, clnt Client, change bucketChange, live, desired *bucketSpec) *probe.Error {
+	var err *probe.Error
+	switch change.Section {
+	case "versioning":
+		status := "enable"
+		if strings.EqualFold(desired.Versioning.Status, "suspended") {
+			status = "suspend"
+		}
+		err = clnt.SetVersion(ctx, status, desired.Versioning.ExcludedPrefixes, desired.Versioning.ExcludeFolders)
+	case "retention":
+		var mode minio.RetentionMode
+		var validity uint64
+		var unit minio.ValidityUnit
+		if desired.Retention.Mode != "" {
+			mode = minio.RetentionMode(strings.ToUpper(desired.Retention.Mode))
+			if validity, unit, err = parseRetentionValidity(desired.Retention.Validity); err != nil {
+				break
+			}
+		}
+		err = clnt.SetObjectLockConfig(ctx, mode, validity, unit)
+	case "encryption":
+		if desired.Encryption.Algorithm == "" {
+			err = clnt.DeleteEncryption(ctx)
+		} else {
+			err = clnt.SetEncryption(ctx, desired.Encryption.Algorithm, desired.Encryption.KMSKeyID)
+		}
+	case "anonymous":
+		access := accessPerms(strings.ToLower(desired.Anonymous.Access))
+		if access == "" {
+			access = accessPrivate
+		}
+		if access == accessCustom {
+			err = clnt.SetAccess(ctx, string(desired.Anonymous.Policy), true)
+		} else {
+			if live.Anonymous != nil && live.Anonymous.Access == string(accessCustom) {
+				// Drop the custom policy, canned access only edits
+				// its own statements.
+				if err = clnt.SetAccess(ctx, "", true); err != nil {
+					break
+				}
+			}
+			err = clnt.SetAccess(ctx, accessPermToString(access), false)
+		}
+	case "tags":
+		if len(*desired.Tags) == 0 {
+			err = clnt.DeleteTags(ctx, "")
+			break
+		}
+		keys := make([]string, 0, len(*desired.Tags))
+		for k := range *desired.Tags {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		pairs := make([]string, 0, len(keys))
+		for _, k := range keys {
+			pairs = append(pairs, k+"="+(*desired.Tags)[k])
+		}
+		err = clnt.SetTags(ctx, "", strings.Join(pairs, "&"))
+	case "quota":
+		var admClnt *madmin.AdminClient
+		if admClnt, err = newAdminClient(aliasedURL); err != nil {
+			break
+		}
+		var size uint64
+		if desired.Quota.Size != "" {
+			size, _ = humanize.ParseBytes(desired.Quota.Size)
+		}
+		quota := &madmin.BucketQuota{}
+		if size > 0 {
+			quota = &madmin.BucketQuota{Quota: size, Size: size, Type: madmin.HardQuota}
+		}
+		_, bucket := url2Alias(aliasedURL)
+		err = probe.NewError(admClnt.SetBucketQuota(ctx, bucket, quota))
+	case "events":
+		err = applyBucketEvents(ctx, clnt, *live.Events, *desired.Events)
+	case "lifecycle":
+		err = clnt.SetLifecycle(ctx, desired.Lifecycle)
+	case "replication":
+		if len(desired.Replication.Rules) == 0 {
+			err = clnt.RemoveReplication(ctx)
+		} else {
+			err = clnt.SetReplication(ctx, desired.Replication, replication.Options{Op: replication.ImportOption})
+		}
+	}
+	if err != nil {
+		return err.Trace(aliasedURL, change.Section)
+	}
+	return nil
+}
+
+// applyBucketEvents removes the notification configurations missing from
+// the spec, then adds the new ones.
+func applyBucketEvents(ctx context.Context, clnt Client, live, desired []bucketEventSpec) *probe.Error {
+	s3Clnt, ok := clnt.(*S3Client)
+	if !ok {
+		return probe.NewError(errors.New("bucket notifications require an S3 server"))
+	}
+	live, desired = canonicalBucketEvents(live), canonicalBucketEvents(desired)
+	contains := func(events []bucketEventSpec, event bucketEventSpec) bool {
+		for _, e := range events {
+			if reflect.DeepEqual(e, event) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, event := range live {
+		if !contains(desired, event) {
+			if err := s3Clnt.RemoveNotificationConfig(ctx, event.ARN, strings.Join(event.Events, ","), event.Prefix, event.Suffix); err != nil {
+				return err
+			}
+		}
+	}
+	for _, event := range desired {
+		if !contains(live, event) {
+			if err := s3Clnt.AddNotificationConfig(ctx, event.ARN, event.Events, event.Prefix, event.Suffix, false); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"encoding/json"
+
+	minio "github.com/trinet2005/oss-go-sdk"
+	"github.com/trinet2005/oss-go-sdk/pkg/lifecycle"
+	"github.com/trinet2005/oss-go-sdk/pkg/replication"
+	. "gopkg.in/check.v1"
+)
+
+const testBucketSpec = `
+versioning:
+  status: enabled
+  excludedPrefixes: [tmp/, cache/]
+retention:
+  mode: governance
+  validity: 30D
+tags:
+  team: analytics
+quota:
+  size: 1TB
+events:
+  - arn: arn:minio:sqs::primary:webhook
+    events: [put, delete]
+    suffix: .csv
+lifecycle:
+  Rules:
+    - ID: expire-logs
+      Status: Enabled
+      Filter:
+        Prefix: logs/
+      Expiration:
+        Days: 7
+`
+
+func (s *TestSuite) TestParseBucketSpec(c *C) {
+	spec, e := parseBucketSpec([]byte(testBucketSpec))
+	c.Assert(e, IsNil)
+	c.Assert(spec.Versioning.ExcludedPrefixes, DeepEquals, []string{"tmp/", "cache/"})
+	c.Assert((*spec.Tags)["team"], Equals, "analytics")
+	c.Assert(*spec.Events, HasLen, 1)
+	c.Assert(spec.Lifecycle.Rules, HasLen, 1)
+	c.Assert(spec.Lifecycle.Rules[0].Expiration.Days, Equals, lifecycle.ExpirationDays(7))
+	c.Assert(spec.Encryption, IsNil)
+
+	// JSON is accepted as well.
+	jsonSpec, e := parseBucketSpec([]byte(`{"encryption": {"algorithm": "sse-s3"}, "tags": {}}`))
+	c.Assert(e, IsNil)
+	c.Assert(jsonSpec.Encryption.Algorithm, Equals, "sse-s3")
+	c.Assert(*jsonSpec.Tags, HasLen, 0)
+
+	for _, invalid := range []string{
+		"versioning: {status: off}",
+		"retention: {mode: strict, validity: 1d}",
+		"retention: {mode: governance}",
+		"encryption: {algorithm: sse-kms}",
+		"anonymous: {access: custom}",
+		"quota: {size: lots}",
+		"events: [{arn: arn:minio:sqs::primary:webhook}]",
+		"unknown: true",
+		"",
+	} {
+		_, e = parseBucketSpec([]byte(invalid))
+		c.Assert(e, NotNil, Commentf("%s", invalid))
+	}
+}
+
+func (s *TestSuite) TestDiffBucketSpec(c *C) {
+	desired, e := parseBucketSpec([]byte(testBucketSpec))
+	c.Assert(e, IsNil)
+
+	// Live configurations are reported differently than written.
+	tags := map[string]string{"team": "analytics"}
+	events := []bucketEventSpec{{ARN: "arn:minio:sqs::primary:webhook", Events: []string{"s3:ObjectRemoved:*", "s3:ObjectCreated:*"}, Suffix: ".csv"}}
+	live := &bucketSpec{
+		Versioning: &bucketVersioningSpec{Status: "Enabled", ExcludedPrefixes: []string{"cache/", "tmp/"}},
+		Retention:  &bucketRetentionSpec{Mode: string(minio.Governance), Validity: "30d"},
+		Encryption: &bucketEncryptionSpec{Algorithm: "sse-s3"},
+		Tags:       &tags,
+		Quota:      &bucketQuotaSpec{Size: "931 GiB"},
+		Events:     &events,
+		Lifecycle:  lifecycle.NewConfiguration(),
+	}
+	changes := diffBucketSpec(live, desired)
+	c.Assert(changes, HasLen, 2)
+	c.Assert(changes[0].Section, Equals, "quota")
+	c.Assert(changes[0].Action, Equals, bucketChangeModify)
+	c.Assert(changes[0].To, Equals, `{"size":"1000000000000"}`)
+	c.Assert(changes[1].Section, Equals, "lifecycle")
+	c.Assert(changes[1].Action, Equals, bucketChangeAdd)
+
+	// Empty sections remove the configuration.
+	desired, e = parseBucketSpec([]byte("encryption: {}\ntags: {}\nanonymous: {access: private}"))
+	c.Assert(e, IsNil)
+	changes = diffBucketSpec(live, desired)
+	c.Assert(changes, HasLen, 2)
+	c.Assert(changes[0], DeepEquals, bucketChange{Section: "encryption", Action: bucketChangeRemove, From: `{"algorithm":"sse-s3"}`})
+	c.Assert(changes[1].Section, Equals, "tags")
+	c.Assert(changes[1].Action, Equals, bucketChangeRemove)
+}
+
+func (s *TestSuite) TestExportBucketSpec(c *C) {
+	tags := map[string]string{}
+	policy := json.RawMessage(`{"Version":"2012-10-17","Statement":[]}`)
+	live := &bucketSpec{
+		ObjectLock: true,
+		Versioning: &bucketVersioningSpec{Status: "Enabled"},
+		Retention:  &bucketRetentionSpec{},
+		Encryption: &bucketEncryptionSpec{},
+		Anonymous:  &bucketAnonymousSpec{Access: string(accessCustom), Policy: policy},
+		Tags:       &tags,
+		Quota:      &bucketQuotaSpec{},
+		Lifecycle:  lifecycle.NewConfiguration(),
+	}
+	spec := exportBucketSpec(live)
+	c.Assert(spec.Retention, IsNil)
+	c.Assert(spec.Tags, IsNil)
+	c.Assert(spec.Lifecycle, IsNil)
+
+	buf, e := marshalBucketSpecYAML(spec)
+	c.Assert(e, IsNil)
+	parsed, e := parseBucketSpec(buf)
+	c.Assert(e, IsNil)
+	c.Assert(parsed.ObjectLock, Equals, true)
+	c.Assert(diffBucketSpec(live, parsed), HasLen, 0)
+
+	c.Assert(formatQuotaSize(1<<40), Equals, "1.0 TiB")
+	c.Assert(formatQuotaSize(1000), Equals, "1000 B")
+	c.Assert(formatQuotaSize(1000*1000), Equals, "1000000")
+}
+
+func (s *TestSuite) TestBucketSpecRulesRoundTrip(c *C) {
+	// Rules as reported by the server, with generated IDs.
+	live := &bucketSpec{
+		Lifecycle: &lifecycle.Configuration{Rules: []lifecycle.Rule{
+			{ID: "cjq1hd1f1ak7qb6jpkfg", Status: "Enabled", RuleFilter: lifecycle.Filter{Prefix: "tmp/"}, Expiration: lifecycle.Expiration{Days: 1}},
+			{ID: "cjq1hd1f1ak7qb6jpkg0", Status: "Enabled", RuleFilter: lifecycle.Filter{Prefix: "logs/"}, Expiration: lifecycle.Expiration{Days: 7}},
+		}},
+		Replication: &replication.Config{Rules: []replication.Rule{
+			{
+				ID:                      "cjq1hd1f1ak7qb6jpkgg",
+				Status:                  replication.Enabled,
+				Priority:                1,
+				DeleteMarkerReplication: replication.DeleteMarkerReplication{Status: replication.Disabled},
+				DeleteReplication:       replication.DeleteReplication{Status: replication.Disabled},
+				Destination:             replication.Destination{Bucket: "arn:minio:replication::1:target"},
+			},
+		}},
+	}
+
+	// Exported and applied again without changes.
+	buf, e := marshalBucketSpecYAML(exportBucketSpec(live))
+	c.Assert(e, IsNil)
+	exported, e := parseBucketSpec(buf)
+	c.Assert(e, IsNil)
+	c.Assert(diffBucketSpec(live, exported), HasLen, 0)
+
+	// Written without IDs and in another order.
+	desired, e := parseBucketSpec([]byte(`
+lifecycle:
+  Rules:
+    - Status: Enabled
+      Filter: {Prefix: logs/}
+      Expiration: {Days: 7}
+    - Status: Enabled
+      Filter: {Prefix: tmp/}
+      Expiration: {Days: 1}
+`))
+	c.Assert(e, IsNil)
+	c.Assert(diffBucketSpec(live, desired), HasLen, 0)
+
+	desired.Lifecycle.Rules[0].Expiration.Days = 30
+	changes := diffBucketSpec(live, desired)
+	c.Assert(changes, HasLen, 1)
+	c.Assert(changes[0].Section, Equals, "lifecycle")
+	c.Assert(changes[0].Action, Equals, bucketChangeModify)
+}
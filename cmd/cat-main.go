@@ -64,7 +64,7 @@ var catCmd = cli.Command{
 	Action:       mainCat,
 	OnUsageError: onUsageError,
 	Before:       setGlobalsFromContext,
-	Flags:        append(append(catFlags, ioFlags...), globalFlags...),
+	Flags:        append(append(append(catFlags, ioFlags...), cseFlags...), globalFlags...),
 	CustomHelpTemplate: `NAME:
   {{.HelpName}} - {{.Usage}}
 
@@ -99,6 +99,12 @@ EXAMPLES:
 
   7. Display the content of a particular object version
      {{.Prompt}} {{.HelpName}} --vid "3ddac055-89a7-40fa-8cd3-530a5581b6b8" play/my-bucket/my-object
+
+  8. Display the content of a client-side encrypted object from an offset, decrypting it with an age identity.
+     {{.Prompt}} {{.HelpName}} --cse-identity ~/.age/key.txt --offset 1048576 play/my-bucket/my-object
+
+  9. Display the last kilobyte of a log uploaded with --compress, it is decompressed transparently.
+     {{.Prompt}} {{.HelpName}} --tail 1024 play/my-bucket/app.log
 `,
 }
 
@@ -166,6 +172,7 @@ type catOpts struct {
 	tailO     int64
 	isZip     bool
 	stdinMode bool
+	cse       *cseKeys
 }
 
 // parseCatSyntax performs command-line input validation for cat command.
@@ -212,6 +219,10 @@ func parseCatSyntax(ctx *cli.Context) catOpts {
 		fatalIf(errInvalidArgument().Trace(), "You cannot use --zip --tail or --offset with stdin")
 	}
 
+	var err *probe.Error
+	o.cse, err = getCSEKeys(ctx)
+	fatalIf(err, "Unable to parse client-side encryption keys.")
+
 	return o
 }
 
@@ -231,25 +242,37 @@ func catURL(ctx context.Context, sourceURL string, encKeyDB map[string][]prefixS
 		// are ignored since some of them have zero size though they
 		// have contents like files under /proc.
 		// 2. extract the version ID if rewind flag is passed
+		// 3. detect objects compressed or encrypted client-side
 		if client, content, err := url2Stat(ctx, sourceURL, o.versionID, false, encKeyDB, o.timeRef, o.isZip); err == nil {
 			if o.versionID == "" {
 				versionID = content.VersionID
 			}
-			if o.tailO > 0 && content.Size > 0 {
-				o.startO = content.Size - o.tailO
+			t, err := newCatTransform(sourceURL, content, o)
+			if err != nil {
+				return err.Trace(sourceURL)
+			}
+			contentSize := t.size
+			if o.tailO > 0 && contentSize < 0 {
+				return probe.NewError(errors.New("unable to use --tail, the uncompressed size of the object is unknown")).Trace(sourceURL)
+			}
+			if o.tailO > 0 && contentSize > 0 {
+				o.startO = contentSize - o.tailO
 				if o.startO < 0 {
 					// Return all.
 					o.startO = 0
 				}
 			}
 
-			if client.GetURL().Type == objectStorage {
-				size = content.Size - o.startO
+			if client.GetURL().Type == objectStorage && contentSize >= 0 {
+				size = contentSize - o.startO
 				if size < 0 {
-					err := probe.NewError(fmt.Errorf("specified offset (%d) bigger than file (%d)", o.startO, content.Size))
+					err := probe.NewError(fmt.Errorf("specified offset (%d) bigger than file (%d)", o.startO, contentSize))
 					return err.Trace(sourceURL)
 				}
 			}
+			if t.object != nil || t.codec != "" {
+				return t.cat(ctx, sourceURL, versionID, encKeyDB, o.startO, size).Trace(sourceURL)
+			}
 		} else {
 			return err.Trace(sourceURL)
 		}
@@ -266,6 +289,101 @@ func catURL(ctx context.Context, sourceURL string, encKeyDB map[string][]prefixS
 	return catOut(reader, size).Trace(sourceURL)
 }
 
+// catTransform - an object compressed or encrypted client-side, which
+// is decrypted and decompressed when displayed.
+type catTransform struct {
+	object    *cseObject
+	plainSize int64
+	codec     string
+	// size of the object once decrypted and decompressed, -1 if unknown.
+	size int64
+}
+
+// newCatTransform detects how an object was transformed client-side.
+// Objects are left as is when they cannot be decrypted.
+func newCatTransform(sourceURL string, content *ClientContent, o catOpts) (t catTransform, err *probe.Error) {
+	t.size = content.Size
+	if isCSEEncrypted(content.Metadata) {
+		if o.cse == nil {
+			return t, nil
+		}
+		if t.object, err = o.cse.open(sourceURL, content.Metadata); err != nil {
+			return t, err
+		}
+		if t.plainSize = cseDecryptedSize(content.Size); t.plainSize < 0 {
+			return t, probe.NewError(errCSEInvalidSize)
+		}
+		t.size = t.plainSize
+	}
+	if t.codec = compressionCodec(content); t.codec != "" {
+		t.size = uncompressedSize(content)
+	}
+	return t, nil
+}
+
+// newReader returns a reader decrypting and decompressing the object
+// read from the start.
+func (t catTransform) newReader(rc io.ReadCloser) (io.ReadCloser, *probe.Error) {
+	var reader io.Reader = rc
+	if t.object != nil {
+		reader = t.object.decrypt(reader, 0, t.plainSize)
+	}
+	closer := io.Closer(rc)
+	if t.codec != "" {
+		dr, e := newDecompressReader(reader, t.codec)
+		if e != nil {
+			return nil, probe.NewError(e)
+		}
+		reader, closer = dr, multiCloser{dr, rc}
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{reader, closer}, nil
+}
+
+// cat displays the object from the offset. Encrypted objects are read
+// from the fragment holding the offset, compressed objects from the
+// start.
+func (t catTransform) cat(ctx context.Context, sourceURL, versionID string, encKeyDB map[string][]prefixSSEPair, offset, size int64) *probe.Error {
+	var rangeStart int64
+	if t.codec == "" {
+		rangeStart = cseCiphertextOffset(offset)
+	}
+	rc, err := getSourceStreamFromURL(ctx, sourceURL, encKeyDB, getSourceOpts{
+		GetOptions: GetOptions{VersionID: versionID, RangeStart: rangeStart},
+	})
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	if t.codec == "" {
+		return catOut(t.object.decrypt(rc, offset, t.plainSize), size)
+	}
+	reader, err := t.newReader(rc)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	if _, e := io.CopyN(io.Discard, reader, offset); e != nil {
+		return probe.NewError(e)
+	}
+	return catOut(reader, size)
+}
+
+// multiCloser closes all of its closers in order.
+type multiCloser []io.Closer
+
+func (m multiCloser) Close() (err error) {
+	for _, c := range m {
+		if e := c.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
 // catOut reads from reader stream and writes to stdout. Also check the length of the
 // read bytes against size parameter (if not -1) and return the appropriate error
 func catOut(r io.Reader, size int64) *probe.Error {
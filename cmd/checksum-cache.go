@@ -0,0 +1,207 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/trinet2005/oss-mc/pkg/probe"
+)
+
+const (
+	globalChecksumCacheFile    = "checksums.json"
+	globalChecksumCacheVersion = "1"
+
+	// checksumMD5 - hex encoded MD5, comparable with the ETag
+	// of objects uploaded in a single part without encryption.
+	checksumMD5 = "MD5"
+
+	// Entries not used for this long are dropped on save.
+	checksumCacheExpiry = 30 * 24 * time.Hour
+)
+
+// checksumCacheEntry - checksums of a file, keyed by algorithm.
+type checksumCacheEntry struct {
+	Checksums map[string]string `json:"checksums"`
+	Accessed  time.Time         `json:"accessed"`
+}
+
+// checksumCacheV1 - on disk format of the checksum cache.
+type checksumCacheV1 struct {
+	Version string                         `json:"version"`
+	Entries map[string]*checksumCacheEntry `json:"entries"`
+}
+
+// checksumCache caches checksums of local files, keyed by inode and
+// modification time so that unchanged files are never read twice.
+type checksumCache struct {
+	mutex   sync.Mutex
+	file    string
+	entries map[string]*checksumCacheEntry
+	dirty   bool
+}
+
+// getChecksumCacheFile - get the checksum cache file path.
+func getChecksumCacheFile() (string, *probe.Error) {
+	configDir, err := getMcConfigDir()
+	if err != nil {
+		return "", err.Trace()
+	}
+	return filepath.Join(configDir, globalChecksumCacheFile), nil
+}
+
+// loadChecksumCache loads the checksum cache, a missing or unreadable
+// cache file results in an empty cache.
+func loadChecksumCache() *checksumCache {
+	cache := &checksumCache{entries: make(map[string]*checksumCacheEntry)}
+
+	file, err := getChecksumCacheFile()
+	if err != nil {
+		return cache
+	}
+	cache.file = file
+
+	data, e := os.ReadFile(file)
+	if e != nil {
+		return cache
+	}
+	var cacheV1 checksumCacheV1
+	if e = json.Unmarshal(data, &cacheV1); e != nil || cacheV1.Version != globalChecksumCacheVersion {
+		return cache
+	}
+	if cacheV1.Entries != nil {
+		cache.entries = cacheV1.Entries
+	}
+	return cache
+}
+
+// checksumCacheKey identifies a version of a file by its inode,
+// modification time and size, or by its path if inodes are not
+// supported.
+func checksumCacheKey(fpath string, fi os.FileInfo) string {
+	if dev, ino, ok := fileInode(fi); ok {
+		return fmt.Sprintf("%d:%d:%d:%d", dev, ino, fi.ModTime().UnixNano(), fi.Size())
+	}
+	return fmt.Sprintf("%s:%d:%d", fpath, fi.ModTime().UnixNano(), fi.Size())
+}
+
+// Checksum returns the checksum of a local file, computing it only
+// if the file has changed since it was last cached.
+func (c *checksumCache) Checksum(fpath, algo string) (string, error) {
+	fi, e := os.Stat(fpath)
+	if e != nil {
+		return "", e
+	}
+	key := checksumCacheKey(fpath, fi)
+
+	c.mutex.Lock()
+	entry, ok := c.entries[key]
+	if ok {
+		entry.Accessed = UTCNow()
+		if sum, ok := entry.Checksums[algo]; ok {
+			c.mutex.Unlock()
+			return sum, nil
+		}
+	}
+	c.mutex.Unlock()
+
+	sum, e := localChecksum(fpath, algo)
+	if e != nil {
+		return "", e
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	entry, ok = c.entries[key]
+	if !ok {
+		entry = &checksumCacheEntry{Checksums: make(map[string]string)}
+		c.entries[key] = entry
+	}
+	entry.Checksums[algo] = sum
+	entry.Accessed = UTCNow()
+	c.dirty = true
+	return sum, nil
+}
+
+// Save writes the cache to disk if it was modified, dropping
+// entries which have not been used for a while.
+func (c *checksumCache) Save() *probe.Error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if !c.dirty || c.file == "" {
+		return nil
+	}
+
+	for key, entry := range c.entries {
+		if time.Since(entry.Accessed) > checksumCacheExpiry {
+			delete(c.entries, key)
+		}
+	}
+
+	data, e := json.Marshal(checksumCacheV1{
+		Version: globalChecksumCacheVersion,
+		Entries: c.entries,
+	})
+	if e != nil {
+		return probe.NewError(e)
+	}
+
+	// Write to a temporary file and rename, so that
+	// the cache is never left half written.
+	tmpFile := c.file + ".tmp"
+	if e = os.WriteFile(tmpFile, data, 0o600); e != nil {
+		return probe.NewError(e)
+	}
+	if e = os.Rename(tmpFile, c.file); e != nil {
+		return probe.NewError(e)
+	}
+	c.dirty = false
+	return nil
+}
+
+// localChecksum computes the checksum of a local file, MD5 is hex
+// encoded like an ETag, additional checksums are base64 encoded.
+func localChecksum(fpath, algo string) (string, error) {
+	if algo != checksumMD5 {
+		checksum, err := parseChecksumAlgo(algo)
+		if err != nil {
+			return "", err.ToGoError()
+		}
+		return fileChecksum(fpath, checksum)
+	}
+
+	f, e := os.Open(fpath)
+	if e != nil {
+		return "", e
+	}
+	defer f.Close()
+	hasher := md5.New()
+	if _, e = io.Copy(hasher, f); e != nil {
+		return "", e
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
@@ -0,0 +1,214 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	minio "github.com/trinet2005/oss-go-sdk"
+	"github.com/trinet2005/oss-mc/pkg/probe"
+)
+
+// maxChecksumBufferSize is the largest single part upload buffered to
+// compute its checksum before it is sent.
+const maxChecksumBufferSize = 64 << 20
+
+// checksumAlgorithms lists the S3 additional checksums accepted by --checksum.
+var checksumAlgorithms = []minio.ChecksumType{
+	minio.ChecksumCRC32C,
+	minio.ChecksumCRC32,
+	minio.ChecksumSHA1,
+	minio.ChecksumSHA256,
+}
+
+// parseChecksumAlgo parses the value of --checksum, an empty
+// value disables checksum verification.
+func parseChecksumAlgo(algo string) (minio.ChecksumType, *probe.Error) {
+	if algo == "" {
+		return minio.ChecksumNone, nil
+	}
+	var names []string
+	for _, c := range checksumAlgorithms {
+		if strings.EqualFold(algo, c.String()) {
+			return c, nil
+		}
+		names = append(names, c.String())
+	}
+	return minio.ChecksumNone, probe.NewError(fmt.Errorf("unsupported checksum algorithm `%s`, supported values are %s",
+		algo, strings.Join(names, ", ")))
+}
+
+// checksumMetadataKey returns the metadata key under which the
+// checksum of type algo is recorded, e.g. `X-Amz-Checksum-Crc32c`.
+func checksumMetadataKey(algo minio.ChecksumType) string {
+	return http.CanonicalHeaderKey(algo.Key())
+}
+
+// isCompositeChecksum returns true for checksums of multipart objects,
+// which are a checksum of the part checksums suffixed with `-<parts>`
+// and hence cannot be compared with a digest of the whole content.
+func isCompositeChecksum(value string) bool {
+	return strings.Contains(value, "-")
+}
+
+// objectChecksums returns the additional checksums of an object
+// keyed by their metadata key.
+func objectChecksums(info minio.ObjectInfo) map[string]string {
+	checksums := make(map[string]string)
+	for algo, value := range map[minio.ChecksumType]string{
+		minio.ChecksumCRC32:  info.ChecksumCRC32,
+		minio.ChecksumCRC32C: info.ChecksumCRC32C,
+		minio.ChecksumSHA1:   info.ChecksumSHA1,
+		minio.ChecksumSHA256: info.ChecksumSHA256,
+	} {
+		if value != "" {
+			checksums[checksumMetadataKey(algo)] = value
+		}
+	}
+	return checksums
+}
+
+// uploadChecksum returns the checksum of type algo echoed by the server
+// after an upload, empty if the server did not return one.
+func uploadChecksum(algo minio.ChecksumType, info minio.UploadInfo) string {
+	switch algo {
+	case minio.ChecksumCRC32:
+		return info.ChecksumCRC32
+	case minio.ChecksumCRC32C:
+		return info.ChecksumCRC32C
+	case minio.ChecksumSHA1:
+		return info.ChecksumSHA1
+	case minio.ChecksumSHA256:
+		return info.ChecksumSHA256
+	}
+	return ""
+}
+
+// fileChecksum computes the checksum of type algo of a local file.
+func fileChecksum(fpath string, algo minio.ChecksumType) (string, error) {
+	f, e := os.Open(fpath)
+	if e != nil {
+		return "", e
+	}
+	defer f.Close()
+	sum, e := algo.ChecksumReader(f)
+	if e != nil {
+		return "", e
+	}
+	return sum.Encoded(), nil
+}
+
+// checksumReader computes the checksum of all the data read through it.
+type checksumReader struct {
+	io.Reader
+	algo   minio.ChecksumType
+	hasher hash.Hash
+}
+
+func newChecksumReader(reader io.Reader, algo minio.ChecksumType) *checksumReader {
+	hasher := algo.Hasher()
+	return &checksumReader{
+		Reader: io.TeeReader(reader, hasher),
+		algo:   algo,
+		hasher: hasher,
+	}
+}
+
+// Sum returns the base64 encoded checksum of the data read so far.
+func (c *checksumReader) Sum() string {
+	return minio.NewChecksum(c.algo, c.hasher.Sum(nil)).Encoded()
+}
+
+// partChecksums computes the CRC32C checksums of the parts of a multipart
+// upload the way minio-go sends them, and the composite checksum the
+// server records for the object.
+type partChecksums struct {
+	partSize int64
+	written  int64
+	hasher   hash.Hash
+	sums     []byte
+}
+
+// newPartChecksums returns the part checksums of an upload of the given
+// size, -1 if unknown, and configured part size, 0 for the default one.
+func newPartChecksums(size int64, partSize uint64) (*partChecksums, error) {
+	_, optimalPartSize, _, e := minio.OptimalPartInfo(size, partSize)
+	if e != nil {
+		return nil, e
+	}
+	return &partChecksums{partSize: optimalPartSize, hasher: crc32.New(crc32.MakeTable(crc32.Castagnoli))}, nil
+}
+
+func (p *partChecksums) Write(b []byte) (int, error) {
+	n := len(b)
+	for len(b) > 0 {
+		chunk := b
+		if rest := p.partSize - p.written; int64(len(chunk)) > rest {
+			chunk = chunk[:rest]
+		}
+		p.hasher.Write(chunk)
+		p.written += int64(len(chunk))
+		b = b[len(chunk):]
+		if p.written == p.partSize {
+			p.sums = p.hasher.Sum(p.sums)
+			p.hasher.Reset()
+			p.written = 0
+		}
+	}
+	return n, nil
+}
+
+// Sum returns the composite checksum of the parts written so far, the
+// base64 CRC32C of the part checksums suffixed with `-<parts>`.
+func (p *partChecksums) Sum() string {
+	sums := p.sums
+	if p.written > 0 || len(sums) == 0 {
+		sums = p.hasher.Sum(sums)
+	}
+	parts := len(sums) / p.hasher.Size()
+	crc := crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	crc.Write(sums)
+	return fmt.Sprintf("%s-%d", base64.StdEncoding.EncodeToString(crc.Sum(nil)), parts)
+}
+
+// verifyChecksum compares the checksum computed while streaming the
+// data with each of the expected values. Empty and composite values
+// are not comparable and are skipped.
+func verifyChecksum(path string, algo minio.ChecksumType, computed string, expected ...string) *probe.Error {
+	for _, value := range expected {
+		if value == "" || isCompositeChecksum(value) {
+			continue
+		}
+		if value != computed {
+			return probe.NewError(ChecksumMismatch{
+				Path:      path,
+				Algorithm: algo.String(),
+				Expected:  value,
+				Computed:  computed,
+			})
+		}
+	}
+	return nil
+}
@@ -18,10 +18,8 @@
 package cmd
 
 import (
-	"crypto/tls"
 	"fmt"
 	"hash/fnv"
-	"net"
 	"net/http"
 	"net/url"
 	"sync"
@@ -58,7 +56,12 @@ func NewAdminFactory() func(config *Config) (*madmin.AdminClient, *probe.Error)
 
 		// Generate a hash out of s3Conf.
 		confHash := fnv.New32a()
-		confHash.Write([]byte(hostName + config.AccessKey + config.SecretKey))
+		confHash.Write([]byte(hostName + config.AccessKey + config.SecretKey + aliasTransportKey(config.AliasTransport) +
+			aliasEndpointsKey(config.Endpoints)))
+		if config.Credentials != nil {
+			// Credentials of a provider may differ by alias.
+			confHash.Write([]byte(config.Alias + credentialsKey(config.Credentials)))
+		}
 		confSum := confHash.Sum32()
 
 		// Lookup previous cache by hash.
@@ -69,6 +72,12 @@ func NewAdminFactory() func(config *Config) (*madmin.AdminClient, *probe.Error)
 		if api, found = clientCache[confSum]; !found {
 			// Admin API only supports signature v4.
 			creds := credentials.NewStaticV4(config.AccessKey, config.SecretKey, config.SessionToken)
+			if config.Credentials != nil {
+				var err *probe.Error
+				if creds, err = newAliasCredentials(config.Alias, config.HostURL, config.Credentials, "S3v4"); err != nil {
+					return nil, err.Trace(config.Alias)
+				}
+			}
 
 			// Not found. Instantiate a new MinIO
 			var e error
@@ -80,28 +89,29 @@ func NewAdminFactory() func(config *Config) (*madmin.AdminClient, *probe.Error)
 				return nil, probe.NewError(e)
 			}
 
-			// Keep TLS config.
-			tlsConfig := &tls.Config{
-				RootCAs: globalRootCAs,
-				// Can't use SSLv3 because of POODLE and BEAST
-				// Can't use TLSv1.0 because of POODLE and BEAST using CBC cipher
-				// Can't use TLSv1.1 because of RC4 cipher usage
-				MinVersion: tls.VersionTLS12,
-			}
-			if config.Insecure {
-				tlsConfig.InsecureSkipVerify = true
+			aliasTr, err := newAliasTransport(config)
+			if err != nil {
+				return nil, err.Trace(config.Alias)
 			}
 
 			var transport http.RoundTripper = &http.Transport{
-				Proxy:                 ieproxy.GetProxyFunc(),
-				DialContext:           newCustomDialContext(config),
+				Proxy:                 aliasTr.proxyFunc(ieproxy.GetProxyFunc()),
+				DialContext:           newCustomDialContext(aliasTr),
 				MaxIdleConnsPerHost:   256,
 				IdleConnTimeout:       90 * time.Second,
 				TLSHandshakeTimeout:   10 * time.Second,
 				ExpectContinueTimeout: 10 * time.Second,
-				TLSClientConfig:       tlsConfig,
+				TLSClientConfig:       aliasTr.tlsConfig(config.Insecure),
 				DisableCompression:    true,
 			}
+			transport = aliasTr.roundTripper(transport)
+			if config.Endpoints != nil {
+				pool, err := getEndpointPool(config, transport)
+				if err != nil {
+					return nil, err.Trace(config.Alias)
+				}
+				transport = pool.roundTripper(transport)
+			}
 			transport = gzhttp.Transport(transport)
 
 			if config.Debug {
@@ -140,6 +150,7 @@ func newAdminClient(aliasedURL string) (*madmin.AdminClient, *probe.Error) {
 	}
 
 	s3Config := NewS3Config(urlStrFull, aliasCfg)
+	s3Config.Alias = alias
 
 	s3Client, err := s3AdminNew(s3Config)
 	if err != nil {
@@ -180,29 +191,20 @@ func newAnonymousClient(aliasedURL string) (*madmin.AnonymousClient, *probe.Erro
 		return nil, probe.NewError(e)
 	}
 
-	// Keep TLS config.
-	tlsConfig := &tls.Config{
-		RootCAs: globalRootCAs,
-		// Can't use SSLv3 because of POODLE and BEAST
-		// Can't use TLSv1.0 because of POODLE and BEAST using CBC cipher
-		// Can't use TLSv1.1 because of RC4 cipher usage
-		MinVersion: tls.VersionTLS12,
-	}
-	if globalInsecure {
-		tlsConfig.InsecureSkipVerify = true
+	// HTTP settings of the alias, without deadlines.
+	aliasTr, err := newAliasTransport(&Config{AliasTransport: aliasCfg.Transport})
+	if err != nil {
+		return nil, err.Trace(aliasedURL)
 	}
 	// Set custom transport
 	var transport http.RoundTripper = &http.Transport{
-		Proxy: ieproxy.GetProxyFunc(),
-		DialContext: (&net.Dialer{
-			Timeout:   10 * time.Second,
-			KeepAlive: 15 * time.Second,
-		}).DialContext,
+		Proxy:                 aliasTr.proxyFunc(ieproxy.GetProxyFunc()),
+		DialContext:           newCustomDialContext(aliasTr),
 		MaxIdleConnsPerHost:   256,
 		IdleConnTimeout:       90 * time.Second,
 		TLSHandshakeTimeout:   10 * time.Second,
 		ExpectContinueTimeout: 10 * time.Second,
-		TLSClientConfig:       tlsConfig,
+		TLSClientConfig:       aliasTr.tlsConfig(globalInsecure),
 		// Set this value so that the underlying transport round-tripper
 		// doesn't try to auto decode the body of objects with
 		// content-encoding set to `gzip`.
@@ -211,6 +213,7 @@ func newAnonymousClient(aliasedURL string) (*madmin.AnonymousClient, *probe.Erro
 		//    https://golang.org/src/net/http/transport.go?h=roundTrip#L1843
 		DisableCompression: true,
 	}
+	transport = aliasTr.roundTripper(transport)
 	if globalDebug {
 		transport = httptracer.GetNewTraceTransport(newTraceV4(), transport)
 	}
@@ -213,6 +213,40 @@ func (e UnexpectedExcessRead) Error() string {
 	return msg
 }
 
+// ChecksumMismatch - checksum of the transferred data does not match.
+type ChecksumMismatch struct {
+	Path      string
+	Algorithm string
+	Expected  string
+	Computed  string
+}
+
+func (e ChecksumMismatch) Error() string {
+	return fmt.Sprintf("%s checksum mismatch for `%s`. Expected `%s`, but computed `%s`.", e.Algorithm, e.Path, e.Expected, e.Computed)
+}
+
+// ChecksumNotVerified - no checksum was recorded to compare the
+// transferred data with.
+type ChecksumNotVerified struct {
+	Path      string
+	Algorithm string
+}
+
+func (e ChecksumNotVerified) Error() string {
+	return fmt.Sprintf("%s checksum of `%s` not verified, the server recorded none.", e.Algorithm, e.Path)
+}
+
+// ChecksumUnsupported - the checksum cannot be recorded for a multipart
+// upload, its parts are sent with CRC32C checksums only.
+type ChecksumUnsupported struct {
+	Path      string
+	Algorithm string
+}
+
+func (e ChecksumUnsupported) Error() string {
+	return fmt.Sprintf("%s checksum cannot be recorded for the multipart upload of `%s`, only CRC32C without --md5 or object locking is, or use --disable-multipart.", e.Algorithm, e.Path)
+}
+
 // SameFile - source and destination are same files.
 type SameFile struct {
 	Source, Destination string
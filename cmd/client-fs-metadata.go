@@ -0,0 +1,207 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/xattr"
+	"github.com/trinet2005/oss-mc/pkg/probe"
+)
+
+const (
+	// Prefix of the extended attributes holding object metadata.
+	fsMetadataXattrPrefix = "user.s3."
+	// Suffix of the file holding object metadata on filesystems
+	// without support for extended attributes.
+	fsMetadataSidecarSuffix = ".mc-meta.json"
+	// Metadata key of object tags, in the query string format of
+	// the tagging header.
+	fsMetadataTaggingKey = "X-Amz-Tagging"
+)
+
+// fsMetadataHeaders - object headers stored along with local files,
+// besides user metadata and tags.
+var fsMetadataHeaders = map[string]bool{
+	"Cache-Control":       true,
+	"Content-Disposition": true,
+	"Content-Encoding":    true,
+	"Content-Language":    true,
+	"Content-Type":        true,
+	"Expires":             true,
+}
+
+// isFSMetadataKey returns true for the metadata stored with local files,
+// the mc attributes are restored by --preserve instead.
+func isFSMetadataKey(key string) bool {
+	key = http.CanonicalHeaderKey(key)
+	switch {
+	case key == metadataKey || key == metadataKeyS3Cmd:
+		return false
+	case strings.HasPrefix(key, "X-Amz-Meta-"), key == fsMetadataTaggingKey:
+		return true
+	}
+	return fsMetadataHeaders[key]
+}
+
+// fsMetadata returns the metadata to be stored with a local file.
+func fsMetadata(metadata map[string]string) map[string]string {
+	m := make(map[string]string)
+	for k, v := range metadata {
+		if isFSMetadataKey(k) && v != "" {
+			m[http.CanonicalHeaderKey(k)] = v
+		}
+	}
+	return m
+}
+
+// writeFSMetadata stores the object metadata and tags of a file in its
+// extended attributes, or in a sidecar JSON file next to it when the
+// filesystem does not support them.
+func writeFSMetadata(path string, metadata map[string]string) *probe.Error {
+	m := fsMetadata(metadata)
+	sidecar := path + fsMetadataSidecarSuffix
+	if len(m) == 0 {
+		return removeFSMetadataSidecar(path)
+	}
+
+	var e error
+	for k, v := range m {
+		if e = xattr.Set(path, fsMetadataXattrPrefix+strings.ToLower(k), []byte(v)); e != nil {
+			break
+		}
+	}
+	if e == nil {
+		return removeFSMetadataSidecar(path)
+	}
+	if !isNotSupported(e) {
+		return probe.NewError(e).Trace(path)
+	}
+
+	data, e := json.Marshal(m)
+	if e != nil {
+		return probe.NewError(e)
+	}
+	if e = os.WriteFile(sidecar, data, 0o666); e != nil {
+		return probe.NewError(e).Trace(sidecar)
+	}
+	return nil
+}
+
+// readFSMetadata returns the object metadata and tags stored with a file,
+// from its extended attributes or its sidecar JSON file.
+func readFSMetadata(path string) (map[string]string, *probe.Error) {
+	m := make(map[string]string)
+	names, e := xattr.List(path)
+	if e != nil && !isNotSupported(e) {
+		return nil, probe.NewError(e).Trace(path)
+	}
+	for _, name := range names {
+		if !strings.HasPrefix(name, fsMetadataXattrPrefix) {
+			continue
+		}
+		data, e := xattr.Get(path, name)
+		if e != nil {
+			return nil, probe.NewError(e).Trace(path, name)
+		}
+		m[http.CanonicalHeaderKey(strings.TrimPrefix(name, fsMetadataXattrPrefix))] = string(data)
+	}
+	if len(m) > 0 {
+		return m, nil
+	}
+
+	sidecar := path + fsMetadataSidecarSuffix
+	data, e := os.ReadFile(sidecar)
+	if e != nil {
+		if os.IsNotExist(e) {
+			return m, nil
+		}
+		return nil, probe.NewError(e).Trace(sidecar)
+	}
+	if e = json.Unmarshal(data, &m); e != nil {
+		return nil, probe.NewError(e).Trace(sidecar)
+	}
+	return m, nil
+}
+
+// removeFSMetadataSidecar removes the sidecar JSON file of a file if any.
+func removeFSMetadataSidecar(path string) *probe.Error {
+	sidecar := path + fsMetadataSidecarSuffix
+	if e := os.Remove(sidecar); e != nil && !os.IsNotExist(e) {
+		return probe.NewError(e).Trace(sidecar)
+	}
+	return nil
+}
+
+// applyFSMetadata sets the metadata stored with a local file on its
+// content, user metadata and tags are also set on their own.
+func applyFSMetadata(content *ClientContent, m map[string]string) {
+	if len(m) == 0 {
+		return
+	}
+	if content.Metadata == nil {
+		content.Metadata = make(map[string]string)
+	}
+	if content.UserMetadata == nil {
+		content.UserMetadata = make(map[string]string)
+	}
+	for k, v := range m {
+		content.Metadata[k] = v
+		switch {
+		case strings.HasPrefix(k, "X-Amz-Meta-"):
+			content.UserMetadata[k] = v
+		case k == fsMetadataTaggingKey:
+			values, e := url.ParseQuery(v)
+			if e != nil {
+				continue
+			}
+			content.Tags = make(map[string]string, len(values))
+			for key := range values {
+				content.Tags[key] = values.Get(key)
+			}
+		}
+	}
+}
+
+// addSourceTagging adds the tags of a source object to its metadata, to
+// be stored along with the local file it is copied to. Tags are only
+// fetched for objects advertising them.
+func addSourceTagging(ctx context.Context, alias, urlStr, versionID string, metadata map[string]string) *probe.Error {
+	count, _ := strconv.Atoi(metadata[http.CanonicalHeaderKey("X-Amz-Tagging-Count")])
+	if count == 0 {
+		return nil
+	}
+	clnt, err := newClientFromAlias(alias, urlStr)
+	if err != nil {
+		return err.Trace(alias, urlStr)
+	}
+	tags, err := clnt.GetTags(ctx, versionID)
+	if err != nil {
+		return err.Trace(urlStr)
+	}
+	if len(tags) > 0 {
+		metadata[fsMetadataTaggingKey] = formatKeyValues(tags)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/xattr"
+	. "gopkg.in/check.v1"
+)
+
+func (s *TestSuite) TestFSMetadataRoundTrip(c *C) {
+	globalFSMetadata = true
+	defer func() { globalFSMetadata = false }()
+
+	root, e := os.MkdirTemp(os.TempDir(), "fs-")
+	c.Assert(e, IsNil)
+	defer os.RemoveAll(root)
+
+	objectPath := filepath.Join(root, "object1")
+	fsClient, err := fsNew(objectPath)
+	c.Assert(err, IsNil)
+
+	data := "hello"
+	_, err = fsClient.Put(context.Background(), bytes.NewReader([]byte(data)), int64(len(data)), nil, PutOptions{
+		metadata: map[string]string{
+			"Content-Type":        "text/x-custom",
+			"Cache-Control":       "max-age=60",
+			"x-amz-meta-color":    "blue",
+			"X-Amz-Tagging":       "project=mc&team=storage+fs",
+			"X-Amz-Request-Id":    "17A2B3C4D5E6F7",
+			"X-Amz-Meta-Mc-Attrs": "mode:33188",
+		},
+	})
+	c.Assert(err, IsNil)
+
+	check := func(content *ClientContent) {
+		c.Assert(content.Metadata["Content-Type"], Equals, "text/x-custom")
+		c.Assert(content.Metadata["Cache-Control"], Equals, "max-age=60")
+		c.Assert(content.Metadata["X-Amz-Request-Id"], Equals, "")
+		c.Assert(content.Metadata["X-Amz-Meta-Mc-Attrs"], Equals, "")
+		c.Assert(content.UserMetadata, DeepEquals, map[string]string{"X-Amz-Meta-Color": "blue"})
+		c.Assert(content.Tags, DeepEquals, map[string]string{"project": "mc", "team": "storage fs"})
+	}
+
+	content, err := fsClient.Stat(context.Background(), StatOptions{})
+	c.Assert(err, IsNil)
+	check(content)
+
+	rootClient, err := fsNew(root + string(filepath.Separator))
+	c.Assert(err, IsNil)
+	var listed []*ClientContent
+	for content := range rootClient.List(context.Background(), ListOptions{ShowDir: DirNone}) {
+		c.Assert(content.Err, IsNil)
+		listed = append(listed, content)
+	}
+	c.Assert(len(listed), Equals, 1)
+	check(listed[0])
+
+	// Metadata is not stored unless requested.
+	globalFSMetadata = false
+	content, err = fsClient.Stat(context.Background(), StatOptions{})
+	c.Assert(err, IsNil)
+	c.Assert(content.Metadata["Cache-Control"], Equals, "")
+	c.Assert(content.Tags, IsNil)
+}
+
+func (s *TestSuite) TestFSMetadataSidecar(c *C) {
+	globalFSMetadata = true
+	defer func() { globalFSMetadata = false }()
+
+	root, e := os.MkdirTemp(os.TempDir(), "fs-")
+	c.Assert(e, IsNil)
+	defer os.RemoveAll(root)
+
+	objectPath := filepath.Join(root, "object1")
+	c.Assert(os.WriteFile(objectPath, []byte("hello"), 0o644), IsNil)
+	sidecar := objectPath + fsMetadataSidecarSuffix
+	c.Assert(os.WriteFile(sidecar, []byte(`{"Content-Type":"text/x-custom","X-Amz-Meta-Color":"blue"}`), 0o644), IsNil)
+
+	m, err := readFSMetadata(objectPath)
+	c.Assert(err, IsNil)
+	c.Assert(m, DeepEquals, map[string]string{"Content-Type": "text/x-custom", "X-Amz-Meta-Color": "blue"})
+
+	// Sidecar files are not listed.
+	rootClient, err := fsNew(root + string(filepath.Separator))
+	c.Assert(err, IsNil)
+	var listed []string
+	for content := range rootClient.List(context.Background(), ListOptions{ShowDir: DirNone}) {
+		c.Assert(content.Err, IsNil)
+		listed = append(listed, filepath.Base(content.URL.Path))
+		c.Assert(content.UserMetadata["X-Amz-Meta-Color"], Equals, "blue")
+	}
+	c.Assert(listed, DeepEquals, []string{"object1"})
+
+	// Stale sidecar files are removed once metadata is stored in
+	// extended attributes.
+	if e = xattr.Set(objectPath, "user.mc-test", []byte("1")); e != nil {
+		c.Skip("extended attributes are not supported")
+	}
+	c.Assert(writeFSMetadata(objectPath, map[string]string{"Content-Type": "text/plain"}), IsNil)
+	_, e = os.Stat(sidecar)
+	c.Assert(os.IsNotExist(e), Equals, true)
+	m, err = readFSMetadata(objectPath)
+	c.Assert(err, IsNil)
+	c.Assert(m, DeepEquals, map[string]string{"Content-Type": "text/plain"})
+
+	// Removing the file removes its sidecar.
+	c.Assert(os.WriteFile(sidecar, []byte(`{}`), 0o644), IsNil)
+	objectClient, err := fsNew(objectPath)
+	c.Assert(err, IsNil)
+	contentCh := make(chan *ClientContent, 1)
+	contentCh <- &ClientContent{URL: *newClientURL(objectPath)}
+	close(contentCh)
+	for result := range objectClient.Remove(context.Background(), false, false, false, false, contentCh) {
+		c.Assert(result.Err, IsNil)
+	}
+	_, e = os.Stat(sidecar)
+	c.Assert(os.IsNotExist(e), Equals, true)
+}
@@ -85,13 +85,9 @@ func fsNew(path string) (Client, *probe.Error) {
 	}, nil
 }
 
-//lint:ignore U1000 Used on some platforms.
 func isNotSupported(e error) bool {
-	if e == nil {
-		return false
-	}
-	errno := e.(*xattr.Error)
-	if errno == nil {
+	var errno *xattr.Error
+	if !errors.As(e, &errno) {
 		return false
 	}
 
@@ -114,6 +110,11 @@ func isIgnoredFile(filename string) bool {
 		}
 	}
 
+	// Metadata of local files stored next to them.
+	if globalFSMetadata && strings.HasSuffix(matchFile, fsMetadataSidecarSuffix) {
+		return true
+	}
+
 	// Default ignore list for all OSes.
 	for _, ignoredFile := range ignoreFiles["default"] {
 		matched, e := filepath.Match(ignoredFile, matchFile)
@@ -209,7 +210,20 @@ func (f *fsClient) Watch(_ context.Context, options WatchOptions) (*WatchObject,
 					continue
 				}
 				if i.IsDir() {
-					// we want files
+					// we want files, a folder moved in brings
+					// its files along without their own events.
+					filepath.Walk(event.Path(), func(path string, fi os.FileInfo, e error) error {
+						if e != nil || !fi.Mode().IsRegular() || isIgnoredFile(path) {
+							return nil
+						}
+						eventChan <- []EventInfo{{
+							Time: UTCNow().Format(timeFormatFS),
+							Size: fi.Size(),
+							Path: path,
+							Type: notification.ObjectCreatedPut,
+						}}
+						return nil
+					})
 					continue
 				}
 				eventChan <- []EventInfo{{
@@ -326,7 +340,15 @@ func (f *fsClient) put(_ context.Context, reader io.Reader, size int64, progress
 		}
 	}
 
-	totalWritten, e := io.Copy(tmpFile, hookreader.NewHook(reader, progress))
+	// Compute the checksum of the data while it is written.
+	var cksumReader *checksumReader
+	source := reader
+	if opts.checksum.IsSet() {
+		cksumReader = newChecksumReader(reader, opts.checksum)
+		source = cksumReader
+	}
+
+	totalWritten, e := io.Copy(tmpFile, hookreader.NewHook(source, progress))
 	if e != nil {
 		tmpFile.Close()
 		return 0, probe.NewError(e)
@@ -366,12 +388,25 @@ func (f *fsClient) put(_ context.Context, reader io.Reader, size int64, progress
 		}
 	}
 
+	// Verify the checksum before committing the data.
+	if cksumReader != nil {
+		if err := verifyChecksum(objectPath, opts.checksum, cksumReader.Sum(), opts.checksumValue); err != nil {
+			return totalWritten, err.Trace(objectPath)
+		}
+	}
+
 	// Safely completed put. Now commit by renaming to actual filename.
 	if e = os.Rename(objectPartPath, objectPath); e != nil {
 		err := f.toClientError(e, objectPath)
 		return totalWritten, err.Trace(objectPartPath, objectPath)
 	}
 
+	if globalFSMetadata {
+		if err := writeFSMetadata(objectPath, opts.metadata); err != nil {
+			return totalWritten, err.Trace(objectPath)
+		}
+	}
+
 	if len(attr) != 0 && opts.isPreserve {
 		atime, mtime, err := parseAtimeMtime(attr)
 		if err != nil {
@@ -440,7 +475,15 @@ func (f *fsClient) putN(_ context.Context, reader io.Reader, size int64, progres
 		}
 	}
 
-	totalWritten, e := io.CopyN(tmpFile, hookreader.NewHook(reader, progress), size)
+	// Compute the checksum of the data while it is written.
+	var cksumReader *checksumReader
+	source := reader
+	if opts.checksum.IsSet() {
+		cksumReader = newChecksumReader(reader, opts.checksum)
+		source = cksumReader
+	}
+
+	totalWritten, e := io.CopyN(tmpFile, hookreader.NewHook(source, progress), size)
 	if e != nil {
 		tmpFile.Close()
 		return 0, probe.NewError(e)
@@ -473,12 +516,25 @@ func (f *fsClient) putN(_ context.Context, reader io.Reader, size int64, progres
 		}
 	}
 
+	// Verify the checksum before committing the data.
+	if cksumReader != nil {
+		if err := verifyChecksum(objectPath, opts.checksum, cksumReader.Sum(), opts.checksumValue); err != nil {
+			return totalWritten, err.Trace(objectPath)
+		}
+	}
+
 	// Safely completed put. Now commit by renaming to actual filename.
 	if e = os.Rename(objectPartPath, objectPath); e != nil {
 		err := f.toClientError(e, objectPath)
 		return totalWritten, err.Trace(objectPartPath, objectPath)
 	}
 
+	if globalFSMetadata {
+		if err := writeFSMetadata(objectPath, opts.metadata); err != nil {
+			return totalWritten, err.Trace(objectPath)
+		}
+	}
+
 	if len(attr) != 0 && opts.isPreserve {
 		atime, mtime, err := parseAtimeMtime(attr)
 		if err != nil {
@@ -553,6 +609,12 @@ func (f *fsClient) Get(_ context.Context, opts GetOptions) (io.ReadCloser, *prob
 			return nil, err.Trace(f.PathURL.Path)
 		}
 	}
+	if opts.RangeLength > 0 {
+		return struct {
+			io.Reader
+			io.Closer
+		}{io.LimitReader(fileData, opts.RangeLength), fileData}, nil
+	}
 
 	return fileData, nil
 }
@@ -632,6 +694,11 @@ func (f *fsClient) Remove(_ context.Context, isIncomplete, _, _, _ bool, content
 				name += partSuffix
 			}
 			e := deleteFile(f.PathURL.Path, name)
+			if e == nil && globalFSMetadata && !isIncomplete {
+				if err := removeFSMetadataSidecar(name); err != nil {
+					errorIf(err.Trace(name), "Unable to remove the metadata of `"+name+"`.")
+				}
+			}
 			if e == nil {
 				res := RemoveResult{}
 				res.ObjectName = content.URL.Path
@@ -758,6 +825,16 @@ func (f *fsClient) List(_ context.Context, opts ListOptions) <-chan *ClientConte
 					continue
 				}
 			}
+			if globalFSMetadata && c.Err == nil && c.Type.IsRegular() {
+				if m, err := readFSMetadata(c.URL.Path); err == nil {
+					applyFSMetadata(c, m)
+				}
+			}
+			if opts.StartAfter != "" && c.Err == nil {
+				if filepath.ToSlash(strings.TrimPrefix(c.URL.Path, f.PathURL.Path)) <= opts.StartAfter {
+					continue
+				}
+			}
 			// Send to filtered channel
 			filteredCh <- c
 		}
@@ -1263,6 +1340,25 @@ func (f *fsClient) Stat(_ context.Context, opts StatOptions) (content *ClientCon
 		"Content-Type": guessURLContentType(f.PathURL.Path),
 	}
 
+	// Compute the requested checksum locally, the same way
+	// an object storage server would record it.
+	if opts.checksum.IsSet() && st.Mode().IsRegular() {
+		sum, e := fileChecksum(f.PathURL.Path, opts.checksum)
+		if e != nil {
+			return nil, f.toClientError(e, f.PathURL.Path).Trace(f.PathURL.Path)
+		}
+		content.Metadata[checksumMetadataKey(opts.checksum)] = sum
+	}
+
+	// Object metadata and tags stored with the file.
+	if globalFSMetadata && st.Mode().IsRegular() {
+		m, err := readFSMetadata(f.PathURL.Path)
+		if err != nil {
+			return nil, err.Trace(f.PathURL.Path)
+		}
+		applyFSMetadata(content, m)
+	}
+
 	path := f.PathURL.String()
 	// Populates meta data with file system attribute only in case of
 	// when preserve flag is passed.
@@ -1276,6 +1372,9 @@ func (f *fsClient) Stat(_ context.Context, opts StatOptions) (content *ClientCon
 			return content, nil
 		}
 		for k, v := range metaData {
+			if strings.HasPrefix(k, fsMetadataXattrPrefix) {
+				continue
+			}
 			content.Metadata[k] = v
 		}
 		content.Metadata[metadataKey] = fileAttr
@@ -25,6 +25,7 @@ import (
 	"path/filepath"
 	"runtime"
 
+	minio "github.com/trinet2005/oss-go-sdk"
 	. "gopkg.in/check.v1"
 )
 
@@ -382,3 +383,39 @@ func (s *TestSuite) TestCopy(c *C) {
 	err = fsClientTarget.Copy(context.Background(), sourcePath, CopyOptions{size: int64(len(data))}, nil)
 	c.Assert(err, IsNil)
 }
+
+// Test put and stat with checksum.
+func (s *TestSuite) TestPutChecksum(c *C) {
+	root, e := os.MkdirTemp(os.TempDir(), "fs-")
+	c.Assert(e, IsNil)
+	defer os.RemoveAll(root)
+
+	objectPath := filepath.Join(root, "object")
+	fsClient, err := fsNew(objectPath)
+	c.Assert(err, IsNil)
+
+	data := []byte("hello world")
+	expected := minio.ChecksumSHA256.ChecksumBytes(data).Encoded()
+
+	// A mismatching checksum must fail and not commit the file.
+	_, err = fsClient.Put(context.Background(), bytes.NewReader(data), int64(len(data)), nil, PutOptions{
+		checksum:      minio.ChecksumSHA256,
+		checksumValue: minio.ChecksumSHA256.ChecksumBytes([]byte("hello")).Encoded(),
+	})
+	c.Assert(err, NotNil)
+	_, ok := err.ToGoError().(ChecksumMismatch)
+	c.Assert(ok, Equals, true)
+	_, e = os.Stat(objectPath)
+	c.Assert(os.IsNotExist(e), Equals, true)
+
+	n, err := fsClient.Put(context.Background(), bytes.NewReader(data), int64(len(data)), nil, PutOptions{
+		checksum:      minio.ChecksumSHA256,
+		checksumValue: expected,
+	})
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, int64(len(data)))
+
+	content, err := fsClient.Stat(context.Background(), StatOptions{checksum: minio.ChecksumSHA256})
+	c.Assert(err, IsNil)
+	c.Assert(content.Metadata[checksumMetadataKey(minio.ChecksumSHA256)], Equals, expected)
+}
@@ -20,7 +20,6 @@ package cmd
 import (
 	"bytes"
 	"context"
-	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -68,6 +67,7 @@ const (
 	amazonHostNameAccelerated = "s3-accelerate.amazonaws.com"
 	googleHostName            = "storage.googleapis.com"
 	serverEncryptionKeyPrefix = "x-amz-server-side-encryption"
+	amzChecksumPrefix         = "x-amz-checksum-"
 
 	defaultRecordDelimiter = "\n"
 	defaultFieldDelimiter  = ","
@@ -93,10 +93,10 @@ const (
 type dialContext func(ctx context.Context, network, addr string) (net.Conn, error)
 
 // newCustomDialContext setups a custom dialer for any external communication and proxies.
-func newCustomDialContext(c *Config) dialContext {
+func newCustomDialContext(t *aliasTransport) dialContext {
 	return func(ctx context.Context, network, addr string) (net.Conn, error) {
 		dialer := &net.Dialer{
-			Timeout:   10 * time.Second,
+			Timeout:   t.connectTimeout,
 			KeepAlive: 15 * time.Second,
 		}
 
@@ -106,8 +106,8 @@ func newCustomDialContext(c *Config) dialContext {
 		}
 
 		dconn := deadlineconn.New(conn).
-			WithReadDeadline(c.ConnReadDeadline).
-			WithWriteDeadline(c.ConnWriteDeadline)
+			WithReadDeadline(t.readTimeout).
+			WithWriteDeadline(t.writeTimeout)
 
 		return dconn, nil
 	}
@@ -149,7 +149,9 @@ func newFactory() func(config *Config) (Client, *probe.Error) {
 
 		// Generate a hash out of s3Conf.
 		confHash := fnv.New32a()
-		confHash.Write([]byte(hostName + config.AccessKey + config.SecretKey + config.SessionToken))
+		confHash.Write([]byte(hostName + config.AccessKey + config.SecretKey + config.SessionToken + config.Alias +
+			credentialsKey(config.Credentials) + aliasTransportKey(config.AliasTransport) +
+			aliasEndpointsKey(config.Endpoints)))
 		confSum := confHash.Sum32()
 
 		// Lookup previous cache by hash.
@@ -164,15 +166,26 @@ func newFactory() func(config *Config) (Client, *probe.Error) {
 			if strings.ToUpper(config.Signature) == "S3V2" {
 				creds = credentials.NewStaticV2(config.AccessKey, config.SecretKey, "")
 			}
+			// Credentials of a provider are retrieved again once expired.
+			if config.Credentials != nil {
+				var err *probe.Error
+				if creds, err = newAliasCredentials(config.Alias, config.HostURL, config.Credentials, config.Signature); err != nil {
+					return nil, err.Trace(config.Alias)
+				}
+			}
 
 			var transport http.RoundTripper
 
 			if config.Transport != nil {
 				transport = config.Transport
 			} else {
+				aliasTr, err := newAliasTransport(config)
+				if err != nil {
+					return nil, err.Trace(config.Alias)
+				}
 				tr := &http.Transport{
-					Proxy:                 http.ProxyFromEnvironment,
-					DialContext:           newCustomDialContext(config),
+					Proxy:                 aliasTr.proxyFunc(http.ProxyFromEnvironment),
+					DialContext:           newCustomDialContext(aliasTr),
 					MaxIdleConnsPerHost:   1024,
 					WriteBufferSize:       32 << 10, // 32KiB moving up from 4KiB default
 					ReadBufferSize:        32 << 10, // 32KiB moving up from 4KiB default
@@ -189,17 +202,7 @@ func newFactory() func(config *Config) (Client, *probe.Error) {
 				}
 				if useTLS {
 					// Keep TLS config.
-					tlsConfig := &tls.Config{
-						RootCAs: globalRootCAs,
-						// Can't use SSLv3 because of POODLE and BEAST
-						// Can't use TLSv1.0 because of POODLE and BEAST using CBC cipher
-						// Can't use TLSv1.1 because of RC4 cipher usage
-						MinVersion: tls.VersionTLS12,
-					}
-					if config.Insecure {
-						tlsConfig.InsecureSkipVerify = true
-					}
-					tr.TLSClientConfig = tlsConfig
+					tr.TLSClientConfig = aliasTr.tlsConfig(config.Insecure)
 
 					// Because we create a custom TLSClientConfig, we have to opt-in to HTTP/2.
 					// See https://github.com/golang/go/issues/14275
@@ -210,10 +213,31 @@ func newFactory() func(config *Config) (Client, *probe.Error) {
 					// 	return nil, probe.NewError(e)
 					// }
 				}
-				transport = tr
+				transport = aliasTr.roundTripper(tr)
+				if config.Endpoints != nil {
+					pool, err := getEndpointPool(config, transport)
+					if err != nil {
+						return nil, err.Trace(config.Alias)
+					}
+					transport = pool.roundTripper(transport)
+				}
 			}
 
-			transport = limiter.New(config.UploadLimit, config.DownloadLimit, transport)
+			if config.Alias != "" {
+				// Limits of the alias, unless overridden on the command line.
+				limits, err := newAliasLimits(config.Alias, config.Limit, limiter.Limits{
+					Upload:   config.UploadLimit,
+					Download: config.DownloadLimit,
+				})
+				if err != nil {
+					return nil, err.Trace(config.Alias)
+				}
+				dynamic := limiter.NewDynamic(limits.Limits(), transport)
+				limits.watch(dynamic)
+				transport = dynamic
+			} else {
+				transport = limiter.New(config.UploadLimit, config.DownloadLimit, transport)
+			}
 
 			if config.Debug {
 				if strings.EqualFold(config.Signature, "S3v4") {
@@ -857,16 +881,26 @@ func (c *S3Client) Get(ctx context.Context, opts GetOptions) (io.ReadCloser, *pr
 	o := minio.GetObjectOptions{
 		ServerSideEncryption: opts.SSE,
 		VersionID:            opts.VersionID,
+		Checksum:             opts.Checksum,
 	}
 	if opts.Zip {
 		o.Set("x-minio-extract", "true")
 	}
-	if opts.RangeStart != 0 {
-		err := o.SetRange(opts.RangeStart, 0)
+	if opts.RangeStart != 0 || opts.RangeLength > 0 {
+		var end int64
+		if opts.RangeLength > 0 {
+			end = opts.RangeStart + opts.RangeLength - 1
+		}
+		err := o.SetRange(opts.RangeStart, end)
 		if err != nil {
 			return nil, probe.NewError(err)
 		}
 	}
+	if opts.MatchETag != "" {
+		if e := o.SetMatchETag(opts.MatchETag); e != nil {
+			return nil, probe.NewError(e)
+		}
+	}
 
 	reader, e := c.api.GetObject(ctx, bucket, object, o)
 	if e != nil {
@@ -948,7 +982,9 @@ func (c *S3Client) Copy(ctx context.Context, source string, opts CopyOptions, pr
 	destOpts.ReplaceMetadata = len(metadata) > 0
 
 	var e error
-	if opts.disableMultipart || opts.size < 64*1024*1024 {
+	// Objects above 5GiB are copied in parts with UploadPartCopy
+	// even when multipart is disabled, CopyObject refuses them.
+	if (opts.disableMultipart && opts.size <= maxCopyObjectSize) || opts.size < 64*1024*1024 {
 		_, e = c.api.CopyObject(ctx, destOpts, srcOpts)
 	} else {
 		_, e = c.api.ComposeObject(ctx, destOpts, srcOpts)
@@ -1082,6 +1118,53 @@ func (c *S3Client) Put(ctx context.Context, reader io.Reader, size int64, progre
 		opts.SendContentMd5 = true
 	}
 
+	var cksumReader *checksumReader
+	var parts *partChecksums
+	if putOpts.checksum.IsSet() {
+		partSize := putOpts.multipartSize
+		if partSize == 0 {
+			partSize = 16 << 20
+		}
+		multipart := size < 0 || (size >= int64(partSize) && !putOpts.disableMultipart)
+		switch {
+		case multipart:
+			// Multipart uploads carry the per part CRC32C checksums
+			// computed by minio-go, unless Content-MD5 is sent, the
+			// composite checksum of the parts is computed the same way to
+			// be compared with the one of the object.
+			if putOpts.checksum != minio.ChecksumCRC32C || opts.SendContentMd5 {
+				return 0, probe.NewError(ChecksumUnsupported{
+					Path:      c.targetURL.String(),
+					Algorithm: putOpts.checksum.String(),
+				})
+			}
+			var e error
+			if parts, e = newPartChecksums(size, putOpts.multipartSize); e != nil {
+				return 0, probe.NewError(e)
+			}
+			reader = io.TeeReader(reader, parts)
+		case putOpts.checksumValue == "" && size <= maxChecksumBufferSize:
+			// Sources without a recorded checksum, like local files, are
+			// only read once: the single part is buffered to send its
+			// checksum along with it.
+			data, e := io.ReadAll(io.LimitReader(reader, size))
+			if e != nil {
+				return 0, probe.NewError(e)
+			}
+			putOpts.checksumValue = putOpts.checksum.ChecksumBytes(data).Encoded()
+			reader = bytes.NewReader(data)
+		}
+
+		cksumReader = newChecksumReader(reader, putOpts.checksum)
+		reader = cksumReader
+
+		// The expected checksum can only be sent along with a single PUT,
+		// the server verifies it and stores it with the object.
+		if putOpts.checksumValue != "" && !multipart {
+			metadata[checksumMetadataKey(putOpts.checksum)] = putOpts.checksumValue
+		}
+	}
+
 	ui, e := c.api.PutObject(ctx, bucket, object, reader, size, opts)
 	if e != nil {
 		errResponse := minio.ToErrorResponse(e)
@@ -1119,11 +1202,72 @@ func (c *S3Client) Put(ctx context.Context, reader io.Reader, size int64, progre
 		if errResponse.Code == "NoSuchKey" {
 			return ui.Size, probe.NewError(ObjectMissing{})
 		}
+		if cksumReader != nil && (errResponse.Code == "BadDigest" || errResponse.Code == "XAmzContentChecksumMismatch") {
+			return ui.Size, probe.NewError(ChecksumMismatch{
+				Path:      c.targetURL.String(),
+				Algorithm: putOpts.checksum.String(),
+				Expected:  putOpts.checksumValue,
+				Computed:  cksumReader.Sum(),
+			})
+		}
 		return ui.Size, probe.NewError(e)
 	}
+	if cksumReader != nil && parts == nil {
+		recorded := uploadChecksum(putOpts.checksum, ui)
+		err := verifyChecksum(c.targetURL.String(), putOpts.checksum, cksumReader.Sum(),
+			putOpts.checksumValue, recorded)
+		if err != nil {
+			return ui.Size, err.Trace(bucket, object)
+		}
+		if putOpts.checksumValue == "" && recorded == "" {
+			return ui.Size, probe.NewError(ChecksumNotVerified{
+				Path:      c.targetURL.String(),
+				Algorithm: putOpts.checksum.String(),
+			}).Trace(bucket, object)
+		}
+	}
+	if parts != nil {
+		if err := c.verifyPartChecksums(ctx, bucket, object, ui, parts, putOpts.sse); err != nil {
+			return ui.Size, err.Trace(bucket, object)
+		}
+	}
 	return ui.Size, nil
 }
 
+// verifyPartChecksums compares the composite CRC32C of the uploaded parts
+// with the one recorded for the object, fetched with a HEAD when it was
+// not returned by the upload. Objects without a composite checksum are
+// reported as not verified.
+func (c *S3Client) verifyPartChecksums(ctx context.Context, bucket, object string, ui minio.UploadInfo, parts *partChecksums, sse encrypt.ServerSide) *probe.Error {
+	recorded := ui.ChecksumCRC32C
+	if recorded == "" {
+		info, e := c.api.StatObject(ctx, bucket, object, minio.StatObjectOptions{
+			ServerSideEncryption: sse,
+			VersionID:            ui.VersionID,
+			Checksum:             true,
+		})
+		if e != nil {
+			return probe.NewError(e)
+		}
+		recorded = info.ChecksumCRC32C
+	}
+	if !isCompositeChecksum(recorded) {
+		return probe.NewError(ChecksumNotVerified{
+			Path:      c.targetURL.String(),
+			Algorithm: minio.ChecksumCRC32C.String(),
+		})
+	}
+	if computed := parts.Sum(); recorded != computed {
+		return probe.NewError(ChecksumMismatch{
+			Path:      c.targetURL.String(),
+			Algorithm: minio.ChecksumCRC32C.String(),
+			Expected:  recorded,
+			Computed:  computed,
+		})
+	}
+	return nil
+}
+
 // PutPart - upload an object with custom metadata. (Same as Put)
 func (c *S3Client) PutPart(ctx context.Context, reader io.Reader, size int64, progress io.Reader, putOpts PutOptions) (int64, *probe.Error) {
 	return c.Put(ctx, reader, size, progress, putOpts)
@@ -1516,7 +1660,7 @@ func (c *S3Client) SetAccess(ctx context.Context, bucketPolicy string, isJSON bo
 }
 
 // listObjectWrapper - select ObjectList mode depending on arguments
-func (c *S3Client) listObjectWrapper(ctx context.Context, bucket, object string, isRecursive bool, timeRef time.Time, withVersions, withDeleteMarkers, metadata bool, maxKeys int, zip bool) <-chan minio.ObjectInfo {
+func (c *S3Client) listObjectWrapper(ctx context.Context, bucket, object string, isRecursive bool, timeRef time.Time, withVersions, withDeleteMarkers, metadata bool, maxKeys int, zip bool, startAfter string) <-chan minio.ObjectInfo {
 	if !timeRef.IsZero() || withVersions {
 		return c.listVersions(ctx, bucket, object, ListOptions{Recursive: isRecursive, TimeRef: timeRef, WithOlderVersions: withVersions, WithDeleteMarkers: withDeleteMarkers})
 	}
@@ -1524,9 +1668,9 @@ func (c *S3Client) listObjectWrapper(ctx context.Context, bucket, object string,
 	if isGoogle(c.targetURL.Host) {
 		// Google Cloud S3 layer doesn't implement ListObjectsV2 implementation
 		// https://github.com/trinet2005/oss-mc/issues/3073
-		return c.api.ListObjects(ctx, bucket, minio.ListObjectsOptions{Prefix: object, Recursive: isRecursive, UseV1: true, MaxKeys: maxKeys})
+		return c.api.ListObjects(ctx, bucket, minio.ListObjectsOptions{Prefix: object, Recursive: isRecursive, UseV1: true, MaxKeys: maxKeys, StartAfter: startAfter})
 	}
-	opts := minio.ListObjectsOptions{Prefix: object, Recursive: isRecursive, WithMetadata: metadata, MaxKeys: maxKeys}
+	opts := minio.ListObjectsOptions{Prefix: object, Recursive: isRecursive, WithMetadata: metadata, MaxKeys: maxKeys, StartAfter: startAfter}
 	if zip {
 		// If prefix ends with .zip, add a slash.
 		if strings.HasSuffix(object, ".zip") {
@@ -1612,7 +1756,7 @@ func (c *S3Client) Stat(ctx context.Context, opts StatOptions) (*ClientContent,
 	// Start with a HEAD request first to return object metadata information.
 	// If the object is not found, continue to look for a directory marker or a prefix
 	if !strings.HasSuffix(path, string(c.targetURL.Separator)) && opts.timeRef.IsZero() {
-		o := minio.StatObjectOptions{ServerSideEncryption: opts.sse, VersionID: opts.versionID}
+		o := minio.StatObjectOptions{ServerSideEncryption: opts.sse, VersionID: opts.versionID, Checksum: opts.checksum.IsSet()}
 		if opts.isZip {
 			o.Set("x-minio-extract", "true")
 		}
@@ -1630,7 +1774,7 @@ func (c *S3Client) Stat(ctx context.Context, opts StatOptions) (*ClientContent,
 
 	nonRecursive := false
 	maxKeys := 1
-	for objectStat := range c.listObjectWrapper(ctx, bucket, path, nonRecursive, opts.timeRef, false, false, false, maxKeys, opts.isZip) {
+	for objectStat := range c.listObjectWrapper(ctx, bucket, path, nonRecursive, opts.timeRef, false, false, false, maxKeys, opts.isZip, "") {
 		if objectStat.Err != nil {
 			return nil, probe.NewError(objectStat.Err)
 		}
@@ -2206,6 +2350,9 @@ func (c *S3Client) objectInfo2ClientContent(bucket string, entry minio.ObjectInf
 	for k := range entry.Metadata {
 		content.Metadata[k] = entry.Metadata.Get(k)
 	}
+	for k, v := range objectChecksums(entry) {
+		content.Metadata[k] = v
+	}
 	attr, _ := parseAttribute(content.UserMetadata)
 	if len(attr) > 0 {
 		_, mtime, _ := parseAtimeMtime(attr)
@@ -2277,7 +2424,11 @@ func (c *S3Client) listInRoutine(ctx context.Context, contentCh chan *ClientCont
 		contentCh <- content
 	default:
 		isRecursive := false
-		for object := range c.listObjectWrapper(ctx, b, o, isRecursive, time.Time{}, false, false, opts.WithMetadata, -1, opts.ListZip) {
+		var startAfter string
+		if opts.StartAfter != "" {
+			startAfter = o + opts.StartAfter
+		}
+		for object := range c.listObjectWrapper(ctx, b, o, isRecursive, time.Time{}, false, false, opts.WithMetadata, -1, opts.ListZip, startAfter) {
 			if object.Err != nil {
 				contentCh <- &ClientContent{
 					Err: probe.NewError(object.Err),
@@ -2325,12 +2476,24 @@ func (c *S3Client) listRecursiveInRoutine(ctx context.Context, contentCh chan *C
 		}
 		sortBucketsNameWithSlash(buckets)
 		for _, bucket := range buckets {
+			// Skip buckets entirely listed before the marker.
+			var startAfter string
+			if opts.StartAfter != "" {
+				markerBucket, markerObject, _ := strings.Cut(opts.StartAfter, "/")
+				if bucket.Name+"/" < markerBucket+"/" {
+					continue
+				}
+				if bucket.Name == markerBucket {
+					startAfter = markerObject
+				}
+			}
+
 			if opts.ShowDir == DirFirst {
 				contentCh <- c.bucketInfo2ClientContent(bucket)
 			}
 
 			isRecursive := true
-			for object := range c.listObjectWrapper(ctx, bucket.Name, o, isRecursive, time.Time{}, false, false, opts.WithMetadata, -1, opts.ListZip) {
+			for object := range c.listObjectWrapper(ctx, bucket.Name, o, isRecursive, time.Time{}, false, false, opts.WithMetadata, -1, opts.ListZip, startAfter) {
 				if object.Err != nil {
 					contentCh <- &ClientContent{
 						Err: probe.NewError(object.Err),
@@ -2346,7 +2509,11 @@ func (c *S3Client) listRecursiveInRoutine(ctx context.Context, contentCh chan *C
 		}
 	default:
 		isRecursive := true
-		for object := range c.listObjectWrapper(ctx, b, o, isRecursive, time.Time{}, false, false, opts.WithMetadata, -1, opts.ListZip) {
+		var startAfter string
+		if opts.StartAfter != "" {
+			startAfter = o + opts.StartAfter
+		}
+		for object := range c.listObjectWrapper(ctx, b, o, isRecursive, time.Time{}, false, false, opts.WithMetadata, -1, opts.ListZip, startAfter) {
 			if object.Err != nil {
 				contentCh <- &ClientContent{
 					Err: probe.NewError(object.Err),
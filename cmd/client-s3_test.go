@@ -21,10 +21,14 @@ package cmd
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"hash/crc32"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
+	"time"
 
 	minio "github.com/trinet2005/oss-go-sdk"
 	. "gopkg.in/check.v1"
@@ -264,3 +268,128 @@ func (s *TestSuite) TestSelectCompressionType(c *C) {
 		c.Assert(cType, DeepEquals, test.compressionType)
 	}
 }
+
+// checksumHandler is an http.Handler recording uploads and returning the
+// composite CRC32C of the parts, corrupted if corrupt is set or left out
+// if noChecksum is set.
+type checksumHandler struct {
+	corrupt    bool
+	noChecksum bool
+	partSums   []byte
+	putHeader  http.Header
+}
+
+func (h *checksumHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	switch {
+	case r.Method == "GET" && query.Has("location"):
+		w.Write([]byte(`<LocationConstraint xmlns="http://s3.amazonaws.com/doc/2006-03-01/">us-east-1</LocationConstraint>`))
+	case r.Method == "POST" && query.Has("uploads"):
+		w.Write([]byte(`<InitiateMultipartUploadResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><Bucket>bucket</Bucket><Key>object</Key><UploadId>upload</UploadId></InitiateMultipartUploadResult>`))
+	case r.Method == "PUT" && query.Has("partNumber"):
+		// Bodies are signed in chunks, the part checksums were verified
+		// by a real server.
+		io.Copy(io.Discard, r.Body)
+		sum, _ := base64.StdEncoding.DecodeString(r.Header.Get("X-Amz-Checksum-Crc32c"))
+		if h.corrupt {
+			sum[0] ^= 0xff
+		}
+		h.partSums = append(h.partSums, sum...)
+		w.Header().Set("ETag", `"`+query.Get("partNumber")+`"`)
+	case r.Method == "POST" && query.Has("uploadId"):
+		composite := crc32.Checksum(h.partSums, crc32.MakeTable(crc32.Castagnoli))
+		checksum := base64.StdEncoding.EncodeToString(binary.BigEndian.AppendUint32(nil, composite)) + "-" + strconv.Itoa(len(h.partSums)/4)
+		if h.noChecksum {
+			checksum = ""
+		}
+		w.Write([]byte(`<CompleteMultipartUploadResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><Bucket>bucket</Bucket><Key>object</Key><ETag>"etag-2"</ETag><ChecksumCRC32C>` + checksum + `</ChecksumCRC32C></CompleteMultipartUploadResult>`))
+	case r.Method == "PUT":
+		io.Copy(io.Discard, r.Body)
+		h.putHeader = r.Header
+		w.Header().Set("ETag", `"etag"`)
+	case r.Method == "HEAD":
+		w.Header().Set("ETag", `"etag-3"`)
+		w.Header().Set("Content-Length", "0")
+		w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+// Test the verification of the checksums of uploads.
+func (s *TestSuite) TestPutChecksumVerify(c *C) {
+	handler := &checksumHandler{}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	conf := new(Config)
+	conf.HostURL = server.URL + "/bucket/object"
+	conf.AccessKey = "WLGDGYAQYIGI833EV05A"
+	conf.SecretKey = "BYvgJM101sHngl2uzjXS/OBF/aMxAN06JrJ3qJlF"
+	conf.Signature = "S3v4"
+	s3c, err := S3New(conf)
+	c.Assert(err, IsNil)
+
+	// Single parts without a recorded checksum are sent with the
+	// checksum of the data read.
+	data := []byte("Hello, World")
+	_, err = s3c.Put(context.Background(), bytes.NewReader(data), int64(len(data)), nil, PutOptions{checksum: minio.ChecksumSHA256})
+	c.Assert(err, IsNil)
+	c.Assert(handler.putHeader.Get("X-Amz-Checksum-Sha256"), Equals, minio.ChecksumSHA256.ChecksumBytes(data).Encoded())
+
+	// Multipart uploads are verified by the composite checksum of their parts.
+	data = bytes.Repeat([]byte("0123456789"), 1<<20+7)
+	opts := PutOptions{checksum: minio.ChecksumCRC32C, multipartSize: 5 << 20, multipartThreads: 1}
+	_, err = s3c.Put(context.Background(), bytes.NewReader(data), int64(len(data)), nil, opts)
+	c.Assert(err, IsNil)
+	c.Assert(len(handler.partSums), Equals, 3*4)
+
+	*handler = checksumHandler{corrupt: true}
+	_, err = s3c.Put(context.Background(), bytes.NewReader(data), int64(len(data)), nil, opts)
+	c.Assert(err, NotNil)
+	_, ok := err.ToGoError().(ChecksumMismatch)
+	c.Assert(ok, Equals, true, Commentf("%v", err))
+
+	// Nothing recorded to compare with.
+	*handler = checksumHandler{noChecksum: true}
+	_, err = s3c.Put(context.Background(), bytes.NewReader(data), int64(len(data)), nil, opts)
+	c.Assert(err, NotNil)
+	_, ok = err.ToGoError().(ChecksumNotVerified)
+	c.Assert(ok, Equals, true, Commentf("%v", err))
+
+	// Other checksums cannot be sent with the parts.
+	*handler = checksumHandler{}
+	opts.checksum = minio.ChecksumSHA256
+	_, err = s3c.Put(context.Background(), bytes.NewReader(data), int64(len(data)), nil, opts)
+	c.Assert(err, NotNil)
+	_, ok = err.ToGoError().(ChecksumUnsupported)
+	c.Assert(ok, Equals, true, Commentf("%v", err))
+	c.Assert(handler.partSums, HasLen, 0)
+}
+
+func (s *TestSuite) TestPartChecksums(c *C) {
+	data := bytes.Repeat([]byte("abc"), 4<<20)
+	partSize := int64(5 << 20)
+	var partSums []byte
+	for off := int64(0); off < int64(len(data)); off += partSize {
+		end := off + partSize
+		if end > int64(len(data)) {
+			end = int64(len(data))
+		}
+		partSums = binary.BigEndian.AppendUint32(partSums, crc32.Checksum(data[off:end], crc32.MakeTable(crc32.Castagnoli)))
+	}
+	composite := crc32.Checksum(partSums, crc32.MakeTable(crc32.Castagnoli))
+	expected := base64.StdEncoding.EncodeToString(binary.BigEndian.AppendUint32(nil, composite)) + "-3"
+
+	parts, e := newPartChecksums(int64(len(data)), uint64(partSize))
+	c.Assert(e, IsNil)
+	// Written in chunks not aligned with the parts.
+	for off := 0; off < len(data); off += 1 << 19 / 3 {
+		end := off + 1<<19/3
+		if end > len(data) {
+			end = len(data)
+		}
+		parts.Write(data[off:end])
+	}
+	c.Assert(parts.Sum(), Equals, expected)
+}
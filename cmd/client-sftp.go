@@ -0,0 +1,880 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/sftp"
+	minio "github.com/trinet2005/oss-go-sdk"
+	"github.com/trinet2005/oss-go-sdk/pkg/encrypt"
+	"github.com/trinet2005/oss-go-sdk/pkg/lifecycle"
+	"github.com/trinet2005/oss-go-sdk/pkg/replication"
+	"github.com/trinet2005/oss-mc/pkg/hookreader"
+	"github.com/trinet2005/oss-mc/pkg/probe"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+const (
+	// sftpScheme - scheme of URLs of files on SFTP servers.
+	sftpScheme = "sftp"
+	// sftpDialTimeout - timeout of connecting to an SFTP server.
+	sftpDialTimeout = 30 * time.Second
+)
+
+// sftp client, paths are absolute on the server.
+type sftpClient struct {
+	PathURL *ClientURL
+	hostCfg *aliasConfigV10
+
+	mutex  sync.Mutex
+	client *sftp.Client
+}
+
+// sftpNew - instantiate a new SFTP client, connections are shared by
+// all clients of the same server and user.
+func sftpNew(urlStr string, hostCfg *aliasConfigV10) (Client, *probe.Error) {
+	pathURL := newClientURL(urlStr)
+	if pathURL.Type != sftpStorage {
+		return nil, errInvalidURL(urlStr).Trace(urlStr)
+	}
+	if pathURL.Path == "" {
+		pathURL.Path = "/"
+	}
+	client, err := sftpConnect(pathURL.Host, hostCfg, nil)
+	if err != nil {
+		return nil, err.Trace(urlStr)
+	}
+	return &sftpClient{PathURL: pathURL, hostCfg: hostCfg, client: client}, nil
+}
+
+var (
+	sftpConnsMutex sync.Mutex
+	sftpConns      = make(map[string]*sftp.Client)
+)
+
+// sftpConnect returns the connection to a server, authenticated as the
+// user in the authority or the access key of the alias. A stale
+// connection which was lost is closed and replaced by a new one.
+func sftpConnect(authority string, hostCfg *aliasConfigV10, stale *sftp.Client) (*sftp.Client, *probe.Error) {
+	user, address := "", authority
+	if i := strings.LastIndex(authority, "@"); i >= 0 {
+		user, address = authority[:i], authority[i+1:]
+	}
+	var password string
+	if hostCfg != nil {
+		if user == "" {
+			user = hostCfg.AccessKey
+		}
+		password = hostCfg.SecretKey
+	}
+	if user == "" {
+		user = os.Getenv("USER")
+	}
+	if _, _, e := net.SplitHostPort(address); e != nil {
+		address = net.JoinHostPort(address, "22")
+	}
+
+	sftpConnsMutex.Lock()
+	defer sftpConnsMutex.Unlock()
+
+	key := user + "@" + address
+	if client, ok := sftpConns[key]; ok {
+		if client != stale {
+			return client, nil
+		}
+		client.Close()
+		delete(sftpConns, key)
+	}
+	config, agentConn, err := sftpClientConfig(user, password)
+	if err != nil {
+		return nil, err.Trace(key)
+	}
+	if agentConn != nil {
+		// The agent is only used during the handshake.
+		defer agentConn.Close()
+	}
+	conn, e := ssh.Dial("tcp", address, config)
+	if e != nil {
+		return nil, probe.NewError(e).Trace(key)
+	}
+	client, e := sftp.NewClient(conn)
+	if e != nil {
+		conn.Close()
+		return nil, probe.NewError(e).Trace(key)
+	}
+	sftpConns[key] = client
+	return client, nil
+}
+
+// sftpClientConfig returns the SSH configuration authenticating with the
+// password of the alias, the SSH agent and the identity files, the host
+// key is checked against the known hosts. The connection to the agent,
+// if any, is closed by the caller once connected.
+func sftpClientConfig(user, password string) (config *ssh.ClientConfig, agentConn net.Conn, err *probe.Error) {
+	var auths []ssh.AuthMethod
+	if password != "" {
+		auths = append(auths, ssh.Password(password))
+	}
+	if socket := os.Getenv("SSH_AUTH_SOCK"); socket != "" {
+		if conn, e := net.Dial("unix", socket); e == nil {
+			agentConn = conn
+			auths = append(auths, ssh.PublicKeysCallback(agent.NewClient(conn).Signers))
+		}
+	}
+	closeAgent := func() {
+		if agentConn != nil {
+			agentConn.Close()
+		}
+	}
+	var signers []ssh.Signer
+	for _, identity := range sftpIdentityFiles() {
+		data, e := os.ReadFile(identity)
+		if e != nil {
+			continue
+		}
+		signer, e := ssh.ParsePrivateKey(data)
+		if e != nil {
+			// Keys protected by a passphrase are used through the agent.
+			continue
+		}
+		signers = append(signers, signer)
+	}
+	if len(signers) > 0 {
+		auths = append(auths, ssh.PublicKeys(signers...))
+	}
+
+	hostKeyCallback := ssh.InsecureIgnoreHostKey()
+	if !globalInsecure {
+		knownHosts := os.Getenv("MC_SFTP_KNOWN_HOSTS")
+		if knownHosts == "" {
+			home, e := os.UserHomeDir()
+			if e != nil {
+				closeAgent()
+				return nil, nil, probe.NewError(e)
+			}
+			knownHosts = filepath.Join(home, ".ssh", "known_hosts")
+		}
+		callback, e := knownhosts.New(knownHosts)
+		if e != nil {
+			closeAgent()
+			return nil, nil, probe.NewError(e).Trace(knownHosts)
+		}
+		hostKeyCallback = callback
+	}
+
+	return &ssh.ClientConfig{
+		User:            user,
+		Auth:            auths,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         sftpDialTimeout,
+	}, agentConn, nil
+}
+
+// sftpIdentityFiles returns the private keys set in MC_SFTP_IDENTITY,
+// comma separated, or the default keys of the user.
+func sftpIdentityFiles() []string {
+	if identities := os.Getenv("MC_SFTP_IDENTITY"); identities != "" {
+		return strings.Split(identities, ",")
+	}
+	home, e := os.UserHomeDir()
+	if e != nil {
+		return nil
+	}
+	var identities []string
+	for _, name := range []string{"id_ed25519", "id_ecdsa", "id_rsa"} {
+		identities = append(identities, filepath.Join(home, ".ssh", name))
+	}
+	return identities
+}
+
+// conn returns the connection of the client.
+func (c *sftpClient) conn() *sftp.Client {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.client
+}
+
+// retry runs fn on the connection of the client, a connection lost
+// since its last use, e.g. closed by the server when idle, is dialed
+// again once.
+func (c *sftpClient) retry(fn func(client *sftp.Client) error) error {
+	stale := c.conn()
+	e := fn(stale)
+	if !errors.Is(e, sftp.ErrSSHFxConnectionLost) {
+		return e
+	}
+	client, err := sftpConnect(c.PathURL.Host, c.hostCfg, stale)
+	if err != nil {
+		return err.ToGoError()
+	}
+	c.mutex.Lock()
+	c.client = client
+	c.mutex.Unlock()
+	return fn(client)
+}
+
+// GetURL get url.
+func (c *sftpClient) GetURL() ClientURL {
+	return *c.PathURL
+}
+
+// toClientError constructs a typed client error for known SFTP errors.
+func (c *sftpClient) toClientError(e error, fpath string) *probe.Error {
+	var status *sftp.StatusError
+	switch {
+	case errors.Is(e, os.ErrNotExist):
+		return probe.NewError(PathNotFound{Path: fpath})
+	case errors.Is(e, os.ErrPermission):
+		return probe.NewError(PathInsufficientPermission{Path: fpath})
+	case errors.As(e, &status) && status.FxCode() == sftp.ErrSSHFxNoSuchFile:
+		return probe.NewError(PathNotFound{Path: fpath})
+	case errors.As(e, &status) && status.FxCode() == sftp.ErrSSHFxPermissionDenied:
+		return probe.NewError(PathInsufficientPermission{Path: fpath})
+	}
+	return probe.NewError(e)
+}
+
+func (c *sftpClient) content(fpath string, fi os.FileInfo) *ClientContent {
+	pathURL := *c.PathURL
+	pathURL.Path = fpath
+	return &ClientContent{
+		URL:  pathURL,
+		Time: fi.ModTime(),
+		Size: fi.Size(),
+		Type: fi.Mode(),
+	}
+}
+
+// Stat - get metadata of a file or folder, symbolic links are followed.
+func (c *sftpClient) Stat(_ context.Context, opts StatOptions) (*ClientContent, *probe.Error) {
+	fpath := c.PathURL.Path
+	if opts.incomplete {
+		fpath += partSuffix
+	}
+	var fi os.FileInfo
+	e := c.retry(func(client *sftp.Client) (e error) {
+		fi, e = client.Stat(fpath)
+		return e
+	})
+	if e != nil {
+		return nil, c.toClientError(e, fpath).Trace(fpath)
+	}
+	content := c.content(c.PathURL.Path, fi)
+	content.Metadata = map[string]string{
+		"Content-Type": guessURLContentType(c.PathURL.Path),
+	}
+	return content, nil
+}
+
+// Get returns a reader of a file from the requested offset.
+func (c *sftpClient) Get(_ context.Context, opts GetOptions) (io.ReadCloser, *probe.Error) {
+	var file *sftp.File
+	e := c.retry(func(client *sftp.Client) (e error) {
+		file, e = client.Open(c.PathURL.Path)
+		return e
+	})
+	if e != nil {
+		return nil, c.toClientError(e, c.PathURL.Path).Trace(c.PathURL.Path)
+	}
+	if opts.RangeStart != 0 {
+		if _, e = file.Seek(opts.RangeStart, io.SeekStart); e != nil {
+			file.Close()
+			return nil, probe.NewError(e).Trace(c.PathURL.Path)
+		}
+	}
+	if opts.RangeLength > 0 {
+		return struct {
+			io.Reader
+			io.Closer
+		}{io.LimitReader(file, opts.RangeLength), file}, nil
+	}
+	return file, nil
+}
+
+// Put - create a new file, written to a temporary file renamed when
+// complete.
+func (c *sftpClient) Put(_ context.Context, reader io.Reader, size int64, progress io.Reader, opts PutOptions) (int64, *probe.Error) {
+	objectPath := c.PathURL.Path
+	objectDir, objectName := path.Split(objectPath)
+	if objectDir != "" {
+		e := c.retry(func(client *sftp.Client) error {
+			return client.MkdirAll(objectDir)
+		})
+		if e != nil {
+			return 0, c.toClientError(e, objectDir).Trace(objectPath)
+		}
+		// An empty object name is a folder.
+		if objectName == "" {
+			return 0, nil
+		}
+	}
+
+	partPath := objectPath + partSuffix
+	var file *sftp.File
+	e := c.retry(func(client *sftp.Client) (e error) {
+		file, e = client.OpenFile(partPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC)
+		return e
+	})
+	if e != nil {
+		return 0, c.toClientError(e, partPath).Trace(objectPath)
+	}
+	client := c.conn()
+	defer client.Remove(partPath)
+
+	totalWritten, e := io.Copy(file, hookreader.NewHook(reader, progress))
+	if e != nil {
+		file.Close()
+		return totalWritten, probe.NewError(e).Trace(objectPath)
+	}
+	if e = file.Close(); e != nil {
+		return totalWritten, probe.NewError(e).Trace(objectPath)
+	}
+
+	if size > 0 {
+		if totalWritten < size {
+			return totalWritten, probe.NewError(UnexpectedEOF{TotalSize: size, TotalWritten: totalWritten})
+		}
+		if totalWritten > size {
+			return totalWritten, probe.NewError(UnexpectedExcessRead{TotalSize: size, TotalWritten: totalWritten})
+		}
+	}
+
+	if e = sftpRename(client, partPath, objectPath); e != nil {
+		return totalWritten, c.toClientError(e, objectPath).Trace(partPath, objectPath)
+	}
+
+	if _, ok := opts.metadata[metadataKey]; ok && opts.isPreserve {
+		attr, e := parseAttribute(opts.metadata)
+		if e != nil {
+			return totalWritten, probe.NewError(e)
+		}
+		atime, mtime, err := parseAtimeMtime(attr)
+		if err != nil {
+			return totalWritten, err.Trace(objectPath)
+		}
+		if !atime.IsZero() && !mtime.IsZero() {
+			if e = client.Chtimes(objectPath, atime, mtime); e != nil {
+				return totalWritten, c.toClientError(e, objectPath).Trace(objectPath)
+			}
+		}
+	}
+	return totalWritten, nil
+}
+
+// sftpRename replaces newpath, servers without the posix-rename
+// extension fail renaming to an existing file.
+func sftpRename(client *sftp.Client, oldpath, newpath string) error {
+	if _, ok := client.HasExtension("posix-rename@openssh.com"); ok {
+		return client.PosixRename(oldpath, newpath)
+	}
+	if e := client.Remove(newpath); e != nil && !errors.Is(e, os.ErrNotExist) {
+		var status *sftp.StatusError
+		if !errors.As(e, &status) || status.FxCode() != sftp.ErrSSHFxNoSuchFile {
+			return e
+		}
+	}
+	return client.Rename(oldpath, newpath)
+}
+
+// PutPart - not supported, files are written in one go.
+func (c *sftpClient) PutPart(ctx context.Context, reader io.Reader, size int64, progress io.Reader, opts PutOptions) (int64, *probe.Error) {
+	return c.Put(ctx, reader, size, progress, opts)
+}
+
+// Copy - copy a file of the same server, the data goes through mc.
+func (c *sftpClient) Copy(ctx context.Context, source string, opts CopyOptions, progress io.Reader) *probe.Error {
+	var file *sftp.File
+	e := c.retry(func(client *sftp.Client) (e error) {
+		file, e = client.Open(source)
+		return e
+	})
+	if e != nil {
+		return c.toClientError(e, source).Trace(source)
+	}
+	defer file.Close()
+
+	putOpts := PutOptions{
+		metadata:   opts.metadata,
+		isPreserve: opts.isPreserve,
+	}
+	if _, err := c.Put(ctx, file, opts.size, progress, putOpts); err != nil {
+		return err.Trace(source, c.PathURL.Path)
+	}
+	return nil
+}
+
+// Remove - remove files and empty folders, folders left empty are
+// removed as well up to the folder of the client.
+func (c *sftpClient) Remove(_ context.Context, isIncomplete, _, _, _ bool, contentCh <-chan *ClientContent) <-chan RemoveResult {
+	resultCh := make(chan RemoveResult)
+	go func() {
+		defer close(resultCh)
+		for content := range contentCh {
+			if content.Err != nil {
+				resultCh <- RemoveResult{Err: content.Err}
+				continue
+			}
+			name := content.URL.Path
+			if isIncomplete {
+				name += partSuffix
+			}
+			err := c.remove(name)
+			if err == nil {
+				res := RemoveResult{}
+				res.ObjectName = content.URL.Path
+				resultCh <- res
+				continue
+			}
+			switch err.ToGoError().(type) {
+			case PathNotFound:
+				// Ignore if the path is already removed.
+			case PathInsufficientPermission:
+				resultCh <- RemoveResult{Err: err}
+			default:
+				resultCh <- RemoveResult{Err: err}
+				return
+			}
+		}
+	}()
+	return resultCh
+}
+
+func (c *sftpClient) remove(name string) *probe.Error {
+	var fi os.FileInfo
+	e := c.retry(func(client *sftp.Client) (e error) {
+		fi, e = client.Lstat(name)
+		return e
+	})
+	if e != nil {
+		return c.toClientError(e, name).Trace(name)
+	}
+	client := c.conn()
+	if fi.IsDir() {
+		e = client.RemoveDirectory(name)
+	} else {
+		e = client.Remove(name)
+	}
+	if e != nil {
+		if fi.IsDir() {
+			// Folders which are not empty are kept.
+			return nil
+		}
+		return c.toClientError(e, name).Trace(name)
+	}
+
+	basePath := strings.TrimSuffix(c.PathURL.Path, "/")
+	for dir := path.Dir(strings.TrimSuffix(name, "/")); strings.HasPrefix(dir, basePath+"/"); dir = path.Dir(dir) {
+		if client.RemoveDirectory(dir) != nil {
+			break
+		}
+	}
+	return nil
+}
+
+// readDir returns the entries of a folder sorted by name, folders are
+// sorted with a trailing slash as in a listing of object storage.
+func (c *sftpClient) readDir(dir string) ([]os.FileInfo, error) {
+	var fis []os.FileInfo
+	e := c.retry(func(client *sftp.Client) (e error) {
+		fis, e = client.ReadDir(dir)
+		return e
+	})
+	if e != nil {
+		return nil, e
+	}
+	client := c.conn()
+	entries := fis[:0]
+	for _, fi := range fis {
+		if fi.Name() == "." || fi.Name() == ".." {
+			continue
+		}
+		if fi.Mode()&os.ModeSymlink != 0 {
+			st, e := client.Stat(path.Join(dir, fi.Name()))
+			if e != nil {
+				// Ignore broken symbolic links.
+				continue
+			}
+			fi = sftpNamedFileInfo{FileInfo: st, name: fi.Name()}
+		}
+		entries = append(entries, fi)
+	}
+	sort.Sort(byDirName(entries))
+	return entries, nil
+}
+
+// sftpNamedFileInfo is the target of a symbolic link under the name of
+// the link.
+type sftpNamedFileInfo struct {
+	os.FileInfo
+	name string
+}
+
+func (fi sftpNamedFileInfo) Name() string {
+	return fi.name
+}
+
+// List - list files and folders.
+func (c *sftpClient) List(ctx context.Context, opts ListOptions) <-chan *ClientContent {
+	contentCh := make(chan *ClientContent, 1)
+	go func() {
+		defer close(contentCh)
+		if opts.ListZip {
+			contentCh <- &ClientContent{Err: probe.NewError(errors.New("zip listing not supported for SFTP"))}
+			return
+		}
+		if opts.Recursive {
+			c.listRecursive(ctx, opts, contentCh)
+		} else {
+			c.listDir(ctx, opts, contentCh)
+		}
+	}()
+	return contentCh
+}
+
+func (c *sftpClient) send(ctx context.Context, opts ListOptions, contentCh chan<- *ClientContent, content *ClientContent) bool {
+	if opts.Incomplete != strings.HasSuffix(content.URL.Path, partSuffix) {
+		return true
+	}
+	if opts.Incomplete {
+		content.URL.Path = strings.TrimSuffix(content.URL.Path, partSuffix)
+	}
+	if opts.StartAfter != "" && strings.TrimPrefix(content.URL.Path, c.PathURL.Path) <= opts.StartAfter {
+		return true
+	}
+	select {
+	case contentCh <- content:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// listDir lists the entries of a folder, or the entries of its parent
+// folder starting with its name if the path does not end with a slash.
+func (c *sftpClient) listDir(ctx context.Context, opts ListOptions, contentCh chan<- *ClientContent) {
+	fpath := c.PathURL.Path
+	dir, prefix := fpath, ""
+	if !strings.HasSuffix(fpath, "/") {
+		dir, prefix = path.Split(fpath)
+	}
+	entries, e := c.readDir(dir)
+	if e != nil {
+		contentCh <- &ClientContent{Err: c.toClientError(e, dir).Trace(dir)}
+		return
+	}
+	for _, fi := range entries {
+		if !strings.HasPrefix(fi.Name(), prefix) || isIgnoredFile(fi.Name()) {
+			continue
+		}
+		if !fi.Mode().IsRegular() && !fi.IsDir() {
+			continue
+		}
+		if !c.send(ctx, opts, contentCh, c.content(path.Join(dir, fi.Name()), fi)) {
+			return
+		}
+	}
+}
+
+// listRecursive lists all files below a folder in lexical order, or
+// below the folders starting with its name if the path does not end with
+// a slash.
+func (c *sftpClient) listRecursive(ctx context.Context, opts ListOptions, contentCh chan<- *ClientContent) {
+	var walk func(dir, prefix string) bool
+	walk = func(dir, prefix string) bool {
+		entries, e := c.readDir(dir)
+		if e != nil {
+			err := c.toClientError(e, dir)
+			select {
+			case contentCh <- &ClientContent{Err: err.Trace(dir)}:
+			case <-ctx.Done():
+				return false
+			}
+			// Unreadable folders are skipped.
+			_, ok := err.ToGoError().(PathInsufficientPermission)
+			return ok
+		}
+		for _, fi := range entries {
+			if !strings.HasPrefix(fi.Name(), prefix) || isIgnoredFile(fi.Name()) {
+				continue
+			}
+			fpath := path.Join(dir, fi.Name())
+			if _, link := fi.(sftpNamedFileInfo); link && fi.IsDir() {
+				// Symbolic links to folders are not followed, as for
+				// local folders, they may loop.
+				continue
+			}
+			switch {
+			case fi.IsDir():
+				content := c.content(fpath+"/", fi)
+				if opts.ShowDir == DirFirst && !opts.Incomplete && !c.send(ctx, opts, contentCh, content) {
+					return false
+				}
+				if !walk(fpath, "") {
+					return false
+				}
+				if opts.ShowDir == DirLast && !opts.Incomplete && !c.send(ctx, opts, contentCh, content) {
+					return false
+				}
+			case fi.Mode().IsRegular():
+				if !c.send(ctx, opts, contentCh, c.content(fpath, fi)) {
+					return false
+				}
+			}
+		}
+		return true
+	}
+
+	fpath := c.PathURL.Path
+	if strings.HasSuffix(fpath, "/") {
+		walk(path.Clean(fpath), "")
+		return
+	}
+	var fi os.FileInfo
+	e := c.retry(func(client *sftp.Client) (e error) {
+		fi, e = client.Stat(fpath)
+		return e
+	})
+	if e == nil && fi.Mode().IsRegular() {
+		c.send(ctx, opts, contentCh, c.content(fpath, fi))
+		return
+	}
+	dir, prefix := path.Split(fpath)
+	walk(path.Clean(dir), prefix)
+}
+
+// MakeBucket - create a folder.
+func (c *sftpClient) MakeBucket(_ context.Context, _ string, _, _ bool) *probe.Error {
+	e := c.retry(func(client *sftp.Client) error {
+		return client.MkdirAll(c.PathURL.Path)
+	})
+	if e != nil {
+		return c.toClientError(e, c.PathURL.Path).Trace(c.PathURL.Path)
+	}
+	return nil
+}
+
+// RemoveBucket - remove a folder, with all its content if forced.
+func (c *sftpClient) RemoveBucket(_ context.Context, forceRemove bool) *probe.Error {
+	e := c.retry(func(client *sftp.Client) error {
+		if forceRemove {
+			return client.RemoveAll(c.PathURL.Path)
+		}
+		return client.RemoveDirectory(c.PathURL.Path)
+	})
+	if e != nil {
+		return c.toClientError(e, c.PathURL.Path).Trace(c.PathURL.Path)
+	}
+	return nil
+}
+
+// ListBuckets - list the folders of the path.
+func (c *sftpClient) ListBuckets(_ context.Context) ([]*ClientContent, *probe.Error) {
+	dir := c.PathURL.Path
+	entries, e := c.readDir(dir)
+	if e != nil {
+		return nil, c.toClientError(e, dir).Trace(dir)
+	}
+	var buckets []*ClientContent
+	for _, fi := range entries {
+		if fi.IsDir() {
+			buckets = append(buckets, c.content(path.Join(dir, fi.Name()), fi))
+		}
+	}
+	return buckets, nil
+}
+
+// AddUserAgent - not applicable to SFTP.
+func (c *sftpClient) AddUserAgent(_, _ string) {
+}
+
+func (c *sftpClient) notImplemented(api string) *probe.Error {
+	return probe.NewError(APINotImplemented{API: api, APIType: "sftp"})
+}
+
+// Select - not supported for SFTP.
+func (c *sftpClient) Select(_ context.Context, _ string, _ encrypt.ServerSide, _ SelectObjectOpts) (io.ReadCloser, *probe.Error) {
+	return nil, c.notImplemented("Select")
+}
+
+// Watch - not supported for SFTP.
+func (c *sftpClient) Watch(_ context.Context, _ WatchOptions) (*WatchObject, *probe.Error) {
+	return nil, c.notImplemented("Watch")
+}
+
+// ShareDownload - not supported for SFTP.
+func (c *sftpClient) ShareDownload(_ context.Context, _ string, _ time.Duration) (string, *probe.Error) {
+	return "", c.notImplemented("ShareDownload")
+}
+
+// ShareUpload - not supported for SFTP.
+func (c *sftpClient) ShareUpload(_ context.Context, _ bool, _ time.Duration, _ string) (string, map[string]string, *probe.Error) {
+	return "", nil, c.notImplemented("ShareUpload")
+}
+
+// SetObjectLockConfig - not supported for SFTP.
+func (c *sftpClient) SetObjectLockConfig(_ context.Context, _ minio.RetentionMode, _ uint64, _ minio.ValidityUnit) *probe.Error {
+	return c.notImplemented("SetObjectLockConfig")
+}
+
+// GetObjectLockConfig - not supported for SFTP.
+func (c *sftpClient) GetObjectLockConfig(_ context.Context) (string, minio.RetentionMode, uint64, minio.ValidityUnit, *probe.Error) {
+	return "", "", 0, "", c.notImplemented("GetObjectLockConfig")
+}
+
+// GetAccess - not supported for SFTP.
+func (c *sftpClient) GetAccess(_ context.Context) (string, string, *probe.Error) {
+	return "", "", c.notImplemented("GetAccess")
+}
+
+// GetAccessRules - not supported for SFTP.
+func (c *sftpClient) GetAccessRules(_ context.Context) (map[string]string, *probe.Error) {
+	return map[string]string{}, c.notImplemented("GetAccessRules")
+}
+
+// SetAccess - not supported for SFTP.
+func (c *sftpClient) SetAccess(_ context.Context, _ string, _ bool) *probe.Error {
+	return c.notImplemented("SetAccess")
+}
+
+// PutObjectRetention - not supported for SFTP.
+func (c *sftpClient) PutObjectRetention(_ context.Context, _ string, _ minio.RetentionMode, _ time.Time, _ bool) *probe.Error {
+	return c.notImplemented("PutObjectRetention")
+}
+
+// GetObjectRetention - not supported for SFTP.
+func (c *sftpClient) GetObjectRetention(_ context.Context, _ string) (minio.RetentionMode, time.Time, *probe.Error) {
+	return "", time.Time{}, c.notImplemented("GetObjectRetention")
+}
+
+// PutObjectLegalHold - not supported for SFTP.
+func (c *sftpClient) PutObjectLegalHold(_ context.Context, _ string, _ minio.LegalHoldStatus) *probe.Error {
+	return c.notImplemented("PutObjectLegalHold")
+}
+
+// GetObjectLegalHold - not supported for SFTP.
+func (c *sftpClient) GetObjectLegalHold(_ context.Context, _ string) (minio.LegalHoldStatus, *probe.Error) {
+	return "", c.notImplemented("GetObjectLegalHold")
+}
+
+// GetTags - not supported for SFTP.
+func (c *sftpClient) GetTags(_ context.Context, _ string) (map[string]string, *probe.Error) {
+	return nil, c.notImplemented("GetTags")
+}
+
+// SetTags - not supported for SFTP.
+func (c *sftpClient) SetTags(_ context.Context, _, _ string) *probe.Error {
+	return c.notImplemented("SetTags")
+}
+
+// DeleteTags - not supported for SFTP.
+func (c *sftpClient) DeleteTags(_ context.Context, _ string) *probe.Error {
+	return c.notImplemented("DeleteTags")
+}
+
+// GetLifecycle - not supported for SFTP.
+func (c *sftpClient) GetLifecycle(_ context.Context) (*lifecycle.Configuration, time.Time, *probe.Error) {
+	return nil, time.Time{}, c.notImplemented("GetLifecycle")
+}
+
+// SetLifecycle - not supported for SFTP.
+func (c *sftpClient) SetLifecycle(_ context.Context, _ *lifecycle.Configuration) *probe.Error {
+	return c.notImplemented("SetLifecycle")
+}
+
+// GetVersion - not supported for SFTP.
+func (c *sftpClient) GetVersion(_ context.Context) (minio.BucketVersioningConfiguration, *probe.Error) {
+	return minio.BucketVersioningConfiguration{}, c.notImplemented("GetVersion")
+}
+
+// SetVersion - not supported for SFTP.
+func (c *sftpClient) SetVersion(_ context.Context, _ string, _ []string, _ bool) *probe.Error {
+	return c.notImplemented("SetVersion")
+}
+
+// GetReplication - not supported for SFTP.
+func (c *sftpClient) GetReplication(_ context.Context) (replication.Config, *probe.Error) {
+	return replication.Config{}, c.notImplemented("GetReplication")
+}
+
+// SetReplication - not supported for SFTP.
+func (c *sftpClient) SetReplication(_ context.Context, _ *replication.Config, _ replication.Options) *probe.Error {
+	return c.notImplemented("SetReplication")
+}
+
+// RemoveReplication - not supported for SFTP.
+func (c *sftpClient) RemoveReplication(_ context.Context) *probe.Error {
+	return c.notImplemented("RemoveReplication")
+}
+
+// GetReplicationMetrics - not supported for SFTP.
+func (c *sftpClient) GetReplicationMetrics(_ context.Context) (replication.MetricsV2, *probe.Error) {
+	return replication.MetricsV2{}, c.notImplemented("GetReplicationMetrics")
+}
+
+// ResetReplication - not supported for SFTP.
+func (c *sftpClient) ResetReplication(_ context.Context, _ time.Duration, _ string) (replication.ResyncTargetsInfo, *probe.Error) {
+	return replication.ResyncTargetsInfo{}, c.notImplemented("ResetReplication")
+}
+
+// ReplicationResyncStatus - not supported for SFTP.
+func (c *sftpClient) ReplicationResyncStatus(_ context.Context, _ string) (replication.ResyncTargetsInfo, *probe.Error) {
+	return replication.ResyncTargetsInfo{}, c.notImplemented("ReplicationResyncStatus")
+}
+
+// GetEncryption - not supported for SFTP.
+func (c *sftpClient) GetEncryption(_ context.Context) (string, string, *probe.Error) {
+	return "", "", c.notImplemented("GetEncryption")
+}
+
+// SetEncryption - not supported for SFTP.
+func (c *sftpClient) SetEncryption(_ context.Context, _, _ string) *probe.Error {
+	return c.notImplemented("SetEncryption")
+}
+
+// DeleteEncryption - not supported for SFTP.
+func (c *sftpClient) DeleteEncryption(_ context.Context) *probe.Error {
+	return c.notImplemented("DeleteEncryption")
+}
+
+// GetBucketInfo - not supported for SFTP.
+func (c *sftpClient) GetBucketInfo(_ context.Context) (BucketInfo, *probe.Error) {
+	return BucketInfo{}, c.notImplemented("GetBucketInfo")
+}
+
+// Restore - not supported for SFTP.
+func (c *sftpClient) Restore(_ context.Context, _ string, _ int) *probe.Error {
+	return c.notImplemented("Restore")
+}
+
+// GetPart - not supported for SFTP.
+func (c *sftpClient) GetPart(_ context.Context, _ int) (io.ReadCloser, *probe.Error) {
+	return nil, c.notImplemented("GetPart")
+}
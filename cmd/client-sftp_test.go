@@ -0,0 +1,401 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/sftp"
+	"github.com/trinet2005/oss-mc/pkg/probe"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+	. "gopkg.in/check.v1"
+)
+
+// sftpTestServer - in-process SSH server serving the local filesystem
+// over SFTP, to the user "user" with the password "secret" or clientKey.
+type sftpTestServer struct {
+	listener net.Listener
+	hostKey  ssh.Signer
+	dir      string
+}
+
+func newSFTPTestServer(c *C, clientKey ssh.PublicKey) *sftpTestServer {
+	_, priv, e := ed25519.GenerateKey(rand.Reader)
+	c.Assert(e, IsNil)
+	hostKey, e := ssh.NewSignerFromKey(priv)
+	c.Assert(e, IsNil)
+
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if conn.User() == "user" && string(password) == "secret" {
+				return nil, nil
+			}
+			return nil, errInvalidArgument().ToGoError()
+		},
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if conn.User() == "user" && bytes.Equal(key.Marshal(), clientKey.Marshal()) {
+				return nil, nil
+			}
+			return nil, errInvalidArgument().ToGoError()
+		},
+	}
+	config.AddHostKey(hostKey)
+
+	listener, e := net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(e, IsNil)
+	go func() {
+		for {
+			conn, e := listener.Accept()
+			if e != nil {
+				return
+			}
+			go serveSFTPTestConn(conn, config)
+		}
+	}()
+
+	dir := c.MkDir()
+	return &sftpTestServer{listener: listener, hostKey: hostKey, dir: dir}
+}
+
+func serveSFTPTestConn(conn net.Conn, config *ssh.ServerConfig) {
+	_, chans, reqs, e := ssh.NewServerConn(conn, config)
+	if e != nil {
+		conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "unknown channel type")
+			continue
+		}
+		channel, requests, e := newChannel.Accept()
+		if e != nil {
+			continue
+		}
+		go func() {
+			for req := range requests {
+				// The payload is the length prefixed subsystem name.
+				ok := req.Type == "subsystem" && len(req.Payload) > 4 && string(req.Payload[4:]) == "sftp"
+				req.Reply(ok, nil)
+				if ok {
+					server, e := sftp.NewServer(channel)
+					if e == nil {
+						server.Serve()
+						server.Close()
+					}
+					channel.Close()
+				}
+			}
+		}()
+	}
+}
+
+func (t *sftpTestServer) addr() string {
+	return t.listener.Addr().String()
+}
+
+// url returns the sftp:// URL of a path below the served folder.
+func (t *sftpTestServer) url(user, path string) string {
+	u := "sftp://" + user + "@" + t.addr() + filepath.ToSlash(filepath.Join(t.dir, path))
+	if strings.HasSuffix(path, "/") && !strings.HasSuffix(u, "/") {
+		u += "/"
+	}
+	return u
+}
+
+// setupSFTPTest starts a server and trusts its host key, the client
+// authenticates with a key set in MC_SFTP_IDENTITY.
+func setupSFTPTest(c *C) (*sftpTestServer, func()) {
+	_, priv, e := ed25519.GenerateKey(rand.Reader)
+	c.Assert(e, IsNil)
+	clientKey, e := ssh.NewSignerFromKey(priv)
+	c.Assert(e, IsNil)
+	server := newSFTPTestServer(c, clientKey.PublicKey())
+
+	keyDir := c.MkDir()
+	der, e := x509.MarshalPKCS8PrivateKey(priv)
+	c.Assert(e, IsNil)
+	identity := filepath.Join(keyDir, "id_ed25519")
+	c.Assert(os.WriteFile(identity, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600), IsNil)
+	knownHosts := filepath.Join(keyDir, "known_hosts")
+	line := knownhosts.Line([]string{knownhosts.Normalize(server.addr())}, server.hostKey.PublicKey())
+	c.Assert(os.WriteFile(knownHosts, []byte(line+"\n"), 0o600), IsNil)
+
+	env := map[string]string{
+		"MC_SFTP_IDENTITY":    identity,
+		"MC_SFTP_KNOWN_HOSTS": knownHosts,
+		"SSH_AUTH_SOCK":       "",
+	}
+	saved := make(map[string]string)
+	for k, v := range env {
+		saved[k] = os.Getenv(k)
+		os.Setenv(k, v)
+	}
+
+	// sftp:// URLs are used without a config file.
+	load := loadMcConfig
+	loadMcConfig = func() (*configV10, *probe.Error) { return newMcConfig(), nil }
+
+	return server, func() {
+		loadMcConfig = load
+		for k, v := range saved {
+			os.Setenv(k, v)
+		}
+		server.listener.Close()
+	}
+}
+
+func (s *TestSuite) TestSFTPURL(c *C) {
+	u := newClientURL("sftp://user@example.com:2222/srv/data")
+	c.Assert(u.Type, Equals, ClientURLType(sftpStorage))
+	c.Assert(u.Host, Equals, "user@example.com:2222")
+	c.Assert(u.Path, Equals, "/srv/data")
+	c.Assert(u.String(), Equals, "sftp://user@example.com:2222/srv/data")
+
+	c.Assert(newClientURL("sftp:///srv/data").Type, Equals, ClientURLType(fileSystem))
+	c.Assert(isValidHostURL("sftp://user@example.com/srv/data"), Equals, true)
+	c.Assert(isValidAPI("sftp"), Equals, true)
+}
+
+func (s *TestSuite) TestSFTPClient(c *C) {
+	server, cleanup := setupSFTPTest(c)
+	defer cleanup()
+	ctx := context.Background()
+
+	// Put creates the missing folders.
+	clnt, err := newClientFromAlias("", server.url("user", "dir/object1"))
+	c.Assert(err, IsNil)
+	data := "hello world"
+	n, err := clnt.Put(ctx, strings.NewReader(data), int64(len(data)), nil, PutOptions{})
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, int64(len(data)))
+
+	clnt, err = newClientFromAlias("", server.url("user", "dir/sub/object2"))
+	c.Assert(err, IsNil)
+	_, err = clnt.Put(ctx, strings.NewReader(data), int64(len(data)), nil, PutOptions{})
+	c.Assert(err, IsNil)
+
+	// A short read fails, the partial file is removed.
+	clnt, err = newClientFromAlias("", server.url("user", "dir/short"))
+	c.Assert(err, IsNil)
+	_, err = clnt.Put(ctx, strings.NewReader(data), int64(len(data))+1, nil, PutOptions{})
+	c.Assert(err, NotNil)
+	_, e := os.Stat(filepath.Join(server.dir, "dir", "short"+partSuffix))
+	c.Assert(os.IsNotExist(e), Equals, true)
+
+	clnt, err = newClientFromAlias("", server.url("user", "dir/object1"))
+	c.Assert(err, IsNil)
+	content, err := clnt.Stat(ctx, StatOptions{})
+	c.Assert(err, IsNil)
+	c.Assert(content.Size, Equals, int64(len(data)))
+	c.Assert(content.Type.IsRegular(), Equals, true)
+
+	reader, err := clnt.Get(ctx, GetOptions{RangeStart: 6, RangeLength: 3})
+	c.Assert(err, IsNil)
+	got, e := io.ReadAll(reader)
+	c.Assert(e, IsNil)
+	reader.Close()
+	c.Assert(string(got), Equals, "wor")
+
+	clnt, err = newClientFromAlias("", server.url("user", "dir/missing"))
+	c.Assert(err, IsNil)
+	_, err = clnt.Stat(ctx, StatOptions{})
+	c.Assert(err, NotNil)
+	_, ok := err.ToGoError().(PathNotFound)
+	c.Assert(ok, Equals, true)
+
+	list := func(path string, opts ListOptions) []string {
+		clnt, err := newClientFromAlias("", server.url("user", path))
+		c.Assert(err, IsNil)
+		var paths []string
+		for content := range clnt.List(ctx, opts) {
+			c.Assert(content.Err, IsNil)
+			paths = append(paths, strings.TrimPrefix(content.URL.Path, filepath.ToSlash(server.dir)))
+		}
+		return paths
+	}
+	c.Assert(list("dir/", ListOptions{Recursive: true}), DeepEquals, []string{"/dir/object1", "/dir/sub/object2"})
+	c.Assert(list("dir/", ListOptions{}), DeepEquals, []string{"/dir/object1", "/dir/sub"})
+	c.Assert(list("dir/obj", ListOptions{}), DeepEquals, []string{"/dir/object1"})
+	c.Assert(list("dir/", ListOptions{Recursive: true, ShowDir: DirFirst}), DeepEquals,
+		[]string{"/dir/object1", "/dir/sub/", "/dir/sub/object2"})
+
+	// Removing the last file of a folder removes the folder.
+	clnt, err = newClientFromAlias("", server.url("user", "dir/"))
+	c.Assert(err, IsNil)
+	contentCh := make(chan *ClientContent, 1)
+	contentCh <- &ClientContent{URL: *newClientURL(server.url("user", "dir/sub/object2"))}
+	close(contentCh)
+	for result := range clnt.Remove(ctx, false, false, false, false, contentCh) {
+		c.Assert(result.Err, IsNil)
+	}
+	_, e = os.Stat(filepath.Join(server.dir, "dir", "sub"))
+	c.Assert(os.IsNotExist(e), Equals, true)
+	_, e = os.Stat(filepath.Join(server.dir, "dir", "object1"))
+	c.Assert(e, IsNil)
+}
+
+func (s *TestSuite) TestSFTPAuth(c *C) {
+	server, cleanup := setupSFTPTest(c)
+	defer cleanup()
+
+	// Password of an alias.
+	_, err := sftpNew(server.url("", "/"), &aliasConfigV10{AccessKey: "user", SecretKey: "secret", API: sftpScheme})
+	c.Assert(err, IsNil)
+
+	// Unknown users are refused.
+	_, err = sftpNew(server.url("nobody", "/"), nil)
+	c.Assert(err, NotNil)
+
+	// Unknown host keys are refused.
+	other := newSFTPTestServer(c, server.hostKey.PublicKey())
+	defer other.listener.Close()
+	_, err = sftpNew(other.url("user", "/"), &aliasConfigV10{AccessKey: "user", SecretKey: "secret"})
+	c.Assert(err, NotNil)
+	c.Assert(strings.Contains(err.ToGoError().Error(), "key"), Equals, true)
+}
+
+func (s *TestSuite) TestSFTPCopy(c *C) {
+	server, cleanup := setupSFTPTest(c)
+	defer cleanup()
+	ctx := context.Background()
+
+	local := c.MkDir()
+	data := "copied over sftp"
+	c.Assert(os.WriteFile(filepath.Join(local, "file.txt"), []byte(data), 0o600), IsNil)
+
+	copyURL := func(source, target string) {
+		srcClnt, err := newClientFromAlias("", source)
+		c.Assert(err, IsNil)
+		srcContent, err := srcClnt.Stat(ctx, StatOptions{})
+		c.Assert(err, IsNil)
+		urls := URLs{
+			SourceContent: srcContent,
+			TargetContent: &ClientContent{URL: *newClientURL(target)},
+		}
+		urls = uploadSourceToTargetURL(ctx, urls, nil, nil, false, false)
+		c.Assert(urls.Error, IsNil)
+	}
+
+	// Local file to the server and back.
+	copyURL(filepath.Join(local, "file.txt"), server.url("user", "up/file.txt"))
+	got, e := os.ReadFile(filepath.Join(server.dir, "up", "file.txt"))
+	c.Assert(e, IsNil)
+	c.Assert(string(got), Equals, data)
+
+	copyURL(server.url("user", "up/file.txt"), filepath.Join(local, "down.txt"))
+	got, e = os.ReadFile(filepath.Join(local, "down.txt"))
+	c.Assert(e, IsNil)
+	c.Assert(string(got), Equals, data)
+
+	// Within the server.
+	copyURL(server.url("user", "up/file.txt"), server.url("user", "up/copy.txt"))
+	got, e = os.ReadFile(filepath.Join(server.dir, "up", "copy.txt"))
+	c.Assert(e, IsNil)
+	c.Assert(string(got), Equals, data)
+}
+
+func (s *TestSuite) TestSFTPReconnect(c *C) {
+	server, cleanup := setupSFTPTest(c)
+	defer cleanup()
+	ctx := context.Background()
+	c.Assert(os.WriteFile(filepath.Join(server.dir, "file.txt"), []byte("data"), 0o600), IsNil)
+
+	clnt, err := sftpNew(server.url("user", "file.txt"), nil)
+	c.Assert(err, IsNil)
+	stale := clnt.(*sftpClient).conn()
+
+	// A lost connection is dropped from the cache and dialed again.
+	c.Assert(stale.Close(), IsNil)
+	content, err := clnt.Stat(ctx, StatOptions{})
+	c.Assert(err, IsNil)
+	c.Assert(content.Size, Equals, int64(4))
+	fresh := clnt.(*sftpClient).conn()
+	c.Assert(fresh == stale, Equals, false)
+
+	clnt, err = sftpNew(server.url("user", "file.txt"), nil)
+	c.Assert(err, IsNil)
+	c.Assert(clnt.(*sftpClient).conn() == fresh, Equals, true)
+}
+
+func (s *TestSuite) TestSFTPAgentClosed(c *C) {
+	server, cleanup := setupSFTPTest(c)
+	defer cleanup()
+
+	socket := filepath.Join(c.MkDir(), "agent.sock")
+	listener, e := net.Listen("unix", socket)
+	c.Assert(e, IsNil)
+	defer listener.Close()
+	served := make(chan struct{})
+	go func() {
+		defer close(served)
+		conn, e := listener.Accept()
+		if e != nil {
+			return
+		}
+		agent.ServeAgent(agent.NewKeyring(), conn)
+	}()
+	saved := os.Getenv("SSH_AUTH_SOCK")
+	defer os.Setenv("SSH_AUTH_SOCK", saved)
+	os.Setenv("SSH_AUTH_SOCK", socket)
+
+	// The agent is dialed for the first connection to the server.
+	_, err := sftpNew(server.url("", "/"), &aliasConfigV10{AccessKey: "user", SecretKey: "secret"})
+	c.Assert(err, IsNil)
+
+	// The agent connection is closed once connected.
+	select {
+	case <-served:
+	case <-time.After(10 * time.Second):
+		c.Fatal("agent connection left open")
+	}
+}
+
+func (s *TestSuite) TestSFTPSymlinkLoop(c *C) {
+	server, cleanup := setupSFTPTest(c)
+	defer cleanup()
+
+	c.Assert(os.MkdirAll(filepath.Join(server.dir, "dir"), 0o755), IsNil)
+	c.Assert(os.WriteFile(filepath.Join(server.dir, "dir", "file"), []byte("data"), 0o644), IsNil)
+	c.Assert(os.Symlink(".", filepath.Join(server.dir, "dir", "self")), IsNil)
+	c.Assert(os.Symlink("file", filepath.Join(server.dir, "dir", "link")), IsNil)
+
+	// Links to files are listed, links to folders are not followed.
+	clnt, err := newClientFromAlias("", server.url("user", "dir/"))
+	c.Assert(err, IsNil)
+	var paths []string
+	for content := range clnt.List(context.Background(), ListOptions{Recursive: true}) {
+		c.Assert(content.Err, IsNil)
+		paths = append(paths, strings.TrimPrefix(content.URL.Path, filepath.ToSlash(server.dir)))
+	}
+	c.Assert(paths, DeepEquals, []string{"/dir/file", "/dir/link"})
+}
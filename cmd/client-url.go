@@ -47,6 +47,7 @@ type ClientURLType int
 const (
 	objectStorage = iota // MinIO and S3 compatible cloud storage
 	fileSystem           // POSIX compatible file systems
+	sftpStorage          // Files on SFTP servers
 )
 
 // Maybe rawurl is of the form scheme:path. (Scheme must be [a-zA-Z][a-zA-Z0-9+-.]*)
@@ -114,6 +115,17 @@ func newClientURL(urlStr string) *ClientURL {
 				Separator:       '/',
 			}
 		}
+		// SFTP authorities may carry the user name.
+		if authority != "" && scheme == sftpScheme {
+			return &ClientURL{
+				Scheme:          scheme,
+				Type:            sftpStorage,
+				Host:            authority,
+				Path:            rest,
+				SchemeSeparator: "://",
+				Separator:       '/',
+			}
+		}
 	}
 	return &ClientURL{
 		Type:      fileSystem,
@@ -155,7 +167,7 @@ func (u ClientURL) String() string {
 		return u.Path
 	}
 	// if objectStorage convert from any non standard paths to a supported URL path style.
-	if u.Type == objectStorage {
+	if u.Type == objectStorage || u.Type == sftpStorage {
 		buf.WriteString(u.Scheme)
 		buf.WriteByte(':')
 		buf.WriteString("//")
@@ -49,6 +49,13 @@ type GetOptions struct {
 	VersionID  string
 	Zip        bool
 	RangeStart int64
+	Checksum   bool
+
+	// RangeLength limits the read to a number of bytes, zero reads
+	// up to the end of the object.
+	RangeLength int64
+	// MatchETag fails the read if the object has been replaced.
+	MatchETag string
 }
 
 // PutOptions holds options for PUT operation
@@ -61,6 +68,8 @@ type PutOptions struct {
 	multipartSize         uint64
 	multipartThreads      uint
 	concurrentStream      bool
+	checksum              minio.ChecksumType
+	checksumValue         string
 }
 
 // StatOptions holds options of the HEAD operation
@@ -71,6 +80,7 @@ type StatOptions struct {
 	timeRef    time.Time
 	versionID  string
 	isZip      bool
+	checksum   minio.ChecksumType
 }
 
 // ListOptions holds options for listing operation
@@ -84,6 +94,9 @@ type ListOptions struct {
 	TimeRef           time.Time
 	ShowDir           DirOpt
 	Count             int
+	// StartAfter skips entries up to and including this path,
+	// relative to the listed URL and separated by slashes.
+	StartAfter string
 }
 
 // CopyOptions holds options for copying operation
@@ -230,6 +243,11 @@ type Config struct {
 	ConnWriteDeadline time.Duration
 	UploadLimit       int64
 	DownloadLimit     int64
+	Alias             string
+	Limit             *aliasLimitV10
+	Credentials       *aliasCredentialsV10
+	AliasTransport    *aliasTransportV10
+	Endpoints         *aliasEndpointsV10
 	Transport         *http.Transport
 }
 
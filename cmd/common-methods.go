@@ -37,6 +37,7 @@ import (
 	"github.com/minio/cli"
 	minio "github.com/trinet2005/oss-go-sdk"
 	"github.com/trinet2005/oss-go-sdk/pkg/encrypt"
+	"github.com/trinet2005/oss-mc/pkg/hookreader"
 	"github.com/trinet2005/oss-mc/pkg/probe"
 	"github.com/trinet2005/oss-pkg/env"
 )
@@ -262,8 +263,12 @@ func getSourceStream(ctx context.Context, alias, urlStr string, opts getSourceOp
 			for k := range oinfo.Metadata {
 				st.Metadata[k] = oinfo.Metadata.Get(k)
 			}
+			for k, v := range objectChecksums(oinfo) {
+				st.Metadata[k] = v
+			}
 			st.ETag = oinfo.ETag
 		} else {
+			// The checksum of local files is computed as they are read.
 			st, err = sourceClnt.Stat(ctx, StatOptions{preserve: opts.preserve, sse: opts.SSE})
 			if err != nil {
 				return nil, nil, err.Trace(alias, urlStr)
@@ -388,6 +393,11 @@ func filterMetadata(metadata map[string]string) map[string]string {
 		if strings.HasPrefix(http.CanonicalHeaderKey(k), http.CanonicalHeaderKey(serverEncryptionKeyPrefix)) {
 			delete(newMetadata, k)
 		}
+		// Checksums are specific to the source object, they are
+		// explicitly passed along when requested.
+		if strings.HasPrefix(http.CanonicalHeaderKey(k), http.CanonicalHeaderKey(amzChecksumPrefix)) {
+			delete(newMetadata, k)
+		}
 	}
 	return newMetadata
 }
@@ -427,8 +437,8 @@ func uploadSourceToTargetURL(ctx context.Context, urls URLs, progress io.Reader,
 	targetAlias := urls.TargetAlias
 	targetURL := urls.TargetContent.URL
 	length := urls.SourceContent.Size
-	sourcePath := filepath.ToSlash(filepath.Join(sourceAlias, urls.SourceContent.URL.Path))
-	targetPath := filepath.ToSlash(filepath.Join(targetAlias, urls.TargetContent.URL.Path))
+	sourcePath := filepath.ToSlash(aliasPath(sourceAlias, urls.SourceContent.URL.Path))
+	targetPath := filepath.ToSlash(aliasPath(targetAlias, urls.TargetContent.URL.Path))
 
 	srcSSE := getSSE(sourcePath, encKeyDB[sourceAlias])
 	tgtSSE := getSSE(targetPath, encKeyDB[targetAlias])
@@ -481,8 +491,15 @@ func uploadSourceToTargetURL(ctx context.Context, urls URLs, progress io.Reader,
 		metadata[http.CanonicalHeaderKey(k)] = v
 	}
 
-	// Optimize for server side copy if the host is same.
-	if sourceAlias == targetAlias && !isZip {
+	// Optimize for server side copy if the host is same, objects
+	// compressed or encrypted client-side have to be streamed
+	// through the client.
+	transform := (urls.cse.canEncrypt() || urls.compress != "") && targetURL.Type == objectStorage
+	// Aliases pointing to the same cluster copy server-side as well.
+	crossAlias := sourceAlias != targetAlias && !isZip && !transform && canCopyServerSide(ctx, urls, srcSSE)
+	// Local paths and sftp:// URLs share the empty alias.
+	sameHost := sourceURL.Type == targetURL.Type && sourceURL.Host == targetURL.Host
+	if ((sourceAlias == targetAlias && sameHost) || crossAlias) && !isZip && !transform {
 		// preserve new metadata and save existing ones.
 		if preserve {
 			currentMetadata, err := getAllMetadata(ctx, sourceAlias, sourceURL.String(), srcSSE, urls)
@@ -521,6 +538,14 @@ func uploadSourceToTargetURL(ctx context.Context, urls URLs, progress io.Reader,
 
 		err = copySourceToTargetURL(ctx, targetAlias, targetURL.String(), sourcePath, sourceVersion, mode, until,
 			legalHold, length, progress, opts)
+		if crossAlias && err != nil {
+			if _, ok := err.ToGoError().(PathInsufficientPermission); ok {
+				// Stream the copy when the target credentials may
+				// not read the source.
+				disableServerCopy(urls)
+				return uploadSourceToTargetURL(ctx, urls, progress, encKeyDB, preserve, isZip)
+			}
+		}
 	} else {
 		if urls.SourceContent.RetentionEnabled {
 			// preserve new metadata and save existing ones.
@@ -548,6 +573,14 @@ func uploadSourceToTargetURL(ctx context.Context, urls URLs, progress io.Reader,
 			return urls.WithError(err.Trace(sourceURL.String()))
 		}
 
+		// Download large objects with concurrent range requests.
+		if sourceURL.Type == objectStorage && targetURL.Type == fileSystem && !isZip && urls.download.split(length) {
+			downloaded, err := downloadSourceToTargetURL(ctx, urls, progress, srcSSE, preserve)
+			if downloaded || err != nil {
+				return urls.WithError(err.Trace(sourceURL.String()))
+			}
+		}
+
 		var reader io.ReadCloser
 		// Proceed with regular stream copy.
 		reader, metadata, err = getSourceStream(ctx, sourceAlias, sourceURL.String(), getSourceOpts{
@@ -555,6 +588,7 @@ func uploadSourceToTargetURL(ctx context.Context, urls URLs, progress io.Reader,
 				VersionID: sourceVersion,
 				SSE:       srcSSE,
 				Zip:       isZip,
+				Checksum:  urls.Checksum.IsSet(),
 			},
 			fetchStat: true,
 			preserve:  preserve,
@@ -564,6 +598,20 @@ func uploadSourceToTargetURL(ctx context.Context, urls URLs, progress io.Reader,
 		}
 		defer reader.Close()
 
+		// Tags are stored along with local files as well.
+		if globalFSMetadata && sourceURL.Type == objectStorage && targetURL.Type == fileSystem {
+			if err = addSourceTagging(ctx, sourceAlias, sourceURL.String(), sourceVersion, metadata); err != nil {
+				return urls.WithError(err.Trace(sourceURL.String()))
+			}
+		}
+
+		// Checksum of the source as recorded by the server or
+		// computed locally, verified against the transferred data.
+		var checksumValue string
+		if urls.Checksum.IsSet() {
+			checksumValue = metadata[checksumMetadataKey(urls.Checksum)]
+		}
+
 		// Get metadata from target content as well
 		for k, v := range urls.TargetContent.Metadata {
 			metadata[http.CanonicalHeaderKey(k)] = v
@@ -597,6 +645,26 @@ func uploadSourceToTargetURL(ctx context.Context, urls URLs, progress io.Reader,
 			isPreserve:       preserve,
 			multipartSize:    multipartSize,
 			multipartThreads: uint(multipartThreads),
+			checksum:         urls.Checksum,
+			checksumValue:    checksumValue,
+		}
+
+		// Compress or encrypt client-side, the progress is
+		// accounted on the source which sizes were listed.
+		if urls.cse != nil || urls.compress != "" || targetURL.Type == fileSystem {
+			src := hookreader.NewHook(io.LimitReader(reader, length), progress)
+			tReader, tLength, transformed, err := transformSourceStream(sourceURL.String(), src, length, putOpts.metadata, urls, targetURL.Type)
+			if err != nil {
+				return urls.WithError(err.Trace(sourceURL.String()))
+			}
+			if transformed {
+				defer tReader.Close()
+				// The source checksum does not match the transformed data.
+				putOpts.checksumValue = ""
+				_, err = putTargetStream(ctx, targetAlias, targetURL.String(), mode, until,
+					legalHold, tReader, tLength, nil, putOpts)
+				return urls.WithError(err.Trace(sourceURL.String()))
+			}
 		}
 
 		if isReadAt(reader) {
@@ -623,6 +691,15 @@ func newClientFromAlias(alias, urlStr string) (Client, *probe.Error) {
 		return nil, err.Trace(alias, urlStr)
 	}
 
+	// sftp:// URLs and aliases of SFTP servers.
+	if newClientURL(urlStr).Type == sftpStorage {
+		sftpClient, err := sftpNew(urlStr, hostCfg)
+		if err != nil {
+			return nil, err.Trace(alias, urlStr)
+		}
+		return sftpClient, nil
+	}
+
 	if hostCfg == nil {
 		// No matching host config. So we treat it like a
 		// filesystem.
@@ -634,6 +711,7 @@ func newClientFromAlias(alias, urlStr string) (Client, *probe.Error) {
 	}
 
 	s3Config := NewS3Config(urlStr, hostCfg)
+	s3Config.Alias = alias
 
 	s3Client, err := S3New(s3Config)
 	if err != nil {
@@ -0,0 +1,222 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+	"github.com/klauspost/compress/zstd"
+	"github.com/minio/cli"
+	"github.com/trinet2005/oss-mc/pkg/probe"
+)
+
+// Metadata of objects compressed client-side.
+const (
+	compressMetaCodec = "X-Amz-Meta-Mc-Compression"
+	compressMetaSize  = "X-Amz-Meta-Mc-Uncompressed-Size"
+)
+
+// Supported compression codecs.
+const (
+	compressZstd = "zstd"
+	compressGzip = "gzip"
+)
+
+// Flags of the commands compressing uploads.
+var compressFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "compress",
+		Usage: "compress objects client-side before upload, choose one of 'zstd' or 'gzip'",
+	},
+}
+
+// parseCompressCodec validates the value of --compress.
+func parseCompressCodec(codec string) (string, *probe.Error) {
+	switch codec = strings.ToLower(codec); codec {
+	case "", compressZstd, compressGzip:
+		return codec, nil
+	}
+	return "", errInvalidArgument().Trace(codec)
+}
+
+// compressionMetadata looks up a compression header in the metadata
+// of an object, stats strip the user metadata prefix.
+func compressionMetadata(content *ClientContent, key string) string {
+	if v := metadataValue(content.Metadata, key); v != "" {
+		return v
+	}
+	if v := metadataValue(content.UserMetadata, key); v != "" {
+		return v
+	}
+	return metadataValue(content.UserMetadata, strings.TrimPrefix(key, "X-Amz-Meta-"))
+}
+
+// compressionCodec returns the codec an object was compressed with
+// client-side, empty if the object is not compressed.
+func compressionCodec(content *ClientContent) string {
+	return strings.ToLower(compressionMetadata(content, compressMetaCodec))
+}
+
+// uncompressedSize returns the size of an object before compression,
+// or -1 if it was not known when the object was uploaded.
+func uncompressedSize(content *ClientContent) int64 {
+	size, e := strconv.ParseInt(compressionMetadata(content, compressMetaSize), 10, 64)
+	if e != nil || size < 0 {
+		return -1
+	}
+	return size
+}
+
+// compressStripMetadata removes the compression headers.
+func compressStripMetadata(metadata map[string]string) {
+	for k := range metadata {
+		switch http.CanonicalHeaderKey(k) {
+		case compressMetaCodec, compressMetaSize:
+			delete(metadata, k)
+		}
+	}
+}
+
+// newCompressReader returns a reader compressing r with the codec,
+// closing it stops the compression.
+func newCompressReader(r io.Reader, codec string) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		var w io.WriteCloser
+		var e error
+		switch codec {
+		case compressZstd:
+			w, e = zstd.NewWriter(pw)
+		case compressGzip:
+			w = gzip.NewWriter(pw)
+		default:
+			e = fmt.Errorf("unsupported compression `%s`", codec)
+		}
+		if e == nil {
+			_, e = io.Copy(w, r)
+			if ce := w.Close(); e == nil {
+				e = ce
+			}
+		}
+		pw.CloseWithError(e)
+	}()
+	return pr
+}
+
+// newDecompressReader returns a reader decompressing r with the codec.
+func newDecompressReader(r io.Reader, codec string) (io.ReadCloser, error) {
+	switch codec {
+	case compressZstd:
+		d, e := zstd.NewReader(r)
+		if e != nil {
+			return nil, e
+		}
+		return d.IOReadCloser(), nil
+	case compressGzip:
+		return gzip.NewReader(r)
+	}
+	return nil, fmt.Errorf("unsupported compression `%s`", codec)
+}
+
+// compressTransfer wraps the source of a copy: objects uploaded to
+// object storage are compressed with the codec, compressed objects
+// downloaded to the local filesystem are decompressed. Objects still
+// encrypted client-side are left as is. The metadata is updated
+// accordingly.
+func compressTransfer(urlStr string, reader io.Reader, size int64, metadata map[string]string, codec string, targetType ClientURLType) (io.ReadCloser, int64, bool, *probe.Error) {
+	content := &ClientContent{Metadata: metadata}
+	storedCodec := compressionCodec(content)
+	switch {
+	case storedCodec != "" && targetType == fileSystem && !isCSEEncrypted(metadata):
+		r, e := newDecompressReader(reader, storedCodec)
+		if e != nil {
+			return nil, 0, false, probe.NewError(e).Trace(urlStr)
+		}
+		size = uncompressedSize(content)
+		compressStripMetadata(metadata)
+		return r, size, true, nil
+	case storedCodec == "" && codec != "" && targetType == objectStorage && !isCSEEncrypted(metadata):
+		metadata[compressMetaCodec] = codec
+		if size >= 0 {
+			metadata[compressMetaSize] = strconv.FormatInt(size, 10)
+		}
+		return newCompressReader(reader, codec), -1, true, nil
+	}
+	return io.NopCloser(reader), size, false, nil
+}
+
+// transformSourceStream compresses and encrypts the source of a copy
+// uploaded to object storage, or decrypts and decompresses the source
+// of a copy downloaded to the local filesystem. It returns false if
+// the source is copied as is.
+func transformSourceStream(urlStr string, reader io.Reader, size int64, metadata map[string]string, urls URLs, targetType ClientURLType) (io.ReadCloser, int64, bool, *probe.Error) {
+	var decrypted, encrypted bool
+	var err *probe.Error
+	if targetType == fileSystem {
+		if reader, size, decrypted, err = urls.cse.transfer(urlStr, reader, size, metadata, targetType); err != nil {
+			return nil, 0, false, err
+		}
+	}
+	rc, size, compressed, err := compressTransfer(urlStr, reader, size, metadata, urls.compress, targetType)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	reader = rc
+	if targetType == objectStorage {
+		if reader, size, encrypted, err = urls.cse.transfer(urlStr, reader, size, metadata, targetType); err != nil {
+			rc.Close()
+			return nil, 0, false, err
+		}
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{reader, rc}, size, decrypted || compressed || encrypted, nil
+}
+
+// sameUncompressedSize returns true if the source or the target was
+// listed as compressed client-side and both have the same size once
+// decompressed.
+func sameUncompressedSize(src, tgt *ClientContent) bool {
+	if src == nil || tgt == nil || (compressionCodec(src) == "" && compressionCodec(tgt) == "") {
+		return false
+	}
+	size := func(content *ClientContent) int64 {
+		if compressionCodec(content) == "" {
+			return content.Size
+		}
+		return uncompressedSize(content)
+	}
+	srcSize := size(src)
+	return srcSize >= 0 && srcSize == size(tgt)
+}
+
+// compressionDescription describes the stored size of an object
+// compressed client-side, the size is unknown if it is the same.
+func compressionDescription(size, storedSize int64, codec string) string {
+	if size == storedSize {
+		return fmt.Sprintf("(%s)", codec)
+	}
+	return fmt.Sprintf("(%s %s)", strings.Join(strings.Fields(humanize.IBytes(uint64(storedSize))), ""), codec)
+}
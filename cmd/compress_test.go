@@ -0,0 +1,135 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *TestSuite) TestCompressTransfer(c *C) {
+	plaintext := []byte(strings.Repeat("2023-06-01T00:00:00Z INFO request served\n", 1000))
+
+	for _, codec := range []string{compressZstd, compressGzip} {
+		metadata := map[string]string{"Content-Type": "text/plain"}
+		reader, size, transformed, err := compressTransfer("file", bytes.NewReader(plaintext), int64(len(plaintext)), metadata, codec, objectStorage)
+		c.Assert(err, IsNil)
+		c.Assert(transformed, Equals, true)
+		c.Assert(size, Equals, int64(-1))
+		compressed, e := io.ReadAll(reader)
+		c.Assert(e, IsNil)
+		c.Assert(reader.Close(), IsNil)
+		c.Assert(len(compressed) < len(plaintext), Equals, true)
+		c.Assert(metadata[compressMetaCodec], Equals, codec)
+
+		content := &ClientContent{Size: int64(len(compressed)), Metadata: metadata}
+		c.Assert(compressionCodec(content), Equals, codec)
+		c.Assert(uncompressedSize(content), Equals, int64(len(plaintext)))
+
+		// Compressed objects are copied between object stores as is.
+		_, _, transformed, err = compressTransfer("object", bytes.NewReader(compressed), int64(len(compressed)), metadata, codec, objectStorage)
+		c.Assert(err, IsNil)
+		c.Assert(transformed, Equals, false)
+
+		reader, size, transformed, err = compressTransfer("object", bytes.NewReader(compressed), int64(len(compressed)), metadata, "", fileSystem)
+		c.Assert(err, IsNil)
+		c.Assert(transformed, Equals, true)
+		c.Assert(size, Equals, int64(len(plaintext)))
+		got, e := io.ReadAll(reader)
+		c.Assert(e, IsNil)
+		c.Assert(got, DeepEquals, plaintext)
+		c.Assert(metadata[compressMetaCodec], Equals, "")
+		c.Assert(metadata["Content-Type"], Equals, "text/plain")
+	}
+
+	_, err := parseCompressCodec("lz4")
+	c.Assert(err, NotNil)
+	codec, err := parseCompressCodec("ZSTD")
+	c.Assert(err, IsNil)
+	c.Assert(codec, Equals, compressZstd)
+}
+
+func (s *TestSuite) TestCompressMetadata(c *C) {
+	// Stats strip the prefix of user metadata, listings do not.
+	content := &ClientContent{UserMetadata: map[string]string{"Mc-Compression": "zstd", "Mc-Uncompressed-Size": "1024"}}
+	c.Assert(compressionCodec(content), Equals, compressZstd)
+	c.Assert(uncompressedSize(content), Equals, int64(1024))
+
+	content = &ClientContent{UserMetadata: map[string]string{"x-amz-meta-mc-compression": "gzip"}}
+	c.Assert(compressionCodec(content), Equals, compressGzip)
+	c.Assert(uncompressedSize(content), Equals, int64(-1))
+
+	c.Assert(compressionCodec(&ClientContent{}), Equals, "")
+
+	// Sizes are only compared uncompressed when listed as compressed.
+	source := &ClientContent{Size: 1024}
+	c.Assert(sameUncompressedSize(source, &ClientContent{Size: 100, UserMetadata: map[string]string{"Mc-Compression": "zstd", "Mc-Uncompressed-Size": "1024"}}), Equals, true)
+	c.Assert(sameUncompressedSize(source, &ClientContent{Size: 100, UserMetadata: map[string]string{"Mc-Compression": "zstd", "Mc-Uncompressed-Size": "1000"}}), Equals, false)
+	c.Assert(sameUncompressedSize(source, &ClientContent{Size: 100, UserMetadata: map[string]string{"Mc-Compression": "zstd"}}), Equals, false)
+	c.Assert(sameUncompressedSize(source, &ClientContent{Size: 100}), Equals, false)
+	c.Assert(compressionDescription(1024, 1024, compressGzip), Equals, "(gzip)")
+	c.Assert(compressionDescription(4096, 1024, compressZstd), Equals, "(1.0KiB zstd)")
+}
+
+func (s *TestSuite) TestCompressEncryptTransform(c *C) {
+	key := make(cseKeyFile, cseDataKeySize)
+	keys := &cseKeys{recipients: []cseRecipient{key}, identities: []cseIdentity{key}}
+	plaintext := []byte(strings.Repeat("compressed first, then encrypted\n", 500))
+
+	// Uploads are compressed and then encrypted.
+	metadata := map[string]string{}
+	urls := URLs{cse: keys, compress: compressZstd}
+	reader, size, transformed, err := transformSourceStream("file", bytes.NewReader(plaintext), int64(len(plaintext)), metadata, urls, objectStorage)
+	c.Assert(err, IsNil)
+	c.Assert(transformed, Equals, true)
+	c.Assert(size, Equals, int64(-1))
+	stored, e := io.ReadAll(reader)
+	c.Assert(e, IsNil)
+	c.Assert(reader.Close(), IsNil)
+	c.Assert(isCSEEncrypted(metadata), Equals, true)
+
+	content := &ClientContent{Size: int64(len(stored)), Metadata: metadata}
+	t, err := newCatTransform("object", content, catOpts{cse: keys})
+	c.Assert(err, IsNil)
+	c.Assert(t.codec, Equals, compressZstd)
+	c.Assert(t.size, Equals, int64(len(plaintext)))
+	rc, err := t.newReader(io.NopCloser(bytes.NewReader(stored)))
+	c.Assert(err, IsNil)
+	got, e := io.ReadAll(rc)
+	c.Assert(e, IsNil)
+	c.Assert(got, DeepEquals, plaintext)
+
+	// Without the keys the object is displayed as stored.
+	t, err = newCatTransform("object", content, catOpts{})
+	c.Assert(err, IsNil)
+	c.Assert(t.codec, Equals, "")
+	c.Assert(t.object, IsNil)
+
+	// Downloads are decrypted and then decompressed.
+	reader, size, transformed, err = transformSourceStream("object", bytes.NewReader(stored), int64(len(stored)), metadata, URLs{cse: keys}, fileSystem)
+	c.Assert(err, IsNil)
+	c.Assert(transformed, Equals, true)
+	c.Assert(size, Equals, int64(len(plaintext)))
+	got, e = io.ReadAll(reader)
+	c.Assert(e, IsNil)
+	c.Assert(got, DeepEquals, plaintext)
+	c.Assert(metadata, HasLen, 0)
+}
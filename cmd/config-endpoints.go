@@ -0,0 +1,330 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/trinet2005/oss-admin-go"
+	"github.com/trinet2005/oss-mc/pkg/probe"
+)
+
+// Selection policies of the endpoints of an alias.
+const (
+	endpointFailover     = "failover"
+	endpointRoundRobin   = "round-robin"
+	endpointLeastLatency = "least-latency"
+)
+
+const (
+	// How often the endpoints of an alias are probed by default.
+	defaultHealthInterval = 10 * time.Second
+
+	// Time given to an endpoint to answer a probe.
+	healthCheckTimeout = 5 * time.Second
+)
+
+// poolEndpoint - an endpoint of an alias with its last known health.
+type poolEndpoint struct {
+	url     *url.URL
+	offline atomic.Bool
+	// Smoothed response time of the probes, zero until measured.
+	latency atomic.Int64
+}
+
+// endpointPool - endpoints of an alias, requests are sent to one of
+// the online endpoints chosen by the policy and retried on another
+// endpoint when idempotent. The endpoints are probed in the background
+// like `mc ping` does.
+type endpointPool struct {
+	policy    string
+	endpoints []*poolEndpoint
+	next      atomic.Uint32
+	interval  time.Duration
+	anon      *madmin.AnonymousClient
+	start     sync.Once
+}
+
+// Pools by alias, shared by the S3 and admin clients.
+var endpointPools = struct {
+	sync.Mutex
+	pools map[string]*endpointPool
+}{pools: make(map[string]*endpointPool)}
+
+// parseEndpoints returns the endpoints of an alias, the server of its
+// URL first.
+func parseEndpoints(hostURL string, cfg *aliasEndpointsV10) ([]*url.URL, *probe.Error) {
+	var endpoints []*url.URL
+	seen := make(map[string]bool)
+	host := newClientURL(hostURL)
+	for _, urlStr := range append([]string{host.Scheme + "://" + host.Host}, cfg.URLs...) {
+		u, e := url.Parse(urlStr)
+		if e != nil {
+			return nil, probe.NewError(e).Trace(urlStr)
+		}
+		if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, probe.NewError(fmt.Errorf("invalid endpoint `%s`", urlStr))
+		}
+		if u.Path != "" && u.Path != "/" {
+			return nil, probe.NewError(fmt.Errorf("endpoint `%s` cannot have a path", urlStr))
+		}
+		if seen[u.Host] {
+			continue
+		}
+		seen[u.Host] = true
+		endpoints = append(endpoints, &url.URL{Scheme: u.Scheme, Host: u.Host})
+	}
+	return endpoints, nil
+}
+
+// validateAliasEndpoints checks the endpoints of an alias.
+func validateAliasEndpoints(hostURL string, cfg *aliasEndpointsV10) *probe.Error {
+	if cfg == nil {
+		return nil
+	}
+	if len(cfg.URLs) == 0 {
+		return probe.NewError(errors.New("no endpoint listed in `urls`"))
+	}
+	switch cfg.Policy {
+	case "", endpointFailover, endpointRoundRobin, endpointLeastLatency:
+	default:
+		return probe.NewError(fmt.Errorf("unknown endpoint policy `%s`, valid options are `[failover, round-robin, least-latency]`", cfg.Policy))
+	}
+	if cfg.HealthInterval != "" {
+		d, e := time.ParseDuration(cfg.HealthInterval)
+		if e != nil {
+			return probe.NewError(e).Trace(cfg.HealthInterval)
+		}
+		if d <= 0 {
+			return probe.NewError(fmt.Errorf("invalid health check interval `%s`", cfg.HealthInterval))
+		}
+	}
+	if _, err := parseEndpoints(hostURL, cfg); err != nil {
+		return err.Trace(hostURL)
+	}
+	return nil
+}
+
+// aliasEndpointsKey identifies the endpoints of an alias for client
+// caches.
+func aliasEndpointsKey(cfg *aliasEndpointsV10) string {
+	if cfg == nil {
+		return ""
+	}
+	return strings.Join(append([]string{cfg.Policy, cfg.HealthInterval}, cfg.URLs...), "\x00")
+}
+
+// getEndpointPool returns the endpoints of the alias of a client
+// config, probed with the HTTP settings of transport.
+func getEndpointPool(config *Config, transport http.RoundTripper) (*endpointPool, *probe.Error) {
+	if err := validateAliasEndpoints(config.HostURL, config.Endpoints); err != nil {
+		return nil, err.Trace(config.Alias)
+	}
+	u := newClientURL(config.HostURL)
+	key := config.Alias + "\x00" + u.Host + "\x00" + aliasEndpointsKey(config.Endpoints)
+
+	endpointPools.Lock()
+	defer endpointPools.Unlock()
+	if pool, ok := endpointPools.pools[key]; ok {
+		return pool, nil
+	}
+
+	urls, err := parseEndpoints(config.HostURL, config.Endpoints)
+	if err != nil {
+		return nil, err.Trace(config.Alias)
+	}
+	anon, e := madmin.NewAnonymousClientNoEndpoint()
+	if e != nil {
+		return nil, probe.NewError(e)
+	}
+	anon.SetCustomTransport(transport)
+
+	pool := &endpointPool{
+		policy:   config.Endpoints.Policy,
+		interval: defaultHealthInterval,
+		anon:     anon,
+	}
+	if pool.policy == "" {
+		pool.policy = endpointFailover
+	}
+	if config.Endpoints.HealthInterval != "" {
+		pool.interval, _ = time.ParseDuration(config.Endpoints.HealthInterval)
+	}
+	for _, u := range urls {
+		pool.endpoints = append(pool.endpoints, &poolEndpoint{url: u})
+	}
+	endpointPools.pools[key] = pool
+	return pool, nil
+}
+
+// checkHealth probes all the endpoints once.
+func (p *endpointPool) checkHealth(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	servers := make([]madmin.ServerProperties, 0, len(p.endpoints))
+	for _, e := range p.endpoints {
+		servers = append(servers, madmin.ServerProperties{Endpoint: e.url.Host, Scheme: e.url.Scheme})
+	}
+	probed := make(map[*poolEndpoint]bool)
+	for result := range p.anon.Alive(ctx, madmin.AliveOpts{}, servers...) {
+		if result.Endpoint == nil {
+			continue
+		}
+		for _, e := range p.endpoints {
+			if e.url.Host != result.Endpoint.Host {
+				continue
+			}
+			probed[e] = true
+			online := result.Error == nil && result.Online
+			e.offline.Store(!online)
+			if online {
+				latency := int64(result.ResponseTime)
+				if prev := e.latency.Load(); prev > 0 {
+					// Smooth the response times, a single slow probe
+					// should not move all the traffic.
+					latency = (prev*3 + latency) / 4
+				}
+				e.latency.Store(latency)
+			}
+		}
+	}
+	// Endpoints not answering before the timeout are offline.
+	for _, e := range p.endpoints {
+		if !probed[e] {
+			e.offline.Store(true)
+		}
+	}
+}
+
+// healthLoop probes the endpoints at every interval.
+func (p *endpointPool) healthLoop() {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		p.checkHealth(context.Background())
+		<-ticker.C
+	}
+}
+
+// pick returns the endpoint a request is sent to, skipping the ones
+// already tried. Offline endpoints are only used when all are offline.
+func (p *endpointPool) pick(tried map[*poolEndpoint]bool) *poolEndpoint {
+	var online, all []*poolEndpoint
+	for _, e := range p.endpoints {
+		if tried[e] {
+			continue
+		}
+		all = append(all, e)
+		if !e.offline.Load() {
+			online = append(online, e)
+		}
+	}
+	candidates := online
+	if len(candidates) == 0 {
+		candidates = all
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	switch p.policy {
+	case endpointRoundRobin:
+		return candidates[(p.next.Add(1)-1)%uint32(len(candidates))]
+	case endpointLeastLatency:
+		best, bestLatency := candidates[0], int64(math.MaxInt64)
+		for _, e := range candidates {
+			// Endpoints not measured yet come last.
+			if latency := e.latency.Load(); latency > 0 && latency < bestLatency {
+				best, bestLatency = e, latency
+			}
+		}
+		return best
+	default:
+		return candidates[0]
+	}
+}
+
+// roundTripper sends the requests of transport to the endpoints of
+// the pool.
+func (p *endpointPool) roundTripper(transport http.RoundTripper) http.RoundTripper {
+	return &endpointTransport{pool: p, transport: transport}
+}
+
+// endpointTransport - sends requests to an endpoint of a pool, the
+// Host header is kept so that signatures stay valid.
+type endpointTransport struct {
+	pool      *endpointPool
+	transport http.RoundTripper
+}
+
+// isRetriable returns true if a request can be sent again to another
+// endpoint after a network error.
+func isRetriable(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+	default:
+		return false
+	}
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+func (t *endpointTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.pool.start.Do(func() { go t.pool.healthLoop() })
+
+	tried := make(map[*poolEndpoint]bool)
+	for {
+		endpoint := t.pool.pick(tried)
+		r := req.Clone(req.Context())
+		if r.Host == "" {
+			r.Host = req.URL.Host
+		}
+		r.URL.Scheme = endpoint.url.Scheme
+		r.URL.Host = endpoint.url.Host
+		if len(tried) > 0 && req.GetBody != nil {
+			body, e := req.GetBody()
+			if e != nil {
+				return nil, e
+			}
+			r.Body = body
+		}
+
+		resp, e := t.transport.RoundTrip(r)
+		if e == nil {
+			return resp, nil
+		}
+		if req.Context().Err() != nil {
+			return nil, e
+		}
+		// Avoid the endpoint until it answers the probes again.
+		endpoint.offline.Store(true)
+		tried[endpoint] = true
+		if !isRetriable(req) || len(tried) == len(t.pool.endpoints) {
+			return nil, e
+		}
+	}
+}
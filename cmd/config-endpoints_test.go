@@ -0,0 +1,192 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+// testEndpoint is a node of a cluster answering the probes of `mc ping`
+// and the requests of a Stat, it records the Host of the requests.
+type testEndpoint struct {
+	*httptest.Server
+	mutex sync.Mutex
+	hosts []string
+}
+
+func newTestEndpoint() *testEndpoint {
+	t := &testEndpoint{}
+	s3 := newTestS3Handler(func(r *http.Request) {
+		t.mutex.Lock()
+		defer t.mutex.Unlock()
+		t.hosts = append(t.hosts, r.Host)
+	})
+	t.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/minio/health/live" {
+			return
+		}
+		s3.ServeHTTP(w, r)
+	}))
+	return t
+}
+
+func (t *testEndpoint) requests() []string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return append([]string(nil), t.hosts...)
+}
+
+func (s *TestSuite) TestValidateAliasEndpoints(c *C) {
+	testCases := []struct {
+		cfg   *aliasEndpointsV10
+		valid bool
+	}{
+		{nil, true},
+		{&aliasEndpointsV10{URLs: []string{"http://node2:9000"}}, true},
+		{&aliasEndpointsV10{URLs: []string{"http://node2:9000", "https://node3:9000"}, Policy: endpointRoundRobin}, true},
+		{&aliasEndpointsV10{URLs: []string{"http://node2:9000"}, Policy: endpointLeastLatency, HealthInterval: "30s"}, true},
+		{&aliasEndpointsV10{}, false},
+		{&aliasEndpointsV10{URLs: []string{"http://node2:9000"}, Policy: "random"}, false},
+		{&aliasEndpointsV10{URLs: []string{"http://node2:9000"}, HealthInterval: "often"}, false},
+		{&aliasEndpointsV10{URLs: []string{"http://node2:9000"}, HealthInterval: "0s"}, false},
+		{&aliasEndpointsV10{URLs: []string{"node2:9000"}}, false},
+		{&aliasEndpointsV10{URLs: []string{"http://node2:9000/bucket"}}, false},
+	}
+	for i, testCase := range testCases {
+		err := validateAliasEndpoints("http://node1:9000", testCase.cfg)
+		c.Assert(err == nil, Equals, testCase.valid, Commentf("Test %d: %v", i+1, err))
+	}
+}
+
+func (s *TestSuite) TestEndpointPoolPick(c *C) {
+	newPool := func(policy string) *endpointPool {
+		pool := &endpointPool{policy: policy}
+		for _, host := range []string{"node1:9000", "node2:9000", "node3:9000"} {
+			pool.endpoints = append(pool.endpoints, &poolEndpoint{url: &url.URL{Scheme: "http", Host: host}})
+		}
+		return pool
+	}
+	none := map[*poolEndpoint]bool{}
+
+	pool := newPool(endpointFailover)
+	c.Assert(pool.pick(none).url.Host, Equals, "node1:9000")
+	pool.endpoints[0].offline.Store(true)
+	c.Assert(pool.pick(none).url.Host, Equals, "node2:9000")
+	c.Assert(pool.pick(map[*poolEndpoint]bool{pool.endpoints[1]: true}).url.Host, Equals, "node3:9000")
+	// Offline endpoints are used when all are offline.
+	pool.endpoints[1].offline.Store(true)
+	pool.endpoints[2].offline.Store(true)
+	c.Assert(pool.pick(none).url.Host, Equals, "node1:9000")
+	c.Assert(pool.pick(map[*poolEndpoint]bool{pool.endpoints[0]: true, pool.endpoints[1]: true, pool.endpoints[2]: true}), IsNil)
+
+	pool = newPool(endpointRoundRobin)
+	pool.endpoints[1].offline.Store(true)
+	var hosts []string
+	for i := 0; i < 4; i++ {
+		hosts = append(hosts, pool.pick(none).url.Host)
+	}
+	c.Assert(hosts, DeepEquals, []string{"node1:9000", "node3:9000", "node1:9000", "node3:9000"})
+	// The counter wraps around.
+	pool.next.Store(math.MaxUint32)
+	c.Assert(pool.pick(none).url.Host, Equals, "node3:9000")
+	c.Assert(pool.pick(none).url.Host, Equals, "node1:9000")
+	c.Assert(pool.pick(none).url.Host, Equals, "node3:9000")
+
+	pool = newPool(endpointLeastLatency)
+	pool.endpoints[0].latency.Store(int64(30 * time.Millisecond))
+	pool.endpoints[2].latency.Store(int64(10 * time.Millisecond))
+	c.Assert(pool.pick(none).url.Host, Equals, "node3:9000")
+	pool.endpoints[2].offline.Store(true)
+	c.Assert(pool.pick(none).url.Host, Equals, "node1:9000")
+}
+
+func (s *TestSuite) TestEndpointPoolHealth(c *C) {
+	node1, node2 := newTestEndpoint(), newTestEndpoint()
+	defer node2.Close()
+	node1.Close()
+
+	config := &Config{
+		Alias:     "endpoints-health",
+		HostURL:   node1.URL,
+		Endpoints: &aliasEndpointsV10{URLs: []string{node2.URL}},
+	}
+	pool, err := getEndpointPool(config, http.DefaultTransport)
+	c.Assert(err, IsNil)
+	pool.checkHealth(context.Background())
+
+	c.Assert(pool.endpoints[0].offline.Load(), Equals, true)
+	c.Assert(pool.endpoints[1].offline.Load(), Equals, false)
+	c.Assert(pool.endpoints[1].latency.Load() > 0, Equals, true)
+}
+
+func (s *TestSuite) TestEndpointPoolFailover(c *C) {
+	node1, node2 := newTestEndpoint(), newTestEndpoint()
+	defer node2.Close()
+	host1 := node1.Listener.Addr().String()
+
+	aliasToConfigMap["endpoints"] = &aliasConfigV10{
+		URL: node1.URL, AccessKey: "access", SecretKey: "secret-key", API: "S3v4", Path: "on",
+		Endpoints: &aliasEndpointsV10{URLs: []string{node2.URL}, HealthInterval: "1h"},
+	}
+	defer delete(aliasToConfigMap, "endpoints")
+
+	clnt, err := newClient("endpoints/bucket/object")
+	c.Assert(err, IsNil)
+	_, err = clnt.Stat(context.Background(), StatOptions{})
+	c.Assert(err, IsNil)
+	c.Assert(len(node1.requests()) > 0, Equals, true)
+	c.Assert(node2.requests(), HasLen, 0)
+
+	// Requests go to the other node once the first one is down, with
+	// the Host the requests were signed with.
+	node1.Close()
+	_, err = clnt.Stat(context.Background(), StatOptions{})
+	c.Assert(err, IsNil)
+	hosts := node2.requests()
+	c.Assert(len(hosts) > 0, Equals, true)
+	c.Assert(hosts[len(hosts)-1], Equals, host1)
+}
+
+func (s *TestSuite) TestEndpointPoolRoundRobin(c *C) {
+	node1, node2 := newTestEndpoint(), newTestEndpoint()
+	defer node1.Close()
+	defer node2.Close()
+
+	aliasToConfigMap["endpoints-rr"] = &aliasConfigV10{
+		URL: node1.URL, AccessKey: "access", SecretKey: "secret-key", API: "S3v4", Path: "on",
+		Endpoints: &aliasEndpointsV10{URLs: []string{node2.URL}, Policy: endpointRoundRobin, HealthInterval: "1h"},
+	}
+	defer delete(aliasToConfigMap, "endpoints-rr")
+
+	clnt, err := newClient("endpoints-rr/bucket/object")
+	c.Assert(err, IsNil)
+	for i := 0; i < 4; i++ {
+		_, err = clnt.Stat(context.Background(), StatOptions{})
+		c.Assert(err, IsNil)
+	}
+	c.Assert(len(node1.requests()) > 0, Equals, true)
+	c.Assert(len(node2.requests()) > 0, Equals, true)
+}
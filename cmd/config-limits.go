@@ -0,0 +1,180 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/trinet2005/oss-mc/pkg/limiter"
+	"github.com/trinet2005/oss-mc/pkg/probe"
+	"github.com/trinet2005/oss-pkg/quick"
+)
+
+// How often the config file is checked for new bandwidth limits.
+const aliasLimitsReloadInterval = 5 * time.Second
+
+// parseLimit parses a bandwidth limit per second such as `100MiB`,
+// an empty or zero limit means unlimited.
+func parseLimit(limit string) (int64, *probe.Error) {
+	if limit == "" {
+		return 0, nil
+	}
+	v, e := humanize.ParseBytes(limit)
+	if e != nil {
+		return 0, probe.NewError(e).Trace(limit)
+	}
+	return int64(v), nil
+}
+
+// parseLimits parses an upload and download bandwidth limit.
+func parseLimits(upload, download string) (limits limiter.Limits, err *probe.Error) {
+	if limits.Upload, err = parseLimit(upload); err != nil {
+		return limits, err.Trace(upload)
+	}
+	if limits.Download, err = parseLimit(download); err != nil {
+		return limits, err.Trace(download)
+	}
+	return limits, nil
+}
+
+// parseLimitSchedule converts the bandwidth limits of an alias
+// into a schedule.
+func parseLimitSchedule(cfg *aliasLimitV10) (schedule limiter.Schedule, err *probe.Error) {
+	if cfg == nil {
+		return schedule, nil
+	}
+	if schedule.Default, err = parseLimits(cfg.Upload, cfg.Download); err != nil {
+		return schedule, err.Trace()
+	}
+	for _, w := range cfg.Schedule {
+		var window limiter.Window
+		var e error
+		if window.Days, e = limiter.ParseDays(w.Days); e != nil {
+			return schedule, probe.NewError(e).Trace(w.Days)
+		}
+		if window.From, e = limiter.ParseTimeOfDay(w.From); e != nil {
+			return schedule, probe.NewError(e).Trace(w.From)
+		}
+		if window.To, e = limiter.ParseTimeOfDay(w.To); e != nil {
+			return schedule, probe.NewError(e).Trace(w.To)
+		}
+		if window.Limits, err = parseLimits(w.Upload, w.Download); err != nil {
+			return schedule, err.Trace()
+		}
+		schedule.Windows = append(schedule.Windows, window)
+	}
+	return schedule, nil
+}
+
+// aliasLimits - bandwidth limits of an alias, reloaded when the config
+// file changes so that long running commands pick up new limits. Limits
+// set on the command line override the ones of the alias, per direction.
+type aliasLimits struct {
+	mutex    sync.Mutex
+	alias    string
+	override limiter.Limits
+	schedule limiter.Schedule
+	modTime  time.Time
+}
+
+func newAliasLimits(alias string, cfg *aliasLimitV10, override limiter.Limits) (*aliasLimits, *probe.Error) {
+	schedule, err := parseLimitSchedule(cfg)
+	if err != nil {
+		return nil, err.Trace(alias)
+	}
+	a := &aliasLimits{
+		alias:    alias,
+		override: override,
+		schedule: schedule,
+	}
+	if configPath, err := getMcConfigPath(); err == nil {
+		if fi, e := os.Stat(configPath); e == nil {
+			a.modTime = fi.ModTime()
+		}
+	}
+	return a, nil
+}
+
+// Limits returns the limits in effect now.
+func (a *aliasLimits) Limits() limiter.Limits {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	limits := a.schedule.At(time.Now())
+	if a.override.Upload != 0 {
+		limits.Upload = a.override.Upload
+	}
+	if a.override.Download != 0 {
+		limits.Download = a.override.Download
+	}
+	return limits
+}
+
+// watch reloads the limits and applies them to the transport
+// periodically, to follow the schedule and the changes of the
+// config file.
+func (a *aliasLimits) watch(transport limiter.Dynamic) {
+	go func() {
+		ticker := time.NewTicker(aliasLimitsReloadInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-globalContext.Done():
+				return
+			case <-ticker.C:
+				a.reload()
+				transport.SetLimits(a.Limits())
+			}
+		}
+	}()
+}
+
+// reload the limits if the config file was modified, the current
+// limits are kept if the new ones are invalid.
+func (a *aliasLimits) reload() {
+	configPath, err := getMcConfigPath()
+	if err != nil {
+		return
+	}
+	fi, e := os.Stat(configPath)
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if e != nil || fi.ModTime().Equal(a.modTime) {
+		return
+	}
+	a.modTime = fi.ModTime()
+
+	cfg := newConfigV10()
+	if _, e = quick.LoadConfig(configPath, nil, cfg); e != nil {
+		errorIf(probe.NewError(e).Trace(configPath), "Unable to reload bandwidth limits of `%s`.", a.alias)
+		return
+	}
+	var limit *aliasLimitV10
+	if aliasCfg, ok := cfg.Aliases[a.alias]; ok {
+		limit = aliasCfg.Limit
+	}
+	schedule, err := parseLimitSchedule(limit)
+	if err != nil {
+		errorIf(err.Trace(a.alias), "Unable to reload bandwidth limits of `%s`.", a.alias)
+		return
+	}
+	a.schedule = schedule
+}
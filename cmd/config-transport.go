@@ -0,0 +1,268 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/trinet2005/oss-mc/pkg/probe"
+)
+
+const (
+	// Timeout of new connections unless set for the alias.
+	defaultConnectTimeout = 10 * time.Second
+
+	// Proxy of an alias ignoring the proxy environment variables.
+	directProxy = "direct"
+)
+
+// aliasTransport - HTTP settings of an alias, from its config merged
+// with the global ones. Deadlines set on the command line take
+// precedence over the timeouts of the alias.
+type aliasTransport struct {
+	rootCAs        *x509.CertPool
+	certificates   []tls.Certificate
+	proxy          func(*http.Request) (*url.URL, error)
+	connectTimeout time.Duration
+	readTimeout    time.Duration
+	writeTimeout   time.Duration
+	headers        http.Header
+}
+
+// parseTimeout parses a timeout of an alias, empty means none.
+func parseTimeout(timeout string) (time.Duration, *probe.Error) {
+	if timeout == "" {
+		return 0, nil
+	}
+	d, e := time.ParseDuration(timeout)
+	if e != nil {
+		return 0, probe.NewError(e).Trace(timeout)
+	}
+	if d < 0 {
+		return 0, probe.NewError(fmt.Errorf("negative timeout `%s`", timeout))
+	}
+	return d, nil
+}
+
+// parseProxy returns the proxy of an alias, nil when the proxy
+// environment variables are used.
+func parseProxy(proxy string) (func(*http.Request) (*url.URL, error), *probe.Error) {
+	switch proxy {
+	case "":
+		return nil, nil
+	case directProxy:
+		return func(*http.Request) (*url.URL, error) { return nil, nil }, nil
+	}
+	u, e := url.Parse(proxy)
+	if e != nil {
+		return nil, probe.NewError(e).Trace(proxy)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, probe.NewError(fmt.Errorf("unsupported proxy `%s`, valid schemes are `[http, https, socks5]`", proxy))
+	}
+	if u.Host == "" {
+		return nil, probe.NewError(fmt.Errorf("proxy `%s` has no host", proxy))
+	}
+	return http.ProxyURL(u), nil
+}
+
+// parseHeaders returns the extra headers of an alias, the ones used to
+// route or sign the requests cannot be set.
+func parseHeaders(headers map[string]string) (http.Header, *probe.Error) {
+	h := make(http.Header, len(headers))
+	for k, v := range headers {
+		key := http.CanonicalHeaderKey(k)
+		switch {
+		case key == "", strings.ContainsAny(key, " :\r\n"):
+			return nil, probe.NewError(fmt.Errorf("invalid header name `%s`", k))
+		case key == "Host", key == "Authorization", key == "Content-Length",
+			strings.HasPrefix(key, "X-Amz-"), strings.HasPrefix(key, "X-Minio-"):
+			return nil, probe.NewError(fmt.Errorf("header `%s` cannot be set on an alias", k))
+		case strings.ContainsAny(v, "\r\n"):
+			return nil, probe.NewError(fmt.Errorf("invalid value of header `%s`", k))
+		}
+		h.Set(key, v)
+	}
+	return h, nil
+}
+
+// loadCABundle returns the global root CAs along with the ones of a
+// PEM bundle.
+func loadCABundle(caFile string) (*x509.CertPool, *probe.Error) {
+	data, e := os.ReadFile(caFile)
+	if e != nil {
+		return nil, probe.NewError(e).Trace(caFile)
+	}
+	var pool *x509.CertPool
+	if globalRootCAs != nil {
+		pool = globalRootCAs.Clone()
+	} else if pool, e = x509.SystemCertPool(); e != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, probe.NewError(fmt.Errorf("no certificate found in `%s`", caFile))
+	}
+	return pool, nil
+}
+
+// validateAliasTransport checks the HTTP settings of an alias, the
+// files are checked to exist but not loaded.
+func validateAliasTransport(cfg *aliasTransportV10) *probe.Error {
+	if cfg == nil {
+		return nil
+	}
+	if (cfg.CertFile == "") != (cfg.KeyFile == "") {
+		return probe.NewError(errors.New("a client certificate requires both `certFile` and `keyFile`"))
+	}
+	for _, file := range []string{cfg.CAFile, cfg.CertFile, cfg.KeyFile} {
+		if file == "" {
+			continue
+		}
+		if _, e := os.Stat(file); e != nil {
+			return probe.NewError(e).Trace(file)
+		}
+	}
+	if _, err := parseProxy(cfg.Proxy); err != nil {
+		return err.Trace()
+	}
+	for _, timeout := range []string{cfg.ConnectTimeout, cfg.ReadTimeout, cfg.WriteTimeout} {
+		if _, err := parseTimeout(timeout); err != nil {
+			return err.Trace()
+		}
+	}
+	if _, err := parseHeaders(cfg.Headers); err != nil {
+		return err.Trace()
+	}
+	return nil
+}
+
+// newAliasTransport resolves the HTTP settings of a client config.
+func newAliasTransport(config *Config) (*aliasTransport, *probe.Error) {
+	t := &aliasTransport{
+		rootCAs:        globalRootCAs,
+		connectTimeout: defaultConnectTimeout,
+		readTimeout:    config.ConnReadDeadline,
+		writeTimeout:   config.ConnWriteDeadline,
+	}
+	cfg := config.AliasTransport
+	if cfg == nil {
+		return t, nil
+	}
+
+	var err *probe.Error
+	if cfg.CAFile != "" {
+		if t.rootCAs, err = loadCABundle(cfg.CAFile); err != nil {
+			return nil, err.Trace()
+		}
+	}
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		certificate, e := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if e != nil {
+			return nil, probe.NewError(e).Trace(cfg.CertFile, cfg.KeyFile)
+		}
+		t.certificates = []tls.Certificate{certificate}
+	}
+	if t.proxy, err = parseProxy(cfg.Proxy); err != nil {
+		return nil, err.Trace()
+	}
+	var timeout time.Duration
+	if timeout, err = parseTimeout(cfg.ConnectTimeout); err != nil {
+		return nil, err.Trace()
+	} else if timeout > 0 {
+		t.connectTimeout = timeout
+	}
+	if timeout, err = parseTimeout(cfg.ReadTimeout); err != nil {
+		return nil, err.Trace()
+	} else if t.readTimeout <= 0 {
+		t.readTimeout = timeout
+	}
+	if timeout, err = parseTimeout(cfg.WriteTimeout); err != nil {
+		return nil, err.Trace()
+	} else if t.writeTimeout <= 0 {
+		t.writeTimeout = timeout
+	}
+	if t.headers, err = parseHeaders(cfg.Headers); err != nil {
+		return nil, err.Trace()
+	}
+	return t, nil
+}
+
+// aliasTransportKey identifies the HTTP settings of an alias for
+// client caches.
+func aliasTransportKey(cfg *aliasTransportV10) string {
+	if cfg == nil {
+		return ""
+	}
+	data, _ := json.Marshal(cfg)
+	return string(data)
+}
+
+// proxyFunc returns the proxy of the alias, or def to use the proxy
+// environment variables.
+func (t *aliasTransport) proxyFunc(def func(*http.Request) (*url.URL, error)) func(*http.Request) (*url.URL, error) {
+	if t.proxy != nil {
+		return t.proxy
+	}
+	return def
+}
+
+// tlsConfig returns the TLS config of the alias.
+func (t *aliasTransport) tlsConfig(insecure bool) *tls.Config {
+	return &tls.Config{
+		RootCAs:      t.rootCAs,
+		Certificates: t.certificates,
+		// Can't use SSLv3 because of POODLE and BEAST
+		// Can't use TLSv1.0 because of POODLE and BEAST using CBC cipher
+		// Can't use TLSv1.1 because of RC4 cipher usage
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: insecure,
+	}
+}
+
+// roundTripper adds the extra headers of the alias to the requests.
+func (t *aliasTransport) roundTripper(rt http.RoundTripper) http.RoundTripper {
+	if len(t.headers) == 0 {
+		return rt
+	}
+	return &headerTransport{transport: rt, headers: t.headers}
+}
+
+// headerTransport - adds static headers to every request.
+type headerTransport struct {
+	transport http.RoundTripper
+	headers   http.Header
+}
+
+func (h *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for k, v := range h.headers {
+		req.Header[k] = v
+	}
+	return h.transport.RoundTrip(req)
+}
@@ -57,6 +57,10 @@ func isValidHostURL(hostURL string) (ok bool) {
 				ok = true
 			}
 		}
+		// SFTP aliases may point to any folder of the server.
+		if url.Type == sftpStorage {
+			ok = true
+		}
 	}
 	return ok
 }
@@ -64,7 +68,7 @@ func isValidHostURL(hostURL string) (ok bool) {
 // isValidAPI - Validates if API signature string of supported type.
 func isValidAPI(api string) (ok bool) {
 	switch strings.ToLower(api) {
-	case "s3v2", "s3v4":
+	case "s3v2", "s3v4", sftpScheme:
 		ok = true
 	}
 	return ok
@@ -36,16 +36,87 @@ var (
 	cfgMutex = &sync.RWMutex{}
 )
 
-// aliasConfig configuration of an alias.
+// aliasConfig configuration of an alias. Bucket and Prefix confine
+// the alias to a prefix of a bucket, the paths of the alias are then
+// relative to it.
 type aliasConfigV10 struct {
-	URL          string `json:"url"`
-	AccessKey    string `json:"accessKey"`
-	SecretKey    string `json:"secretKey"`
-	SessionToken string `json:"sessionToken,omitempty"`
-	API          string `json:"api"`
-	Path         string `json:"path"`
-	License      string `json:"license,omitempty"`
-	APIKey       string `json:"apiKey,omitempty"`
+	URL          string               `json:"url"`
+	AccessKey    string               `json:"accessKey"`
+	SecretKey    string               `json:"secretKey"`
+	SessionToken string               `json:"sessionToken,omitempty"`
+	API          string               `json:"api"`
+	Path         string               `json:"path"`
+	Bucket       string               `json:"bucket,omitempty"`
+	Prefix       string               `json:"prefix,omitempty"`
+	License      string               `json:"license,omitempty"`
+	APIKey       string               `json:"apiKey,omitempty"`
+	Limit        *aliasLimitV10       `json:"limit,omitempty"`
+	Credentials  *aliasCredentialsV10 `json:"credentials,omitempty"`
+	Transport    *aliasTransportV10   `json:"transport,omitempty"`
+	Endpoints    *aliasEndpointsV10   `json:"endpoints,omitempty"`
+}
+
+// aliasCredentialsV10 external source of the credentials of an alias:
+// the output of a command, a shared credentials file, environment
+// variables, the encrypted credential store or a login to the STS API
+// of the server. Only how to log in is stored for STS, the temporary
+// credentials are refreshed when they expire.
+type aliasCredentialsV10 struct {
+	Provider  string `json:"provider"`
+	Command   string `json:"command,omitempty"`
+	File      string `json:"file,omitempty"`
+	Profile   string `json:"profile,omitempty"`
+	Prefix    string `json:"prefix,omitempty"`
+	Endpoint  string `json:"endpoint,omitempty"`
+	Username  string `json:"username,omitempty"`
+	TokenFile string `json:"tokenFile,omitempty"`
+	CertFile  string `json:"certFile,omitempty"`
+	KeyFile   string `json:"keyFile,omitempty"`
+	RoleARN   string `json:"roleArn,omitempty"`
+	Duration  string `json:"duration,omitempty"`
+}
+
+// aliasTransportV10 HTTP settings of an alias: a CA bundle trusted
+// along with the global CAs, a client certificate, a proxy used instead
+// of the proxy environment variables (`direct` for none), timeouts and
+// static headers added to every request.
+type aliasTransportV10 struct {
+	CAFile         string            `json:"caFile,omitempty"`
+	CertFile       string            `json:"certFile,omitempty"`
+	KeyFile        string            `json:"keyFile,omitempty"`
+	Proxy          string            `json:"proxy,omitempty"`
+	ConnectTimeout string            `json:"connectTimeout,omitempty"`
+	ReadTimeout    string            `json:"readTimeout,omitempty"`
+	WriteTimeout   string            `json:"writeTimeout,omitempty"`
+	Headers        map[string]string `json:"headers,omitempty"`
+}
+
+// aliasEndpointsV10 other endpoints of the cluster of an alias, with
+// the policy choosing the endpoint of each request among the online
+// ones: `failover` (in order, the default), `round-robin` or
+// `least-latency`.
+type aliasEndpointsV10 struct {
+	URLs           []string `json:"urls"`
+	Policy         string   `json:"policy,omitempty"`
+	HealthInterval string   `json:"healthInterval,omitempty"`
+}
+
+// aliasLimitV10 bandwidth limits of an alias, sizes are per second
+// and an empty or zero size means unlimited.
+type aliasLimitV10 struct {
+	Upload   string                `json:"upload,omitempty"`
+	Download string                `json:"download,omitempty"`
+	Schedule []aliasLimitWindowV10 `json:"schedule,omitempty"`
+}
+
+// aliasLimitWindowV10 bandwidth limits of an alias during a time of
+// the day window e.g. from "09:00" to "18:00" on "mon-fri".
+type aliasLimitWindowV10 struct {
+	Days     string `json:"days,omitempty"`
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Upload   string `json:"upload,omitempty"`
+	Download string `json:"download,omitempty"`
 }
 
 // configV10 config version.
@@ -62,5 +62,19 @@ func validateConfigHost(host aliasConfigV10) (bool, []string) {
 		validationSuccessful = false
 		hostErrors = append(hostErrors, errInvalidURL(host.URL).ToGoError().Error())
 	}
+	if host.Credentials != nil {
+		if err := validateAliasCredentials(host.Credentials); err != nil {
+			validationSuccessful = false
+			hostErrors = append(hostErrors, err.ToGoError().Error())
+		}
+	}
+	if err := validateAliasTransport(host.Transport); err != nil {
+		validationSuccessful = false
+		hostErrors = append(hostErrors, err.ToGoError().Error())
+	}
+	if err := validateAliasEndpoints(host.URL, host.Endpoints); err != nil {
+		validationSuccessful = false
+		hostErrors = append(hostErrors, err.ToGoError().Error())
+	}
 	return validationSuccessful, hostErrors
 }
@@ -323,16 +323,83 @@ func expandAlias(aliasedURL string) (alias, urlStr string, aliasCfg *aliasConfig
 	}
 
 	aliasCfg = aliasToConfigMap[alias]
+	if aliasCfg == nil {
+		// Find the matching alias entry and expand the URL.
+		aliasCfg = mustGetHostConfig(alias)
+	}
 	if aliasCfg != nil {
+		if path, err = scopeAliasPath(aliasCfg, path); err != nil {
+			return "", "", nil, err.Trace(aliasedURL)
+		}
 		return alias, urlJoinPath(aliasCfg.URL, path), aliasCfg, nil
 	}
 
-	// Find the matching alias entry and expand the URL.
-	if aliasCfg = mustGetHostConfig(alias); aliasCfg != nil {
-		return alias, urlJoinPath(aliasCfg.URL, path), aliasCfg, nil
+	return "", aliasedURL, nil, nil // No matching entry found. Return original URL as is.
+}
+
+// isScopedAlias returns true if the alias is confined to a bucket.
+func isScopedAlias(aliasCfg *aliasConfigV10) bool {
+	return aliasCfg != nil && aliasCfg.Bucket != ""
+}
+
+// aliasScope returns the bucket and prefix an alias is confined to,
+// with a trailing slash.
+func aliasScope(aliasCfg *aliasConfigV10) string {
+	scope := aliasCfg.Bucket + "/"
+	if prefix := strings.Trim(aliasCfg.Prefix, "/"); prefix != "" {
+		scope += prefix + "/"
 	}
+	return scope
+}
 
-	return "", aliasedURL, nil, nil // No matching entry found. Return original URL as is.
+// aliasScopeURL returns the URL the paths of an alias are relative to.
+func aliasScopeURL(aliasCfg *aliasConfigV10) string {
+	if !isScopedAlias(aliasCfg) {
+		return aliasCfg.URL
+	}
+	return urlJoinPath(aliasCfg.URL, aliasScope(aliasCfg))
+}
+
+// isEscapingPath returns true if a path has `..` elements.
+func isEscapingPath(path string) bool {
+	for _, elem := range strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if elem == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+// scopeAliasPath returns the path of a scoped alias inside its bucket
+// and prefix, the path of other aliases is returned as is. Paths of
+// scoped aliases cannot go up with `..`.
+func scopeAliasPath(aliasCfg *aliasConfigV10, path string) (string, *probe.Error) {
+	if !isScopedAlias(aliasCfg) {
+		return path, nil
+	}
+	if isEscapingPath(path) {
+		return "", probe.NewError(fmt.Errorf("path `%s` is outside of `%s`, the scope of the alias", path, aliasScope(aliasCfg)))
+	}
+	return aliasScope(aliasCfg) + strings.TrimLeft(filepath.ToSlash(path), "/"), nil
+}
+
+// aliasPath returns the aliased path of urlPath, the path of a URL
+// expanded from an aliased URL of alias. The bucket and prefix of scoped
+// aliases are left out, expanding the aliased path adds them back.
+func aliasPath(alias, urlPath string) string {
+	if alias != "" && !env.IsSet(mcEnvHostPrefix+alias) {
+		aliasCfg := aliasToConfigMap[alias]
+		if aliasCfg == nil {
+			aliasCfg = mustGetHostConfig(alias)
+		}
+		if isScopedAlias(aliasCfg) {
+			scope := "/" + strings.TrimSuffix(aliasScope(aliasCfg), "/")
+			if p := filepath.ToSlash(urlPath); p == scope || strings.HasPrefix(p, scope+"/") {
+				urlPath = strings.TrimPrefix(p, scope)
+			}
+		}
+	}
+	return filepath.Join(alias, urlPath)
 }
 
 // mustExpandAlias expands aliased URL if any match is found, returns as is otherwise.
@@ -17,7 +17,15 @@
 
 package cmd
 
-import "testing"
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/trinet2005/oss-mc/pkg/limiter"
+)
 
 // Tests valid host URL functionality.
 func TestParseEnvURLStr(t *testing.T) {
@@ -142,3 +150,155 @@ func TestParseEnvURLStrInvalid(t *testing.T) {
 		t.Fatalf("Expected failure")
 	}
 }
+
+func TestExpandScopedAlias(t *testing.T) {
+	aliasToConfigMap["logs"] = &aliasConfigV10{URL: "https://minio.example.com", Bucket: "logs", Prefix: "prod/2024"}
+	aliasToConfigMap["archive"] = &aliasConfigV10{URL: "https://minio.example.com", Bucket: "archive"}
+	defer delete(aliasToConfigMap, "logs")
+	defer delete(aliasToConfigMap, "archive")
+
+	testCases := []struct {
+		aliasedURL string
+		urlStr     string
+		fail       bool
+	}{
+		{"logs", "https://minio.example.com/logs/prod/2024/", false},
+		{"logs/", "https://minio.example.com/logs/prod/2024/", false},
+		{"logs/app/out.log", "https://minio.example.com/logs/prod/2024/app/out.log", false},
+		{"logs/app/", "https://minio.example.com/logs/prod/2024/app/", false},
+		{"archive/2023/", "https://minio.example.com/archive/2023/", false},
+		{"logs/..", "", true},
+		{"logs/app/../../2023/", "", true},
+		{"archive/../logs", "", true},
+	}
+	for _, testCase := range testCases {
+		_, urlStr, _, err := expandAlias(testCase.aliasedURL)
+		if testCase.fail {
+			if err == nil {
+				t.Fatalf("%s: expected to fail, got %s", testCase.aliasedURL, urlStr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: unexpected error %s", testCase.aliasedURL, err)
+		}
+		if urlStr != testCase.urlStr {
+			t.Fatalf("%s: expected %s, got %s", testCase.aliasedURL, testCase.urlStr, urlStr)
+		}
+	}
+}
+
+func TestListScopedAlias(t *testing.T) {
+	var prefixes []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			t.Errorf("Unexpected request %s %s outside of the bucket", r.Method, r.URL)
+		}
+		if r.URL.Query().Has("location") {
+			w.Write([]byte(`<LocationConstraint xmlns="http://s3.amazonaws.com/doc/2006-03-01/">us-east-1</LocationConstraint>`))
+			return
+		}
+		prefixes = append(prefixes, r.URL.Query().Get("prefix"))
+		w.Write([]byte(`<ListBucketResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><Name>logs</Name>` +
+			`<KeyCount>0</KeyCount><MaxKeys>1000</MaxKeys><IsTruncated>false</IsTruncated></ListBucketResult>`))
+	}))
+	defer server.Close()
+
+	aliasToConfigMap["scoped"] = &aliasConfigV10{
+		URL: server.URL, AccessKey: "access", SecretKey: "secret-key", API: "S3v4", Path: "on",
+		Bucket: "logs", Prefix: "prod/2024",
+	}
+	defer delete(aliasToConfigMap, "scoped")
+
+	clnt, err := newClient("scoped/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for content := range clnt.List(context.Background(), ListOptions{ShowDir: DirNone}) {
+		if content.Err != nil {
+			t.Fatal(content.Err)
+		}
+	}
+	if len(prefixes) == 0 || prefixes[len(prefixes)-1] != "prod/2024/" {
+		t.Fatalf("Expected a listing of prod/2024/, got %v", prefixes)
+	}
+}
+
+func TestAliasPathScoped(t *testing.T) {
+	aliasToConfigMap["logs"] = &aliasConfigV10{URL: "https://minio.example.com", Bucket: "logs", Prefix: "prod/2024"}
+	defer delete(aliasToConfigMap, "logs")
+	aliasToConfigMap["plain"] = &aliasConfigV10{URL: "https://minio.example.com"}
+	defer delete(aliasToConfigMap, "plain")
+
+	testCases := []struct {
+		alias, urlPath, aliased string
+	}{
+		{"logs", "/logs/prod/2024/app/out.log", "logs/app/out.log"},
+		{"logs", "/logs/prod/2024/", "logs"},
+		{"logs", "/logs/prod/2024", "logs"},
+		{"logs", "/logs/prod/20245/out.log", "logs/logs/prod/20245/out.log"},
+		{"plain", "/bucket/out.log", "plain/bucket/out.log"},
+	}
+	for _, testCase := range testCases {
+		if aliased := filepath.ToSlash(aliasPath(testCase.alias, testCase.urlPath)); aliased != testCase.aliased {
+			t.Errorf("%s %s: expected %s, got %s", testCase.alias, testCase.urlPath, testCase.aliased, aliased)
+		}
+	}
+
+	// The aliased path expands back to the same URL.
+	_, urlStr, _, err := expandAlias(filepath.ToSlash(aliasPath("logs", "/logs/prod/2024/app/out.log")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if urlStr != "https://minio.example.com/logs/prod/2024/app/out.log" {
+		t.Fatalf("Unexpected URL %s", urlStr)
+	}
+}
+
+func TestScopedAliasSSE(t *testing.T) {
+	aliasToConfigMap["logs"] = &aliasConfigV10{URL: "https://minio.example.com", Bucket: "logs", Prefix: "prod/2024"}
+	defer delete(aliasToConfigMap, "logs")
+
+	encKeyDB, err := parseEncryptionKeys("logs/secret/=32byteslongsecretkeymustbegiven1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, urlStr, _, err := expandAlias("logs/secret/report.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := &ClientContent{URL: *newClientURL(urlStr), ETag: "5d41402abc4b2a76b9719d911017c592"}
+	if sse := getSSE(filepath.ToSlash(aliasPath("logs", content.URL.Path)), encKeyDB["logs"]); sse == nil {
+		t.Fatal("Expected the SSE-C key of logs/secret/ to match")
+	}
+	if sse := getSSE(filepath.ToSlash(aliasPath("logs", "/logs/prod/2024/public/report.csv")), encKeyDB["logs"]); sse != nil {
+		t.Fatal("Expected no SSE-C key outside of logs/secret/")
+	}
+
+	// SSE-C objects have no ETag digest.
+	comparer := contentComparer{encKeyDB: encKeyDB}
+	if etag := comparer.etag("logs", content); etag != "" {
+		t.Fatalf("Expected the ETag of an SSE-C object to be ignored, got %s", etag)
+	}
+}
+
+func TestAliasLimitsOverride(t *testing.T) {
+	cfg := &aliasLimitV10{Upload: "1MiB", Download: "2MiB"}
+	testCases := []struct {
+		override, expected limiter.Limits
+	}{
+		{limiter.Limits{}, limiter.Limits{Upload: 1 << 20, Download: 2 << 20}},
+		{limiter.Limits{Upload: 5 << 20}, limiter.Limits{Upload: 5 << 20, Download: 2 << 20}},
+		{limiter.Limits{Download: 5 << 20}, limiter.Limits{Upload: 1 << 20, Download: 5 << 20}},
+		{limiter.Limits{Upload: 3, Download: 4}, limiter.Limits{Upload: 3, Download: 4}},
+	}
+	for i, testCase := range testCases {
+		limits, err := newAliasLimits("myminio", cfg, testCase.override)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := limits.Limits(); got != testCase.expected {
+			t.Errorf("Test %d: expected %v, got %v", i+1, testCase.expected, got)
+		}
+	}
+}
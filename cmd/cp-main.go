@@ -87,6 +87,10 @@ var (
 			Name:  "md5",
 			Usage: "force all upload(s) to calculate md5sum checksum",
 		},
+		cli.StringFlag{
+			Name:  "checksum",
+			Usage: "verify transferred object(s) end-to-end with an additional checksum (CRC32C, CRC32, SHA1, SHA256), multipart uploads only support CRC32C",
+		},
 		cli.StringFlag{
 			Name:  "tags",
 			Usage: "apply one or more tags to the uploaded objects",
@@ -126,7 +130,7 @@ var cpCmd = cli.Command{
 	Action:       mainCopy,
 	OnUsageError: onUsageError,
 	Before:       setGlobalsFromContext,
-	Flags:        append(append(cpFlags, ioFlags...), globalFlags...),
+	Flags:        append(append(append(append(append(cpFlags, ioFlags...), cseFlags...), compressFlags...), downloadFlags...), globalFlags...),
 	CustomHelpTemplate: `NAME:
   {{.HelpName}} - {{.Usage}}
 
@@ -202,6 +206,32 @@ EXAMPLES:
   20. Set tags to the uploaded objects
       {{.Prompt}} {{.HelpName}} -r --tags "category=prod&type=backup" ./data/ play/another-bucket/
 
+  21. Copy a folder recursively and verify every object end-to-end with a SHA256 checksum.
+      {{.Prompt}} {{.HelpName}} -r --checksum SHA256 ./data/ play/mybucket/
+
+  22. Copy a folder recursively encrypting every object client-side for an age recipient.
+      {{.Prompt}} {{.HelpName}} -r --cse-recipient age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p ./data/ play/mybucket/
+
+  23. Copy client-side encrypted objects back to a local folder, decrypting them with a key file.
+      {{.Prompt}} {{.HelpName}} -r --cse-key ~/.mc/cse.key play/mybucket/ ./data/
+
+  24. Copy a folder of logs recursively compressing every object with zstd, they are decompressed when copied back.
+      {{.Prompt}} {{.HelpName}} -r --compress zstd ./logs/ play/mybucket/logs/
+
+  25. Download a large object with 8 concurrent range requests of 128MiB, an interrupted download resumes with the missing parts.
+      {{.Prompt}} {{.HelpName}} --download-parallel 8 --download-part-size 128MiB play/mybucket/backup.tar.gz ./
+
+  26. Copy a bucket between two aliases of the same cluster with different credentials, the data is copied server-side.
+      {{.Prompt}} {{.HelpName}} -r teamA/reports/ teamB/archive/reports/
+
+  27. Copy a bucket to a local folder and back keeping content type, user metadata and tags in extended attributes of the files.
+      {{.Prompt}} {{.HelpName}} -r -a --fs-metadata play/mybucket/ ./backup/
+      {{.Prompt}} {{.HelpName}} -r -a --fs-metadata ./backup/ play/mybucket/
+
+  28. Copy a folder of an SFTP server to a bucket, authenticating with the SSH agent or the keys in MC_SFTP_IDENTITY.
+      The host key is checked against ~/.ssh/known_hosts, or the file in MC_SFTP_KNOWN_HOSTS.
+      {{.Prompt}} {{.HelpName}} -r sftp://backup@sftp.example.com/srv/exports/ play/mybucket/exports/
+
 `,
 }
 
@@ -255,12 +285,12 @@ func doCopy(ctx context.Context, cpURLs URLs, pg ProgressReader, encKeyDB map[st
 	targetAlias := cpURLs.TargetAlias
 	targetURL := cpURLs.TargetContent.URL
 	length := cpURLs.SourceContent.Size
-	sourcePath := filepath.ToSlash(filepath.Join(sourceAlias, sourceURL.Path))
+	sourcePath := filepath.ToSlash(aliasPath(sourceAlias, sourceURL.Path))
 
 	if progressReader, ok := pg.(*progressBar); ok {
 		progressReader.SetCaption(cpURLs.SourceContent.URL.String() + ":")
 	} else {
-		targetPath := filepath.ToSlash(filepath.Join(targetAlias, targetURL.Path))
+		targetPath := filepath.ToSlash(aliasPath(targetAlias, targetURL.Path))
 		printMsg(copyMessage{
 			Source:     sourcePath,
 			Target:     targetPath,
@@ -399,6 +429,18 @@ func doCopySession(ctx context.Context, cancelCopy context.CancelFunc, cli *cli.
 	// Check if the target path has object locking enabled
 	withLock, _ := isBucketLockEnabled(ctx, targetURL)
 
+	checksum, err := parseChecksumAlgo(cli.String("checksum"))
+	fatalIf(err.Trace(cli.String("checksum")), "Unable to parse --checksum.")
+
+	cse, err := getCSEKeys(cli)
+	fatalIf(err, "Unable to parse client-side encryption keys.")
+
+	compress, err := parseCompressCodec(cli.String("compress"))
+	fatalIf(err, "Unable to parse --compress.")
+
+	download, err := parseDownloadOptions(cli)
+	fatalIf(err, "Unable to parse ranged download options.")
+
 	if session != nil {
 		// isCopied returns true if an object has been already copied
 		// or not. This is useful when we resume from a session.
@@ -545,6 +587,10 @@ func doCopySession(ctx context.Context, cancelCopy context.CancelFunc, cli *cli.
 
 				cpURLs.MD5 = cli.Bool("md5") || withLock
 				cpURLs.DisableMultipart = cli.Bool("disable-multipart")
+				cpURLs.Checksum = checksum
+				cpURLs.cse = cse
+				cpURLs.compress = compress
+				cpURLs.download = download
 
 				// Verify if previously copied, notify progress bar.
 				if isCopied != nil && isCopied(cpURLs.SourceContent.URL.String()) {
@@ -724,6 +770,7 @@ func mainCopy(cliCtx *cli.Context) error {
 			}
 			session.Header.UserMetaData = userMetaMap
 			session.Header.CommandBoolFlags["md5"] = cliCtx.Bool("md5")
+			session.Header.CommandStringFlags["checksum"] = cliCtx.String("checksum")
 			session.Header.CommandBoolFlags["disable-multipart"] = cliCtx.Bool("disable-multipart")
 
 			var e error
@@ -57,6 +57,10 @@ func checkCopySyntax(ctx context.Context, cliCtx *cli.Context, encKeyDB map[stri
 		fatalIf(errDummy().Trace(cliCtx.Args()...), "--zip and --rewind cannot be used together")
 	}
 
+	if _, err := parseChecksumAlgo(cliCtx.String("checksum")); err != nil {
+		fatalIf(err.Trace(cliCtx.String("checksum")), "Unable to parse --checksum.")
+	}
+
 	// Verify if source(s) exists.
 	for _, srcURL := range srcURLs {
 		var err *probe.Error
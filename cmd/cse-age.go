@@ -0,0 +1,106 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"filippo.io/age"
+	"github.com/trinet2005/oss-mc/pkg/probe"
+)
+
+// Data keys are wrapped for age X25519 recipients by encrypting them as
+// age files, so that keys generated by age-keygen can be used.
+
+const ageRecipientPrefix = "age1"
+
+// ageRecipient - an age public key.
+type ageRecipient struct {
+	age.Recipient
+}
+
+// parseAgeRecipient parses an age1... public key.
+func parseAgeRecipient(s string) (ageRecipient, error) {
+	r, e := age.ParseX25519Recipient(s)
+	if e != nil {
+		return ageRecipient{}, e
+	}
+	return ageRecipient{r}, nil
+}
+
+func (r ageRecipient) wrap(dataKey []byte) (string, []byte, error) {
+	var buf bytes.Buffer
+	w, e := age.Encrypt(&buf, r.Recipient)
+	if e != nil {
+		return "", nil, e
+	}
+	if _, e = w.Write(dataKey); e != nil {
+		return "", nil, e
+	}
+	if e = w.Close(); e != nil {
+		return "", nil, e
+	}
+	return cseWrapAge, buf.Bytes(), nil
+}
+
+// ageIdentity - an age private key.
+type ageIdentity struct {
+	age.Identity
+}
+
+// loadAgeIdentities reads an identity file as written by age-keygen.
+func loadAgeIdentities(fpath string) ([]cseIdentity, *probe.Error) {
+	f, e := os.Open(fpath)
+	if e != nil {
+		return nil, probe.NewError(e)
+	}
+	defer f.Close()
+
+	ids, e := age.ParseIdentities(f)
+	if e != nil {
+		return nil, probe.NewError(fmt.Errorf("no age identity found in `%s`: %w", fpath, e))
+	}
+	identities := make([]cseIdentity, 0, len(ids))
+	for _, id := range ids {
+		identities = append(identities, ageIdentity{id})
+	}
+	return identities, nil
+}
+
+func (i ageIdentity) unwrap(scheme string, wrapped []byte) ([]byte, error) {
+	if scheme != cseWrapAge {
+		return nil, errCSEUnwrap
+	}
+	r, e := age.Decrypt(bytes.NewReader(wrapped), i.Identity)
+	if e != nil {
+		var noMatch *age.NoIdentityMatchError
+		if errors.As(e, &noMatch) {
+			return nil, errCSEUnwrap
+		}
+		return nil, e
+	}
+	dataKey, e := io.ReadAll(io.LimitReader(r, cseDataKeySize+1))
+	if e != nil || len(dataKey) != cseDataKeySize {
+		return nil, errCSEUnwrap
+	}
+	return dataKey, nil
+}
@@ -0,0 +1,164 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/trinet2005/oss-pkg/env"
+)
+
+// Data keys are wrapped for OpenPGP recipients by GnuPG, which keeps
+// private keys and passphrases in its agent. Running gpg for every
+// object is slow and a PGP message is large, so the data keys are
+// wrapped with AES-GCM by a key encryption key, only the key encryption
+// key is encrypted by gpg, once per run for all the recipients.
+
+// gpgCommand runs gpg with input on its standard input.
+func gpgCommand(input []byte, args ...string) ([]byte, error) {
+	gpg := env.Get("MC_CSE_GPG", "gpg")
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(gpg, append([]string{"--batch", "--quiet", "--no-tty"}, args...)...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if e := cmd.Run(); e != nil {
+		return nil, fmt.Errorf("%s: %w: %s", gpg, e, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// gpgSealedKeySize - size of a data key sealed by the key encryption
+// key, the nonce followed by the sealed key and its tag.
+const gpgSealedKeySize = 12 + cseDataKeySize + 16
+
+// gpgSeal seals a key with AES-GCM.
+func gpgSeal(kek, key []byte) ([]byte, error) {
+	aead, e := gpgAEAD(kek)
+	if e != nil {
+		return nil, e
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, e = io.ReadFull(rand.Reader, nonce); e != nil {
+		return nil, e
+	}
+	return aead.Seal(nonce, nonce, key, []byte(cseWrapPGP)), nil
+}
+
+// gpgOpen opens a key sealed by gpgSeal.
+func gpgOpen(kek, sealed []byte) ([]byte, error) {
+	aead, e := gpgAEAD(kek)
+	if e != nil {
+		return nil, e
+	}
+	return aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(cseWrapPGP))
+}
+
+func gpgAEAD(kek []byte) (cipher.AEAD, error) {
+	block, e := aes.NewCipher(kek)
+	if e != nil {
+		return nil, e
+	}
+	return cipher.NewGCM(block)
+}
+
+// gpgRecipients - key ids, fingerprints or user ids known to GnuPG,
+// the data keys are wrapped once for all of them.
+type gpgRecipients struct {
+	ids []string
+
+	once    sync.Once
+	kek     []byte
+	message []byte
+	err     error
+}
+
+// encryptKEK generates the key encryption key and encrypts it with gpg.
+func (r *gpgRecipients) encryptKEK() {
+	r.kek = make([]byte, cseDataKeySize)
+	if _, r.err = io.ReadFull(rand.Reader, r.kek); r.err != nil {
+		return
+	}
+	args := []string{"--trust-model", "always", "--encrypt"}
+	for _, id := range r.ids {
+		args = append(args, "--recipient", id)
+	}
+	r.message, r.err = gpgCommand(r.kek, args...)
+}
+
+// wrap returns the sealed data key followed by the PGP message holding
+// the key encryption key.
+func (r *gpgRecipients) wrap(dataKey []byte) (string, []byte, error) {
+	r.once.Do(r.encryptKEK)
+	if r.err != nil {
+		return "", nil, r.err
+	}
+	sealed, e := gpgSeal(r.kek, dataKey)
+	if e != nil {
+		return "", nil, e
+	}
+	return cseWrapPGP, append(sealed, r.message...), nil
+}
+
+// gpgIdentity - the private keys of the GnuPG keyring, key encryption
+// keys are decrypted once per PGP message.
+type gpgIdentity struct {
+	mu   sync.Mutex
+	keks map[[sha256.Size]byte][]byte
+}
+
+func (i *gpgIdentity) unwrap(scheme string, wrapped []byte) ([]byte, error) {
+	if scheme != cseWrapPGP || len(wrapped) <= gpgSealedKeySize {
+		return nil, errCSEUnwrap
+	}
+	sealed, message := wrapped[:gpgSealedKeySize], wrapped[gpgSealedKeySize:]
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	id := sha256.Sum256(message)
+	kek, ok := i.keks[id]
+	if !ok {
+		// Failures are cached too, gpg is not asked twice for
+		// a message it is unable to decrypt.
+		var e error
+		if kek, e = gpgCommand(message, "--decrypt"); e != nil || len(kek) != cseDataKeySize {
+			kek = nil
+		}
+		if i.keks == nil {
+			i.keks = make(map[[sha256.Size]byte][]byte)
+		}
+		i.keks[id] = kek
+	}
+	if kek == nil {
+		return nil, errCSEUnwrap
+	}
+	dataKey, e := gpgOpen(kek, sealed)
+	if e != nil {
+		return nil, errCSEUnwrap
+	}
+	return dataKey, nil
+}
@@ -0,0 +1,471 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/minio/cli"
+	"github.com/secure-io/sio-go"
+	"github.com/trinet2005/oss-mc/pkg/probe"
+)
+
+// Client-side encryption (CSE) encrypts objects with a random data key
+// before they leave the client, in the DARE format. The data key is
+// wrapped for every recipient and stored in the object metadata, the
+// server never sees plaintext or keys.
+
+// Metadata of client-side encrypted objects.
+const (
+	cseMetaAlgorithm = "X-Amz-Meta-Mc-Cse-Algorithm"
+	cseMetaNonce     = "X-Amz-Meta-Mc-Cse-Nonce"
+	cseMetaKey       = "X-Amz-Meta-Mc-Cse-Key"
+)
+
+const (
+	cseDataKeySize = 32
+
+	// S3 limits the user metadata of an object, keys and values,
+	// the wrapped data keys must fit with the other user metadata.
+	cseMaxUserMetadataSize = 2 << 10
+
+	// Key wrapping schemes, prefixed to the wrapped data keys.
+	cseWrapKeyFile = "key"
+	cseWrapAge     = "age"
+	cseWrapPGP     = "pgp"
+)
+
+// cseAlgorithm - AEAD used to encrypt the object data.
+var cseAlgorithm = sio.AES_256_GCM
+
+// Flags of the commands supporting client-side encryption.
+var cseFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:   "cse-key",
+		Usage:  "encrypt/decrypt objects client-side with a 256 bit key read from a file",
+		EnvVar: "MC_CSE_KEY",
+	},
+	cli.StringSliceFlag{
+		Name:  "cse-recipient",
+		Usage: "encrypt objects client-side for an age public key or a GnuPG key id",
+	},
+	cli.StringSliceFlag{
+		Name:  "cse-identity",
+		Usage: "decrypt client-side encrypted objects with an age identity file, or 'pgp' for the GnuPG keyring only",
+	},
+}
+
+// cseRecipient wraps data keys for someone able to unwrap them.
+type cseRecipient interface {
+	wrap(dataKey []byte) (scheme string, wrapped []byte, err error)
+}
+
+// cseIdentity unwraps data keys wrapped for it.
+type cseIdentity interface {
+	unwrap(scheme string, wrapped []byte) ([]byte, error)
+}
+
+// errCSEUnwrap is returned by identities unable to unwrap a data key.
+var errCSEUnwrap = errors.New("data key not wrapped for this identity")
+
+// errCSEInvalidSize - the size is not the size of an encrypted object.
+var errCSEInvalidSize = errors.New("invalid size of a client-side encrypted object")
+
+// errCSEMetadataTooLarge - the wrapped data keys do not fit in the user metadata.
+var errCSEMetadataTooLarge = fmt.Errorf("the wrapped data keys exceed the %d bytes of user metadata of an object, use fewer recipients or smaller keys", cseMaxUserMetadataSize)
+
+// cseKeys - recipients to encrypt for and identities to decrypt with.
+type cseKeys struct {
+	recipients []cseRecipient
+	identities []cseIdentity
+}
+
+// getCSEKeys parses the client-side encryption flags, it returns nil if
+// client-side encryption is not used.
+func getCSEKeys(cliCtx *cli.Context) (*cseKeys, *probe.Error) {
+	keyFile := cliCtx.String("cse-key")
+	recipients := cliCtx.StringSlice("cse-recipient")
+	identities := cliCtx.StringSlice("cse-identity")
+	if keyFile == "" && len(recipients) == 0 && len(identities) == 0 {
+		return nil, nil
+	}
+
+	keys := &cseKeys{}
+	if keyFile != "" {
+		key, err := loadCSEKeyFile(keyFile)
+		if err != nil {
+			return nil, err.Trace(keyFile)
+		}
+		keys.recipients = append(keys.recipients, key)
+		keys.identities = append(keys.identities, key)
+	}
+	gpgRecipientIDs := &gpgRecipients{}
+	for _, recipient := range recipients {
+		if strings.HasPrefix(recipient, ageRecipientPrefix) {
+			r, e := parseAgeRecipient(recipient)
+			if e != nil {
+				return nil, probe.NewError(e).Trace(recipient)
+			}
+			keys.recipients = append(keys.recipients, r)
+			continue
+		}
+		gpgRecipientIDs.ids = append(gpgRecipientIDs.ids, recipient)
+	}
+	if len(gpgRecipientIDs.ids) > 0 {
+		keys.recipients = append(keys.recipients, gpgRecipientIDs)
+	}
+	for _, identity := range identities {
+		if identity == cseWrapPGP {
+			continue
+		}
+		ids, err := loadAgeIdentities(identity)
+		if err != nil {
+			return nil, err.Trace(identity)
+		}
+		keys.identities = append(keys.identities, ids...)
+	}
+	// Keys wrapped for GnuPG recipients are unwrapped by the GnuPG agent.
+	keys.identities = append(keys.identities, &gpgIdentity{})
+	return keys, nil
+}
+
+// canEncrypt returns true if objects are to be encrypted.
+func (k *cseKeys) canEncrypt() bool {
+	return k != nil && len(k.recipients) > 0
+}
+
+// cseKeyFile - a 256 bit key wrapping data keys with AES-GCM.
+type cseKeyFile []byte
+
+// loadCSEKeyFile reads a hex or base64 encoded 256 bit key.
+func loadCSEKeyFile(fpath string) (cseKeyFile, *probe.Error) {
+	data, e := os.ReadFile(fpath)
+	if e != nil {
+		return nil, probe.NewError(e)
+	}
+	s := strings.TrimSpace(string(data))
+	if key, e := hex.DecodeString(s); e == nil && len(key) == cseDataKeySize {
+		return key, nil
+	}
+	if key, e := base64.StdEncoding.DecodeString(s); e == nil && len(key) == cseDataKeySize {
+		return key, nil
+	}
+	return nil, probe.NewError(fmt.Errorf("key file `%s` must hold a hex or base64 encoded 256 bit key", fpath))
+}
+
+func (k cseKeyFile) aead() (cipher.AEAD, error) {
+	block, e := aes.NewCipher(k)
+	if e != nil {
+		return nil, e
+	}
+	return cipher.NewGCM(block)
+}
+
+func (k cseKeyFile) wrap(dataKey []byte) (string, []byte, error) {
+	aead, e := k.aead()
+	if e != nil {
+		return "", nil, e
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, e = io.ReadFull(rand.Reader, nonce); e != nil {
+		return "", nil, e
+	}
+	return cseWrapKeyFile, aead.Seal(nonce, nonce, dataKey, []byte(cseMetaKey)), nil
+}
+
+func (k cseKeyFile) unwrap(scheme string, wrapped []byte) ([]byte, error) {
+	aead, e := k.aead()
+	if e != nil {
+		return nil, e
+	}
+	if scheme != cseWrapKeyFile || len(wrapped) < aead.NonceSize() {
+		return nil, errCSEUnwrap
+	}
+	dataKey, e := aead.Open(nil, wrapped[:aead.NonceSize()], wrapped[aead.NonceSize():], []byte(cseMetaKey))
+	if e != nil {
+		return nil, errCSEUnwrap
+	}
+	return dataKey, nil
+}
+
+// metadataValue looks up a header in the metadata of an object, listings
+// and stats may report user metadata in any case.
+func metadataValue(metadata map[string]string, key string) string {
+	if v, ok := metadata[key]; ok {
+		return v
+	}
+	for k, v := range metadata {
+		if strings.EqualFold(k, key) {
+			return v
+		}
+	}
+	return ""
+}
+
+// userMetadataSize returns the size of the user metadata as counted by
+// S3, the keys without their X-Amz-Meta- prefix and the values.
+func userMetadataSize(metadata map[string]string) int {
+	const prefix = "X-Amz-Meta-"
+	var size int
+	for k, v := range metadata {
+		if len(k) > len(prefix) && strings.EqualFold(k[:len(prefix)], prefix) {
+			size += len(k) - len(prefix) + len(v)
+		}
+	}
+	return size
+}
+
+// isCSEEncrypted returns true if the metadata belongs to a client-side
+// encrypted object.
+func isCSEEncrypted(metadata map[string]string) bool {
+	return metadataValue(metadata, cseMetaKey) != ""
+}
+
+// cseStripMetadata removes the client-side encryption headers.
+func cseStripMetadata(metadata map[string]string) {
+	for k := range metadata {
+		switch http.CanonicalHeaderKey(k) {
+		case cseMetaAlgorithm, cseMetaNonce, cseMetaKey:
+			delete(metadata, k)
+		}
+	}
+}
+
+// cseFragmentSize - size of an encrypted DARE fragment.
+func cseFragmentSize() int64 {
+	return sio.BufSize + 16
+}
+
+// cseEncryptedSize returns the size of an encrypted object.
+func cseEncryptedSize(size int64) int64 {
+	if size < 0 {
+		return size
+	}
+	n, r := size/sio.BufSize, size%sio.BufSize
+	if r > 0 || size == 0 {
+		return n*cseFragmentSize() + r + 16
+	}
+	return n * cseFragmentSize()
+}
+
+// cseDecryptedSize returns the size of the plaintext of an encrypted
+// object, or -1 if the size is not the size of an encrypted object.
+func cseDecryptedSize(size int64) int64 {
+	n, r := size/cseFragmentSize(), size%cseFragmentSize()
+	switch {
+	case size <= 0 || (r > 0 && r <= 16 && size != 16):
+		return -1
+	case r == 0:
+		return n * sio.BufSize
+	}
+	return n*sio.BufSize + r - 16
+}
+
+// cseSameSize returns true if one of the contents may be the client-side
+// encrypted copy of the other.
+func cseSameSize(first, second *ClientContent) bool {
+	if first == nil || second == nil {
+		return false
+	}
+	return cseEncryptedSize(first.Size) == second.Size || cseEncryptedSize(second.Size) == first.Size
+}
+
+// cseCiphertextOffset returns the offset of the fragment holding the
+// plaintext at offset, decryption of a range starts there.
+func cseCiphertextOffset(offset int64) int64 {
+	return offset / sio.BufSize * cseFragmentSize()
+}
+
+// encrypt returns a reader encrypting r and the metadata to store with
+// the encrypted object, size is -1 if unknown.
+func (k *cseKeys) encrypt(r io.Reader, size int64) (io.Reader, int64, map[string]string, *probe.Error) {
+	dataKey := make([]byte, cseDataKeySize)
+	if _, e := io.ReadFull(rand.Reader, dataKey); e != nil {
+		return nil, 0, nil, probe.NewError(e)
+	}
+	stream, e := cseAlgorithm.Stream(dataKey)
+	if e != nil {
+		return nil, 0, nil, probe.NewError(e)
+	}
+	nonce := make([]byte, stream.NonceSize())
+	if _, e = io.ReadFull(rand.Reader, nonce); e != nil {
+		return nil, 0, nil, probe.NewError(e)
+	}
+
+	wrappedKeys := make([]string, 0, len(k.recipients))
+	for _, recipient := range k.recipients {
+		scheme, wrapped, e := recipient.wrap(dataKey)
+		if e != nil {
+			return nil, 0, nil, probe.NewError(e)
+		}
+		wrappedKeys = append(wrappedKeys, scheme+":"+base64.StdEncoding.EncodeToString(wrapped))
+	}
+
+	metadata := map[string]string{
+		cseMetaAlgorithm: cseAlgorithm.String(),
+		cseMetaNonce:     base64.StdEncoding.EncodeToString(nonce),
+		cseMetaKey:       strings.Join(wrappedKeys, " "),
+	}
+	return stream.EncryptReader(r, nonce, nil), cseEncryptedSize(size), metadata, nil
+}
+
+// cseObject - an encrypted object whose data key was unwrapped.
+type cseObject struct {
+	stream *sio.Stream
+	nonce  []byte
+}
+
+// open unwraps the data key of an encrypted object.
+func (k *cseKeys) open(urlStr string, metadata map[string]string) (*cseObject, *probe.Error) {
+	if algorithm := metadataValue(metadata, cseMetaAlgorithm); algorithm != cseAlgorithm.String() {
+		return nil, probe.NewError(fmt.Errorf("unsupported client-side encryption algorithm `%s`", algorithm)).Trace(urlStr)
+	}
+	nonce, e := base64.StdEncoding.DecodeString(metadataValue(metadata, cseMetaNonce))
+	if e != nil {
+		return nil, probe.NewError(e).Trace(urlStr)
+	}
+
+	for _, wrappedKey := range strings.Fields(metadataValue(metadata, cseMetaKey)) {
+		scheme, value, _ := strings.Cut(wrappedKey, ":")
+		wrapped, e := base64.StdEncoding.DecodeString(value)
+		if e != nil {
+			continue
+		}
+		for _, identity := range k.identities {
+			dataKey, e := identity.unwrap(scheme, wrapped)
+			if e != nil {
+				continue
+			}
+			stream, e := cseAlgorithm.Stream(dataKey)
+			if e != nil || len(nonce) != stream.NonceSize() {
+				return nil, probe.NewError(errors.New("invalid client-side encryption metadata")).Trace(urlStr)
+			}
+			return &cseObject{stream: stream, nonce: nonce}, nil
+		}
+	}
+	return nil, errCSEKeyNotFound(urlStr)
+}
+
+// decrypt returns the plaintext from offset to the end of an object of
+// the given plaintext size, the ciphertext must start at the offset
+// returned by cseCiphertextOffset.
+func (o *cseObject) decrypt(ciphertext io.Reader, offset, size int64) io.Reader {
+	r := &cseStreamReaderAt{
+		r:    ciphertext,
+		pos:  cseCiphertextOffset(offset),
+		back: int(2*cseFragmentSize() + 1),
+	}
+	plaintext := io.NewSectionReader(o.stream.DecryptReaderAt(r, o.nonce, nil), offset, size-offset)
+	return bufio.NewReaderSize(plaintext, 1<<20)
+}
+
+// cseStreamReaderAt serves reads at increasing offsets from a stream,
+// DARE decryption steps back at most a fragment which is kept around.
+type cseStreamReaderAt struct {
+	r    io.Reader
+	pos  int64
+	buf  []byte
+	back int
+}
+
+func (s *cseStreamReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	start := s.pos - int64(len(s.buf))
+	if off < start {
+		return 0, errors.New("client-side encryption: unable to seek backwards")
+	}
+	if off > s.pos {
+		n, e := io.CopyN(io.Discard, s.r, off-s.pos)
+		s.pos += n
+		s.buf = s.buf[:0]
+		if e != nil {
+			return 0, e
+		}
+	}
+
+	var n int
+	if off < s.pos {
+		n = copy(p, s.buf[off-start:])
+	}
+	for n < len(p) {
+		m, e := s.r.Read(p[n:])
+		s.remember(p[n : n+m])
+		n += m
+		if e != nil {
+			return n, e
+		}
+	}
+	return n, nil
+}
+
+// remember keeps the last bytes read from the stream.
+func (s *cseStreamReaderAt) remember(p []byte) {
+	s.pos += int64(len(p))
+	s.buf = append(s.buf, p...)
+	if len(s.buf) > 2*s.back {
+		s.buf = append(s.buf[:0], s.buf[len(s.buf)-s.back:]...)
+	}
+}
+
+// transfer wraps the source of a copy: objects uploaded to object
+// storage are encrypted, encrypted objects downloaded to the local
+// filesystem are decrypted. Encrypted objects copied between object
+// stores are left as is. The metadata is updated accordingly.
+func (k *cseKeys) transfer(urlStr string, reader io.Reader, size int64, metadata map[string]string, targetType ClientURLType) (io.Reader, int64, bool, *probe.Error) {
+	if k == nil {
+		return reader, size, false, nil
+	}
+
+	encrypted := isCSEEncrypted(metadata)
+	switch {
+	case encrypted && targetType == fileSystem:
+		plainSize := cseDecryptedSize(size)
+		if plainSize < 0 {
+			return nil, 0, false, probe.NewError(errCSEInvalidSize).Trace(urlStr)
+		}
+		object, err := k.open(urlStr, metadata)
+		if err != nil {
+			return nil, 0, false, err
+		}
+		cseStripMetadata(metadata)
+		return object.decrypt(reader, 0, plainSize), plainSize, true, nil
+	case !encrypted && targetType == objectStorage && k.canEncrypt():
+		encReader, encSize, encMetadata, err := k.encrypt(reader, size)
+		if err != nil {
+			return nil, 0, false, err.Trace(urlStr)
+		}
+		for key, value := range encMetadata {
+			metadata[key] = value
+		}
+		if userMetadataSize(metadata) > cseMaxUserMetadataSize {
+			return nil, 0, false, probe.NewError(errCSEMetadataTooLarge).Trace(urlStr)
+		}
+		return encReader, encSize, true, nil
+	}
+	return reader, size, false, nil
+}
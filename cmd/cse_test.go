@@ -0,0 +1,215 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"filippo.io/age"
+	"github.com/secure-io/sio-go"
+	. "gopkg.in/check.v1"
+)
+
+func (s *TestSuite) TestCSESizes(c *C) {
+	for _, size := range []int64{0, 1, sio.BufSize - 1, sio.BufSize, sio.BufSize + 1, 3*sio.BufSize + 7} {
+		encSize := cseEncryptedSize(size)
+		c.Assert(encSize > size, Equals, true, Commentf("size %d", size))
+		c.Assert(cseDecryptedSize(encSize), Equals, size, Commentf("size %d", size))
+	}
+	c.Assert(cseEncryptedSize(-1), Equals, int64(-1))
+	c.Assert(cseDecryptedSize(0), Equals, int64(-1))
+	c.Assert(cseDecryptedSize(10), Equals, int64(-1))
+	c.Assert(cseDecryptedSize(cseFragmentSize()+16), Equals, int64(-1))
+	c.Assert(cseCiphertextOffset(sio.BufSize+1), Equals, cseFragmentSize())
+}
+
+func (s *TestSuite) TestCSEKeyFile(c *C) {
+	key := make([]byte, cseDataKeySize)
+	_, e := rand.Read(key)
+	c.Assert(e, IsNil)
+
+	keyPath := filepath.Join(c.MkDir(), "cse.key")
+	c.Assert(os.WriteFile(keyPath, []byte(hex.EncodeToString(key)+"\n"), 0o600), IsNil)
+	keyFile, err := loadCSEKeyFile(keyPath)
+	c.Assert(err, IsNil)
+	c.Assert([]byte(keyFile), DeepEquals, key)
+
+	c.Assert(os.WriteFile(keyPath, []byte("tooshort"), 0o600), IsNil)
+	_, err = loadCSEKeyFile(keyPath)
+	c.Assert(err, NotNil)
+
+	keys := &cseKeys{recipients: []cseRecipient{keyFile}, identities: []cseIdentity{keyFile}}
+	plaintext := make([]byte, 2*sio.BufSize+123)
+	_, e = rand.Read(plaintext)
+	c.Assert(e, IsNil)
+
+	for _, size := range []int{0, 1, sio.BufSize, sio.BufSize + 1, len(plaintext)} {
+		encReader, encSize, metadata, err := keys.encrypt(bytes.NewReader(plaintext[:size]), int64(size))
+		c.Assert(err, IsNil)
+		ciphertext, e := io.ReadAll(encReader)
+		c.Assert(e, IsNil)
+		c.Assert(int64(len(ciphertext)), Equals, encSize)
+		c.Assert(isCSEEncrypted(metadata), Equals, true)
+
+		object, err := keys.open("play/bucket/object", metadata)
+		c.Assert(err, IsNil)
+
+		// Ranged reads start at the fragment holding the offset.
+		for _, offset := range []int{0, 1, sio.BufSize - 1, sio.BufSize, sio.BufSize + 5, size} {
+			if offset > size {
+				continue
+			}
+			start := cseCiphertextOffset(int64(offset))
+			got, e := io.ReadAll(object.decrypt(bytes.NewReader(ciphertext[start:]), int64(offset), int64(size)))
+			c.Assert(e, IsNil, Commentf("size %d, offset %d", size, offset))
+			c.Assert(got, DeepEquals, append([]byte{}, plaintext[offset:size]...), Commentf("size %d, offset %d", size, offset))
+		}
+	}
+}
+
+func (s *TestSuite) TestCSEAge(c *C) {
+	key, e := age.GenerateX25519Identity()
+	c.Assert(e, IsNil)
+	identityPath := filepath.Join(c.MkDir(), "key.txt")
+	c.Assert(os.WriteFile(identityPath, []byte("# public key: "+key.Recipient().String()+"\n"+key.String()+"\n"), 0o600), IsNil)
+
+	recipient, e := parseAgeRecipient(key.Recipient().String())
+	c.Assert(e, IsNil)
+	identities, err := loadAgeIdentities(identityPath)
+	c.Assert(err, IsNil)
+	c.Assert(identities, HasLen, 1)
+
+	keys := &cseKeys{recipients: []cseRecipient{recipient}, identities: identities}
+	plaintext := []byte("client-side encrypted for an age recipient")
+	encReader, _, metadata, err := keys.encrypt(bytes.NewReader(plaintext), int64(len(plaintext)))
+	c.Assert(err, IsNil)
+	ciphertext, e := io.ReadAll(encReader)
+	c.Assert(e, IsNil)
+
+	object, err := keys.open("play/bucket/object", metadata)
+	c.Assert(err, IsNil)
+	got, e := io.ReadAll(object.decrypt(bytes.NewReader(ciphertext), 0, int64(len(plaintext))))
+	c.Assert(e, IsNil)
+	c.Assert(got, DeepEquals, plaintext)
+
+	// Another key is unable to unwrap the data key.
+	other := make(cseKeyFile, cseDataKeySize)
+	_, err = (&cseKeys{identities: []cseIdentity{other}}).open("play/bucket/object", metadata)
+	c.Assert(err, NotNil)
+	_, ok := err.ToGoError().(cseKeyNotFoundErr)
+	c.Assert(ok, Equals, true)
+}
+
+func (s *TestSuite) TestCSETransfer(c *C) {
+	key := make(cseKeyFile, cseDataKeySize)
+	keys := &cseKeys{recipients: []cseRecipient{key}, identities: []cseIdentity{key}}
+	plaintext := []byte("uploaded encrypted, downloaded decrypted")
+
+	metadata := map[string]string{"Content-Type": "text/plain"}
+	reader, size, transformed, err := keys.transfer("file", bytes.NewReader(plaintext), int64(len(plaintext)), metadata, objectStorage)
+	c.Assert(err, IsNil)
+	c.Assert(transformed, Equals, true)
+	c.Assert(size, Equals, cseEncryptedSize(int64(len(plaintext))))
+	ciphertext, e := io.ReadAll(reader)
+	c.Assert(e, IsNil)
+
+	// Encrypted objects are copied between object stores as is.
+	_, _, transformed, err = keys.transfer("object", bytes.NewReader(ciphertext), size, metadata, objectStorage)
+	c.Assert(err, IsNil)
+	c.Assert(transformed, Equals, false)
+
+	reader, size, transformed, err = keys.transfer("object", bytes.NewReader(ciphertext), size, metadata, fileSystem)
+	c.Assert(err, IsNil)
+	c.Assert(transformed, Equals, true)
+	c.Assert(size, Equals, int64(len(plaintext)))
+	got, e := io.ReadAll(reader)
+	c.Assert(e, IsNil)
+	c.Assert(got, DeepEquals, plaintext)
+	c.Assert(isCSEEncrypted(metadata), Equals, false)
+	c.Assert(metadata["Content-Type"], Equals, "text/plain")
+}
+
+func (s *TestSuite) TestCSEGPG(c *C) {
+	if runtime.GOOS == "windows" {
+		c.Skip("the fake gpg is a shell script")
+	}
+	// A fake gpg logging its calls, the key encryption key is
+	// "encrypted" by prefixing it.
+	dir := c.MkDir()
+	calls := filepath.Join(dir, "calls")
+	script := "#!/bin/sh\necho \"$@\" >> " + calls + "\n" +
+		"case \"$*\" in\n" +
+		"*--encrypt*) printf PGP; cat ;;\n" +
+		"*--decrypt*) tail -c +4 ;;\n" +
+		"esac\n"
+	gpg := filepath.Join(dir, "gpg")
+	c.Assert(os.WriteFile(gpg, []byte(script), 0o700), IsNil)
+	defer os.Unsetenv("MC_CSE_GPG")
+	c.Assert(os.Setenv("MC_CSE_GPG", gpg), IsNil)
+
+	keys := &cseKeys{
+		recipients: []cseRecipient{&gpgRecipients{ids: []string{"alice", "bob"}}},
+		identities: []cseIdentity{&gpgIdentity{}},
+	}
+	plaintext := []byte("client-side encrypted for GnuPG recipients")
+	for i := 0; i < 3; i++ {
+		encReader, _, metadata, err := keys.encrypt(bytes.NewReader(plaintext), int64(len(plaintext)))
+		c.Assert(err, IsNil)
+		ciphertext, e := io.ReadAll(encReader)
+		c.Assert(e, IsNil)
+		object, err := keys.open("play/bucket/object", metadata)
+		c.Assert(err, IsNil)
+		got, e := io.ReadAll(object.decrypt(bytes.NewReader(ciphertext), 0, int64(len(plaintext))))
+		c.Assert(e, IsNil)
+		c.Assert(got, DeepEquals, plaintext)
+	}
+
+	// gpg encrypted and decrypted once for all the objects.
+	log, e := os.ReadFile(calls)
+	c.Assert(e, IsNil)
+	lines := strings.Split(strings.TrimSpace(string(log)), "\n")
+	c.Assert(lines, HasLen, 2)
+	c.Assert(strings.Contains(lines[0], "--recipient alice --recipient bob"), Equals, true)
+	c.Assert(strings.Contains(lines[1], "--decrypt"), Equals, true)
+}
+
+func (s *TestSuite) TestCSEMetadataSize(c *C) {
+	keys := &cseKeys{}
+	for i := 0; i < 10; i++ {
+		key, e := age.GenerateX25519Identity()
+		c.Assert(e, IsNil)
+		keys.recipients = append(keys.recipients, ageRecipient{key.Recipient()})
+	}
+	metadata := map[string]string{}
+	_, _, _, err := keys.transfer("file", bytes.NewReader(nil), 0, metadata, objectStorage)
+	c.Assert(err, NotNil)
+	c.Assert(err.ToGoError(), Equals, errCSEMetadataTooLarge)
+
+	keys.recipients = keys.recipients[:1]
+	metadata = map[string]string{"X-Amz-Meta-Owner": "alice"}
+	_, _, _, err = keys.transfer("file", bytes.NewReader(nil), 0, metadata, objectStorage)
+	c.Assert(err, IsNil)
+	c.Assert(userMetadataSize(metadata) <= cseMaxUserMetadataSize, Equals, true)
+}
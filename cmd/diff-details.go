@@ -0,0 +1,155 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/trinet2005/oss-pkg/console"
+)
+
+// Exit status of 'diff --exit-code' when the folders differ.
+const diffExitStatus = 2
+
+// diffDetail is a metadata key or tag differing between two objects, an
+// empty value means the key is missing on that side.
+type diffDetail struct {
+	Attribute string `json:"attribute"`
+	Key       string `json:"key"`
+	First     string `json:"first,omitempty"`
+	Second    string `json:"second,omitempty"`
+}
+
+// String indented diff detail.
+func (d diffDetail) String() string {
+	quote := func(value string) string {
+		if value == "" {
+			return "(none)"
+		}
+		return strconv.Quote(value)
+	}
+	return fmt.Sprintf("    %s %s: %s -> %s", d.Attribute, d.Key, quote(d.First), quote(d.Second))
+}
+
+// diffItemAttributes - attributes of an itemized change, in order.
+var diffItemAttributes = []struct {
+	flag   byte
+	differ func(first, second *ClientContent) bool
+}{
+	{'s', func(first, second *ClientContent) bool { return first.Size != second.Size }},
+	{'t', func(first, second *ClientContent) bool {
+		return !first.Time.Truncate(time.Second).Equal(second.Time.Truncate(time.Second))
+	}},
+	{'e', func(first, second *ClientContent) bool {
+		firstETag, secondETag := strings.Trim(first.ETag, "\""), strings.Trim(second.ETag, "\"")
+		return firstETag != "" && secondETag != "" && firstETag != secondETag
+	}},
+	{'m', func(first, second *ClientContent) bool {
+		return len(diffMetadata(first.UserMetadata, second.UserMetadata)) > 0
+	}},
+	{'g', func(first, second *ClientContent) bool { return len(diffTags(first.Tags, second.Tags)) > 0 }},
+	{'k', func(first, second *ClientContent) bool {
+		return contentStorageClass(first) != contentStorageClass(second)
+	}},
+}
+
+func contentStorageClass(content *ClientContent) string {
+	if content.StorageClass == "" {
+		return "STANDARD"
+	}
+	return content.StorageClass
+}
+
+// itemizeDiff returns the attributes differing between two objects in
+// the style of rsync: one flag per attribute, '.' if it is the same,
+// '+' for objects only in the first folder and '-' for objects only in
+// the second one. The content flag comes first, it is only known when
+// the contents were compared.
+func itemizeDiff(first, second *ClientContent, contentDiffers bool) string {
+	item := make([]byte, 0, len(diffItemAttributes)+1)
+	switch {
+	case second == nil:
+		return strings.Repeat("+", cap(item))
+	case first == nil:
+		return strings.Repeat("-", cap(item))
+	case contentDiffers:
+		item = append(item, 'c')
+	default:
+		item = append(item, '.')
+	}
+	for _, attr := range diffItemAttributes {
+		if attr.differ(first, second) {
+			item = append(item, attr.flag)
+		} else {
+			item = append(item, '.')
+		}
+	}
+	return string(item)
+}
+
+// diffMetadata returns the user metadata differing between two objects,
+// keys are compared regardless of their case.
+func diffMetadata(first, second map[string]string) []diffDetail {
+	normalize := func(m map[string]string) map[string]string {
+		n := make(map[string]string, len(m))
+		for k, v := range m {
+			k = http.CanonicalHeaderKey(k)
+			if k == activeActiveSourceModTimeKey {
+				continue
+			}
+			n[k] = v
+		}
+		return n
+	}
+	return diffMaps("metadata", normalize(first), normalize(second))
+}
+
+// diffTags returns the tags differing between two objects.
+func diffTags(first, second map[string]string) []diffDetail {
+	return diffMaps("tags", first, second)
+}
+
+func diffMaps(attribute string, first, second map[string]string) []diffDetail {
+	var details []diffDetail
+	for k, v := range first {
+		if w, ok := second[k]; !ok || v != w {
+			details = append(details, diffDetail{Attribute: attribute, Key: k, First: v, Second: w})
+		}
+	}
+	for k, v := range second {
+		if _, ok := first[k]; !ok {
+			details = append(details, diffDetail{Attribute: attribute, Key: k, Second: v})
+		}
+	}
+	sort.Slice(details, func(i, j int) bool { return details[i].Key < details[j].Key })
+	return details
+}
+
+// diffDetailsString returns the colorized details of a diff message.
+func diffDetailsString(details []diffDetail) string {
+	var b strings.Builder
+	for _, detail := range details {
+		b.WriteString("\n" + console.Colorize("DiffDetail", detail.String()))
+	}
+	return b.String()
+}
@@ -32,7 +32,24 @@ import (
 
 // diff specific flags.
 var (
-	diffFlags = []cli.Flag{}
+	diffFlags = []cli.Flag{
+		cli.BoolFlag{
+			Name:  "content",
+			Usage: "compare the content of objects with the same size, by checksum or byte by byte",
+		},
+		cli.BoolFlag{
+			Name:  "metadata",
+			Usage: "compare metadata and tags, printing the keys which differ",
+		},
+		cli.BoolFlag{
+			Name:  "itemize, i",
+			Usage: "print the attributes which differ for each object (see ITEMIZE)",
+		},
+		cli.BoolFlag{
+			Name:  "exit-code",
+			Usage: "exit with status 2 if the folders differ, 1 if some objects could not be compared and 0 if they are identical",
+		},
+	}
 )
 
 // Compute differences in object name, size, and date between two buckets.
@@ -53,19 +70,39 @@ FLAGS:
   {{range .VisibleFlags}}{{.}}
   {{end}}
 DESCRIPTION:
-  Diff only calculates differences in object name, size and time. It *DOES NOT* compare objects' contents.
+  Diff only calculates differences in object name, size and time. It *DOES NOT* compare objects' contents
+  unless --content is set. Objects are compared by their ETag or checksums when both sides have one,
+  otherwise their data is read and compared byte by byte.
 
 LEGEND:
   < - object is only in source.
   > - object is only in destination.
   ! - newer object is in source.
 
+ITEMIZE:
+  --itemize prints a flag for each attribute of an object, '.' if it is the same on both sides,
+  '+' if the object is only in source and '-' if it is only in destination.
+
+     c - content differs, with --content.
+     s - size differs.
+     t - modification time differs.
+     e - ETag differs.
+     m - user metadata differs.
+     g - tags differ.
+     k - storage class differs.
+
 EXAMPLES:
   1. Compare a local folder with a folder on Amazon S3 cloud storage.
      {{.Prompt}} {{.HelpName}} ~/Photos s3/mybucket/Photos
 
   2. Compare two folders on a local filesystem.
      {{.Prompt}} {{.HelpName}} ~/Photos /Media/Backup/Photos
+
+  3. Compare the content, metadata and tags of two buckets, failing a CI pipeline if they differ.
+     {{.Prompt}} {{.HelpName}} --content --metadata --exit-code s3/mybucket play/mybucket
+
+  4. Print the attributes which differ for each object of two buckets.
+     {{.Prompt}} {{.HelpName}} --itemize s3/mybucket play/mybucket
 `,
 }
 
@@ -76,33 +113,41 @@ type diffMessage struct {
 	SecondURL     string       `json:"second"`
 	Diff          differType   `json:"diff"`
 	Error         *probe.Error `json:"error,omitempty"`
+	Changes       string       `json:"changes,omitempty"`
+	Details       []diffDetail `json:"details,omitempty"`
 	firstContent  *ClientContent
 	secondContent *ClientContent
+	itemize       bool
 }
 
 // String colorized diff message
 func (d diffMessage) String() string {
-	msg := ""
+	var colorTag, op, url string
 	switch d.Diff {
 	case differInFirst:
-		msg = console.Colorize("DiffOnlyInFirst", "< "+d.FirstURL)
+		colorTag, op, url = "DiffOnlyInFirst", "<", d.FirstURL
 	case differInSecond:
-		msg = console.Colorize("DiffOnlyInSecond", "> "+d.SecondURL)
+		colorTag, op, url = "DiffOnlyInSecond", ">", d.SecondURL
 	case differInType:
-		msg = console.Colorize("DiffType", "! "+d.SecondURL)
+		colorTag, op, url = "DiffType", "!", d.SecondURL
 	case differInSize:
-		msg = console.Colorize("DiffSize", "! "+d.SecondURL)
+		colorTag, op, url = "DiffSize", "!", d.SecondURL
 	case differInMetadata:
-		msg = console.Colorize("DiffMetadata", "! "+d.SecondURL)
+		colorTag, op, url = "DiffMetadata", "!", d.SecondURL
+	case differInContent:
+		colorTag, op, url = "DiffContent", "!", d.SecondURL
 	case differInAASourceMTime:
-		msg = console.Colorize("DiffMMSourceMTime", "! "+d.SecondURL)
+		colorTag, op, url = "DiffMMSourceMTime", "!", d.SecondURL
 	case differInNone:
-		msg = console.Colorize("DiffInNone", "= "+d.FirstURL)
+		colorTag, op, url = "DiffInNone", "=", d.FirstURL
 	default:
 		fatalIf(errDummy().Trace(d.FirstURL, d.SecondURL),
 			"Unhandled difference between `"+d.FirstURL+"` and `"+d.SecondURL+"`.")
 	}
-	return msg
+	if d.itemize {
+		op += " " + d.Changes
+	}
+	return console.Colorize(colorTag, op+" "+url) + diffDetailsString(d.Details)
 }
 
 // JSON jsonified diff message
@@ -155,8 +200,17 @@ func checkDiffSyntax(ctx context.Context, cliCtx *cli.Context, encKeyDB map[stri
 	}
 }
 
+// diffOptions - options of 'diff' beyond names, sizes and times.
+type diffOptions struct {
+	content  bool
+	metadata bool
+	itemize  bool
+	exitCode bool
+	encKeyDB map[string][]prefixSSEPair
+}
+
 // doDiffMain runs the diff.
-func doDiffMain(ctx context.Context, firstURL, secondURL string) error {
+func doDiffMain(ctx context.Context, firstURL, secondURL string, opts diffOptions) error {
 	// Source and targets are always directories
 	sourceSeparator := string(newClientURL(firstURL).Separator)
 	if !strings.HasSuffix(firstURL, sourceSeparator) {
@@ -183,16 +237,79 @@ func doDiffMain(ctx context.Context, firstURL, secondURL string) error {
 			fmt.Sprintf("Failed to diff '%s' and '%s'", firstURL, secondURL))
 	}
 
+	var comparer *contentComparer
+	if opts.content {
+		comparer = &contentComparer{
+			sourceAlias: firstAlias,
+			targetAlias: secondAlias,
+			encKeyDB:    opts.encKeyDB,
+			cache:       loadChecksumCache(),
+		}
+		defer func() {
+			errorIf(comparer.cache.Save(), "Unable to save checksum cache.")
+		}()
+	}
+
+	// Objects with the same name, size and time are needed to
+	// compare their content and metadata.
+	returnSimilar := opts.content || opts.metadata
+
 	// Diff first and second urls.
-	for diffMsg := range objectDifference(ctx, firstClient, secondClient, true) {
+	differs, failed := false, false
+	for diffMsg := range objectDifference(ctx, firstClient, secondClient, true, false, returnSimilar, "") {
 		if diffMsg.Error != nil {
 			errorIf(diffMsg.Error, "Unable to calculate objects difference.")
 			// Ignore error and proceed to next object.
+			failed = true
+			continue
+		}
+
+		first, second := diffMsg.firstContent, diffMsg.secondContent
+		if first != nil && second != nil && diffMsg.Diff != differInType {
+			if opts.metadata {
+				diffMsg.Details = append(diffMetadata(first.UserMetadata, second.UserMetadata), diffTags(first.Tags, second.Tags)...)
+				if len(diffMsg.Details) > 0 && diffMsg.Diff == differInNone {
+					diffMsg.Diff = differInMetadata
+				}
+			}
+			if comparer != nil && diffMsg.Diff != differInSize {
+				same, known := comparer.compareDigests(ctx, first, second)
+				if !known {
+					var err *probe.Error
+					same, err = comparer.sameStream(ctx, first, second)
+					if err != nil {
+						// The differences found so far are shown without
+						// the content verdict.
+						errorIf(err, "Unable to compare the content of `"+diffMsg.FirstURL+"` and `"+diffMsg.SecondURL+"`.")
+						failed, same = true, true
+					}
+				}
+				if !same {
+					diffMsg.Diff = differInContent
+				}
+			}
+		}
+		if diffMsg.Diff == differInNone {
 			continue
 		}
+
+		diffMsg.itemize = opts.itemize
+		if opts.itemize {
+			diffMsg.Changes = itemizeDiff(first, second, diffMsg.Diff == differInContent)
+		}
+		differs = true
 		printMsg(diffMsg)
 	}
 
+	if opts.exitCode {
+		switch {
+		case failed:
+			// Some objects were not compared, they may differ.
+			return exitStatus(globalErrorExitStatus)
+		case differs:
+			return exitStatus(diffExitStatus)
+		}
+	}
 	return nil
 }
 
@@ -216,10 +333,18 @@ func mainDiff(cliCtx *cli.Context) error {
 	console.SetColor("DiffSize", color.New(color.FgYellow, color.Bold))
 	console.SetColor("DiffMetadata", color.New(color.FgYellow, color.Bold))
 	console.SetColor("DiffMMSourceMTime", color.New(color.FgYellow, color.Bold))
+	console.SetColor("DiffContent", color.New(color.FgYellow, color.Bold))
+	console.SetColor("DiffDetail", color.New(color.FgYellow))
 
 	URLs := cliCtx.Args()
 	firstURL := URLs.Get(0)
 	secondURL := URLs.Get(1)
 
-	return doDiffMain(ctx, firstURL, secondURL)
+	return doDiffMain(ctx, firstURL, secondURL, diffOptions{
+		content:  cliCtx.Bool("content"),
+		metadata: cliCtx.Bool("metadata"),
+		itemize:  cliCtx.Bool("itemize"),
+		exitCode: cliCtx.Bool("exit-code"),
+		encKeyDB: encKeyDB,
+	})
 }
@@ -0,0 +1,221 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"io"
+	"path/filepath"
+	"strings"
+
+	minio "github.com/trinet2005/oss-go-sdk"
+	"github.com/trinet2005/oss-go-sdk/pkg/encrypt"
+	"github.com/trinet2005/oss-mc/pkg/probe"
+)
+
+// contentComparer decides whether two objects with the same name and
+// size hold the same data, without transferring the data. Local files
+// are hashed, their checksums are cached across runs.
+type contentComparer struct {
+	sourceAlias, targetAlias string
+	encKeyDB                 map[string][]prefixSSEPair
+	cache                    *checksumCache
+}
+
+// isMD5ETag returns true if the ETag is the MD5 of the content, which is
+// the case for objects uploaded in a single part without encryption.
+func isMD5ETag(etag string) bool {
+	if len(etag) != 32 {
+		return false
+	}
+	_, e := hex.DecodeString(etag)
+	return e == nil
+}
+
+// etag returns the ETag of an object if it can be trusted to be a digest
+// of its content, SSE-C objects have random ETags.
+func (c contentComparer) etag(alias string, content *ClientContent) string {
+	sse := getSSE(filepath.ToSlash(aliasPath(alias, content.URL.Path)), c.encKeyDB[alias])
+	if sse != nil && sse.Type() == encrypt.SSEC {
+		return ""
+	}
+	return strings.Trim(content.ETag, "\"")
+}
+
+// checksums returns the full object additional checksums of an object,
+// composite checksums of multipart objects are left out.
+func (c contentComparer) checksums(ctx context.Context, alias string, content *ClientContent) map[string]string {
+	checksums := make(map[string]string)
+
+	clnt, err := newClientFromAlias(alias, content.URL.String())
+	if err != nil {
+		return checksums
+	}
+	st, err := clnt.Stat(ctx, StatOptions{
+		versionID: content.VersionID,
+		sse:       getSSE(filepath.ToSlash(aliasPath(alias, content.URL.Path)), c.encKeyDB[alias]),
+		checksum:  minio.ChecksumCRC32C,
+	})
+	if err != nil {
+		return checksums
+	}
+	for _, algo := range checksumAlgorithms {
+		key := checksumMetadataKey(algo)
+		if value := st.Metadata[key]; value != "" && !isCompositeChecksum(value) {
+			checksums[algo.String()] = value
+		}
+	}
+	return checksums
+}
+
+// sameContent returns true if the source and target hold the same data.
+// Objects are compared by their ETag when it is an MD5, otherwise by an
+// additional checksum known for both sides. When no digest can be
+// compared the contents are considered different.
+func (c contentComparer) sameContent(ctx context.Context, src, tgt *ClientContent) bool {
+	same, known := c.compareDigests(ctx, src, tgt)
+	return same && known
+}
+
+// contentDifference refines the difference found between the source and
+// target by their size and modification time, by comparing their content.
+// Objects without digests comparable on both sides, such as multipart or
+// SSE-C objects, keep the difference found.
+func (c contentComparer) contentDifference(ctx context.Context, diff differType, src, tgt *ClientContent) differType {
+	switch diff {
+	case differInNone:
+		// Same size and modtime, the content may still differ.
+		if same, known := c.compareDigests(ctx, src, tgt); known && !same {
+			return differInContent
+		}
+	case differInAASourceMTime:
+		// Rewritten with the same content, nothing to copy.
+		if c.sameContent(ctx, src, tgt) {
+			return differInNone
+		}
+	}
+	return diff
+}
+
+// compareDigests compares the digests of the source and target, known is
+// false when no digest is available for both sides.
+func (c contentComparer) compareDigests(ctx context.Context, src, tgt *ClientContent) (same, known bool) {
+	if src == nil || tgt == nil || src.Size != tgt.Size {
+		return false, true
+	}
+
+	srcLocal := src.URL.Type == fileSystem
+	tgtLocal := tgt.URL.Type == fileSystem
+
+	switch {
+	case srcLocal && tgtLocal:
+		srcSum, e := c.cache.Checksum(src.URL.Path, checksumMD5)
+		if e != nil {
+			return false, false
+		}
+		tgtSum, e := c.cache.Checksum(tgt.URL.Path, checksumMD5)
+		return e == nil && srcSum == tgtSum, e == nil
+	case !srcLocal && !tgtLocal:
+		srcETag, tgtETag := c.etag(c.sourceAlias, src), c.etag(c.targetAlias, tgt)
+		if isMD5ETag(srcETag) && isMD5ETag(tgtETag) {
+			return strings.EqualFold(srcETag, tgtETag), true
+		}
+		srcSums := c.checksums(ctx, c.sourceAlias, src)
+		if len(srcSums) == 0 {
+			return false, false
+		}
+		tgtSums := c.checksums(ctx, c.targetAlias, tgt)
+		for algo, value := range srcSums {
+			if tgtValue, ok := tgtSums[algo]; ok {
+				return value == tgtValue, true
+			}
+		}
+		return false, false
+	}
+
+	local, remote, remoteAlias := src, tgt, c.targetAlias
+	if !srcLocal {
+		local, remote, remoteAlias = tgt, src, c.sourceAlias
+	}
+	if etag := c.etag(remoteAlias, remote); isMD5ETag(etag) {
+		sum, e := c.cache.Checksum(local.URL.Path, checksumMD5)
+		return e == nil && strings.EqualFold(sum, etag), e == nil
+	}
+	for algo, value := range c.checksums(ctx, remoteAlias, remote) {
+		sum, e := c.cache.Checksum(local.URL.Path, algo)
+		return e == nil && sum == value, e == nil
+	}
+	return false, false
+}
+
+// sameStream compares the data of the source and target byte by byte,
+// for objects without comparable digests.
+func (c contentComparer) sameStream(ctx context.Context, src, tgt *ClientContent) (bool, *probe.Error) {
+	if src.Size != tgt.Size {
+		return false, nil
+	}
+	srcReader, err := c.open(ctx, c.sourceAlias, src)
+	if err != nil {
+		return false, err.Trace(src.URL.String())
+	}
+	defer srcReader.Close()
+	tgtReader, err := c.open(ctx, c.targetAlias, tgt)
+	if err != nil {
+		return false, err.Trace(tgt.URL.String())
+	}
+	defer tgtReader.Close()
+
+	srcBuf, tgtBuf := make([]byte, 1<<20), make([]byte, 1<<20)
+	for {
+		n, e := io.ReadFull(srcReader, srcBuf)
+		if e != nil && e != io.EOF && e != io.ErrUnexpectedEOF {
+			return false, probe.NewError(e).Trace(src.URL.String())
+		}
+		m, e2 := io.ReadFull(tgtReader, tgtBuf[:n])
+		if e2 != nil && e2 != io.EOF && e2 != io.ErrUnexpectedEOF {
+			return false, probe.NewError(e2).Trace(tgt.URL.String())
+		}
+		if m != n || !bytes.Equal(srcBuf[:n], tgtBuf[:m]) {
+			return false, nil
+		}
+		if e != nil {
+			// The source is exhausted, so must be the target.
+			extra, e := tgtReader.Read(tgtBuf[:1])
+			return extra == 0 && (e == io.EOF || e == nil), nil
+		}
+	}
+}
+
+func (c contentComparer) open(ctx context.Context, alias string, content *ClientContent) (io.ReadCloser, *probe.Error) {
+	var clnt Client
+	var err *probe.Error
+	if content.URL.Type == fileSystem {
+		clnt, err = fsNew(content.URL.Path)
+	} else {
+		clnt, err = newClientFromAlias(alias, content.URL.String())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return clnt.Get(ctx, GetOptions{
+		VersionID: content.VersionID,
+		SSE:       getSSE(filepath.ToSlash(aliasPath(alias, content.URL.Path)), c.encKeyDB[alias]),
+	})
+}
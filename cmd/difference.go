@@ -42,6 +42,7 @@ const (
 	differInFirst                    // only in source (FIRST)
 	differInSecond                   // only in target (SECOND)
 	differInAASourceMTime            // differs in active-active source modtime
+	differInContent                  // differs in content
 )
 
 func (d differType) String() string {
@@ -54,6 +55,8 @@ func (d differType) String() string {
 		return "metadata"
 	case differInAASourceMTime:
 		return "mm-source-mtime"
+	case differInContent:
+		return "content"
 	case differInType:
 		return "type"
 	case differInFirst:
@@ -161,14 +164,17 @@ func metadataEqual(m1, m2 map[string]string) bool {
 	return true
 }
 
-func objectDifference(ctx context.Context, sourceClnt, targetClnt Client, isMetadata bool) (diffCh chan diffMessage) {
+// objectDifference lists and compares the source and the target, objects
+// are listed with their metadata if it is compared or if listMetadata is set.
+func objectDifference(ctx context.Context, sourceClnt, targetClnt Client, isMetadata, listMetadata, returnSimilar bool, startAfter string) (diffCh chan diffMessage) {
+	withMetadata := isMetadata || listMetadata
 	sourceURL := sourceClnt.GetURL().String()
-	sourceCh := sourceClnt.List(ctx, ListOptions{Recursive: true, WithMetadata: isMetadata, ShowDir: DirNone})
+	sourceCh := sourceClnt.List(ctx, ListOptions{Recursive: true, WithMetadata: withMetadata, ShowDir: DirNone, StartAfter: startAfter})
 
 	targetURL := targetClnt.GetURL().String()
-	targetCh := targetClnt.List(ctx, ListOptions{Recursive: true, WithMetadata: isMetadata, ShowDir: DirNone})
+	targetCh := targetClnt.List(ctx, ListOptions{Recursive: true, WithMetadata: withMetadata, ShowDir: DirNone, StartAfter: startAfter})
 
-	return difference(sourceURL, sourceCh, targetURL, targetCh, isMetadata, false)
+	return difference(sourceURL, sourceCh, targetURL, targetCh, isMetadata, returnSimilar)
 }
 
 func bucketDifference(ctx context.Context, sourceClnt, targetClnt Client) (diffCh chan diffMessage) {
@@ -344,10 +350,8 @@ func differenceInternal(sourceURL string, srcCh <-chan *ClientContent, targetURL
 					firstContent:  srcCtnt,
 					secondContent: tgtCtnt,
 				}
-			}
-
-			// No differ
-			if returnSimilar {
+			} else if returnSimilar {
+				// No differ
 				diffCh <- diffMessage{
 					FirstURL:      srcCtnt.URL.String(),
 					SecondURL:     tgtCtnt.URL.String(),
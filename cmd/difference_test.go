@@ -18,7 +18,14 @@
 package cmd
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
+	"time"
 )
 
 var testCases = []struct {
@@ -47,3 +54,157 @@ func TestExcludeOptions(t *testing.T) {
 		}
 	}
 }
+
+func TestSameContent(t *testing.T) {
+	root := t.TempDir()
+	write := func(name, data string) *ClientContent {
+		fpath := filepath.Join(root, name)
+		if e := os.WriteFile(fpath, []byte(data), 0o600); e != nil {
+			t.Fatal(e)
+		}
+		return &ClientContent{URL: *newClientURL(fpath), Size: int64(len(data))}
+	}
+
+	comparer := contentComparer{cache: &checksumCache{entries: make(map[string]*checksumCacheEntry)}}
+	first, second, third := write("first", "hello"), write("second", "hello"), write("third", "world")
+	if !comparer.sameContent(context.Background(), first, second) {
+		t.Fatal("Expected files with the same content to be equal")
+	}
+	if comparer.sameContent(context.Background(), first, third) {
+		t.Fatal("Expected files with different content to differ")
+	}
+	if len(comparer.cache.entries) != 3 || !comparer.cache.dirty {
+		t.Fatalf("Expected 3 cached checksums, found %d", len(comparer.cache.entries))
+	}
+
+	// Local file against an object uploaded in a single part.
+	object := &ClientContent{URL: *newClientURL("http://localhost/bucket/first"), Size: 5, ETag: "5d41402abc4b2a76b9719d911017c592"}
+	if !comparer.sameContent(context.Background(), first, object) {
+		t.Fatal("Expected file to match the ETag of the object")
+	}
+	if comparer.sameContent(context.Background(), third, object) {
+		t.Fatal("Expected file not to match the ETag of the object")
+	}
+}
+
+func TestContentDifference(t *testing.T) {
+	etags := map[string]string{
+		"/bucket/multipart-1": "9b2cf535f27731c974343645a3985328-2",
+		"/bucket/multipart-2": "9b2cf535f27731c974343645a3985328-2",
+		"/bucket/single-1":    "5d41402abc4b2a76b9719d911017c592",
+		"/bucket/single-2":    "7d793037a0760186574b0282f2f435e7",
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has("location") {
+			w.Write([]byte(`<LocationConstraint xmlns="http://s3.amazonaws.com/doc/2006-03-01/">us-east-1</LocationConstraint>`))
+			return
+		}
+		w.Header().Set("ETag", `"`+etags[r.URL.Path]+`"`)
+		w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+		w.Header().Set("Content-Length", "5")
+	}))
+	defer server.Close()
+
+	aliasToConfigMap["digests"] = &aliasConfigV10{URL: server.URL, AccessKey: "access", SecretKey: "secret-key", API: "S3v4", Path: "on"}
+	defer delete(aliasToConfigMap, "digests")
+
+	object := func(path string) *ClientContent {
+		return &ClientContent{URL: *newClientURL(server.URL + path), Size: 5, ETag: etags[path]}
+	}
+	comparer := contentComparer{sourceAlias: "digests", targetAlias: "digests"}
+	for _, testCase := range []struct {
+		diff     differType
+		src, tgt string
+		expected differType
+	}{
+		// Multipart ETags are no digests of the content, the objects are not copied again.
+		{differInNone, "/bucket/multipart-1", "/bucket/multipart-2", differInNone},
+		{differInNone, "/bucket/single-1", "/bucket/single-1", differInNone},
+		{differInNone, "/bucket/single-1", "/bucket/single-2", differInContent},
+		{differInAASourceMTime, "/bucket/single-1", "/bucket/single-1", differInNone},
+		{differInAASourceMTime, "/bucket/multipart-1", "/bucket/multipart-2", differInAASourceMTime},
+	} {
+		diff := comparer.contentDifference(context.Background(), testCase.diff, object(testCase.src), object(testCase.tgt))
+		if diff != testCase.expected {
+			t.Errorf("%s %s: expected %s, got %s", testCase.src, testCase.tgt, testCase.expected, diff)
+		}
+	}
+}
+
+func TestSameStream(t *testing.T) {
+	root := t.TempDir()
+	write := func(name, data string) *ClientContent {
+		fpath := filepath.Join(root, name)
+		if e := os.WriteFile(fpath, []byte(data), 0o600); e != nil {
+			t.Fatal(e)
+		}
+		return &ClientContent{URL: *newClientURL(fpath), Size: int64(len(data))}
+	}
+
+	comparer := contentComparer{}
+	first, second, third := write("first", "hello"), write("second", "hello"), write("third", "hellp")
+	for _, testCase := range []struct {
+		src, tgt *ClientContent
+		same     bool
+	}{
+		{first, second, true},
+		{first, third, false},
+		{first, &ClientContent{URL: first.URL, Size: 4}, false},
+	} {
+		same, err := comparer.sameStream(context.Background(), testCase.src, testCase.tgt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if same != testCase.same {
+			t.Errorf("Expected %s and %s to be the same: %v, got %v", testCase.src.URL, testCase.tgt.URL, testCase.same, same)
+		}
+	}
+}
+
+func TestItemizeDiff(t *testing.T) {
+	now := time.Now()
+	first := &ClientContent{
+		Size:         5,
+		Time:         now,
+		ETag:         `"5d41402abc4b2a76b9719d911017c592"`,
+		UserMetadata: map[string]string{"X-Amz-Meta-Owner": "jane", "content-type": "text/plain"},
+		Tags:         map[string]string{"project": "alpha"},
+	}
+	second := &ClientContent{
+		Size:         5,
+		Time:         now.Add(time.Hour),
+		ETag:         "7d793037a0760186574b0282f2f435e7",
+		UserMetadata: map[string]string{"x-amz-meta-owner": "john", "Content-Type": "text/plain", "X-Amz-Meta-Mm-Source-Mtime": "x"},
+		Tags:         map[string]string{"project": "alpha"},
+		StorageClass: "STANDARD",
+	}
+
+	for _, testCase := range []struct {
+		first, second  *ClientContent
+		contentDiffers bool
+		expected       string
+	}{
+		{first, second, true, "c.tem.."},
+		{first, first, false, "......."},
+		{first, nil, false, "+++++++"},
+		{nil, second, false, "-------"},
+		{first, &ClientContent{Size: 6, Time: now, StorageClass: "GLACIER", Tags: map[string]string{}}, false, ".s..mgk"},
+	} {
+		if changes := itemizeDiff(testCase.first, testCase.second, testCase.contentDiffers); changes != testCase.expected {
+			t.Errorf("Expected %s, got %s", testCase.expected, changes)
+		}
+	}
+
+	details := append(diffMetadata(first.UserMetadata, second.UserMetadata), diffTags(map[string]string{"a": "1"}, map[string]string{"b": "2"})...)
+	expected := []diffDetail{
+		{Attribute: "metadata", Key: "X-Amz-Meta-Owner", First: "jane", Second: "john"},
+		{Attribute: "tags", Key: "a", First: "1"},
+		{Attribute: "tags", Key: "b", Second: "2"},
+	}
+	if !reflect.DeepEqual(details, expected) {
+		t.Errorf("Expected %v, got %v", expected, details)
+	}
+	if s := expected[1].String(); s != `    tags a: "1" -> (none)` {
+		t.Errorf("Unexpected detail %s", s)
+	}
+}
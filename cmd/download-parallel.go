@@ -0,0 +1,447 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/dustin/go-humanize"
+	"github.com/minio/cli"
+	"github.com/trinet2005/oss-go-sdk/pkg/encrypt"
+	"github.com/trinet2005/oss-mc/pkg/hookreader"
+	"github.com/trinet2005/oss-mc/pkg/probe"
+	"github.com/trinet2005/oss-pkg/env"
+)
+
+const (
+	defaultDownloadPartSize = "64MiB"
+	defaultDownloadParallel = "4"
+
+	// downloadStateMagic ends the `.part.minio` file of a ranged
+	// download, preceded by the length of the download state.
+	downloadStateMagic = "mc-dl-v1"
+)
+
+// Flags of the commands downloading objects with range requests.
+var downloadFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "download-part-size",
+		Usage: "download objects larger than this size in parts with concurrent range requests, defaults to MC_DOWNLOAD_PART_SIZE or 64MiB",
+	},
+	cli.IntFlag{
+		Name:  "download-parallel",
+		Usage: "number of parts of an object downloaded concurrently, 1 disables ranged downloads, defaults to MC_DOWNLOAD_PARALLEL or 4",
+	},
+}
+
+// downloadOptions - how large objects are split into range requests.
+type downloadOptions struct {
+	partSize int64
+	parallel int
+}
+
+// parseDownloadOptions parses the ranged download flags, falling back to
+// the environment and the defaults.
+func parseDownloadOptions(cliCtx *cli.Context) (downloadOptions, *probe.Error) {
+	var o downloadOptions
+
+	partSize := cliCtx.String("download-part-size")
+	if partSize == "" {
+		partSize = env.Get("MC_DOWNLOAD_PART_SIZE", defaultDownloadPartSize)
+	}
+	size, e := humanize.ParseBytes(partSize)
+	if e != nil || size == 0 {
+		return o, errInvalidArgument().Trace(partSize)
+	}
+	o.partSize = int64(size)
+
+	if cliCtx.IsSet("download-parallel") {
+		o.parallel = cliCtx.Int("download-parallel")
+	} else {
+		parallel := env.Get("MC_DOWNLOAD_PARALLEL", defaultDownloadParallel)
+		if o.parallel, e = strconv.Atoi(parallel); e != nil {
+			return o, errInvalidArgument().Trace(parallel)
+		}
+	}
+	if o.parallel < 1 {
+		return o, errInvalidArgument().Trace(strconv.Itoa(o.parallel))
+	}
+	return o, nil
+}
+
+// split returns true if an object of this size is downloaded in parts.
+func (o downloadOptions) split(size int64) bool {
+	return o.parallel > 1 && o.partSize > 0 && size > o.partSize
+}
+
+// downloadState - the parts of an object written to the `.part.minio`
+// file, kept after the data until the download completes.
+type downloadState struct {
+	ETag      string `json:"etag"`
+	VersionID string `json:"versionId,omitempty"`
+	Size      int64  `json:"size"`
+	PartSize  int64  `json:"partSize"`
+	// Done has a character per part, '1' once the part is written.
+	Done string `json:"done"`
+}
+
+func newDownloadState(content *ClientContent, partSize int64) *downloadState {
+	s := &downloadState{
+		ETag:      strings.Trim(content.ETag, "\""),
+		VersionID: content.VersionID,
+		Size:      content.Size,
+		PartSize:  partSize,
+	}
+	s.Done = strings.Repeat("0", s.parts())
+	return s
+}
+
+// parts returns the number of parts of the download.
+func (s *downloadState) parts() int {
+	return int((s.Size + s.PartSize - 1) / s.PartSize)
+}
+
+// part returns the offset and the length of a part.
+func (s *downloadState) part(i int) (offset, length int64) {
+	offset = int64(i) * s.PartSize
+	length = s.PartSize
+	if offset+length > s.Size {
+		length = s.Size - offset
+	}
+	return offset, length
+}
+
+// sameObject returns true if the state belongs to a download of the same
+// object version, split the same way.
+func (s *downloadState) sameObject(o *downloadState) bool {
+	return s.ETag == o.ETag && s.VersionID == o.VersionID && s.Size == o.Size &&
+		s.PartSize == o.PartSize && len(s.Done) == len(o.Done)
+}
+
+// readDownloadState reads the state at the end of a `.part.minio` file
+// holding size bytes of data.
+func readDownloadState(f *os.File, size int64) (*downloadState, error) {
+	fi, e := f.Stat()
+	if e != nil {
+		return nil, e
+	}
+	trailerSize := int64(len(downloadStateMagic) + 8)
+	if fi.Size() < size+trailerSize {
+		return nil, errors.New("no download state")
+	}
+	trailer := make([]byte, trailerSize)
+	if _, e = f.ReadAt(trailer, fi.Size()-trailerSize); e != nil {
+		return nil, e
+	}
+	if string(trailer[8:]) != downloadStateMagic {
+		return nil, errors.New("no download state")
+	}
+	n := int64(binary.LittleEndian.Uint64(trailer[:8]))
+	if n != fi.Size()-trailerSize-size {
+		return nil, errors.New("corrupted download state")
+	}
+	data := make([]byte, n)
+	if _, e = f.ReadAt(data, size); e != nil {
+		return nil, e
+	}
+	var s downloadState
+	if e = json.Unmarshal(data, &s); e != nil {
+		return nil, e
+	}
+	return &s, nil
+}
+
+// write writes the state after the data of a `.part.minio` file, the
+// state of a download always has the same length.
+func (s *downloadState) write(f *os.File) error {
+	data, e := json.Marshal(s)
+	if e != nil {
+		return e
+	}
+	var buf bytes.Buffer
+	buf.Write(data)
+	binary.Write(&buf, binary.LittleEndian, uint64(len(data)))
+	buf.WriteString(downloadStateMagic)
+	_, e = f.WriteAt(buf.Bytes(), s.Size)
+	return e
+}
+
+// offsetWriter writes sequentially from an offset of a file.
+type offsetWriter struct {
+	w      io.WriterAt
+	offset int64
+}
+
+func (o *offsetWriter) Write(p []byte) (int, error) {
+	n, e := o.w.WriteAt(p, o.offset)
+	o.offset += int64(n)
+	return n, e
+}
+
+// reportProgress reports data already downloaded to the progress.
+func reportProgress(progress io.Reader, n int64) {
+	if progress != nil && n > 0 {
+		io.Copy(io.Discard, hookreader.NewHook(io.LimitReader(zeroReader{}, n), progress))
+	}
+}
+
+// zeroReader - an endless stream of zeros.
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
+
+// downloadParallel downloads an object to a local file with concurrent
+// range requests, written at their offset of the `.part.minio` file. An
+// interrupted download resumes with the parts not written yet, as long
+// as the object has not been replaced in between.
+func downloadParallel(ctx context.Context, sourceClnt Client, content *ClientContent, sse encrypt.ServerSide, targetPath string, o downloadOptions, progress io.Reader, opts PutOptions) *probe.Error {
+	sourceURL := sourceClnt.GetURL().String()
+	var err *probe.Error
+
+	if e := os.MkdirAll(filepath.Dir(targetPath), 0o777); e != nil {
+		return probe.NewError(e).Trace(targetPath)
+	}
+	partPath := targetPath + partSuffix
+	f, e := os.OpenFile(partPath, os.O_CREATE|os.O_RDWR, 0o666)
+	if e != nil {
+		return probe.NewError(e).Trace(partPath)
+	}
+	defer f.Close()
+
+	state := newDownloadState(content, o.partSize)
+	if previous, e := readDownloadState(f, content.Size); e == nil && previous.sameObject(state) {
+		state = previous
+	} else {
+		// Start over, the file is allocated up to the object size.
+		if e = f.Truncate(0); e == nil {
+			e = f.Truncate(content.Size)
+		}
+		if e == nil {
+			e = state.write(f)
+		}
+		if e != nil {
+			return probe.NewError(e).Trace(partPath)
+		}
+	}
+
+	var mutex sync.Mutex
+	done := []byte(state.Done)
+	markDone := func(i int) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		// Persist the data before the part is recorded as written.
+		if e := f.Sync(); e != nil {
+			return e
+		}
+		done[i] = '1'
+		state.Done = string(done)
+		return state.write(f)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	partsCh := make(chan int)
+	errCh := make(chan *probe.Error, o.parallel)
+	var wg sync.WaitGroup
+	for w := 0; w < o.parallel; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range partsCh {
+				offset, length := state.part(i)
+				reader, err := sourceClnt.Get(ctx, GetOptions{
+					SSE:         sse,
+					VersionID:   content.VersionID,
+					RangeStart:  offset,
+					RangeLength: length,
+					MatchETag:   state.ETag,
+				})
+				if err != nil {
+					errCh <- err.Trace(sourceURL)
+					cancel()
+					return
+				}
+				n, e := io.Copy(&offsetWriter{w: f, offset: offset}, hookreader.NewHook(io.LimitReader(reader, length), progress))
+				reader.Close()
+				if e == nil && n < length {
+					e = UnexpectedEOF{TotalSize: length, TotalWritten: n}
+				}
+				if e == nil {
+					e = markDone(i)
+				}
+				if e != nil {
+					errCh <- probe.NewError(e).Trace(sourceURL)
+					cancel()
+					return
+				}
+			}
+		}()
+	}
+
+	var written int64
+	go func() {
+		defer close(partsCh)
+		for i := range done {
+			if done[i] == '1' {
+				continue
+			}
+			select {
+			case partsCh <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	for i := range done {
+		if done[i] == '1' {
+			_, length := state.part(i)
+			written += length
+		}
+	}
+	reportProgress(progress, written)
+
+	wg.Wait()
+	close(errCh)
+	if err = <-errCh; err != nil {
+		return err
+	}
+	if e = ctx.Err(); e != nil {
+		return probe.NewError(e)
+	}
+
+	// Drop the download state, the file only holds the object now.
+	if e = f.Truncate(content.Size); e != nil {
+		return probe.NewError(e).Trace(partPath)
+	}
+
+	if opts.checksum.IsSet() && opts.checksumValue != "" {
+		sum, e := fileChecksum(partPath, opts.checksum)
+		if e != nil {
+			return probe.NewError(e).Trace(partPath)
+		}
+		if err = verifyChecksum(targetPath, opts.checksum, sum, opts.checksumValue); err != nil {
+			os.Remove(partPath)
+			return err
+		}
+	}
+
+	attr := make(map[string]string)
+	if _, ok := opts.metadata[metadataKey]; ok && opts.isPreserve {
+		if attr, e = parseAttribute(opts.metadata); e != nil {
+			return probe.NewError(e)
+		}
+		if err = preserveAttributes(f, attr); err != nil {
+			errorIf(err.Trace(targetPath), "Unable to preserve attributes, continuing to copy the content.")
+		}
+	}
+
+	// Close the file before renaming, windows disallows
+	// renames of open files.
+	if e = f.Close(); e != nil {
+		return probe.NewError(e).Trace(partPath)
+	}
+	if e = os.Rename(partPath, targetPath); e != nil {
+		return probe.NewError(e).Trace(partPath, targetPath)
+	}
+
+	if globalFSMetadata {
+		if err = writeFSMetadata(targetPath, opts.metadata); err != nil {
+			return err.Trace(targetPath)
+		}
+	}
+
+	if len(attr) != 0 {
+		atime, mtime, err := parseAtimeMtime(attr)
+		if err != nil {
+			return err.Trace(targetPath)
+		}
+		if !atime.IsZero() && !mtime.IsZero() {
+			if e = os.Chtimes(targetPath, atime, mtime); e != nil {
+				return probe.NewError(e).Trace(targetPath)
+			}
+		}
+	}
+	return nil
+}
+
+// downloadSourceToTargetURL downloads a large object to the local
+// filesystem in parts, returns false if the object has to be streamed
+// instead: client-side encrypted or compressed objects are transformed
+// sequentially.
+func downloadSourceToTargetURL(ctx context.Context, urls URLs, progress io.Reader, sse encrypt.ServerSide, preserve bool) (bool, *probe.Error) {
+	sourceURL := urls.SourceContent.URL.String()
+	sourceClnt, err := newClientFromAlias(urls.SourceAlias, sourceURL)
+	if err != nil {
+		return false, err.Trace(urls.SourceAlias, sourceURL)
+	}
+	st, err := sourceClnt.Stat(ctx, StatOptions{
+		versionID: urls.SourceContent.VersionID,
+		sse:       sse,
+		preserve:  preserve,
+		checksum:  urls.Checksum,
+	})
+	if err != nil {
+		return false, err.Trace(sourceURL)
+	}
+	if st.ETag == "" || !urls.download.split(st.Size) || isCSEEncrypted(st.Metadata) || compressionCodec(st) != "" {
+		return false, nil
+	}
+
+	metadata := make(map[string]string)
+	for k, v := range st.Metadata {
+		metadata[http.CanonicalHeaderKey(k)] = v
+	}
+	for k, v := range urls.TargetContent.Metadata {
+		metadata[http.CanonicalHeaderKey(k)] = v
+	}
+	for k, v := range urls.TargetContent.UserMetadata {
+		metadata[http.CanonicalHeaderKey(k)] = v
+	}
+	if globalFSMetadata {
+		if err = addSourceTagging(ctx, urls.SourceAlias, sourceURL, urls.SourceContent.VersionID, metadata); err != nil {
+			return false, err.Trace(sourceURL)
+		}
+	}
+
+	opts := PutOptions{
+		metadata:   metadata,
+		isPreserve: preserve,
+		checksum:   urls.Checksum,
+	}
+	if urls.Checksum.IsSet() {
+		opts.checksumValue = metadata[checksumMetadataKey(urls.Checksum)]
+	}
+	return true, downloadParallel(ctx, sourceClnt, st, sse, urls.TargetContent.URL.Path, urls.download, progress, opts)
+}
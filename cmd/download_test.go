@@ -0,0 +1,111 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"math/rand"
+	"os"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *TestSuite) TestDownloadState(c *C) {
+	dir := c.MkDir()
+	f, e := os.Create(filepath.Join(dir, "object"+partSuffix))
+	c.Assert(e, IsNil)
+	defer f.Close()
+
+	state := newDownloadState(&ClientContent{Size: 2500, ETag: "\"abc\"", VersionID: "v1"}, 1000)
+	c.Assert(state.parts(), Equals, 3)
+	c.Assert(state.Done, Equals, "000")
+	offset, length := state.part(2)
+	c.Assert(offset, Equals, int64(2000))
+	c.Assert(length, Equals, int64(500))
+
+	_, e = readDownloadState(f, state.Size)
+	c.Assert(e, NotNil)
+
+	c.Assert(f.Truncate(state.Size), IsNil)
+	c.Assert(state.write(f), IsNil)
+	state.Done = "101"
+	c.Assert(state.write(f), IsNil)
+
+	got, e := readDownloadState(f, state.Size)
+	c.Assert(e, IsNil)
+	c.Assert(got, DeepEquals, state)
+	c.Assert(got.sameObject(newDownloadState(&ClientContent{Size: 2500, ETag: "abc", VersionID: "v1"}, 1000)), Equals, true)
+	c.Assert(got.sameObject(newDownloadState(&ClientContent{Size: 2500, ETag: "abd", VersionID: "v1"}, 1000)), Equals, false)
+	c.Assert(got.sameObject(newDownloadState(&ClientContent{Size: 2500, ETag: "abc", VersionID: "v1"}, 500)), Equals, false)
+
+	c.Assert(downloadOptions{partSize: 1000, parallel: 4}.split(2500), Equals, true)
+	c.Assert(downloadOptions{partSize: 1000, parallel: 4}.split(1000), Equals, false)
+	c.Assert(downloadOptions{partSize: 1000, parallel: 1}.split(2500), Equals, false)
+}
+
+func (s *TestSuite) TestDownloadParallel(c *C) {
+	dir := c.MkDir()
+	data := make([]byte, 10*1024+17)
+	rand.New(rand.NewSource(1)).Read(data)
+	sourcePath := filepath.Join(dir, "source")
+	c.Assert(os.WriteFile(sourcePath, data, 0o644), IsNil)
+
+	clnt, err := fsNew(sourcePath)
+	c.Assert(err, IsNil)
+	content := &ClientContent{Size: int64(len(data)), ETag: "etag"}
+	o := downloadOptions{partSize: 1024, parallel: 3}
+
+	targetPath := filepath.Join(dir, "target", "object")
+	c.Assert(downloadParallel(context.Background(), clnt, content, nil, targetPath, o, nil, PutOptions{}), IsNil)
+	got, e := os.ReadFile(targetPath)
+	c.Assert(e, IsNil)
+	c.Assert(bytes.Equal(got, data), Equals, true)
+	_, e = os.Stat(targetPath + partSuffix)
+	c.Assert(os.IsNotExist(e), Equals, true)
+
+	// An interrupted download only fetches the parts not written yet.
+	resumePath := filepath.Join(dir, "resume")
+	f, e := os.Create(resumePath + partSuffix)
+	c.Assert(e, IsNil)
+	c.Assert(f.Truncate(content.Size), IsNil)
+	state := newDownloadState(content, o.partSize)
+	state.Done = "1" + state.Done[1:]
+	c.Assert(state.write(f), IsNil)
+	c.Assert(f.Close(), IsNil)
+
+	c.Assert(downloadParallel(context.Background(), clnt, content, nil, resumePath, o, nil, PutOptions{}), IsNil)
+	got, e = os.ReadFile(resumePath)
+	c.Assert(e, IsNil)
+	c.Assert(len(got), Equals, len(data))
+	c.Assert(bytes.Equal(got[:1024], make([]byte, 1024)), Equals, true)
+	c.Assert(bytes.Equal(got[1024:], data[1024:]), Equals, true)
+
+	// The state of another object version is discarded.
+	f, e = os.Create(resumePath + partSuffix)
+	c.Assert(e, IsNil)
+	c.Assert(f.Truncate(content.Size), IsNil)
+	c.Assert(state.write(f), IsNil)
+	c.Assert(f.Close(), IsNil)
+	content.ETag = "replaced"
+	c.Assert(downloadParallel(context.Background(), clnt, content, nil, resumePath, o, nil, PutOptions{}), IsNil)
+	got, e = os.ReadFile(resumePath)
+	c.Assert(e, IsNil)
+	c.Assert(bytes.Equal(got, data), Equals, true)
+}
@@ -0,0 +1,252 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	json "github.com/minio/colorjson"
+	"github.com/trinet2005/oss-mc/pkg/probe"
+	"github.com/trinet2005/oss-pkg/console"
+)
+
+// duGroupKeys - keys supported by --group-by.
+var duGroupKeys = []string{"storage-class", "age", "ext", "version", "delete-marker"}
+
+// duAgeBucket is a range of object ages, from the upper bound of the
+// previous bucket up to max. The last bucket has no upper bound.
+type duAgeBucket struct {
+	label string
+	max   time.Duration
+}
+
+// parseDuAgeBuckets parses comma separated upper bounds of age buckets
+// such as "30d,90d" into the buckets 0-30d, 30d-90d and 90d+.
+func parseDuAgeBuckets(s string) ([]duAgeBucket, error) {
+	var buckets []duAgeBucket
+	lower := "0"
+	for _, bound := range strings.Split(s, ",") {
+		bound = strings.TrimSpace(bound)
+		d, e := ParseDuration(bound)
+		if e != nil {
+			return nil, e
+		}
+		if len(buckets) > 0 && time.Duration(d) <= buckets[len(buckets)-1].max || d <= 0 {
+			return nil, fmt.Errorf("age bucket `%s` must be positive and larger than the previous one", bound)
+		}
+		buckets = append(buckets, duAgeBucket{label: lower + "-" + bound, max: time.Duration(d)})
+		lower = bound
+	}
+	return append(buckets, duAgeBucket{label: lower + "+", max: -1}), nil
+}
+
+// Structured message of a group of objects.
+type duGroupMessage struct {
+	Status  string `json:"status"`
+	Prefix  string `json:"prefix"`
+	GroupBy string `json:"groupBy"`
+	Group   string `json:"group"`
+	Size    int64  `json:"size"`
+	Objects int64  `json:"objects"`
+}
+
+// Colorized message for console printing.
+func (r duGroupMessage) String() string {
+	humanSize := strings.Join(strings.Fields(humanize.IBytes(uint64(r.Size))), "")
+	cnt := fmt.Sprintf("%d object", r.Objects)
+	if r.GroupBy == "version" || r.GroupBy == "delete-marker" {
+		cnt = fmt.Sprintf("%d version", r.Objects)
+	}
+	if r.Objects != 1 {
+		cnt += "s" // pluralize
+	}
+	return fmt.Sprintf("%s\t%s\t%s", console.Colorize("Size", humanSize),
+		console.Colorize("Objects", cnt),
+		console.Colorize("Prefix", r.Group))
+}
+
+// JSON'ified message for scripting.
+func (r duGroupMessage) JSON() string {
+	msgBytes, e := json.MarshalIndent(r, "", " ")
+	fatalIf(probe.NewError(e), "Unable to marshal into JSON.")
+	return string(msgBytes)
+}
+
+// duGrouper sums the size and number of listed objects per group.
+type duGrouper struct {
+	groupBy string
+	ages    []duAgeBucket
+	now     time.Time
+	groups  map[string]*duGroupMessage
+}
+
+func newDuGrouper(groupBy string, ages []duAgeBucket, now time.Time) *duGrouper {
+	return &duGrouper{groupBy: groupBy, ages: ages, now: now, groups: make(map[string]*duGroupMessage)}
+}
+
+// group returns the group of an object, delete markers only belong
+// to a group when grouping by delete-marker.
+func (g *duGrouper) group(content *ClientContent) (string, bool) {
+	if content.IsDeleteMarker && g.groupBy != "delete-marker" {
+		return "", false
+	}
+	switch g.groupBy {
+	case "storage-class":
+		if content.StorageClass == "" {
+			return "STANDARD", true
+		}
+		return content.StorageClass, true
+	case "age":
+		age := g.now.Sub(content.Time)
+		for _, bucket := range g.ages {
+			if bucket.max < 0 || age < bucket.max {
+				return bucket.label, true
+			}
+		}
+	case "ext":
+		if ext := strings.ToLower(path.Ext(content.URL.Path)); ext != "" {
+			return ext, true
+		}
+		return "(none)", true
+	case "version":
+		if content.IsLatest || content.VersionID == "" {
+			return "current", true
+		}
+		return "noncurrent", true
+	case "delete-marker":
+		if content.IsDeleteMarker {
+			return "delete-marker", true
+		}
+		return "object", true
+	}
+	return "", false
+}
+
+func (g *duGrouper) add(content *ClientContent) {
+	name, ok := g.group(content)
+	if !ok {
+		return
+	}
+	group, ok := g.groups[name]
+	if !ok {
+		group = &duGroupMessage{Status: "success", GroupBy: g.groupBy, Group: name}
+		g.groups[name] = group
+	}
+	group.Size += content.Size
+	group.Objects++
+}
+
+// messages returns the groups in order, age buckets from the youngest
+// to the oldest and other groups by name.
+func (g *duGrouper) messages(prefix string) []duGroupMessage {
+	order := make(map[string]int, len(g.ages))
+	for i, bucket := range g.ages {
+		order[bucket.label] = i
+	}
+	msgs := make([]duGroupMessage, 0, len(g.groups))
+	for _, group := range g.groups {
+		group.Prefix = prefix
+		msgs = append(msgs, *group)
+	}
+	sort.Slice(msgs, func(i, j int) bool {
+		if g.groupBy == "age" {
+			return order[msgs[i].Group] < order[msgs[j].Group]
+		}
+		return msgs[i].Group < msgs[j].Group
+	})
+	return msgs
+}
+
+// duGroup summarizes the disk usage of all objects below urlStr per group.
+func duGroup(ctx context.Context, urlStr string, timeRef time.Time, withVersions bool, groupBy string, ages []duAgeBucket) error {
+	targetAlias, targetURL, _ := mustExpandAlias(urlStr)
+	if !strings.HasSuffix(targetURL, "/") {
+		targetURL += "/"
+	}
+
+	clnt, pErr := newClientFromAlias(targetAlias, targetURL)
+	if pErr != nil {
+		errorIf(pErr.Trace(urlStr), "Failed to summarize disk usage `"+urlStr+"`.")
+		return exitStatus(globalErrorExitStatus)
+	}
+
+	// Version states need all versions.
+	if groupBy == "version" || groupBy == "delete-marker" {
+		withVersions = true
+	}
+	now := time.Now()
+	if !timeRef.IsZero() {
+		now = timeRef
+	}
+	grouper := newDuGrouper(groupBy, ages, now)
+
+	for content := range clnt.List(ctx, ListOptions{
+		TimeRef:           timeRef,
+		WithOlderVersions: withVersions,
+		WithDeleteMarkers: groupBy == "delete-marker",
+		Recursive:         true,
+		ShowDir:           DirNone,
+	}) {
+		if content.Err != nil {
+			switch content.Err.ToGoError().(type) {
+			// handle this specifically for filesystem related errors.
+			case BrokenSymlink, TooManyLevelsSymlink, PathNotFound, ObjectOnGlacier:
+				continue
+			case PathInsufficientPermission:
+				errorIf(content.Err.Trace(clnt.GetURL().String()), "Unable to list folder.")
+				continue
+			}
+			errorIf(content.Err.Trace(urlStr), "Failed to find disk usage of `"+urlStr+"` recursively.")
+			return exitStatus(globalErrorExitStatus)
+		}
+		if content.Type.IsDir() {
+			continue
+		}
+		grouper.add(content)
+	}
+
+	for _, msg := range grouper.messages(strings.TrimSuffix(urlStr, "/")) {
+		printMsg(msg)
+	}
+	return nil
+}
+
+// checkDuGroupBy validates --group-by and returns the age buckets.
+func checkDuGroupBy(groupBy, ageBuckets string) ([]duAgeBucket, error) {
+	valid := false
+	for _, key := range duGroupKeys {
+		valid = valid || key == groupBy
+	}
+	if !valid {
+		return nil, fmt.Errorf("unknown group `%s`, expected one of %s", groupBy, strings.Join(duGroupKeys, ", "))
+	}
+	if groupBy != "age" {
+		return nil, nil
+	}
+	if ageBuckets == "" {
+		return nil, errors.New("--age-buckets cannot be empty")
+	}
+	return parseDuAgeBuckets(ageBuckets)
+}
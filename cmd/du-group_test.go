@@ -0,0 +1,89 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *TestSuite) TestParseDuAgeBuckets(c *C) {
+	buckets, e := parseDuAgeBuckets("30d, 90d")
+	c.Assert(e, IsNil)
+	c.Assert(buckets, DeepEquals, []duAgeBucket{
+		{label: "0-30d", max: 30 * 24 * time.Hour},
+		{label: "30d-90d", max: 90 * 24 * time.Hour},
+		{label: "90d+", max: -1},
+	})
+
+	for _, s := range []string{"", "30x", "90d,30d", "0d"} {
+		_, e = parseDuAgeBuckets(s)
+		c.Assert(e, NotNil, Commentf("%q", s))
+	}
+}
+
+func (s *TestSuite) TestDuGrouper(c *C) {
+	now := time.Now()
+	day := 24 * time.Hour
+	content := func(key string, size int64, age time.Duration, storageClass, versionID string, latest, deleteMarker bool) *ClientContent {
+		return &ClientContent{
+			URL:            ClientURL{Path: "bucket/" + key},
+			Size:           size,
+			Time:           now.Add(-age),
+			StorageClass:   storageClass,
+			VersionID:      versionID,
+			IsLatest:       latest,
+			IsDeleteMarker: deleteMarker,
+		}
+	}
+	contents := []*ClientContent{
+		content("a.JPG", 10, day, "", "v3", false, true),
+		content("a.JPG", 100, 10*day, "", "v2", true, false),
+		content("a.JPG", 200, 40*day, "GLACIER", "v1", false, false),
+		content("b.txt", 1, 100*day, "STANDARD", "", false, false),
+		content("dir/c", 5, 0, "GLACIER", "", false, false),
+	}
+	ages, e := parseDuAgeBuckets("30d,90d")
+	c.Assert(e, IsNil)
+
+	testCases := []struct {
+		groupBy  string
+		expected []string
+	}{
+		{"storage-class", []string{"GLACIER 205 2", "STANDARD 101 2"}},
+		{"age", []string{"0-30d 105 2", "30d-90d 200 1", "90d+ 1 1"}},
+		{"ext", []string{"(none) 5 1", ".jpg 300 2", ".txt 1 1"}},
+		{"version", []string{"current 106 3", "noncurrent 200 1"}},
+		{"delete-marker", []string{"delete-marker 10 1", "object 306 4"}},
+	}
+	for _, testCase := range testCases {
+		grouper := newDuGrouper(testCase.groupBy, ages, now)
+		for _, content := range contents {
+			grouper.add(content)
+		}
+		var groups []string
+		for _, msg := range grouper.messages("alias/bucket") {
+			c.Assert(msg.Prefix, Equals, "alias/bucket")
+			c.Assert(msg.GroupBy, Equals, testCase.groupBy)
+			groups = append(groups, fmt.Sprintf("%s %d %d", msg.Group, msg.Size, msg.Objects))
+		}
+		c.Assert(groups, DeepEquals, testCase.expected)
+	}
+}
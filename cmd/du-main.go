@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"net/url"
 	"path"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -52,6 +53,15 @@ var (
 			Name:  "versions",
 			Usage: "include all object versions",
 		},
+		cli.StringFlag{
+			Name:  "group-by",
+			Usage: "summarize disk usage per 'storage-class', 'age', 'ext', 'version' or 'delete-marker'",
+		},
+		cli.StringFlag{
+			Name:  "age-buckets",
+			Usage: "comma separated upper bounds of age groups for '--group-by age'",
+			Value: "30d,90d",
+		},
 	}
 )
 
@@ -72,6 +82,17 @@ USAGE:
 FLAGS:
   {{range .VisibleFlags}}{{.}}
   {{end}}
+GROUPS:
+  --group-by summarizes all objects below TARGET per group, with their total size
+  and number of objects:
+
+     storage-class --> Storage class of objects, STANDARD if unset.
+     age           --> Age of objects, in ranges set by --age-buckets. The default
+                       "30d,90d" reports 0-30d, 30d-90d and 90d+.
+     ext           --> Lowercase extension of object names.
+     version       --> 'current' and 'noncurrent' versions, implies --versions.
+     delete-marker --> Delete markers and other versions, implies --versions.
+
 ENVIRONMENT VARIABLES:
   MC_ENCRYPT_KEY: list of comma delimited prefix=secret values
 
@@ -87,6 +108,15 @@ EXAMPLES:
 
   4. Summarize disk usage of 'jazz-songs' bucket with all objects versions
      {{.Prompt}} {{.HelpName}} --versions s3/jazz-songs/
+
+  5. Summarize disk usage of 'jazz-songs' bucket per storage class
+     {{.Prompt}} {{.HelpName}} --group-by storage-class s3/jazz-songs/
+
+  6. Summarize disk usage of all versions of 'jazz-songs' bucket by age, up to 1 week, 1 year and older
+     {{.Prompt}} {{.HelpName}} --versions --group-by age --age-buckets 7d,365d s3/jazz-songs/
+
+  7. Summarize disk usage of noncurrent versions in 'jazz-songs' bucket
+     {{.Prompt}} {{.HelpName}} --group-by version s3/jazz-songs/
 `,
 }
 
@@ -174,7 +204,7 @@ func du(ctx context.Context, urlStr string, timeRef time.Time, withVersions bool
 
 			subDirAlias := content.URL.Path
 			if targetAlias != "" {
-				subDirAlias = targetAlias + "/" + content.URL.Path
+				subDirAlias = filepath.ToSlash(aliasPath(targetAlias, content.URL.Path))
 			}
 			used, n, err := du(ctx, subDirAlias, timeRef, withVersions, depth, encKeyDB)
 			if err != nil {
@@ -242,12 +272,30 @@ func mainDu(cliCtx *cli.Context) error {
 	withVersions := cliCtx.Bool("versions")
 	timeRef := parseRewindFlag(cliCtx.String("rewind"))
 
+	groupBy := cliCtx.String("group-by")
+	var ages []duAgeBucket
+	if groupBy != "" {
+		if cliCtx.IsSet("depth") || cliCtx.IsSet("recursive") {
+			fatalIf(errInvalidArgument(), "--group-by cannot be used with --depth or --recursive.")
+		}
+		var e error
+		ages, e = checkDuGroupBy(groupBy, cliCtx.String("age-buckets"))
+		fatalIf(probe.NewError(e).Trace(groupBy), "Unable to parse --group-by.")
+	}
+
 	var duErr error
 	for _, urlStr := range cliCtx.Args() {
 		if !isAliasURLDir(ctx, urlStr, nil, time.Time{}) {
 			fatalIf(errInvalidArgument().Trace(urlStr), fmt.Sprintf("Source `%s` is not a folder. Only folders are supported by 'du' command.", urlStr))
 		}
 
+		if groupBy != "" {
+			if err := duGroup(ctx, urlStr, timeRef, withVersions, groupBy, ages); duErr == nil {
+				duErr = err
+			}
+			continue
+		}
+
 		if _, _, err := du(ctx, urlStr, timeRef, withVersions, depth, encKeyDB); duErr == nil {
 			duErr = err
 		}
@@ -0,0 +1,387 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+	"github.com/minio/cli"
+)
+
+// findExpr is a boolean expression over the matching flags of find,
+// evaluated against the path relative to the target and its content.
+//
+//	expr    := and { ( -o | --or ) and }
+//	and     := unary { [ -a | --and ] unary }
+//	unary   := ( ! | --not ) unary | '(' expr ')' | predicate
+//
+// Predicates are --name, --path, --regex, --ignore, --larger, --smaller,
+// --older-than, --newer-than, --metadata and --tags with their value.
+type findExpr interface {
+	match(path string, content contentMessage) bool
+	String() string
+}
+
+type (
+	findAnd []findExpr
+	findOr  []findExpr
+	findNot struct{ expr findExpr }
+
+	findPredicate struct {
+		flag, value string
+		fn          func(path string, content contentMessage) bool
+	}
+)
+
+func (e findAnd) match(path string, content contentMessage) bool {
+	for _, expr := range e {
+		if !expr.match(path, content) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e findAnd) String() string {
+	return joinFindExprs([]findExpr(e), " ")
+}
+
+func (e findOr) match(path string, content contentMessage) bool {
+	for _, expr := range e {
+		if expr.match(path, content) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e findOr) String() string {
+	return joinFindExprs([]findExpr(e), " -o ")
+}
+
+func (e findNot) match(path string, content contentMessage) bool {
+	return !e.expr.match(path, content)
+}
+
+func (e findNot) String() string {
+	return "! " + e.expr.String()
+}
+
+func (e findPredicate) match(path string, content contentMessage) bool {
+	return e.fn(path, content)
+}
+
+func (e findPredicate) String() string {
+	return "--" + e.flag + " " + e.value
+}
+
+func joinFindExprs(exprs []findExpr, sep string) string {
+	s := make([]string, 0, len(exprs))
+	for _, expr := range exprs {
+		str := expr.String()
+		if _, ok := expr.(findPredicate); !ok {
+			str = "( " + str + " )"
+		}
+		s = append(s, str)
+	}
+	return strings.Join(s, sep)
+}
+
+// findExprFlags - flags defining the operators of find expressions, the
+// expression itself is parsed from the raw command line.
+var findExprFlags = []cli.Flag{
+	cli.BoolFlag{
+		Name:  "or, o",
+		Usage: "match if either the expression before or after matches, see EXPRESSIONS",
+	},
+	cli.BoolFlag{
+		Name:  "and, a",
+		Usage: "match if both the expressions before and after match, the default",
+	},
+	cli.BoolFlag{
+		Name:  "not",
+		Usage: "negate the following expression, same as '!'",
+	},
+}
+
+// isFindOperator returns true for the tokens of find expressions.
+func isFindOperator(arg string) bool {
+	switch arg {
+	case "(", ")", "!", "-o", "--o", "-or", "--or", "-a", "--a", "-and", "--and", "-not", "--not":
+		return true
+	}
+	return false
+}
+
+// findFlagName returns the name of a command line flag and its inline
+// value if any.
+func findFlagName(arg string) (name, value string, hasValue bool) {
+	if !strings.HasPrefix(arg, "-") || arg == "-" || arg == "--" {
+		return "", "", false
+	}
+	name = strings.TrimLeft(arg, "-")
+	if i := strings.Index(name, "="); i >= 0 {
+		return name[:i], name[i+1:], true
+	}
+	return name, "", false
+}
+
+// boolFlagNames returns the names of the flags without value.
+func boolFlagNames(flags []cli.Flag) map[string]bool {
+	names := make(map[string]bool)
+	for _, flag := range flags {
+		switch flag.(type) {
+		case cli.BoolFlag, cli.BoolTFlag:
+			for _, name := range strings.Split(flag.GetName(), ",") {
+				names[strings.TrimSpace(name)] = true
+			}
+		}
+	}
+	return names
+}
+
+// findExprParser parses find expressions out of command line arguments,
+// other flags are skipped and positional arguments are targets.
+type findExprParser struct {
+	tokens  []string
+	pos     int
+	targets []string
+	// Predicates reading metadata or tags of objects.
+	withMetadata bool
+}
+
+// parseFindExpr parses the find expression of a command line, returns a
+// nil expression if there is none.
+func parseFindExpr(args []string, boolFlags map[string]bool) (findExpr, []string, bool, error) {
+	p := &findExprParser{}
+	terminated := false
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case terminated:
+			p.targets = append(p.targets, arg)
+		case arg == "--":
+			terminated = true
+		case isFindOperator(arg):
+			p.tokens = append(p.tokens, arg)
+		default:
+			name, _, hasValue := findFlagName(arg)
+			switch {
+			case name == "":
+				p.targets = append(p.targets, arg)
+			case isFindPredicate(name):
+				p.tokens = append(p.tokens, arg)
+				if !hasValue && i+1 < len(args) {
+					i++
+					p.tokens = append(p.tokens, args[i])
+				}
+			case !hasValue && !boolFlags[name]:
+				// Skip the value of other flags.
+				i++
+			}
+		}
+	}
+	if len(p.tokens) == 0 {
+		return nil, p.targets, false, nil
+	}
+	expr, e := p.parseOr()
+	if e != nil {
+		return nil, nil, false, e
+	}
+	if p.pos < len(p.tokens) {
+		return nil, nil, false, fmt.Errorf("unexpected `%s` in find expression", p.tokens[p.pos])
+	}
+	return expr, p.targets, p.withMetadata, nil
+}
+
+func (p *findExprParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *findExprParser) parseOr() (findExpr, error) {
+	expr, e := p.parseAnd()
+	if e != nil {
+		return nil, e
+	}
+	or := findOr{expr}
+	for isFindOr(p.peek()) {
+		p.pos++
+		if expr, e = p.parseAnd(); e != nil {
+			return nil, e
+		}
+		or = append(or, expr)
+	}
+	if len(or) == 1 {
+		return or[0], nil
+	}
+	return or, nil
+}
+
+func (p *findExprParser) parseAnd() (findExpr, error) {
+	var and findAnd
+	for {
+		switch p.peek() {
+		case "-a", "--a", "-and", "--and":
+			if len(and) == 0 {
+				return nil, errors.New("missing expression before `-a` in find expression")
+			}
+			p.pos++
+		case "", ")":
+			return and.reduce(p)
+		}
+		if isFindOr(p.peek()) {
+			return and.reduce(p)
+		}
+		expr, e := p.parseUnary()
+		if e != nil {
+			return nil, e
+		}
+		and = append(and, expr)
+	}
+}
+
+// reduce returns the conjunction of the expressions parsed before an
+// operator, the expression itself if there is only one.
+func (e findAnd) reduce(p *findExprParser) (findExpr, error) {
+	switch len(e) {
+	case 0:
+		if p.pos < len(p.tokens) {
+			return nil, fmt.Errorf("missing expression before `%s` in find expression", p.peek())
+		}
+		return nil, errors.New("incomplete find expression")
+	case 1:
+		return e[0], nil
+	}
+	return e, nil
+}
+
+func isFindOr(tok string) bool {
+	switch tok {
+	case "-o", "--o", "-or", "--or":
+		return true
+	}
+	return false
+}
+
+func (p *findExprParser) parseUnary() (findExpr, error) {
+	switch tok := p.peek(); tok {
+	case "!", "-not", "--not":
+		p.pos++
+		expr, e := p.parseUnary()
+		if e != nil {
+			return nil, e
+		}
+		return findNot{expr}, nil
+	case "(":
+		p.pos++
+		expr, e := p.parseOr()
+		if e != nil {
+			return nil, e
+		}
+		if p.peek() != ")" {
+			return nil, errors.New("missing `)` in find expression")
+		}
+		p.pos++
+		return expr, nil
+	case "":
+		return nil, errors.New("incomplete find expression")
+	default:
+		name, value, hasValue := findFlagName(tok)
+		p.pos++
+		if !isFindPredicate(name) {
+			return nil, fmt.Errorf("unexpected `%s` in find expression", tok)
+		}
+		if !hasValue {
+			if p.pos >= len(p.tokens) {
+				return nil, fmt.Errorf("missing value of `%s` in find expression", tok)
+			}
+			value = p.tokens[p.pos]
+			p.pos++
+		}
+		if name == "metadata" || name == "tags" {
+			p.withMetadata = true
+		}
+		return newFindPredicate(name, value)
+	}
+}
+
+func isFindPredicate(name string) bool {
+	switch name {
+	case "name", "path", "regex", "ignore", "larger", "smaller", "older-than", "newer-than", "metadata", "tags":
+		return true
+	}
+	return false
+}
+
+// newFindPredicate returns the predicate of a matching flag, following the
+// semantics of the flag without expressions.
+func newFindPredicate(name, value string) (findExpr, error) {
+	p := findPredicate{flag: name, value: value}
+	switch name {
+	case "name":
+		p.fn = func(path string, _ contentMessage) bool { return nameMatch(value, path) }
+	case "path":
+		p.fn = func(path string, _ contentMessage) bool { return pathMatch(value, path) }
+	case "ignore":
+		p.fn = func(path string, _ contentMessage) bool { return !pathMatch(value, path) }
+	case "regex":
+		re, e := regexp.Compile(value)
+		if e != nil {
+			return nil, e
+		}
+		p.fn = func(path string, _ contentMessage) bool { return re.MatchString(path) }
+	case "larger", "smaller":
+		size, e := humanize.ParseBytes(value)
+		if e != nil {
+			return nil, e
+		}
+		if name == "larger" {
+			p.fn = func(_ string, content contentMessage) bool { return int64(size) < content.Size }
+		} else {
+			p.fn = func(_ string, content contentMessage) bool { return int64(size) > content.Size }
+		}
+	case "older-than", "newer-than":
+		if _, e := ParseDuration(value); e != nil {
+			return nil, e
+		}
+		if name == "older-than" {
+			p.fn = func(_ string, content contentMessage) bool { return !isOlder(content.Time, value) }
+		} else {
+			p.fn = func(_ string, content contentMessage) bool { return !isNewer(content.Time, value) }
+		}
+	case "metadata", "tags":
+		key, re, e := parseRegexMatch(value)
+		if e != nil {
+			return nil, e
+		}
+		m := map[string]*regexp.Regexp{key: re}
+		if name == "metadata" {
+			p.fn = func(_ string, content contentMessage) bool { return matchRegexMaps(m, content.Metadata) }
+		} else {
+			p.fn = func(_ string, content contentMessage) bool { return matchRegexMaps(m, content.Tags) }
+		}
+	}
+	return p, nil
+}
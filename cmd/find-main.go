@@ -93,6 +93,11 @@ var (
 			Name:  "tags",
 			Usage: "match tags with RE2 regex pattern. Specify each with key=regex. MinIO server only.",
 		},
+		cli.IntFlag{
+			Name:  "exec-parallel",
+			Usage: "number of --exec processes to run concurrently",
+			Value: 1,
+		},
 	}
 )
 
@@ -102,7 +107,7 @@ var findCmd = cli.Command{
 	Action:       mainFind,
 	OnUsageError: onUsageError,
 	Before:       setGlobalsFromContext,
-	Flags:        append(findFlags, globalFlags...),
+	Flags:        append(append(findFlags, findExprFlags...), globalFlags...),
 	CustomHelpTemplate: `NAME:
   {{.HelpName}} - {{.Usage}}
 
@@ -122,6 +127,18 @@ UNITS
   --older-than, --newer-than flags accept the string for days, hours and minutes 
   i.e. 1d2h30m states 1 day, 2 hours and 30 minutes.
 
+EXPRESSIONS
+  Matching flags are combined with AND by default. They can be combined into
+  boolean expressions with the following operators, in order of precedence:
+
+     \( EXPR \)        --> Groups an expression, parentheses must be escaped from the shell.
+     ! EXPR, --not EXPR --> Matches if EXPR does not match.
+     EXPR -a EXPR       --> Matches if both expressions match, same as EXPR EXPR.
+     EXPR -o EXPR       --> Matches if either expression matches.
+
+  Operators apply to --name, --path, --regex, --ignore, --larger, --smaller,
+  --older-than, --newer-than, --metadata and --tags.
+
 FORMAT
   Support string substitutions with special interpretations for following keywords.
   Keywords supported if target is filesystem or object storage:
@@ -130,6 +147,7 @@ FORMAT
      {base}    --> Substitutes to basename of path.
      {dir}     --> Substitutes to dirname of the path.
      {size}    --> Substitutes to object size of the path.
+     {etag}    --> Substitutes to object ETag of the path.
      {time}    --> Substitutes to object modified time of the path.
      {version} --> Substitutes to object version identifier.
 
@@ -171,12 +189,21 @@ EXAMPLES:
 
   11. Copy all versions of all objects in bucket in the local machine
       {{.Prompt}} {{.HelpName}} s3/bucket --versions --exec "mc cp --version-id {version} {} /tmp/dir/{}.{version}"
+
+  12. Find all images with ".jpg" or ".png" extension larger than 1 MB under "s3/photos".
+      {{.Prompt}} {{.HelpName}} s3/photos \( --name "*.jpg" -o --name "*.png" \) --larger 1MB
+
+  13. Find all objects under "s3/bucket" which are not tagged with "project=alpha".
+      {{.Prompt}} {{.HelpName}} s3/bucket ! --tags "project=alpha"
+
+  14. Download all objects under "s3/bucket" with 8 concurrent processes, printing their ETag.
+      {{.Prompt}} {{.HelpName}} s3/bucket --exec-parallel 8 --exec "sh -c 'mc cp {} /tmp/dir/{base} && echo {etag} {base}'"
 `,
 }
 
 // checkFindSyntax - validate the passed arguments
 func checkFindSyntax(ctx context.Context, cliCtx *cli.Context, encKeyDB map[string][]prefixSSEPair) {
-	args := cliCtx.Args()
+	args := findTargets(cliCtx)
 	if !args.Present() {
 		args = []string{"./"} // No args just default to present directory.
 	} else if args.Get(0) == "." {
@@ -189,6 +216,13 @@ func checkFindSyntax(ctx context.Context, cliCtx *cli.Context, encKeyDB map[stri
 		}
 	}
 
+	if cliCtx.Int("exec-parallel") < 1 {
+		fatalIf(errInvalidArgument().Trace(cliCtx.String("exec-parallel")), "--exec-parallel must be at least 1.")
+	}
+	if _, _, _, e := findExpression(cliCtx); e != nil {
+		fatalIf(probe.NewError(e), "Unable to parse find expression.")
+	}
+
 	// Extract input URLs and validate.
 	for _, url := range args {
 		_, _, err := url2Stat(ctx, url, "", false, encKeyDB, time.Time{}, false)
@@ -202,6 +236,39 @@ func checkFindSyntax(ctx context.Context, cliCtx *cli.Context, encKeyDB map[stri
 	}
 }
 
+// findTargets returns the positional arguments of find, without the
+// tokens of find expressions.
+func findTargets(cliCtx *cli.Context) cli.Args {
+	var args cli.Args
+	for _, arg := range cliCtx.Args() {
+		if !isFindOperator(arg) {
+			args = append(args, arg)
+		}
+	}
+	return args
+}
+
+// findExpression parses the find expression out of the raw command line,
+// since flags lose their order once parsed. Returns a nil expression if
+// the command line has no operators.
+func findExpression(cliCtx *cli.Context) (findExpr, []string, bool, error) {
+	var raw []string
+	if parent := cliCtx.Parent(); parent != nil {
+		raw = parent.Args().Tail()
+	}
+	operators := false
+	for _, arg := range raw {
+		if isFindOperator(arg) {
+			operators = true
+			break
+		}
+	}
+	if !operators {
+		return nil, nil, false, nil
+	}
+	return parseFindExpr(raw, boolFlagNames(cliCtx.Command.Flags))
+}
+
 // Find context is container to hold all parsed input arguments,
 // each parsed input is stored in its native typed form for
 // ease of repurposing.
@@ -222,6 +289,12 @@ type findContext struct {
 	withOlderVersions bool
 	matchMeta         map[string]*regexp.Regexp
 	matchTags         map[string]*regexp.Regexp
+	execParallel      int
+
+	// Boolean expression of matching flags, replaces the
+	// individual patterns above when set.
+	expr         findExpr
+	withMetadata bool
 
 	// Internal values
 	targetAlias   string
@@ -245,7 +318,9 @@ func mainFind(cliCtx *cli.Context) error {
 
 	checkFindSyntax(ctx, cliCtx, encKeyDB)
 
-	args := cliCtx.Args()
+	expr, _, withMetadata, _ := findExpression(cliCtx)
+
+	args := findTargets(cliCtx)
 	if !args.Present() {
 		args = []string{"./"} // Not args present default to present directory.
 	} else if args.Get(0) == "." {
@@ -287,7 +362,7 @@ func mainFind(cliCtx *cli.Context) error {
 
 	var targetFullURL string
 	if hostCfg != nil {
-		targetFullURL = hostCfg.URL
+		targetFullURL = aliasScopeURL(hostCfg)
 	}
 	var regMatch *regexp.Regexp
 	if cliCtx.String("regex") != "" {
@@ -315,5 +390,8 @@ func mainFind(cliCtx *cli.Context) error {
 		clnt:              clnt,
 		matchMeta:         getRegexMap(cliCtx, "metadata"),
 		matchTags:         getRegexMap(cliCtx, "tags"),
+		execParallel:      cliCtx.Int("exec-parallel"),
+		expr:              expr,
+		withMetadata:      withMetadata,
 	})
 }
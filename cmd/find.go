@@ -27,6 +27,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -126,13 +127,22 @@ func getExitStatus(err error) int {
 // execFind executes the input command line, additionally formats input
 // for the command line in accordance with subsititution arguments.
 func execFind(ctx context.Context, args string, fileContent contentMessage) {
+	if err := runExecFind(ctx, args, fileContent); err != nil {
+		// Return exit status of the command run
+		os.Exit(getExitStatus(err))
+	}
+}
+
+// runExecFind runs the input command line for a matching object, prints
+// its output and returns the error of the command if any.
+func runExecFind(ctx context.Context, args string, fileContent contentMessage) error {
 	split, err := shlex.Split(args)
 	if err != nil {
 		console.Println(console.Colorize("FindExecErr", "Unable to parse --exec: "+err.Error()))
-		os.Exit(getExitStatus(err))
+		return err
 	}
 	if len(split) == 0 {
-		return
+		return nil
 	}
 	for i, arg := range split {
 		split[i] = stringsReplace(ctx, arg, fileContent)
@@ -147,10 +157,67 @@ func execFind(ctx context.Context, args string, fileContent contentMessage) {
 			console.Println(console.Colorize("FindExecErr", strings.TrimSpace(stderr.String())))
 		}
 		console.Println(console.Colorize("FindExecErr", err.Error()))
-		// Return exit status of the command run
-		os.Exit(getExitStatus(err))
+		return err
 	}
 	console.PrintC(out.String())
+	return nil
+}
+
+// findExecutor runs the --exec command line of matching objects with a
+// number of concurrent processes, the first failure stops the find.
+type findExecutor struct {
+	ctx     context.Context
+	args    string
+	jobs    chan contentMessage
+	wg      sync.WaitGroup
+	once    sync.Once
+	failure error
+	failed  chan struct{}
+}
+
+func newFindExecutor(ctx context.Context, args string, parallel int) *findExecutor {
+	x := &findExecutor{
+		ctx:    ctx,
+		args:   args,
+		jobs:   make(chan contentMessage),
+		failed: make(chan struct{}),
+	}
+	for i := 0; i < parallel; i++ {
+		x.wg.Add(1)
+		go func() {
+			defer x.wg.Done()
+			for fileContent := range x.jobs {
+				if err := runExecFind(ctx, args, fileContent); err != nil {
+					x.once.Do(func() {
+						x.failure = err
+						close(x.failed)
+					})
+				}
+			}
+		}()
+	}
+	return x
+}
+
+// run queues the command line of a matching object, returns false once
+// a command failed.
+func (x *findExecutor) run(fileContent contentMessage) bool {
+	select {
+	case x.jobs <- fileContent:
+		return true
+	case <-x.failed:
+		return false
+	}
+}
+
+// wait waits for the queued commands, exits with the status of the
+// first failed command.
+func (x *findExecutor) wait() {
+	close(x.jobs)
+	x.wg.Wait()
+	if x.failure != nil {
+		os.Exit(getExitStatus(x.failure))
+	}
 }
 
 // watchFind - enables listening on the input path, listens for all file/object
@@ -275,7 +342,12 @@ func doFind(ctxCtx context.Context, ctx *findContext) error {
 		WithDeleteMarkers: false,
 		Recursive:         true,
 		ShowDir:           DirFirst,
-		WithMetadata:      len(ctx.matchMeta) > 0 || len(ctx.matchTags) > 0,
+		WithMetadata:      len(ctx.matchMeta) > 0 || len(ctx.matchTags) > 0 || ctx.withMetadata,
+	}
+
+	var executor *findExecutor
+	if ctx.execCmd != "" && ctx.execParallel > 1 {
+		executor = newFindExecutor(ctxCtx, ctx.execCmd, ctx.execParallel)
 	}
 
 	// iterate over all content which is within the given directory
@@ -311,6 +383,7 @@ func doFind(ctxCtx context.Context, ctx *findContext) error {
 			Size:      content.Size,
 			Metadata:  content.UserMetadata,
 			Tags:      content.Tags,
+			ETag:      strings.Trim(content.ETag, "\""),
 		}
 
 		// Match the incoming content, didn't match return.
@@ -319,6 +392,12 @@ func doFind(ctxCtx context.Context, ctx *findContext) error {
 		} // For all matching content
 
 		// proceed to either exec, format the output string.
+		if executor != nil {
+			if !executor.run(fileContent) {
+				break
+			}
+			continue
+		}
 		if ctx.execCmd != "" {
 			execFind(ctxCtx, ctx.execCmd, fileContent)
 			continue
@@ -329,6 +408,9 @@ func doFind(ctxCtx context.Context, ctx *findContext) error {
 
 		printMsg(findMessage{fileContent})
 	}
+	if executor != nil {
+		executor.wait()
+	}
 
 	// Success, notice watch will execute in defer only if enabled and this call
 	// will return after watch is canceled.
@@ -380,6 +462,12 @@ func stringsReplace(ctx context.Context, args string, fileContent contentMessage
 		str = strings.ReplaceAll(str, `{"url"}`, strconv.Quote(getShareURL(ctx, fileContent.Key)))
 	}
 
+	// replace all instances of {etag}
+	str = strings.ReplaceAll(str, "{etag}", fileContent.ETag)
+
+	// replace all instances of {"etag"}
+	str = strings.ReplaceAll(str, `{"etag"}`, strconv.Quote(fileContent.ETag))
+
 	// replace all instances of {version}
 	str = strings.ReplaceAll(str, `{version}`, fileContent.VersionID)
 
@@ -401,6 +489,9 @@ func matchFind(ctx *findContext, fileContent contentMessage) (match bool) {
 	// Trim the prefix such that we will apply file path matching techniques
 	// on path excluding the starting prefix.
 	path := strings.TrimPrefix(fileContent.Key, prefixPath)
+	if ctx.expr != nil {
+		return ctx.expr.match(path, fileContent)
+	}
 	if match && ctx.ignorePattern != "" {
 		match = !pathMatch(ctx.ignorePattern, path)
 	}
@@ -494,6 +585,20 @@ func getRegexMap(cliCtx *cli.Context, key string) map[string]*regexp.Regexp {
 	return reMap
 }
 
+// parseRegexMatch parses a key=regex match of metadata or tags, a nil
+// regex matches a missing or empty value.
+func parseRegexMatch(v string) (string, *regexp.Regexp, error) {
+	split := strings.SplitN(v, "=", 2)
+	if len(split) < 2 {
+		return "", nil, fmt.Errorf("want one = separator in `%s`, got none", v)
+	}
+	if len(split[1]) == 0 {
+		return split[0], nil, nil
+	}
+	re, err := regexp.Compile(split[1])
+	return split[0], re, err
+}
+
 // matchRegexMaps will check if all regexes in 'm' match values in 'v' with the same key.
 // If a regex is nil, it must either not exist in v or have a 0 length value.
 func matchRegexMaps(m map[string]*regexp.Regexp, v map[string]string) bool {
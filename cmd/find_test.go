@@ -400,6 +400,18 @@ func TestStringReplace(t *testing.T) {
 				Time: time.Unix(2147483647, 0).UTC(),
 			},
 		},
+		// Tests string replace {etag}
+		{
+			str:         `{etag}`,
+			expectedStr: `d41d8cd98f00b204e9800998ecf8427e`,
+			content:     contentMessage{ETag: "d41d8cd98f00b204e9800998ecf8427e"},
+		},
+		// Tests string replace {"etag"} with quotes.
+		{
+			str:         `{"etag"}`,
+			expectedStr: `"d41d8cd98f00b204e9800998ecf8427e"`,
+			content:     contentMessage{ETag: "d41d8cd98f00b204e9800998ecf8427e"},
+		},
 	}
 	for i, testCase := range testCases {
 		gotStr := stringsReplace(context.Background(), testCase.str, testCase.content)
@@ -448,3 +460,83 @@ func TestGetExitStatus(t *testing.T) {
 		}
 	}
 }
+
+// Tests parsing and evaluation of find expressions.
+func TestFindExpr(t *testing.T) {
+	boolFlags := boolFlagNames(append(findFlags, findExprFlags...))
+	jpg := contentMessage{Key: "photos/a.jpg", Size: 2 << 20}
+	png := contentMessage{Key: "photos/b.png", Size: 10}
+	txt := contentMessage{Key: "docs/c.txt", Size: 2 << 20, Tags: map[string]string{"project": "alpha"}}
+
+	testCases := []struct {
+		args    []string
+		targets []string
+		str     string
+		matches []bool // jpg, png, txt
+		err     bool
+	}{
+		{
+			args:    []string{"s3/bucket", "--name", "*.jpg"},
+			targets: []string{"s3/bucket"},
+			str:     "--name *.jpg",
+			matches: []bool{true, false, false},
+		},
+		{
+			args:    []string{"s3/bucket", "--name", "*.jpg", "-o", "--name=*.png"},
+			targets: []string{"s3/bucket"},
+			str:     "--name *.jpg -o --name *.png",
+			matches: []bool{true, true, false},
+		},
+		{
+			args:    []string{"(", "--name", "*.jpg", "-o", "--name", "*.txt", ")", "--larger", "1MB", "s3/bucket"},
+			targets: []string{"s3/bucket"},
+			str:     "( --name *.jpg -o --name *.txt ) --larger 1MB",
+			matches: []bool{true, false, true},
+		},
+		{
+			args:    []string{"--name", "*.jpg", "-o", "--name", "*.txt", "-a", "--larger", "1MB"},
+			str:     "--name *.jpg -o ( --name *.txt --larger 1MB )",
+			matches: []bool{true, false, true},
+		},
+		{
+			args:    []string{"s3/a", "--exec-parallel", "4", "!", "--tags", "project=alpha", "--versions", "s3/b"},
+			targets: []string{"s3/a", "s3/b"},
+			str:     "! --tags project=alpha",
+			matches: []bool{true, true, false},
+		},
+		{
+			args:    []string{"--not", "(", "--path", "photos/*", ")"},
+			str:     "! --path photos/*",
+			matches: []bool{false, false, true},
+		},
+		{args: []string{"(", "--name", "*.jpg"}, err: true},
+		{args: []string{"--name", "*.jpg", ")"}, err: true},
+		{args: []string{"-o", "--name", "*.jpg"}, err: true},
+		{args: []string{"--name", "*.jpg", "!"}, err: true},
+		{args: []string{"--larger", "1XB"}, err: true},
+		{args: []string{"--regex", "("}, err: true},
+	}
+	for i, testCase := range testCases {
+		expr, targets, _, err := parseFindExpr(testCase.args, boolFlags)
+		if testCase.err {
+			if err == nil {
+				t.Errorf("Test %d: Expected error, got %s", i+1, expr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Test %d: Unexpected error: %v", i+1, err)
+		}
+		if strings.Join(targets, " ") != strings.Join(testCase.targets, " ") {
+			t.Errorf("Test %d: Expected targets %v, got %v", i+1, testCase.targets, targets)
+		}
+		if expr.String() != testCase.str {
+			t.Errorf("Test %d: Expected %s, got %s", i+1, testCase.str, expr)
+		}
+		for j, content := range []contentMessage{jpg, png, txt} {
+			if match := expr.match(content.Key, content); match != testCase.matches[j] {
+				t.Errorf("Test %d: Expected match %v for %s, got %v", i+1, testCase.matches[j], content.Key, match)
+			}
+		}
+	}
+}
@@ -58,6 +58,11 @@ var globalFlags = []cli.Flag{
 		Name:  "limit-download",
 		Usage: "limits downloads to a maximum rate in KiB/s, MiB/s, GiB/s. (default: unlimited)",
 	},
+	cli.BoolFlag{
+		Name:   "fs-metadata",
+		Usage:  "store object metadata and tags with local files, in extended attributes or a sidecar JSON file",
+		EnvVar: "MC_FS_METADATA",
+	},
 	cli.DurationFlag{
 		Name:   "conn-read-deadline",
 		Usage:  "custom connection READ deadline",
@@ -76,6 +76,9 @@ var (
 	globalLimitUpload   uint64
 	globalLimitDownload uint64
 
+	// Store object metadata and tags with local files.
+	globalFSMetadata = false
+
 	globalContext, globalCancel = context.WithCancel(context.Background())
 )
 
@@ -98,6 +101,7 @@ func setGlobalsFromContext(ctx *cli.Context) error {
 	insecure := ctx.IsSet("insecure") || ctx.GlobalIsSet("insecure")
 	devMode := ctx.IsSet("dev") || ctx.GlobalIsSet("dev")
 	airgapped := ctx.IsSet("airgap") || ctx.GlobalIsSet("airgap")
+	fsMetadata := ctx.Bool("fs-metadata") || ctx.GlobalBool("fs-metadata")
 
 	globalQuiet = globalQuiet || quiet
 	globalDebug = globalDebug || debug
@@ -107,6 +111,7 @@ func setGlobalsFromContext(ctx *cli.Context) error {
 	globalInsecure = globalInsecure || insecure
 	globalDevMode = globalDevMode || devMode
 	globalAirgapped = globalAirgapped || airgapped
+	globalFSMetadata = globalFSMetadata || fsMetadata
 
 	// Disable colorified messages if requested.
 	if globalNoColor || globalQuiet {
@@ -59,7 +59,7 @@ var headCmd = cli.Command{
 	Action:       mainHead,
 	OnUsageError: onUsageError,
 	Before:       setGlobalsFromContext,
-	Flags:        append(append(headFlags, ioFlags...), globalFlags...),
+	Flags:        append(append(append(headFlags, ioFlags...), cseFlags...), globalFlags...),
 	CustomHelpTemplate: `NAME:
   {{.HelpName}} - {{.Usage}}
 
@@ -92,7 +92,7 @@ EXAMPLES:
 }
 
 // headURL displays contents of a URL to stdout.
-func headURL(sourceURL, sourceVersion string, timeRef time.Time, encKeyDB map[string][]prefixSSEPair, nlines int64, zip bool) *probe.Error {
+func headURL(sourceURL, sourceVersion string, timeRef time.Time, encKeyDB map[string][]prefixSSEPair, cse *cseKeys, nlines int64, zip bool) *probe.Error {
 	var reader io.ReadCloser
 	switch sourceURL {
 	case "-":
@@ -100,9 +100,24 @@ func headURL(sourceURL, sourceVersion string, timeRef time.Time, encKeyDB map[st
 	default:
 		var err *probe.Error
 		var metadata map[string]string
+		// Objects compressed client-side are decompressed, objects
+		// encrypted client-side are decrypted with the given keys.
+		_, content, err := url2Stat(context.Background(), sourceURL, sourceVersion, false, encKeyDB, timeRef, zip)
+		if err != nil {
+			return err.Trace(sourceURL)
+		}
+		t, err := newCatTransform(sourceURL, content, catOpts{cse: cse})
+		if err != nil {
+			return err.Trace(sourceURL)
+		}
 		if reader, metadata, err = getSourceStreamMetadataFromURL(context.Background(), sourceURL, sourceVersion, timeRef, encKeyDB, zip); err != nil {
 			return err.Trace(sourceURL)
 		}
+		if t.object != nil || t.codec != "" {
+			if reader, err = t.newReader(reader); err != nil {
+				return err.Trace(sourceURL)
+			}
+		}
 		ctype := metadata["Content-Type"]
 		if strings.Contains(ctype, "gzip") {
 			var e error
@@ -192,6 +207,9 @@ func mainHead(ctx *cli.Context) error {
 
 	args, versionID, timeRef := parseHeadSyntax(ctx)
 
+	cse, err := getCSEKeys(ctx)
+	fatalIf(err, "Unable to parse client-side encryption keys.")
+
 	stdinMode := len(args) == 0
 
 	// handle std input data.
@@ -202,7 +220,7 @@ func mainHead(ctx *cli.Context) error {
 
 	// Convert arguments to URLs: expand alias, fix format.
 	for _, url := range ctx.Args() {
-		fatalIf(headURL(url, versionID, timeRef, encKeyDB, ctx.Int64("lines"), ctx.Bool("zip")).Trace(url), "Unable to read from `"+url+"`.")
+		fatalIf(headURL(url, versionID, timeRef, encKeyDB, cse, ctx.Int64("lines"), ctx.Bool("zip")).Trace(url), "Unable to read from `"+url+"`.")
 	}
 
 	return nil
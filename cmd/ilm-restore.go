@@ -21,6 +21,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"path/filepath"
 	"time"
 
 	"github.com/minio/cli"
@@ -165,7 +166,7 @@ func waitRestoreObject(ctx context.Context, targetAlias, targetURL, versionID st
 	for {
 		opts := StatOptions{
 			versionID: versionID,
-			sse:       getSSE(targetAlias+clnt.GetURL().Path, encKeyDB[targetAlias]),
+			sse:       getSSE(filepath.ToSlash(aliasPath(targetAlias, clnt.GetURL().Path)), encKeyDB[targetAlias]),
 		}
 		st, err := clnt.Stat(ctx, opts)
 		if err != nil {
@@ -0,0 +1,226 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+	"github.com/minio/cli"
+	json "github.com/minio/colorjson"
+	"github.com/trinet2005/oss-mc/pkg/probe"
+	"github.com/trinet2005/oss-pkg/console"
+)
+
+var inventoryGenerateFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "format",
+		Usage: "manifest format, one of csv, jsonl or parquet. Guessed from the output file name by default",
+	},
+	cli.StringFlag{
+		Name:  "output, o",
+		Usage: "write the manifest to a file instead of the standard output",
+	},
+	cli.BoolFlag{
+		Name:  "versions",
+		Usage: "include all the versions and delete markers",
+	},
+	cli.StringFlag{
+		Name:  "sign-key",
+		Usage: "sign the manifest with the Ed25519 private key of a PEM file, requires --output",
+	},
+}
+
+var inventoryGenerateCmd = cli.Command{
+	Name:            "generate",
+	Usage:           "generate a manifest of the objects in a bucket or a folder",
+	Action:          mainInventoryGenerate,
+	Before:          setGlobalsFromContext,
+	Flags:           append(inventoryGenerateFlags, globalFlags...),
+	HideHelpCommand: true,
+	OnUsageError:    onUsageError,
+	CustomHelpTemplate: `NAME:
+  {{.HelpName}} - {{.Usage}}
+
+USAGE:
+  {{.HelpName}} [FLAGS] TARGET
+
+FLAGS:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}
+DESCRIPTION:
+  The manifest lists for every object its key relative to TARGET, version, size, modification time,
+  ETag, checksum, storage class, retention and legal hold. Checksums, retention and legal hold are
+  recorded when the server reports them in listings. Local files are recorded with their MD5 as ETag.
+
+  Manifests written with --output come with a signature file, named after the manifest with a .sig
+  extension, holding the SHA-256 digest of the manifest. With --sign-key, the digest is signed with
+  an Ed25519 private key, e.g. generated with 'openssl genpkey -algorithm ed25519 -out key.pem'.
+
+EXAMPLES:
+  1. Write a CSV manifest of a bucket to the standard output.
+     {{.Prompt}} {{.HelpName}} myminio/mybucket
+
+  2. Write a Parquet manifest of all the versions of the objects under a prefix.
+     {{.Prompt}} {{.HelpName}} --versions --output photos.parquet myminio/mybucket/photos
+
+  3. Write a JSON lines manifest of a local folder.
+     {{.Prompt}} {{.HelpName}} --format jsonl ~/Photos > photos.jsonl
+
+  4. Write a signed manifest of a bucket to mybucket.csv and mybucket.csv.sig.
+     {{.Prompt}} {{.HelpName}} --sign-key audit-key.pem --output mybucket.csv myminio/mybucket
+`,
+}
+
+// inventoryGenerateMessage container for manifest generation messages.
+type inventoryGenerateMessage struct {
+	Status    string `json:"status"`
+	URL       string `json:"url"`
+	Output    string `json:"output"`
+	Format    string `json:"format"`
+	Signature string `json:"signature,omitempty"`
+	Objects   int64  `json:"objects"`
+	TotalSize int64  `json:"totalSize"`
+}
+
+// String colorized manifest generation message.
+func (i inventoryGenerateMessage) String() string {
+	msg := console.Colorize("InventorySummary", fmt.Sprintf("Wrote a manifest of %d objects (%s) in `%s` to `%s`.",
+		i.Objects, humanize.IBytes(uint64(i.TotalSize)), i.URL, i.Output))
+	if i.Signature != "" {
+		msg += console.Colorize("InventorySummary", fmt.Sprintf(" Signature written to `%s`.", i.Signature))
+	}
+	return msg
+}
+
+// JSON jsonified manifest generation message.
+func (i inventoryGenerateMessage) JSON() string {
+	i.Status = "success"
+	jsonBytes, e := json.MarshalIndent(i, "", " ")
+	fatalIf(probe.NewError(e), "Unable to marshal into JSON.")
+	return string(jsonBytes)
+}
+
+func checkInventoryGenerateSyntax(cliCtx *cli.Context) {
+	if len(cliCtx.Args()) != 1 {
+		showCommandHelpAndExit(cliCtx, 1) // last argument is exit code
+	}
+	if cliCtx.String("sign-key") != "" && cliCtx.String("output") == "" {
+		fatalIf(errInvalidArgument().Trace(), "--sign-key requires --output, the signature is written next to the manifest.")
+	}
+}
+
+// mainInventoryGenerate is the handle for "mc inventory generate" command.
+func mainInventoryGenerate(cliCtx *cli.Context) error {
+	ctx, cancelGenerate := context.WithCancel(globalContext)
+	defer cancelGenerate()
+
+	checkInventoryGenerateSyntax(cliCtx)
+	setInventoryColors()
+
+	output := cliCtx.String("output")
+	versions := cliCtx.Bool("versions")
+	format, err := inventoryFormat(cliCtx.String("format"), output)
+	fatalIf(err, "Unable to parse --format.")
+
+	var signKey ed25519.PrivateKey
+	if keyFile := cliCtx.String("sign-key"); keyFile != "" {
+		signKey, err = loadInventorySigningKey(keyFile)
+		fatalIf(err, "Unable to load the signing key.")
+	}
+
+	// Keys are listed relative to the target folder.
+	targetURL := cliCtx.Args().Get(0)
+	separator := string(newClientURL(targetURL).Separator)
+	if !strings.HasSuffix(targetURL, separator) {
+		targetURL += separator
+	}
+	clnt, err := newClient(targetURL)
+	fatalIf(err.Trace(targetURL), "Unable to initialize `"+targetURL+"`.")
+	rootURL := clnt.GetURL().String()
+
+	var w io.Writer = os.Stdout
+	var f *os.File
+	hash := sha256.New()
+	if output != "" {
+		var e error
+		f, e = os.Create(output)
+		fatalIf(probe.NewError(e).Trace(output), "Unable to create the manifest file.")
+		w = io.MultiWriter(f, hash)
+	}
+	iw, e := newInventoryWriter(w, format)
+	fatalIf(probe.NewError(e).Trace(output), "Unable to write the manifest.")
+
+	cache := loadChecksumCache()
+	defer func() {
+		errorIf(cache.Save().Trace(), "Unable to save the checksum cache.")
+	}()
+
+	var cErr error
+	msg := inventoryGenerateMessage{URL: cliCtx.Args().Get(0), Output: output, Format: format}
+	for content := range clnt.List(ctx, ListOptions{
+		Recursive:         true,
+		WithOlderVersions: versions,
+		WithDeleteMarkers: versions,
+		WithMetadata:      true,
+		ShowDir:           DirNone,
+	}) {
+		if content.Err != nil {
+			errorIf(content.Err.Trace(targetURL), "Unable to list `"+targetURL+"`.")
+			cErr = exitStatus(globalErrorExitStatus)
+			continue
+		}
+		key := filepath.ToSlash(strings.TrimPrefix(content.URL.String(), rootURL))
+		record := newInventoryRecord(key, content)
+		if !versions {
+			record.IsLatest = true
+		}
+		// Local files are recorded with their MD5, like the ETag of
+		// an object uploaded in a single part.
+		if content.URL.Type == fileSystem && content.Type.IsRegular() {
+			record.ETag, e = cache.Checksum(content.URL.Path, checksumMD5)
+			if e != nil {
+				errorIf(probe.NewError(e).Trace(content.URL.Path), "Unable to compute the checksum of `"+content.URL.Path+"`.")
+				cErr = exitStatus(globalErrorExitStatus)
+			}
+		}
+		e = iw.Write(record)
+		fatalIf(probe.NewError(e).Trace(output), "Unable to write the manifest.")
+		msg.Objects++
+		if !record.IsDeleteMarker {
+			msg.TotalSize += record.Size
+		}
+	}
+
+	fatalIf(probe.NewError(iw.Close()).Trace(output), "Unable to write the manifest.")
+	if f != nil {
+		fatalIf(probe.NewError(f.Close()).Trace(output), "Unable to write the manifest.")
+		err = saveInventorySignature(output, newInventorySignature(hash.Sum(nil), signKey))
+		fatalIf(err, "Unable to write the signature of the manifest.")
+		msg.Signature = output + inventorySignatureExt
+		printMsg(msg)
+	}
+	return cErr
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"github.com/fatih/color"
+	"github.com/minio/cli"
+	"github.com/trinet2005/oss-pkg/console"
+)
+
+var inventorySubcommands = []cli.Command{
+	inventoryGenerateCmd,
+	inventoryVerifyCmd,
+}
+
+var inventoryCmd = cli.Command{
+	Name:            "inventory",
+	Usage:           "generate and verify manifests of bucket contents",
+	Action:          mainInventory,
+	Before:          setGlobalsFromContext,
+	HideHelpCommand: true,
+	Flags:           globalFlags,
+	Subcommands:     inventorySubcommands,
+}
+
+// mainInventory is the handle for "mc inventory" command.
+func mainInventory(ctx *cli.Context) error {
+	commandNotFound(ctx, inventorySubcommands)
+	return nil
+	// Sub-commands like generate and verify have their own main.
+}
+
+// setInventoryColors - colors of the inventory messages.
+func setInventoryColors() {
+	console.SetColor("InventoryMissing", color.New(color.FgRed))
+	console.SetColor("InventoryExtra", color.New(color.FgGreen))
+	console.SetColor("InventoryChanged", color.New(color.FgYellow, color.Bold))
+	console.SetColor("InventorySummary", color.New(color.FgWhite, color.Bold))
+}
@@ -0,0 +1,314 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/trinet2005/oss-mc/pkg/probe"
+)
+
+// Supported inventory manifest formats.
+const (
+	inventoryFormatCSV     = "csv"
+	inventoryFormatJSONL   = "jsonl"
+	inventoryFormatParquet = "parquet"
+)
+
+// inventoryRecord - an object version listed in a manifest. Keys are
+// relative to the listed URL and separated by slashes.
+type inventoryRecord struct {
+	Key            string    `json:"key"`
+	VersionID      string    `json:"versionId,omitempty"`
+	IsLatest       bool      `json:"isLatest"`
+	IsDeleteMarker bool      `json:"isDeleteMarker,omitempty"`
+	Size           int64     `json:"size"`
+	LastModified   time.Time `json:"lastModified"`
+	ETag           string    `json:"etag,omitempty"`
+	Checksum       string    `json:"checksum,omitempty"`
+	StorageClass   string    `json:"storageClass,omitempty"`
+	RetentionMode  string    `json:"retentionMode,omitempty"`
+	RetainUntil    string    `json:"retainUntilDate,omitempty"`
+	LegalHold      string    `json:"legalHold,omitempty"`
+}
+
+// inventoryColumns - columns of CSV and Parquet manifests, in order.
+var inventoryColumns = []string{
+	"key", "versionId", "isLatest", "isDeleteMarker", "size", "lastModified",
+	"etag", "checksum", "storageClass", "retentionMode", "retainUntilDate", "legalHold",
+}
+
+// set sets the field of the record stored in the named column, unknown
+// columns are ignored.
+func (r *inventoryRecord) set(column string, value interface{}) {
+	switch v := value.(type) {
+	case string:
+		switch column {
+		case "key":
+			r.Key = v
+		case "versionId":
+			r.VersionID = v
+		case "etag":
+			r.ETag = v
+		case "checksum":
+			r.Checksum = v
+		case "storageClass":
+			r.StorageClass = v
+		case "retentionMode":
+			r.RetentionMode = v
+		case "retainUntilDate":
+			r.RetainUntil = v
+		case "legalHold":
+			r.LegalHold = v
+		}
+	case bool:
+		switch column {
+		case "isLatest":
+			r.IsLatest = v
+		case "isDeleteMarker":
+			r.IsDeleteMarker = v
+		}
+	case int64:
+		switch column {
+		case "size":
+			r.Size = v
+		case "lastModified":
+			r.LastModified = time.UnixMilli(v).UTC()
+		}
+	}
+}
+
+// checksum returns the algorithm and the value of the recorded checksum.
+func (r inventoryRecord) checksum() (algo, value string) {
+	algo, value, _ = strings.Cut(r.Checksum, ":")
+	return algo, value
+}
+
+// newInventoryRecord builds the manifest record of a listed object.
+func newInventoryRecord(key string, content *ClientContent) inventoryRecord {
+	r := inventoryRecord{
+		Key:            key,
+		VersionID:      content.VersionID,
+		IsLatest:       content.IsLatest,
+		IsDeleteMarker: content.IsDeleteMarker,
+		Size:           content.Size,
+		LastModified:   content.Time.UTC(),
+		ETag:           strings.Trim(content.ETag, "\""),
+		StorageClass:   content.StorageClass,
+		RetentionMode:  content.RetentionMode,
+		LegalHold:      content.LegalHold,
+	}
+	for _, algo := range checksumAlgorithms {
+		if value := inventoryMetadata(content, checksumMetadataKey(algo)); value != "" {
+			r.Checksum = algo.String() + ":" + value
+			break
+		}
+	}
+	if r.RetentionMode == "" {
+		r.RetentionMode = inventoryMetadata(content, AmzObjectLockMode)
+	}
+	r.RetainUntil = inventoryMetadata(content, AmzObjectLockRetainUntilDate)
+	if r.LegalHold == "" {
+		r.LegalHold = inventoryMetadata(content, AmzObjectLockLegalHold)
+	}
+	return r
+}
+
+// inventoryMetadata looks up a header in the metadata of an object,
+// listings may report them in any case.
+func inventoryMetadata(content *ClientContent, key string) string {
+	for _, metadata := range []map[string]string{content.Metadata, content.UserMetadata} {
+		if v, ok := metadata[key]; ok {
+			return v
+		}
+		for k, v := range metadata {
+			if strings.EqualFold(k, key) {
+				return v
+			}
+		}
+	}
+	return ""
+}
+
+// inventoryFormat returns the manifest format, guessed from the file
+// extension when not set.
+func inventoryFormat(format, fpath string) (string, *probe.Error) {
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(fpath)) {
+		case ".jsonl", ".ndjson", ".json":
+			return inventoryFormatJSONL, nil
+		case ".parquet":
+			return inventoryFormatParquet, nil
+		}
+		return inventoryFormatCSV, nil
+	}
+	switch format = strings.ToLower(format); format {
+	case inventoryFormatCSV, inventoryFormatJSONL, inventoryFormatParquet:
+		return format, nil
+	}
+	return "", probe.NewError(fmt.Errorf("unknown format `%s`, supported formats are csv, jsonl and parquet", format))
+}
+
+// inventoryWriter writes manifest records.
+type inventoryWriter interface {
+	Write(inventoryRecord) error
+	Close() error
+}
+
+// newInventoryWriter returns a writer of manifests in the given format,
+// closing it does not close w.
+func newInventoryWriter(w io.Writer, format string) (inventoryWriter, error) {
+	switch format {
+	case inventoryFormatJSONL:
+		bw := bufio.NewWriter(w)
+		return &jsonlInventoryWriter{w: bw, enc: json.NewEncoder(bw)}, nil
+	case inventoryFormatParquet:
+		// The parquet writer buffers a row group, no need to buffer twice.
+		return newParquetInventoryWriter(w)
+	}
+	cw := &csvInventoryWriter{w: csv.NewWriter(w)}
+	if e := cw.w.Write(inventoryColumns); e != nil {
+		return nil, e
+	}
+	return cw, nil
+}
+
+type csvInventoryWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvInventoryWriter) Write(r inventoryRecord) error {
+	return c.w.Write([]string{
+		r.Key, r.VersionID, strconv.FormatBool(r.IsLatest), strconv.FormatBool(r.IsDeleteMarker),
+		strconv.FormatInt(r.Size, 10), r.LastModified.Format(time.RFC3339Nano),
+		r.ETag, r.Checksum, r.StorageClass, r.RetentionMode, r.RetainUntil, r.LegalHold,
+	})
+}
+
+func (c *csvInventoryWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type jsonlInventoryWriter struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func (j *jsonlInventoryWriter) Write(r inventoryRecord) error {
+	return j.enc.Encode(r)
+}
+
+func (j *jsonlInventoryWriter) Close() error {
+	return j.w.Flush()
+}
+
+// readInventory calls fn for every record of a manifest file.
+func readInventory(fpath, format string, fn func(inventoryRecord) error) *probe.Error {
+	f, e := os.Open(fpath)
+	if e != nil {
+		return probe.NewError(e)
+	}
+	defer f.Close()
+
+	switch format {
+	case inventoryFormatJSONL:
+		e = readJSONLInventory(f, fn)
+	case inventoryFormatParquet:
+		e = readParquetInventory(f, fn)
+	default:
+		e = readCSVInventory(f, fn)
+	}
+	return probe.NewError(e).Trace(fpath)
+}
+
+func readJSONLInventory(f *os.File, fn func(inventoryRecord) error) error {
+	scanner := bufio.NewScanner(f)
+	// Keys may be up to 1024 bytes, leave room for long metadata.
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var r inventoryRecord
+		if e := json.Unmarshal(scanner.Bytes(), &r); e != nil {
+			return fmt.Errorf("line %d: %w", line, e)
+		}
+		if e := fn(r); e != nil {
+			return e
+		}
+	}
+	return scanner.Err()
+}
+
+func readCSVInventory(f *os.File, fn func(inventoryRecord) error) error {
+	reader := csv.NewReader(f)
+	header, e := reader.Read()
+	if e != nil {
+		return e
+	}
+	for {
+		values, e := reader.Read()
+		if e == io.EOF {
+			return nil
+		}
+		if e != nil {
+			return e
+		}
+		var r inventoryRecord
+		for i, value := range values {
+			if i >= len(header) {
+				break
+			}
+			switch column := header[i]; column {
+			case "isLatest", "isDeleteMarker":
+				b, e := strconv.ParseBool(value)
+				if e != nil {
+					return fmt.Errorf("column %s: %w", column, e)
+				}
+				r.set(column, b)
+			case "size":
+				n, e := strconv.ParseInt(value, 10, 64)
+				if e != nil {
+					return fmt.Errorf("column %s: %w", column, e)
+				}
+				r.set(column, n)
+			case "lastModified":
+				t, e := time.Parse(time.RFC3339Nano, value)
+				if e != nil {
+					return fmt.Errorf("column %s: %w", column, e)
+				}
+				r.LastModified = t
+			default:
+				r.set(column, value)
+			}
+		}
+		if e = fn(r); e != nil {
+			return e
+		}
+	}
+}
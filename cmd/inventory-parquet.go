@@ -0,0 +1,116 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"io"
+
+	goparquet "github.com/fraugster/parquet-go"
+	"github.com/fraugster/parquet-go/parquet"
+	"github.com/fraugster/parquet-go/parquetschema"
+)
+
+// inventoryParquetSchema - schema of Parquet manifests, the columns are
+// the ones of inventoryColumns.
+const inventoryParquetSchema = `message inventory {
+	required binary key (STRING);
+	required binary versionId (STRING);
+	required boolean isLatest;
+	required boolean isDeleteMarker;
+	required int64 size;
+	required int64 lastModified (TIMESTAMP(MILLIS, true));
+	required binary etag (STRING);
+	required binary checksum (STRING);
+	required binary storageClass (STRING);
+	required binary retentionMode (STRING);
+	required binary retainUntilDate (STRING);
+	required binary legalHold (STRING);
+}`
+
+// Size of the data buffered in memory before a row group is written.
+const inventoryParquetRowGroupSize = 64 << 20
+
+type parquetInventoryWriter struct {
+	w *goparquet.FileWriter
+}
+
+// newParquetInventoryWriter starts a Snappy compressed Parquet manifest,
+// it only appends to w so it can stream to a pipe.
+func newParquetInventoryWriter(w io.Writer) (*parquetInventoryWriter, error) {
+	schemaDef, e := parquetschema.ParseSchemaDefinition(inventoryParquetSchema)
+	if e != nil {
+		return nil, e
+	}
+	return &parquetInventoryWriter{
+		w: goparquet.NewFileWriter(w,
+			goparquet.WithSchemaDefinition(schemaDef),
+			goparquet.WithCompressionCodec(parquet.CompressionCodec_SNAPPY),
+			goparquet.WithMaxRowGroupSize(inventoryParquetRowGroupSize),
+			goparquet.WithCreator("mc"),
+		),
+	}, nil
+}
+
+func (p *parquetInventoryWriter) Write(r inventoryRecord) error {
+	return p.w.AddData(map[string]interface{}{
+		"key":             []byte(r.Key),
+		"versionId":       []byte(r.VersionID),
+		"isLatest":        r.IsLatest,
+		"isDeleteMarker":  r.IsDeleteMarker,
+		"size":            r.Size,
+		"lastModified":    r.LastModified.UnixMilli(),
+		"etag":            []byte(r.ETag),
+		"checksum":        []byte(r.Checksum),
+		"storageClass":    []byte(r.StorageClass),
+		"retentionMode":   []byte(r.RetentionMode),
+		"retainUntilDate": []byte(r.RetainUntil),
+		"legalHold":       []byte(r.LegalHold),
+	})
+}
+
+func (p *parquetInventoryWriter) Close() error {
+	return p.w.Close()
+}
+
+// readParquetInventory calls fn for every record of a Parquet manifest,
+// columns unknown to inventoryRecord are ignored.
+func readParquetInventory(r io.ReadSeeker, fn func(inventoryRecord) error) error {
+	reader, e := goparquet.NewFileReader(r)
+	if e != nil {
+		return e
+	}
+	for {
+		row, e := reader.NextRow()
+		if e == io.EOF {
+			return nil
+		}
+		if e != nil {
+			return e
+		}
+		var record inventoryRecord
+		for column, value := range row {
+			if b, ok := value.([]byte); ok {
+				value = string(b)
+			}
+			record.set(column, value)
+		}
+		if e = fn(record); e != nil {
+			return e
+		}
+	}
+}
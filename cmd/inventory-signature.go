@@ -0,0 +1,167 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/trinet2005/oss-mc/pkg/probe"
+)
+
+// Manifests are signed in a detached file named after the manifest.
+const (
+	inventorySignatureExt     = ".sig"
+	inventorySignatureVersion = "1"
+	inventorySignatureEd25519 = "ed25519"
+)
+
+// inventorySignatureV1 - detached signature of a manifest: the SHA-256
+// digest of the manifest and, when signed with a key, the Ed25519
+// signature of that digest.
+type inventorySignatureV1 struct {
+	Version   string `json:"version"`
+	SHA256    string `json:"sha256"`
+	Algorithm string `json:"algorithm,omitempty"`
+	Signature string `json:"signature,omitempty"`
+}
+
+// readPEMBlock returns the single PEM block of a key file.
+func readPEMBlock(fpath string) (*pem.Block, error) {
+	data, e := os.ReadFile(fpath)
+	if e != nil {
+		return nil, e
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+	return block, nil
+}
+
+// loadInventorySigningKey loads an Ed25519 private key from a PKCS #8
+// PEM file, as generated by `openssl genpkey -algorithm ed25519`.
+func loadInventorySigningKey(fpath string) (ed25519.PrivateKey, *probe.Error) {
+	block, e := readPEMBlock(fpath)
+	if e != nil {
+		return nil, probe.NewError(e).Trace(fpath)
+	}
+	key, e := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if e != nil {
+		return nil, probe.NewError(e).Trace(fpath)
+	}
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, probe.NewError(fmt.Errorf("`%s` is not an Ed25519 private key", fpath))
+	}
+	return privateKey, nil
+}
+
+// loadInventoryVerifyingKey loads an Ed25519 public key from a PKIX PEM
+// file, as extracted by `openssl pkey -pubout`.
+func loadInventoryVerifyingKey(fpath string) (ed25519.PublicKey, *probe.Error) {
+	block, e := readPEMBlock(fpath)
+	if e != nil {
+		return nil, probe.NewError(e).Trace(fpath)
+	}
+	key, e := x509.ParsePKIXPublicKey(block.Bytes)
+	if e != nil {
+		return nil, probe.NewError(e).Trace(fpath)
+	}
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, probe.NewError(fmt.Errorf("`%s` is not an Ed25519 public key", fpath))
+	}
+	return publicKey, nil
+}
+
+// newInventorySignature returns the signature of a manifest of the given
+// SHA-256 digest, only holding the digest when key is nil.
+func newInventorySignature(digest []byte, key ed25519.PrivateKey) inventorySignatureV1 {
+	sig := inventorySignatureV1{Version: inventorySignatureVersion, SHA256: hex.EncodeToString(digest)}
+	if key != nil {
+		sig.Algorithm = inventorySignatureEd25519
+		sig.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key, digest))
+	}
+	return sig
+}
+
+// saveInventorySignature writes the signature of a manifest next to it.
+func saveInventorySignature(manifest string, sig inventorySignatureV1) *probe.Error {
+	data, e := json.MarshalIndent(sig, "", " ")
+	if e != nil {
+		return probe.NewError(e)
+	}
+	if e = os.WriteFile(manifest+inventorySignatureExt, append(data, '\n'), 0o644); e != nil {
+		return probe.NewError(e).Trace(manifest + inventorySignatureExt)
+	}
+	return nil
+}
+
+// verifyInventorySignature checks a manifest against the signature file
+// next to it. Manifests without signature file pass unless a public key
+// is given, the signature is then required and verified. Returns true if
+// a signature file was found.
+func verifyInventorySignature(manifest string, key ed25519.PublicKey) (bool, *probe.Error) {
+	data, e := os.ReadFile(manifest + inventorySignatureExt)
+	if os.IsNotExist(e) && key == nil {
+		return false, nil
+	}
+	if e != nil {
+		return false, probe.NewError(e).Trace(manifest + inventorySignatureExt)
+	}
+	var sig inventorySignatureV1
+	if e = json.Unmarshal(data, &sig); e != nil {
+		return false, probe.NewError(e).Trace(manifest + inventorySignatureExt)
+	}
+	if sig.Version != inventorySignatureVersion {
+		return false, errInvalidArgument().Trace(manifest+inventorySignatureExt, sig.Version)
+	}
+
+	f, e := os.Open(manifest)
+	if e != nil {
+		return false, probe.NewError(e).Trace(manifest)
+	}
+	defer f.Close()
+	hash := sha256.New()
+	if _, e = io.Copy(hash, f); e != nil {
+		return false, probe.NewError(e).Trace(manifest)
+	}
+	digest := hash.Sum(nil)
+	if hex.EncodeToString(digest) != sig.SHA256 {
+		return true, probe.NewError(fmt.Errorf("`%s` does not match its SHA-256 digest, it was modified", manifest))
+	}
+
+	if key == nil {
+		return true, nil
+	}
+	signature, e := base64.StdEncoding.DecodeString(sig.Signature)
+	if sig.Algorithm != inventorySignatureEd25519 || e != nil || !ed25519.Verify(key, digest, signature) {
+		return true, probe.NewError(fmt.Errorf("`%s` is not signed by the given key", manifest))
+	}
+	return true, nil
+}
@@ -0,0 +1,322 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/minio/cli"
+	json "github.com/minio/colorjson"
+	"github.com/trinet2005/oss-mc/pkg/probe"
+	"github.com/trinet2005/oss-pkg/console"
+)
+
+var inventoryVerifyFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "format",
+		Usage: "manifest format, one of csv, jsonl or parquet. Guessed from the manifest file name by default",
+	},
+	cli.StringFlag{
+		Name:  "verify-key",
+		Usage: "require the manifest to be signed with the Ed25519 public key of a PEM file",
+	},
+}
+
+var inventoryVerifyCmd = cli.Command{
+	Name:            "verify",
+	Usage:           "compare a bucket or a folder with a manifest",
+	Action:          mainInventoryVerify,
+	Before:          setGlobalsFromContext,
+	Flags:           append(inventoryVerifyFlags, globalFlags...),
+	HideHelpCommand: true,
+	OnUsageError:    onUsageError,
+	CustomHelpTemplate: `NAME:
+  {{.HelpName}} - {{.Usage}}
+
+USAGE:
+  {{.HelpName}} [FLAGS] MANIFEST TARGET
+
+FLAGS:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}
+DESCRIPTION:
+  The latest versions listed in the manifest are compared with the objects in TARGET, by size
+  and by ETag or checksum. Local files are hashed to be compared with the recorded ETags and
+  checksums. The command exits with a non-zero status if any difference is found.
+
+  When the manifest comes with a signature file (MANIFEST.sig), the manifest is checked against its
+  SHA-256 digest first. With --verify-key, the signature is required and must match the public key.
+
+LEGEND:
+  - object is listed in the manifest but missing in the target.
+  + object is in the target but not listed in the manifest.
+  ! object differs from the manifest.
+
+EXAMPLES:
+  1. Verify a bucket against a manifest generated earlier.
+     {{.Prompt}} {{.HelpName}} mybucket.csv myminio/mybucket
+
+  2. Verify a restored local folder against the manifest of a bucket.
+     {{.Prompt}} {{.HelpName}} photos.parquet /mnt/restore/photos
+
+  3. Verify a bucket against a manifest signed with the private key of audit-pub.pem.
+     {{.Prompt}} {{.HelpName}} --verify-key audit-pub.pem mybucket.csv myminio/mybucket
+`,
+}
+
+// inventoryVerifyMessage - an entry which does not match the manifest.
+type inventoryVerifyMessage struct {
+	Status string `json:"status"`
+	Key    string `json:"key"`
+	Result string `json:"result"`
+	Diff   string `json:"diff,omitempty"`
+}
+
+// String colorized verification message.
+func (i inventoryVerifyMessage) String() string {
+	switch i.Result {
+	case "missing":
+		return console.Colorize("InventoryMissing", "- "+i.Key)
+	case "extra":
+		return console.Colorize("InventoryExtra", "+ "+i.Key)
+	}
+	return console.Colorize("InventoryChanged", "! "+i.Key+" ("+i.Diff+")")
+}
+
+// JSON jsonified verification message.
+func (i inventoryVerifyMessage) JSON() string {
+	i.Status = "success"
+	jsonBytes, e := json.MarshalIndent(i, "", " ")
+	fatalIf(probe.NewError(e), "Unable to marshal into JSON.")
+	return string(jsonBytes)
+}
+
+// inventoryVerifySummary - counts of a verification.
+type inventoryVerifySummary struct {
+	Status   string `json:"status"`
+	Manifest string `json:"manifest"`
+	URL      string `json:"url"`
+	Signed   bool   `json:"signed"`
+	Objects  int64  `json:"objects"`
+	Missing  int64  `json:"missing"`
+	Extra    int64  `json:"extra"`
+	Changed  int64  `json:"changed"`
+}
+
+// String colorized verification summary.
+func (i inventoryVerifySummary) String() string {
+	return console.Colorize("InventorySummary", fmt.Sprintf("Verified %d objects of `%s` in `%s`: %d missing, %d extra, %d changed.",
+		i.Objects, i.Manifest, i.URL, i.Missing, i.Extra, i.Changed))
+}
+
+// JSON jsonified verification summary.
+func (i inventoryVerifySummary) JSON() string {
+	i.Status = "success"
+	if i.Missing > 0 || i.Extra > 0 || i.Changed > 0 {
+		i.Status = "failure"
+	}
+	jsonBytes, e := json.MarshalIndent(i, "", " ")
+	fatalIf(probe.NewError(e), "Unable to marshal into JSON.")
+	return string(jsonBytes)
+}
+
+func checkInventoryVerifySyntax(cliCtx *cli.Context) {
+	if len(cliCtx.Args()) != 2 {
+		showCommandHelpAndExit(cliCtx, 1) // last argument is exit code
+	}
+}
+
+// inventoryContent returns the listing entry of a manifest record
+// as it would be found in the target.
+func inventoryContent(targetURL string, r inventoryRecord) *ClientContent {
+	content := &ClientContent{
+		URL:           *newClientURL(urlJoinPath(targetURL, r.Key)),
+		Size:          r.Size,
+		Time:          r.LastModified,
+		Type:          os.FileMode(0o644),
+		ETag:          r.ETag,
+		StorageClass:  r.StorageClass,
+		RetentionMode: r.RetentionMode,
+		LegalHold:     r.LegalHold,
+		VersionID:     r.VersionID,
+		Metadata:      map[string]string{},
+	}
+	if algo, value := r.checksum(); value != "" {
+		if checksum, err := parseChecksumAlgo(algo); err == nil && checksum.IsSet() {
+			content.Metadata[checksumMetadataKey(checksum)] = value
+		}
+	}
+	return content
+}
+
+// inventoryDifference compares an entry of the manifest with the target
+// entry of the same name and size, local files are hashed.
+func inventoryDifference(expected, actual *ClientContent, cache *checksumCache) differType {
+	if actual.URL.Type == fileSystem {
+		if etag := expected.ETag; isMD5ETag(etag) {
+			if sum, e := cache.Checksum(actual.URL.Path, checksumMD5); e == nil && !strings.EqualFold(sum, etag) {
+				return differInContent
+			}
+			return differInNone
+		}
+		for _, algo := range checksumAlgorithms {
+			value := expected.Metadata[checksumMetadataKey(algo)]
+			if value == "" || isCompositeChecksum(value) {
+				continue
+			}
+			if sum, e := cache.Checksum(actual.URL.Path, algo.String()); e == nil && sum != value {
+				return differInContent
+			}
+			break
+		}
+		return differInNone
+	}
+
+	// ETags of multipart or encrypted objects are not the MD5 recorded
+	// for local files.
+	if etag := strings.Trim(actual.ETag, "\""); isMD5ETag(expected.ETag) && isMD5ETag(etag) && !strings.EqualFold(expected.ETag, etag) {
+		return differInContent
+	}
+	for _, algo := range checksumAlgorithms {
+		key := checksumMetadataKey(algo)
+		value, actualValue := expected.Metadata[key], inventoryMetadata(actual, key)
+		if value != "" && actualValue != "" && value != actualValue {
+			return differInContent
+		}
+	}
+
+	// Only compare the attributes recorded in the manifest and reported
+	// by the target.
+	for _, attr := range [][2]string{
+		{expected.StorageClass, actual.StorageClass},
+		{expected.RetentionMode, inventoryMetadata(actual, AmzObjectLockMode)},
+		{expected.LegalHold, inventoryMetadata(actual, AmzObjectLockLegalHold)},
+	} {
+		if attr[0] != "" && attr[1] != "" && !strings.EqualFold(attr[0], attr[1]) {
+			return differInMetadata
+		}
+	}
+	return differInNone
+}
+
+// mainInventoryVerify is the handle for "mc inventory verify" command.
+func mainInventoryVerify(cliCtx *cli.Context) error {
+	ctx, cancelVerify := context.WithCancel(globalContext)
+	defer cancelVerify()
+
+	checkInventoryVerifySyntax(cliCtx)
+	setInventoryColors()
+
+	manifest := cliCtx.Args().Get(0)
+	format, err := inventoryFormat(cliCtx.String("format"), manifest)
+	fatalIf(err, "Unable to parse --format.")
+
+	var verifyKey ed25519.PublicKey
+	if keyFile := cliCtx.String("verify-key"); keyFile != "" {
+		verifyKey, err = loadInventoryVerifyingKey(keyFile)
+		fatalIf(err, "Unable to load the verification key.")
+	}
+	signed, err := verifyInventorySignature(manifest, verifyKey)
+	fatalIf(err, "Unable to verify the signature of the manifest `"+manifest+"`.")
+
+	// Only the latest versions are expected in the target.
+	var records []inventoryRecord
+	err = readInventory(manifest, format, func(r inventoryRecord) error {
+		if r.IsLatest && !r.IsDeleteMarker {
+			records = append(records, r)
+		}
+		return nil
+	})
+	fatalIf(err, "Unable to read the manifest `"+manifest+"`.")
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Key < records[j].Key
+	})
+
+	targetURL := cliCtx.Args().Get(1)
+	separator := string(newClientURL(targetURL).Separator)
+	if !strings.HasSuffix(targetURL, separator) {
+		targetURL += separator
+	}
+	clnt, err := newClient(targetURL)
+	fatalIf(err.Trace(targetURL), "Unable to initialize `"+targetURL+"`.")
+	rootURL := clnt.GetURL().String()
+
+	manifestCh := make(chan *ClientContent)
+	go func() {
+		defer close(manifestCh)
+		for _, r := range records {
+			select {
+			case <-ctx.Done():
+				return
+			case manifestCh <- inventoryContent(rootURL, r):
+			}
+		}
+	}()
+	targetCh := clnt.List(ctx, ListOptions{Recursive: true, WithMetadata: true, ShowDir: DirNone})
+
+	cache := loadChecksumCache()
+	defer func() {
+		errorIf(cache.Save().Trace(), "Unable to save the checksum cache.")
+	}()
+
+	var cErr error
+	summary := inventoryVerifySummary{Manifest: manifest, URL: cliCtx.Args().Get(1), Signed: signed, Objects: int64(len(records))}
+	for diffMsg := range difference(rootURL, manifestCh, rootURL, targetCh, false, true) {
+		if diffMsg.Error != nil {
+			errorIf(diffMsg.Error, "Unable to verify `"+targetURL+"`.")
+			cErr = exitStatus(globalErrorExitStatus)
+			continue
+		}
+
+		switch diffMsg.Diff {
+		case differInNone, differInAASourceMTime:
+			// Modification times are not reliable once copied,
+			// compare the content.
+			diffMsg.Diff = inventoryDifference(diffMsg.firstContent, diffMsg.secondContent, cache)
+		}
+
+		msg := inventoryVerifyMessage{Diff: diffMsg.Diff.String()}
+		switch diffMsg.Diff {
+		case differInNone:
+			continue
+		case differInFirst:
+			msg.Key, msg.Result, msg.Diff = diffMsg.FirstURL, "missing", ""
+			summary.Missing++
+		case differInSecond:
+			msg.Key, msg.Result, msg.Diff = diffMsg.SecondURL, "extra", ""
+			summary.Extra++
+		default:
+			msg.Key, msg.Result = diffMsg.SecondURL, "changed"
+			summary.Changed++
+		}
+		msg.Key = filepath.ToSlash(strings.TrimPrefix(msg.Key, rootURL))
+		printMsg(msg)
+	}
+
+	printMsg(summary)
+	if summary.Missing > 0 || summary.Extra > 0 || summary.Changed > 0 {
+		cErr = exitStatus(globalErrorExitStatus)
+	}
+	return cErr
+}
@@ -0,0 +1,188 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	goparquet "github.com/fraugster/parquet-go"
+	"github.com/fraugster/parquet-go/parquet"
+	. "gopkg.in/check.v1"
+)
+
+func (s *TestSuite) TestParquetInventorySchema(c *C) {
+	var buf bytes.Buffer
+	w, e := newInventoryWriter(&buf, inventoryFormatParquet)
+	c.Assert(e, IsNil)
+	c.Assert(w.Write(inventoryRecord{Key: "a", Size: 1, LastModified: time.Unix(1, 0)}), IsNil)
+	c.Assert(w.Close(), IsNil)
+
+	// Written with the columns and types other readers expect.
+	meta, e := goparquet.ReadFileMetaData(bytes.NewReader(buf.Bytes()), true)
+	c.Assert(e, IsNil)
+	c.Assert(meta.NumRows, Equals, int64(1))
+	var columns []string
+	for _, element := range meta.Schema[1:] {
+		columns = append(columns, element.Name)
+		switch element.Name {
+		case "lastModified":
+			c.Assert(element.GetLogicalType().IsSetTIMESTAMP(), Equals, true)
+			c.Assert(element.GetConvertedType(), Equals, parquet.ConvertedType_TIMESTAMP_MILLIS)
+		case "key":
+			c.Assert(element.GetLogicalType().IsSetSTRING(), Equals, true)
+			c.Assert(element.GetConvertedType(), Equals, parquet.ConvertedType_UTF8)
+		}
+	}
+	c.Assert(columns, DeepEquals, inventoryColumns)
+}
+
+func (s *TestSuite) TestInventoryFormats(c *C) {
+	records := []inventoryRecord{
+		{
+			Key: "a/b, \"quoted\".txt", VersionID: "v1", IsLatest: true, Size: 10,
+			LastModified: time.Date(2022, 1, 2, 3, 4, 5, 6000000, time.UTC),
+			ETag:         "d41d8cd98f00b204e9800998ecf8427e", Checksum: "CRC32C:AAAAAA==",
+			StorageClass: "STANDARD", RetentionMode: "GOVERNANCE",
+			RetainUntil: "2030-01-01T00:00:00Z", LegalHold: "ON",
+		},
+		{Key: "a/deleted", VersionID: "v2", IsDeleteMarker: true, LastModified: time.Unix(1, 0).UTC()},
+	}
+
+	dir := c.MkDir()
+	for _, format := range []string{inventoryFormatCSV, inventoryFormatJSONL, inventoryFormatParquet} {
+		fpath := filepath.Join(dir, "manifest."+format)
+		detected, err := inventoryFormat("", fpath)
+		c.Assert(err, IsNil)
+		c.Assert(detected, Equals, format)
+
+		f, e := os.Create(fpath)
+		c.Assert(e, IsNil)
+		w, e := newInventoryWriter(f, format)
+		c.Assert(e, IsNil)
+		for _, r := range records {
+			c.Assert(w.Write(r), IsNil)
+		}
+		c.Assert(w.Close(), IsNil)
+		c.Assert(f.Close(), IsNil)
+
+		var read []inventoryRecord
+		err = readInventory(fpath, format, func(r inventoryRecord) error {
+			read = append(read, r)
+			return nil
+		})
+		c.Assert(err, IsNil)
+		c.Assert(read, DeepEquals, records, Commentf("format %s", format))
+	}
+
+	_, err := inventoryFormat("xml", "")
+	c.Assert(err, NotNil)
+}
+
+func (s *TestSuite) TestInventoryDifference(c *C) {
+	dir := c.MkDir()
+	fpath := filepath.Join(dir, "file")
+	c.Assert(os.WriteFile(fpath, []byte("hello"), 0o644), IsNil)
+	local := &ClientContent{URL: *newClientURL(fpath), Size: 5}
+
+	cache := &checksumCache{entries: make(map[string]*checksumCacheEntry)}
+	expected := inventoryContent(dir, inventoryRecord{Key: "file", Size: 5, ETag: "5d41402abc4b2a76b9719d911017c592"})
+	c.Assert(inventoryDifference(expected, local, cache), Equals, differInNone)
+	expected = inventoryContent(dir, inventoryRecord{Key: "file", Size: 5, ETag: "00000000000000000000000000000000"})
+	c.Assert(inventoryDifference(expected, local, cache), Equals, differInContent)
+
+	remote := &ClientContent{URL: *newClientURL("https://s3.example.com/bucket/file"), ETag: "\"abc-2\"", StorageClass: "STANDARD"}
+	expected = inventoryContent("https://s3.example.com/bucket/", inventoryRecord{Key: "file", ETag: "abc-2", StorageClass: "STANDARD"})
+	c.Assert(inventoryDifference(expected, remote, cache), Equals, differInNone)
+	expected.StorageClass = "GLACIER"
+	c.Assert(inventoryDifference(expected, remote, cache), Equals, differInMetadata)
+	expected.ETag = "abc-3"
+	c.Assert(inventoryDifference(expected, remote, cache), Equals, differInMetadata)
+
+	// Manifests of local folders record the MD5 and no storage class.
+	expected = inventoryContent("https://s3.example.com/bucket/", inventoryRecord{Key: "file", ETag: "5d41402abc4b2a76b9719d911017c592"})
+	c.Assert(inventoryDifference(expected, remote, cache), Equals, differInNone)
+	remote.ETag = "\"00000000000000000000000000000000\""
+	c.Assert(inventoryDifference(expected, remote, cache), Equals, differInContent)
+}
+
+func (s *TestSuite) TestInventorySignature(c *C) {
+	dir := c.MkDir()
+	publicKey, privateKey, e := ed25519.GenerateKey(rand.Reader)
+	c.Assert(e, IsNil)
+	der, e := x509.MarshalPKCS8PrivateKey(privateKey)
+	c.Assert(e, IsNil)
+	privateFile := filepath.Join(dir, "key.pem")
+	c.Assert(os.WriteFile(privateFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600), IsNil)
+	der, e = x509.MarshalPKIXPublicKey(publicKey)
+	c.Assert(e, IsNil)
+	publicFile := filepath.Join(dir, "pub.pem")
+	c.Assert(os.WriteFile(publicFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600), IsNil)
+
+	signKey, err := loadInventorySigningKey(privateFile)
+	c.Assert(err, IsNil)
+	verifyKey, err := loadInventoryVerifyingKey(publicFile)
+	c.Assert(err, IsNil)
+	_, otherKey, e := ed25519.GenerateKey(rand.Reader)
+	c.Assert(e, IsNil)
+
+	manifest := filepath.Join(dir, "inventory.csv")
+	data := []byte(strings.Join(inventoryColumns, ",") + "\n")
+	digest := sha256.Sum256(data)
+	c.Assert(os.WriteFile(manifest, data, 0o644), IsNil)
+
+	// Unsigned manifests only pass without a key.
+	signed, err := verifyInventorySignature(manifest, nil)
+	c.Assert(err, IsNil)
+	c.Assert(signed, Equals, false)
+	_, err = verifyInventorySignature(manifest, verifyKey)
+	c.Assert(err, NotNil)
+
+	c.Assert(saveInventorySignature(manifest, newInventorySignature(digest[:], signKey)), IsNil)
+	signed, err = verifyInventorySignature(manifest, verifyKey)
+	c.Assert(err, IsNil)
+	c.Assert(signed, Equals, true)
+
+	// Signed with another key.
+	c.Assert(saveInventorySignature(manifest, newInventorySignature(digest[:], otherKey)), IsNil)
+	_, err = verifyInventorySignature(manifest, verifyKey)
+	c.Assert(err, NotNil)
+
+	// Digest only, the manifest is checked but not the signer.
+	c.Assert(saveInventorySignature(manifest, newInventorySignature(digest[:], nil)), IsNil)
+	_, err = verifyInventorySignature(manifest, nil)
+	c.Assert(err, IsNil)
+	_, err = verifyInventorySignature(manifest, verifyKey)
+	c.Assert(err, NotNil)
+
+	// Modified manifest.
+	c.Assert(saveInventorySignature(manifest, newInventorySignature(digest[:], signKey)), IsNil)
+	c.Assert(os.WriteFile(manifest, append(data, "a,1\n"...), 0o644), IsNil)
+	_, err = verifyInventorySignature(manifest, verifyKey)
+	c.Assert(err, NotNil)
+	_, err = verifyInventorySignature(manifest, nil)
+	c.Assert(err, NotNil)
+}
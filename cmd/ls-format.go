@@ -0,0 +1,266 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/trinet2005/oss-mc/pkg/probe"
+)
+
+// lsColumns - columns supported by --columns and their template.
+var lsColumns = map[string]string{
+	"time":          `{{date .Time}}`,
+	"size":          `{{size .Size}}`,
+	"key":           `{{.Key}}`,
+	"type":          `{{.Filetype}}`,
+	"etag":          `{{.ETag}}`,
+	"storage-class": `{{.StorageClass}}`,
+	"version-id":    `{{.VersionID}}`,
+	"version":       `v{{.VersionOrd}}`,
+	"delete-marker": `{{.IsDeleteMarker}}`,
+	"metadata":      `{{kv .Metadata}}`,
+	"tags":          `{{kv .Tags}}`,
+	"compression":   `{{.Compression}}`,
+}
+
+// lsMetadataFields - fields only known when listing with metadata.
+var lsMetadataFields = []string{".Metadata", ".Tags", ".Compression", ".StoredSize"}
+
+// lsSortKeys - keys supported by --sort.
+var lsSortKeys = []string{"name", "size", "time"}
+
+// lsFormat holds the custom output of ls, either a list of columns or a
+// template, and the order objects are printed in. Objects are buffered
+// until the listing is complete when sorting.
+type lsFormat struct {
+	columns []*template.Template
+	tmpl    *template.Template
+	sortBy  string
+	reverse bool
+	rawSize bool
+
+	// withMetadata is set if the output needs the metadata of objects.
+	withMetadata bool
+
+	msgs []contentMessage
+}
+
+// lsFormatMessage is a listed object printed with a custom format, the
+// JSON output is left unchanged.
+type lsFormatMessage struct {
+	contentMessage
+	text string
+}
+
+// String formatted string message.
+func (m lsFormatMessage) String() string {
+	return m.text
+}
+
+// newLsFormat returns the custom output of ls, nil if the default one
+// is requested.
+func newLsFormat(columns, format, sortBy string, reverse, rawSize bool) (*lsFormat, error) {
+	if columns == "" && format == "" && sortBy == "" && !reverse && !rawSize {
+		return nil, nil
+	}
+	if columns != "" && format != "" {
+		return nil, fmt.Errorf("--columns and --format are mutually exclusive")
+	}
+	f := &lsFormat{sortBy: sortBy, reverse: reverse, rawSize: rawSize}
+	if reverse && sortBy == "" {
+		f.sortBy = "name"
+	}
+	if f.sortBy != "" {
+		valid := false
+		for _, key := range lsSortKeys {
+			valid = valid || key == f.sortBy
+		}
+		if !valid {
+			return nil, fmt.Errorf("unknown sort key `%s`, expected one of %s", sortBy, strings.Join(lsSortKeys, ", "))
+		}
+	}
+	if columns != "" {
+		for _, column := range strings.Split(columns, ",") {
+			column = strings.TrimSpace(column)
+			text, ok := lsColumns[column]
+			switch {
+			case ok:
+			case strings.HasPrefix(column, "metadata."):
+				text = `{{meta .Metadata ` + strconv.Quote(strings.TrimPrefix(column, "metadata.")) + `}}`
+			case strings.HasPrefix(column, "tags."):
+				text = `{{index .Tags ` + strconv.Quote(strings.TrimPrefix(column, "tags.")) + `}}`
+			default:
+				return nil, fmt.Errorf("unknown column `%s`", column)
+			}
+			f.withMetadata = f.withMetadata || usesMetadata(text)
+			tmpl, e := f.parse(column, text)
+			if e != nil {
+				return nil, e
+			}
+			f.columns = append(f.columns, tmpl)
+		}
+	}
+	if format != "" {
+		tmpl, e := f.parse("format", format)
+		if e != nil {
+			return nil, e
+		}
+		f.tmpl = tmpl
+		f.withMetadata = usesMetadata(format)
+	}
+	return f, nil
+}
+
+// usesMetadata returns true if a template refers to fields only known
+// when listing with metadata.
+func usesMetadata(text string) bool {
+	for _, field := range lsMetadataFields {
+		if strings.Contains(text, field) {
+			return true
+		}
+	}
+	return false
+}
+
+// needsMetadata returns true if objects must be listed with metadata.
+func (f *lsFormat) needsMetadata() bool {
+	return f != nil && f.withMetadata
+}
+
+func (f *lsFormat) parse(name, text string) (*template.Template, error) {
+	return template.New(name).Option("missingkey=zero").Funcs(template.FuncMap{
+		"size": f.size,
+		"date": func(t time.Time) string { return t.Format(printDate) },
+		"meta": lookupMetadata,
+		"kv":   formatKeyValues,
+	}).Parse(text)
+}
+
+// size returns the size in bytes with --raw-size, human readable otherwise.
+func (f *lsFormat) size(size int64) string {
+	if f.rawSize {
+		return strconv.FormatInt(size, 10)
+	}
+	return strings.Join(strings.Fields(humanize.IBytes(uint64(size))), "")
+}
+
+// lookupMetadata returns the value of a metadata key regardless of its
+// case and of the user metadata prefix.
+func lookupMetadata(metadata map[string]string, key string) string {
+	for k, v := range metadata {
+		if strings.EqualFold(k, key) || strings.EqualFold(strings.TrimPrefix(strings.ToLower(k), "x-amz-meta-"), key) {
+			return v
+		}
+	}
+	return ""
+}
+
+// formatKeyValues formats a map as a query string sorted by key.
+func formatKeyValues(m map[string]string) string {
+	values := make(url.Values, len(m))
+	for k, v := range m {
+		values.Set(k, v)
+	}
+	return values.Encode()
+}
+
+// render formats a listed object, the columns are separated by tabs and
+// empty ones are printed as '-'.
+func (f *lsFormat) render(c contentMessage) (string, error) {
+	var buf bytes.Buffer
+	if f.tmpl != nil {
+		if e := f.tmpl.Execute(&buf, c); e != nil {
+			return "", e
+		}
+		return buf.String(), nil
+	}
+	cells := make([]string, 0, len(f.columns))
+	for _, column := range f.columns {
+		buf.Reset()
+		if e := column.Execute(&buf, c); e != nil {
+			return "", e
+		}
+		cell := buf.String()
+		if cell == "" {
+			cell = "-"
+		}
+		cells = append(cells, cell)
+	}
+	return strings.Join(cells, "\t"), nil
+}
+
+// print prints a listed object, or keeps it until flush when sorting.
+func (f *lsFormat) print(c contentMessage) {
+	if f.sortBy != "" {
+		f.msgs = append(f.msgs, c)
+		return
+	}
+	f.printMsg(c)
+}
+
+func (f *lsFormat) printMsg(c contentMessage) {
+	c.rawSize = f.rawSize
+	if f.tmpl == nil && f.columns == nil {
+		printMsg(c)
+		return
+	}
+	text, e := f.render(c)
+	if e != nil {
+		errorIf(probe.NewError(e).Trace(c.Key), "Unable to format `"+c.Key+"`.")
+		return
+	}
+	printMsg(lsFormatMessage{contentMessage: c, text: text})
+}
+
+// flush sorts and prints the objects kept so far.
+func (f *lsFormat) flush() {
+	for _, c := range f.sorted() {
+		f.printMsg(c)
+	}
+	f.msgs = nil
+}
+
+// sorted returns the objects kept so far in order, versions of an object
+// stay in their listing order.
+func (f *lsFormat) sorted() []contentMessage {
+	less := func(i, j int) bool {
+		a, b := f.msgs[i], f.msgs[j]
+		switch f.sortBy {
+		case "size":
+			return a.Size < b.Size
+		case "time":
+			return a.Time.Before(b.Time)
+		}
+		return a.Key < b.Key
+	}
+	if f.reverse {
+		sort.SliceStable(f.msgs, func(i, j int) bool { return less(j, i) })
+	} else {
+		sort.SliceStable(f.msgs, less)
+	}
+	return f.msgs
+}
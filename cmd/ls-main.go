@@ -60,6 +60,26 @@ var (
 			Name:  "zip",
 			Usage: "list files inside zip archive (MinIO servers only)",
 		},
+		cli.StringFlag{
+			Name:  "columns",
+			Usage: "print comma separated columns, separated by tabs (see COLUMNS)",
+		},
+		cli.StringFlag{
+			Name:  "format",
+			Usage: "print objects with a Go template (see COLUMNS)",
+		},
+		cli.StringFlag{
+			Name:  "sort",
+			Usage: "sort objects by 'name', 'size' or 'time'",
+		},
+		cli.BoolFlag{
+			Name:  "reverse",
+			Usage: "reverse the sort order",
+		},
+		cli.BoolFlag{
+			Name:  "raw-size",
+			Usage: "print sizes in bytes",
+		},
 	}
 )
 
@@ -80,6 +100,28 @@ USAGE:
 FLAGS:
   {{range .VisibleFlags}}{{.}}
   {{end}}
+COLUMNS:
+  --columns accepts the following names, --format accepts a Go template with the
+  fields and functions below.
+
+     time           --> Last modified time, {{"{{date .Time}}"}}.
+     size           --> Size, {{"{{size .Size}}"}} or {{"{{.Size}}"}} in bytes.
+     key            --> Object name, {{"{{.Key}}"}}.
+     type           --> 'file' or 'folder', {{"{{.Filetype}}"}}.
+     etag           --> ETag, {{"{{.ETag}}"}}.
+     storage-class  --> Storage class, {{"{{.StorageClass}}"}}.
+     version-id     --> Version identifier, {{"{{.VersionID}}"}}.
+     version        --> Version ordinal, {{"{{.VersionOrd}}"}}.
+     delete-marker  --> Whether the version is a delete marker, {{"{{.IsDeleteMarker}}"}}.
+     metadata       --> All metadata, {{"{{kv .Metadata}}"}}.
+     metadata.NAME  --> Metadata NAME, {{"{{meta .Metadata \"NAME\"}}"}}.
+     tags           --> All tags, {{"{{kv .Tags}}"}}.
+     tags.NAME      --> Tag NAME, {{"{{index .Tags \"NAME\"}}"}}.
+     compression    --> Codec of objects compressed by mc, {{"{{.Compression}}"}}, their size is the
+                        uncompressed size and {{"{{.StoredSize}}"}} the compressed size.
+
+  Metadata, tags and compression are only listed when requested.
+
 EXAMPLES:
   1. List buckets on Amazon S3 cloud storage.
      {{.Prompt}} {{.HelpName}} s3
@@ -112,6 +154,15 @@ EXAMPLES:
   
   10. List all objects on mybucket, for the GLACIER storage class
      {{.Prompt}} {{.HelpName}} --storage-class 'GLACIER' s3/mybucket 
+
+  11. List the ETag, storage class and size in bytes of all objects on mybucket.
+     {{.Prompt}} {{.HelpName}} --recursive --raw-size --columns key,etag,storage-class,size s3/mybucket
+
+  12. List the ten largest objects on mybucket.
+     {{.Prompt}} {{.HelpName}} --recursive --sort size --reverse s3/mybucket | head -10
+
+  13. List all versions on mybucket with their tag "project" using a template.
+     {{.Prompt}} {{.HelpName}} --versions --format '{{"{{.Key}} {{.VersionID}} {{index .Tags \"project\"}}"}}' s3/mybucket
 `,
 }
 
@@ -179,6 +230,9 @@ func checkListSyntax(cliCtx *cli.Context) ([]string, doListOptions) {
 	if listZip && (withOlderVersions || !timeRef.IsZero()) {
 		fatalIf(errInvalidArgument().Trace(args...), "Zip file listing can only be performed on the latest version")
 	}
+	format, e := newLsFormat(cliCtx.String("columns"), cliCtx.String("format"), cliCtx.String("sort"), cliCtx.Bool("reverse"), cliCtx.Bool("raw-size"))
+	fatalIf(probe.NewError(e), "Unable to parse output format.")
+
 	storageClasss := cliCtx.String("storage-class")
 	opts := doListOptions{
 		timeRef:           timeRef,
@@ -188,6 +242,7 @@ func checkListSyntax(cliCtx *cli.Context) ([]string, doListOptions) {
 		withOlderVersions: withOlderVersions,
 		listZip:           listZip,
 		filter:            storageClasss,
+		format:            format,
 	}
 	return args, opts
 }
@@ -208,6 +263,7 @@ func mainList(cliCtx *cli.Context) error {
 	console.SetColor("Time", color.New(color.FgGreen))
 	console.SetColor("Summarize", color.New(color.Bold))
 	console.SetColor("SC", color.New(color.FgBlue))
+	console.SetColor("Compression", color.New(color.FgHiYellow))
 
 	// check 'ls' cliCtx arguments.
 	args, opts := checkListSyntax(cliCtx)
@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -51,17 +52,30 @@ type contentMessage struct {
 	VersionIndex   int    `json:"versionIndex,omitempty"`
 	IsDeleteMarker bool   `json:"isDeleteMarker,omitempty"`
 	StorageClass   string `json:"storageClass,omitempty"`
+	StoredSize     int64  `json:"storedSize,omitempty"`
+	Compression    string `json:"compression,omitempty"`
 
 	Metadata map[string]string `json:"metadata,omitempty"`
 	Tags     map[string]string `json:"tags,omitempty"`
+
+	// Print the exact size in bytes.
+	rawSize bool
 }
 
 // String colorized string message.
 func (c contentMessage) String() string {
 	message := console.Colorize("Time", fmt.Sprintf("[%s]", c.Time.Format(printDate)))
-	message += console.Colorize("Size", fmt.Sprintf("%7s", strings.Join(strings.Fields(humanize.IBytes(uint64(c.Size))), "")))
+	size := strings.Join(strings.Fields(humanize.IBytes(uint64(c.Size))), "")
+	if c.rawSize {
+		size = strconv.FormatInt(c.Size, 10)
+	}
+	message += console.Colorize("Size", fmt.Sprintf("%7s", size))
 	fileDesc := ""
 
+	if c.Compression != "" {
+		message += " " + console.Colorize("Compression", compressionDescription(c.Size, c.StoredSize, c.Compression))
+	}
+
 	if c.StorageClass != "" {
 		message += " " + console.Colorize("SC", c.StorageClass)
 	}
@@ -139,8 +153,26 @@ func generateContentMessages(clntURL ClientURL, ctnts []*ClientContent, printAll
 		}()
 
 		contentMsg.Size = c.Size
+		// Objects compressed client-side are listed with their
+		// uncompressed size, the stored size is shown alongside.
+		if codec := compressionCodec(c); codec != "" {
+			contentMsg.Compression = codec
+			contentMsg.StoredSize = c.Size
+			if size := uncompressedSize(c); size >= 0 {
+				contentMsg.Size = size
+			}
+		}
 		contentMsg.StorageClass = c.StorageClass
 		contentMsg.Metadata = c.Metadata
+		if len(c.UserMetadata) > 0 {
+			contentMsg.Metadata = make(map[string]string, len(c.Metadata)+len(c.UserMetadata))
+			for k, v := range c.Metadata {
+				contentMsg.Metadata[k] = v
+			}
+			for k, v := range c.UserMetadata {
+				contentMsg.Metadata[k] = v
+			}
+		}
 		contentMsg.Tags = c.Tags
 
 		md5sum := strings.TrimPrefix(c.ETag, "\"")
@@ -181,11 +213,17 @@ func sortObjectVersions(ctntVersions []*ClientContent) {
 type summaryMessage struct {
 	TotalObjects int64 `json:"totalObjects"`
 	TotalSize    int64 `json:"totalSize"`
+
+	rawSize bool
 }
 
 // String colorized string message
 func (s summaryMessage) String() string {
-	msg := console.Colorize("Summarize", fmt.Sprintf("\nTotal Size: %s", humanize.IBytes(uint64(s.TotalSize))))
+	size := humanize.IBytes(uint64(s.TotalSize))
+	if s.rawSize {
+		size = strconv.FormatInt(s.TotalSize, 10)
+	}
+	msg := console.Colorize("Summarize", fmt.Sprintf("\nTotal Size: %s", size))
 	msg += "\n" + console.Colorize("Summarize", fmt.Sprintf("Total Objects: %d", s.TotalObjects))
 	return msg
 }
@@ -198,10 +236,14 @@ func (s summaryMessage) JSON() string {
 }
 
 // Pretty print the list of versions belonging to one object
-func printObjectVersions(clntURL ClientURL, ctntVersions []*ClientContent, printAllVersions bool) {
+func printObjectVersions(clntURL ClientURL, ctntVersions []*ClientContent, o doListOptions) {
 	sortObjectVersions(ctntVersions)
-	msgs := generateContentMessages(clntURL, ctntVersions, printAllVersions)
+	msgs := generateContentMessages(clntURL, ctntVersions, o.withOlderVersions)
 	for _, msg := range msgs {
+		if o.format != nil {
+			o.format.print(msg)
+			continue
+		}
 		printMsg(msg)
 	}
 }
@@ -214,6 +256,7 @@ type doListOptions struct {
 	withOlderVersions bool
 	listZip           bool
 	filter            string
+	format            *lsFormat
 }
 
 // doList - list all entities inside a folder.
@@ -232,6 +275,7 @@ func doList(ctx context.Context, clnt Client, o doListOptions) error {
 		TimeRef:           o.timeRef,
 		WithOlderVersions: o.withOlderVersions || !o.timeRef.IsZero(),
 		WithDeleteMarkers: true,
+		WithMetadata:      o.format.needsMetadata(),
 		ShowDir:           DirNone,
 		ListZip:           o.listZip,
 	}) {
@@ -247,7 +291,7 @@ func doList(ctx context.Context, clnt Client, o doListOptions) error {
 
 		if lastPath != content.URL.Path {
 			// Print any object in the current list before reinitializing it
-			printObjectVersions(clnt.GetURL(), perObjectVersions, o.withOlderVersions)
+			printObjectVersions(clnt.GetURL(), perObjectVersions, o)
 			lastPath = content.URL.Path
 			perObjectVersions = []*ClientContent{}
 		}
@@ -257,12 +301,16 @@ func doList(ctx context.Context, clnt Client, o doListOptions) error {
 		totalObjects++
 	}
 
-	printObjectVersions(clnt.GetURL(), perObjectVersions, o.withOlderVersions)
+	printObjectVersions(clnt.GetURL(), perObjectVersions, o)
+	if o.format != nil {
+		o.format.flush()
+	}
 
 	if o.isSummary {
 		printMsg(summaryMessage{
 			TotalObjects: totalObjects,
 			TotalSize:    totalSize,
+			rawSize:      o.format != nil && o.format.rawSize,
 		})
 	}
 
@@ -16,3 +16,90 @@
 // along with this program.  If not, see <http://www.gnu.org/licenses/>.
 
 package cmd
+
+import (
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *TestSuite) TestLsFormat(c *C) {
+	msg := contentMessage{
+		Key:          "dir/object",
+		Size:         2048,
+		ETag:         "abc",
+		Time:         time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		StorageClass: "STANDARD",
+		Metadata:     map[string]string{"X-Amz-Meta-Owner": "jane", "Content-Type": "text/plain"},
+		Tags:         map[string]string{"b": "2", "a": "1 2"},
+	}
+
+	f, e := newLsFormat("", "", "", false, false)
+	c.Assert(e, IsNil)
+	c.Assert(f, IsNil)
+	c.Assert(f.needsMetadata(), Equals, false)
+
+	// Objects are only listed with metadata when it is printed.
+	for _, args := range [][]string{{"key,size", "", "false"}, {"", "{{.Key}}", "false"}, {"metadata.owner", "", "true"}, {"tags", "", "true"}, {"key,compression", "", "true"}, {"", "{{.StoredSize}}", "true"}} {
+		f, e = newLsFormat(args[0], args[1], "", false, false)
+		c.Assert(e, IsNil)
+		c.Assert(f.needsMetadata(), Equals, args[2] == "true", Commentf("%v", args))
+	}
+
+	testCases := []struct {
+		columns, format string
+		rawSize         bool
+		expected        string
+	}{
+		{columns: "key,size,etag", expected: "dir/object\t2.0KiB\tabc"},
+		{columns: "key, size", rawSize: true, expected: "dir/object\t2048"},
+		{columns: "time,storage-class,version-id", expected: "2020-01-02 03:04:05 UTC\tSTANDARD\t-"},
+		{columns: "metadata.owner,metadata.content-type,tags.a,tags.c", expected: "jane\ttext/plain\t1 2\t-"},
+		{columns: "tags", expected: "a=1+2&b=2"},
+		{format: `{{.Key}} {{.Size}} {{index .Tags "b"}}`, expected: "dir/object 2048 2"},
+		{format: `{{size .Size}}`, rawSize: true, expected: "2048"},
+	}
+	for _, testCase := range testCases {
+		f, e := newLsFormat(testCase.columns, testCase.format, "", false, testCase.rawSize)
+		c.Assert(e, IsNil)
+		text, e := f.render(msg)
+		c.Assert(e, IsNil)
+		c.Assert(text, Equals, testCase.expected)
+	}
+
+	for _, args := range [][]string{{"key", "{{.Key}}", ""}, {"owner", "", ""}, {"", "{{.Key", ""}, {"", "", "color"}} {
+		_, e = newLsFormat(args[0], args[1], args[2], false, false)
+		c.Assert(e, NotNil)
+	}
+}
+
+func (s *TestSuite) TestLsFormatSort(c *C) {
+	msgs := []contentMessage{
+		{Key: "b", Size: 3, Time: time.Unix(1, 0)},
+		{Key: "a", Size: 2, Time: time.Unix(3, 0), VersionID: "v2"},
+		{Key: "a", Size: 1, Time: time.Unix(2, 0), VersionID: "v1"},
+		{Key: "c", Size: 1, Time: time.Unix(4, 0)},
+	}
+	testCases := []struct {
+		sortBy   string
+		reverse  bool
+		expected []string
+	}{
+		{"name", false, []string{"a/v2", "a/v1", "b/", "c/"}},
+		{"", true, []string{"c/", "b/", "a/v2", "a/v1"}},
+		{"size", false, []string{"a/v1", "c/", "a/v2", "b/"}},
+		{"time", true, []string{"c/", "a/v2", "a/v1", "b/"}},
+	}
+	for _, testCase := range testCases {
+		f, e := newLsFormat("", "", testCase.sortBy, testCase.reverse, false)
+		c.Assert(e, IsNil)
+		for _, msg := range msgs {
+			f.print(msg)
+		}
+		var keys []string
+		for _, msg := range f.sorted() {
+			keys = append(keys, msg.Key+"/"+msg.VersionID)
+		}
+		c.Assert(keys, DeepEquals, testCase.expected)
+	}
+}
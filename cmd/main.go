@@ -427,6 +427,9 @@ var appCmds = []cli.Command{
 	mvCmd,
 	rmCmd,
 	mirrorCmd,
+	syncCmd,
+	serveCmd,
+	sessionCmd,
 	catCmd,
 	headCmd,
 	pipeCmd,
@@ -451,6 +454,8 @@ var appCmds = []cli.Command{
 	policyCmd,
 	tagCmd,
 	diffCmd,
+	inventoryCmd,
+	bucketCmd,
 	replicateCmd,
 	adminCmd,
 	idpCmd,
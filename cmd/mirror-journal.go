@@ -0,0 +1,439 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/cli"
+	"github.com/trinet2005/oss-mc/pkg/probe"
+	"github.com/trinet2005/oss-pkg/console"
+)
+
+// A mirror session keeps a header with the mirror command and the
+// listing marker, and an append-only journal of the keys mirrored after
+// the marker. Every completed key is written to the journal with a
+// single write, so that the journal survives the process being killed.
+
+const (
+	globalMirrorJournalVersion = "1"
+
+	// How often the header with the listing marker is rewritten.
+	mirrorJournalSaveInterval = time.Second
+)
+
+// mirrorJournalHeader - mirror session header.
+type mirrorJournalHeader struct {
+	Version      string              `json:"version"`
+	When         time.Time           `json:"time"`
+	RootPath     string              `json:"workingFolder"`
+	CommandArgs  []string            `json:"cmdArgs"`
+	CommandFlags map[string][]string `json:"cmdFlags"`
+	// All the keys up to and including the marker are mirrored.
+	Marker string `json:"marker"`
+	// Number of keys mirrored so far.
+	Completed int64 `json:"completed"`
+}
+
+// mirrorJournal - resumable mirror session.
+type mirrorJournal struct {
+	mutex     sync.Mutex
+	Header    *mirrorJournalHeader
+	SessionID string
+	journalFP *os.File
+
+	// Keys after the marker mirrored by a previous run.
+	done map[string]bool
+	// Keys queued in listing order, not yet covered by the marker.
+	pending []string
+	// Keys mirrored by this run, not yet covered by the marker.
+	completed map[string]bool
+	lastSave  time.Time
+}
+
+// String colorized mirror session message.
+func (j *mirrorJournal) String() string {
+	message := console.Colorize("SessionID", fmt.Sprintf("%s -> ", j.SessionID))
+	message = message + console.Colorize("SessionTime", fmt.Sprintf("[%s]", j.Header.When.Local().Format(printDate)))
+	message = message + console.Colorize("Command", fmt.Sprintf(" mirror %s", strings.Join(j.Header.CommandArgs, " ")))
+	return message + fmt.Sprintf(" (%d mirrored)", j.Header.Completed)
+}
+
+// JSON jsonified mirror session message.
+func (j *mirrorJournal) JSON() string {
+	sessionMsg := sessionMessage{
+		Status:      "success",
+		SessionID:   j.SessionID,
+		Time:        j.Header.When.Local(),
+		CommandType: "mirror",
+		CommandArgs: j.Header.CommandArgs,
+	}
+	sessionBytes, e := json.MarshalIndent(sessionMsg, "", " ")
+	fatalIf(probe.NewError(e), "Unable to marshal into JSON.")
+
+	return string(sessionBytes)
+}
+
+// getMirrorJournalFile - get the header file of a mirror session.
+func getMirrorJournalFile(sid string) (string, *probe.Error) {
+	sessionDir, err := getSessionDir()
+	if err != nil {
+		return "", err.Trace()
+	}
+	return filepath.Join(sessionDir, sid+".mirror"), nil
+}
+
+// getMirrorJournalDataFile - get the journal file of a mirror session.
+func getMirrorJournalDataFile(sid string) (string, *probe.Error) {
+	sessionDir, err := getSessionDir()
+	if err != nil {
+		return "", err.Trace()
+	}
+	return filepath.Join(sessionDir, sid+".journal"), nil
+}
+
+// isMirrorJournalExists verifies if given mirror session exists.
+func isMirrorJournalExists(sid string) bool {
+	journalFile, err := getMirrorJournalFile(sid)
+	fatalIf(err.Trace(sid), "Unable to determine mirror session filename for `"+sid+"`.")
+
+	_, e := os.Stat(journalFile)
+	return e == nil
+}
+
+// getMirrorJournalIDs - get all mirror sessions.
+func getMirrorJournalIDs() (sids []string) {
+	sessionDir, err := getSessionDir()
+	fatalIf(err.Trace(), "Unable to access session folder.")
+
+	journalList, e := filepath.Glob(sessionDir + "/*.mirror")
+	fatalIf(probe.NewError(e), "Unable to access session folder `"+sessionDir+"`.")
+
+	for _, path := range journalList {
+		sids = append(sids, strings.TrimSuffix(filepath.Base(path), ".mirror"))
+	}
+	return sids
+}
+
+// captureMirrorFlags records the mirror flags set on the command line,
+// global flags are taken from the command resuming the session.
+func captureMirrorFlags(cliCtx *cli.Context) map[string][]string {
+	flags := make(map[string][]string)
+	for _, f := range append(append(append(append(mirrorFlags, ioFlags...), cseFlags...), compressFlags...), downloadFlags...) {
+		name := strings.TrimSpace(strings.Split(f.GetName(), ",")[0])
+		if name == "resume" || name == "checkpoint" || !cliCtx.IsSet(name) {
+			continue
+		}
+		switch f.(type) {
+		case cli.BoolFlag:
+			flags[name] = []string{strconv.FormatBool(cliCtx.Bool(name))}
+		case cli.StringSliceFlag:
+			flags[name] = cliCtx.StringSlice(name)
+		case cli.IntFlag:
+			flags[name] = []string{strconv.Itoa(cliCtx.Int(name))}
+		default:
+			flags[name] = []string{cliCtx.String(name)}
+		}
+	}
+	return flags
+}
+
+// newMirrorJournal creates a new mirror session for the command.
+func newMirrorJournal(sid string, cliCtx *cli.Context) (*mirrorJournal, *probe.Error) {
+	rootPath, e := os.Getwd()
+	if e != nil {
+		return nil, probe.NewError(e)
+	}
+
+	j := &mirrorJournal{
+		Header: &mirrorJournalHeader{
+			Version:      globalMirrorJournalVersion,
+			When:         UTCNow(),
+			RootPath:     rootPath,
+			CommandArgs:  cliCtx.Args(),
+			CommandFlags: captureMirrorFlags(cliCtx),
+		},
+		SessionID: sid,
+		done:      make(map[string]bool),
+		completed: make(map[string]bool),
+	}
+	if err := j.openJournal(nil); err != nil {
+		return nil, err.Trace(sid)
+	}
+	if err := j.Save(); err != nil {
+		j.Delete()
+		return nil, err.Trace(sid)
+	}
+	return j, nil
+}
+
+// loadMirrorJournalHeader reads the header of a mirror session.
+func loadMirrorJournalHeader(sid string) (*mirrorJournalHeader, *probe.Error) {
+	journalFile, err := getMirrorJournalFile(sid)
+	if err != nil {
+		return nil, err.Trace(sid)
+	}
+	data, e := os.ReadFile(journalFile)
+	if e != nil {
+		return nil, probe.NewError(e)
+	}
+	header := &mirrorJournalHeader{}
+	if e = json.Unmarshal(data, header); e != nil {
+		return nil, probe.NewError(e).Trace(sid)
+	}
+	if header.Version != globalMirrorJournalVersion {
+		msg := fmt.Sprintf("Mirror session version %s does not match mc mirror session version %s.",
+			header.Version, globalMirrorJournalVersion)
+		return nil, probe.NewError(errors.New(msg)).Trace(sid, header.Version)
+	}
+	return header, nil
+}
+
+// loadMirrorJournal loads a mirror session, keys journaled before the
+// marker are dropped from the journal.
+func loadMirrorJournal(sid string) (*mirrorJournal, *probe.Error) {
+	header, err := loadMirrorJournalHeader(sid)
+	if err != nil {
+		return nil, err.Trace(sid)
+	}
+
+	j := &mirrorJournal{
+		Header:    header,
+		SessionID: sid,
+		done:      make(map[string]bool),
+		completed: make(map[string]bool),
+	}
+
+	dataFile, err := getMirrorJournalDataFile(sid)
+	if err != nil {
+		return nil, err.Trace(sid)
+	}
+	data, e := os.ReadFile(dataFile)
+	if e != nil && !os.IsNotExist(e) {
+		return nil, probe.NewError(e)
+	}
+	var keys []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var key string
+		// A partially written last entry does not parse and is
+		// skipped, that key is mirrored again.
+		if json.Unmarshal(scanner.Bytes(), &key) != nil {
+			continue
+		}
+		if key > header.Marker && !j.done[key] {
+			j.done[key] = true
+			keys = append(keys, key)
+		}
+	}
+	if err = j.openJournal(keys); err != nil {
+		return nil, err.Trace(sid)
+	}
+	return j, nil
+}
+
+// openJournal rewrites the journal with the given keys and opens it
+// for appending.
+func (j *mirrorJournal) openJournal(keys []string) *probe.Error {
+	dataFile, err := getMirrorJournalDataFile(j.SessionID)
+	if err != nil {
+		return err.Trace()
+	}
+
+	var buf bytes.Buffer
+	for _, key := range keys {
+		line, _ := json.Marshal(key)
+		buf.Write(append(line, '\n'))
+	}
+	tmpFile := dataFile + ".tmp"
+	if e := os.WriteFile(tmpFile, buf.Bytes(), 0o600); e != nil {
+		return probe.NewError(e)
+	}
+	if e := os.Rename(tmpFile, dataFile); e != nil {
+		return probe.NewError(e)
+	}
+
+	fp, e := os.OpenFile(dataFile, os.O_WRONLY|os.O_APPEND, 0o600)
+	if e != nil {
+		return probe.NewError(e)
+	}
+	j.journalFP = fp
+	return nil
+}
+
+// cliContext rebuilds the mirror command line of the session.
+func (j *mirrorJournal) cliContext(cliCtx *cli.Context) (*cli.Context, *probe.Error) {
+	set := flag.NewFlagSet(cliCtx.Command.Name, flag.ContinueOnError)
+	for _, f := range cliCtx.Command.Flags {
+		f.Apply(set)
+	}
+	if e := set.Parse(j.Header.CommandArgs); e != nil {
+		return nil, probe.NewError(e)
+	}
+	for name, values := range j.Header.CommandFlags {
+		for _, value := range values {
+			if e := set.Set(name, value); e != nil {
+				return nil, probe.NewError(e).Trace(name, value)
+			}
+		}
+	}
+	if e := set.Set("checkpoint", "true"); e != nil {
+		return nil, probe.NewError(e)
+	}
+
+	// Local paths are relative to the folder of the original command.
+	if e := os.Chdir(j.Header.RootPath); e != nil {
+		return nil, probe.NewError(e)
+	}
+
+	newCtx := cli.NewContext(cliCtx.App, set, cliCtx.Parent())
+	newCtx.Command = cliCtx.Command
+	return newCtx, nil
+}
+
+// IsDone returns true if the key was mirrored by a previous run.
+func (j *mirrorJournal) IsDone(key string) bool {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return j.done[key]
+}
+
+// Queue records that the key, the next one in listing order,
+// is about to be mirrored.
+func (j *mirrorJournal) Queue(key string) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.pending = append(j.pending, key)
+}
+
+// Done journals a mirrored key and moves the marker past all the
+// keys mirrored so far.
+func (j *mirrorJournal) Done(key string) *probe.Error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	line, e := json.Marshal(key)
+	if e != nil {
+		return probe.NewError(e)
+	}
+	if _, e = j.journalFP.Write(append(line, '\n')); e != nil {
+		return probe.NewError(e)
+	}
+
+	j.Header.Completed++
+	j.completed[key] = true
+	for len(j.pending) > 0 && j.completed[j.pending[0]] {
+		delete(j.completed, j.pending[0])
+		j.Header.Marker = j.pending[0]
+		j.pending = j.pending[1:]
+	}
+
+	if time.Since(j.lastSave) < mirrorJournalSaveInterval {
+		return nil
+	}
+	return j.save()
+}
+
+// Save the session header and flush the journal.
+func (j *mirrorJournal) Save() *probe.Error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return j.save()
+}
+
+func (j *mirrorJournal) save() *probe.Error {
+	if e := j.journalFP.Sync(); e != nil {
+		return probe.NewError(e)
+	}
+
+	data, e := json.MarshalIndent(j.Header, "", "\t")
+	if e != nil {
+		return probe.NewError(e)
+	}
+	journalFile, err := getMirrorJournalFile(j.SessionID)
+	if err != nil {
+		return err.Trace(j.SessionID)
+	}
+
+	// Write to a temporary file and rename, so that a crash
+	// never leaves a partially written header behind.
+	tmpFile := journalFile + ".tmp"
+	if e = os.WriteFile(tmpFile, data, 0o600); e != nil {
+		return probe.NewError(e)
+	}
+	if e = os.Rename(tmpFile, journalFile); e != nil {
+		return probe.NewError(e)
+	}
+	j.lastSave = time.Now()
+	return nil
+}
+
+// Close saves the session and closes the journal.
+func (j *mirrorJournal) Close() *probe.Error {
+	if err := j.Save(); err != nil {
+		return err.Trace(j.SessionID)
+	}
+	if e := j.journalFP.Close(); e != nil {
+		return probe.NewError(e)
+	}
+	return nil
+}
+
+// Delete removes all the session files.
+func (j *mirrorJournal) Delete() *probe.Error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	if j.journalFP != nil {
+		// Ignore any error, the journal may be closed already.
+		j.journalFP.Close()
+	}
+	return removeMirrorJournal(j.SessionID)
+}
+
+// removeMirrorJournal removes the files of a mirror session.
+func removeMirrorJournal(sid string) *probe.Error {
+	dataFile, err := getMirrorJournalDataFile(sid)
+	if err != nil {
+		return err.Trace(sid)
+	}
+	if e := os.Remove(dataFile); e != nil && !os.IsNotExist(e) {
+		return probe.NewError(e)
+	}
+
+	journalFile, err := getMirrorJournalFile(sid)
+	if err != nil {
+		return err.Trace(sid)
+	}
+	if e := os.Remove(journalFile); e != nil {
+		return probe.NewError(e)
+	}
+	return nil
+}
@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"math/rand"
 	"net/http"
+	"os"
 	"path"
 	"path/filepath"
 	"runtime"
@@ -71,6 +72,10 @@ var (
 			Name:  "remove",
 			Usage: "remove extraneous object(s) on target",
 		},
+		cli.BoolFlag{
+			Name:  "detect-moves",
+			Usage: "copy object(s) moved or renamed on source server-side on target instead of uploading them again, requires --remove",
+		},
 		cli.StringFlag{
 			Name:  "region",
 			Usage: "specify region when creating new bucket(s) on target",
@@ -84,6 +89,22 @@ var (
 			Name:  "md5",
 			Usage: "force all upload(s) to calculate md5sum checksum",
 		},
+		cli.StringFlag{
+			Name:  "checksum",
+			Usage: "verify transferred object(s) end-to-end with an additional checksum (CRC32C, CRC32, SHA1, SHA256), multipart uploads only support CRC32C",
+		},
+		cli.BoolFlag{
+			Name:  "checkpoint",
+			Usage: "journal mirrored object(s) on disk so that an interrupted mirror can be resumed",
+		},
+		cli.StringFlag{
+			Name:  "resume",
+			Usage: "resume an interrupted mirror session by its ID, see `mc session list`",
+		},
+		cli.BoolFlag{
+			Name:  "compare-content",
+			Usage: "compare object(s) by content (ETag/MD5 or additional checksum) instead of modtime, local checksums are cached",
+		},
 		cli.BoolFlag{
 			Name:   "multi-master",
 			Usage:  "enable multi-master multi-site setup",
@@ -135,7 +156,7 @@ var mirrorCmd = cli.Command{
 	Action:       mainMirror,
 	OnUsageError: onUsageError,
 	Before:       setGlobalsFromContext,
-	Flags:        append(append(mirrorFlags, ioFlags...), globalFlags...),
+	Flags:        append(append(append(append(append(mirrorFlags, ioFlags...), cseFlags...), compressFlags...), downloadFlags...), globalFlags...),
 	CustomHelpTemplate: `NAME:
   {{.HelpName}} - {{.Usage}}
 
@@ -202,6 +223,34 @@ EXAMPLES:
   16. Cross mirror between sites in a active-active deployment.
       Site-A: {{.Prompt}} {{.HelpName}} --active-active siteA siteB
       Site-B: {{.Prompt}} {{.HelpName}} --active-active siteB siteA
+
+  17. Mirror a local folder to MinIO cloud storage and verify every object end-to-end with a CRC32C checksum.
+      {{.Prompt}} {{.HelpName}} --checksum CRC32C backup/ play/archive
+
+  18. Mirror a local folder nightly, skipping files rewritten with identical content.
+      {{.Prompt}} {{.HelpName}} --overwrite --compare-content backup/ play/archive
+
+  19. Mirror a large bucket with a checkpoint journal, then resume it after an interruption.
+      {{.Prompt}} {{.HelpName}} --checkpoint s3/bigbucket play/bigbucket
+      {{.Prompt}} {{.HelpName}} --resume mirror-ab1b3fe2b5e0d6f0c7d1a3e7d5bb3c2f1e0a5d8c9b7a6f5e4d3c2b1a0f9e8d7c
+
+  20. Mirror a local folder encrypting every object client-side with a key file.
+      {{.Prompt}} {{.HelpName}} --cse-key ~/.mc/cse.key backup/ play/archive
+
+  21. Mirror a folder of text logs compressing every object with zstd.
+      {{.Prompt}} {{.HelpName}} --compress zstd /var/log/archive/ play/logs
+
+  22. Mirror a bucket of large objects locally, downloading 16 parts of every object concurrently.
+      {{.Prompt}} {{.HelpName}} --download-parallel 16 play/videos ./videos
+
+  23. Mirror a bucket to a local folder keeping object metadata and tags with the files, in a sidecar JSON file on filesystems without extended attributes.
+      {{.Prompt}} {{.HelpName}} -a --fs-metadata play/photos ./photos
+
+  24. Continuously mirror a local folder, files and folders renamed locally are moved on the target without uploading them again.
+      {{.Prompt}} {{.HelpName}} --watch --remove --detect-moves /var/lib/projects play/projects
+
+  25. Report the objects that would be moved on the target since the last mirror, without changing anything.
+      {{.Prompt}} {{.HelpName}} --remove --detect-moves --dry-run --json ~/photos play/photos
 `,
 }
 
@@ -255,6 +304,9 @@ type mirrorJob struct {
 	sourceURL string
 	targetURL string
 
+	// moves of watched local files, with --detect-moves
+	moves *mirrorMoveTracker
+
 	opts mirrorOptions
 }
 
@@ -288,7 +340,7 @@ func (mj *mirrorJob) doCreateBucket(ctx context.Context, sURLs URLs) URLs {
 	}
 
 	// Construct proper path with alias.
-	aliasedURL := filepath.Join(sURLs.TargetAlias, sURLs.TargetContent.URL.Path)
+	aliasedURL := aliasPath(sURLs.TargetAlias, sURLs.TargetContent.URL.Path)
 	clnt, pErr := newClient(aliasedURL)
 	if pErr != nil {
 		return sURLs.WithError(pErr)
@@ -308,7 +360,7 @@ func (mj *mirrorJob) doDeleteBucket(ctx context.Context, sURLs URLs) URLs {
 	}
 
 	// Construct proper path with alias.
-	aliasedURL := filepath.Join(sURLs.TargetAlias, sURLs.TargetContent.URL.Path)
+	aliasedURL := aliasPath(sURLs.TargetAlias, sURLs.TargetContent.URL.Path)
 	clnt, pErr := newClient(aliasedURL)
 	if pErr != nil {
 		return sURLs.WithError(pErr)
@@ -334,7 +386,7 @@ func (mj *mirrorJob) doRemove(ctx context.Context, sURLs URLs) URLs {
 	}
 
 	// Construct proper path with alias.
-	targetWithAlias := filepath.Join(sURLs.TargetAlias, sURLs.TargetContent.URL.Path)
+	targetWithAlias := aliasPath(sURLs.TargetAlias, sURLs.TargetContent.URL.Path)
 	clnt, pErr := newClient(targetWithAlias)
 	if pErr != nil {
 		return sURLs.WithError(pErr)
@@ -455,8 +507,8 @@ func (mj *mirrorJob) doMirror(ctx context.Context, sURLs URLs) URLs {
 	// Initialize additional target user metadata.
 	sURLs.TargetContent.UserMetadata = mj.opts.userMetadata
 
-	sourcePath := filepath.ToSlash(filepath.Join(sourceAlias, sourceURL.Path))
-	targetPath := filepath.ToSlash(filepath.Join(targetAlias, targetURL.Path))
+	sourcePath := filepath.ToSlash(aliasPath(sourceAlias, sourceURL.Path))
+	targetPath := filepath.ToSlash(aliasPath(targetAlias, targetURL.Path))
 	mj.status.PrintMsg(mirrorMessage{
 		Source:     sourcePath,
 		Target:     targetPath,
@@ -466,6 +518,10 @@ func (mj *mirrorJob) doMirror(ctx context.Context, sURLs URLs) URLs {
 	})
 	sURLs.MD5 = mj.opts.md5
 	sURLs.DisableMultipart = mj.opts.disableMultipart
+	sURLs.Checksum = mj.opts.checksum
+	sURLs.cse = mj.opts.cse
+	sURLs.compress = mj.opts.compress
+	sURLs.download = mj.opts.download
 
 	now := time.Now()
 	ret := uploadSourceToTargetURL(ctx, sURLs, mj.status, mj.opts.encKeyDB, mj.opts.isMetadata, false)
@@ -535,11 +591,18 @@ func (mj *mirrorJob) monitorMirrorStatus(cancel context.CancelFunc) (errDuringMi
 			continue
 		}
 
-		if sURLs.SourceContent != nil {
+		if mj.opts.journal != nil && sURLs.journalKey != "" {
+			errorIf(mj.opts.journal.Done(sURLs.journalKey).Trace(sURLs.journalKey),
+				"Unable to save mirror session `%s`.", mj.opts.journal.SessionID)
+		}
+
+		if sURLs.movedFrom != nil {
+			// Moved server-side, nothing was uploaded.
+		} else if sURLs.SourceContent != nil {
 			mirrorTotalUploadedBytes.Add(float64(sURLs.SourceContent.Size))
 		} else if sURLs.TargetContent != nil {
 			// Construct user facing message and path.
-			targetPath := filepath.ToSlash(filepath.Join(sURLs.TargetAlias, sURLs.TargetContent.URL.Path))
+			targetPath := filepath.ToSlash(aliasPath(sURLs.TargetAlias, sURLs.TargetContent.URL.Path))
 			mj.status.PrintMsg(rmMessage{Key: targetPath})
 		}
 	}
@@ -547,21 +610,60 @@ func (mj *mirrorJob) monitorMirrorStatus(cancel context.CancelFunc) (errDuringMi
 	return
 }
 
+// watchSourceURL returns the alias and the expanded URL of the watched
+// source, the absolute path of a local source.
+func (mj *mirrorJob) watchSourceURL() (sourceAlias, sourceURLFull string) {
+	// It will change the expanded alias back to the alias
+	// again, by replacing the sourceUrlFull with the sourceAlias.
+	// This url will be used to mirror.
+	sourceAlias, sourceURLFull, _ = mustExpandAlias(mj.sourceURL)
+
+	// If the passed source URL points to fs, fetch the absolute src path
+	// to correctly calculate targetPath
+	if sourceAlias == "" {
+		tmpSrcURL, e := filepath.Abs(sourceURLFull)
+		if e == nil {
+			sourceURLFull = tmpSrcURL
+		}
+	}
+	return sourceAlias, sourceURLFull
+}
+
+// queueWatchRemove queues the removal of the target of a removed source path.
+func (mj *mirrorJob) queueWatchRemove(ctx context.Context, sourceAlias, targetPath string) {
+	// newClient needs the unexpanded  path, newCLientURL needs the expanded path
+	targetAlias, expandedTargetPath, _ := mustExpandAlias(targetPath)
+	mirrorURL := URLs{
+		SourceAlias:      sourceAlias,
+		SourceContent:    nil,
+		TargetAlias:      targetAlias,
+		TargetContent:    &ClientContent{URL: *newClientURL(expandedTargetPath)},
+		MD5:              mj.opts.md5,
+		DisableMultipart: mj.opts.disableMultipart,
+		encKeyDB:         mj.opts.encKeyDB,
+	}
+	mirrorURL.TotalCount = mj.status.GetCounts()
+	mirrorURL.TotalSize = mj.status.Get()
+	mj.parallel.queueTask(func() URLs {
+		return mj.doRemove(ctx, mirrorURL)
+	}, 0)
+}
+
+// flushWatchRemoves queues the removals held back for moves which are due.
+func (mj *mirrorJob) flushWatchRemoves(ctx context.Context) {
+	sourceAlias, sourceURLFull := mj.watchSourceURL()
+	for _, eventPath := range mj.moves.expired(time.Now()) {
+		sourceSuffix := strings.TrimPrefix(eventPath, sourceURLFull)
+		if matchExcludeOptions(mj.opts.excludeOptions, sourceSuffix) {
+			continue
+		}
+		mj.queueWatchRemove(ctx, sourceAlias, urlJoinPath(mj.targetURL, sourceSuffix))
+	}
+}
+
 func (mj *mirrorJob) watchMirrorEvents(ctx context.Context, events []EventInfo) {
 	for _, event := range events {
-		// It will change the expanded alias back to the alias
-		// again, by replacing the sourceUrlFull with the sourceAlias.
-		// This url will be used to mirror.
-		sourceAlias, sourceURLFull, _ := mustExpandAlias(mj.sourceURL)
-
-		// If the passed source URL points to fs, fetch the absolute src path
-		// to correctly calculate targetPath
-		if sourceAlias == "" {
-			tmpSrcURL, e := filepath.Abs(sourceURLFull)
-			if e == nil {
-				sourceURLFull = tmpSrcURL
-			}
-		}
+		sourceAlias, sourceURLFull := mj.watchSourceURL()
 		eventPath := event.Path
 		if runtime.GOOS == "darwin" {
 			// Strip the prefixes in the event path. Happens in darwin OS only
@@ -614,6 +716,16 @@ func (mj *mirrorJob) watchMirrorEvents(ctx context.Context, events []EventInfo)
 				// to avoid copying it.
 				continue
 			}
+			if mj.moves != nil {
+				if oldPath, ok := mj.moves.moved(eventPath); ok {
+					_, oldTargetPath, _ := mustExpandAlias(urlJoinPath(mj.targetURL, strings.TrimPrefix(oldPath, sourceURLFull)))
+					mirrorURL.movedFrom = &ClientContent{URL: *newClientURL(oldTargetPath), Size: event.Size}
+					mj.parallel.queueTask(func() URLs {
+						return mj.doMoveWatch(ctx, mirrorURL)
+					}, 0)
+					continue
+				}
+			}
 			mj.parallel.queueTask(func() URLs {
 				return mj.doMirrorWatch(ctx, targetPath, tgtSSE, mirrorURL)
 			}, mirrorURL.SourceContent.Size)
@@ -622,21 +734,16 @@ func (mj *mirrorJob) watchMirrorEvents(ctx context.Context, events []EventInfo)
 				// Ignore delete cascading delete events if cyclical.
 				continue
 			}
-			mirrorURL := URLs{
-				SourceAlias:      sourceAlias,
-				SourceContent:    nil,
-				TargetAlias:      targetAlias,
-				TargetContent:    &ClientContent{URL: *targetURL},
-				MD5:              mj.opts.md5,
-				DisableMultipart: mj.opts.disableMultipart,
-				encKeyDB:         mj.opts.encKeyDB,
+			if !mj.opts.isRemove && !mj.opts.activeActive {
+				continue
 			}
-			mirrorURL.TotalCount = mj.status.GetCounts()
-			mirrorURL.TotalSize = mj.status.Get()
-			if mirrorURL.TargetContent != nil && (mj.opts.isRemove || mj.opts.activeActive) {
-				mj.parallel.queueTask(func() URLs {
-					return mj.doRemove(ctx, mirrorURL)
-				}, 0)
+			if mj.moves == nil {
+				mj.queueWatchRemove(ctx, sourceAlias, targetPath)
+				continue
+			}
+			// Hold back removals, the files may show up under a new name.
+			for _, removedPath := range mj.moves.removed(eventPath, time.Now()) {
+				mj.queueWatchRemove(ctx, sourceAlias, urlJoinPath(mj.targetURL, strings.TrimPrefix(removedPath, sourceURLFull)))
 			}
 		} else if event.Type == notification.BucketCreatedAll {
 			mirrorURL := URLs{
@@ -665,8 +772,18 @@ func (mj *mirrorJob) watchMirrorEvents(ctx context.Context, events []EventInfo)
 func (mj *mirrorJob) watchMirror(ctx context.Context) {
 	defer mj.watcher.Stop()
 
+	// Removals held back for moves are queued once due.
+	var flushCh <-chan time.Time
+	if mj.moves != nil {
+		ticker := time.NewTicker(mirrorMoveDelay / 4)
+		defer ticker.Stop()
+		flushCh = ticker.C
+	}
+
 	for {
 		select {
+		case <-flushCh:
+			mj.flushWatchRemoves(ctx)
 		case events, ok := <-mj.watcher.Events():
 			if !ok {
 				return
@@ -699,6 +816,12 @@ func (mj *mirrorJob) watchURL(ctx context.Context, sourceClient Client) *probe.E
 
 // Fetch urls that need to be mirrored
 func (mj *mirrorJob) startMirror(ctx context.Context) {
+	if mj.opts.checksumCache != nil {
+		defer func() {
+			errorIf(mj.opts.checksumCache.Save().Trace(), "Unable to save checksum cache.")
+		}()
+	}
+
 	URLsCh := prepareMirrorURLs(ctx, mj.sourceURL, mj.targetURL, mj.opts)
 
 	for {
@@ -708,6 +831,11 @@ func (mj *mirrorJob) startMirror(ctx context.Context) {
 				return
 			}
 			if sURLs.Error != nil {
+				// Keys which failed are not mirrored, the
+				// session is not resumed past them.
+				if mj.opts.journal != nil && sURLs.journalKey != "" {
+					mj.opts.journal.Queue(sURLs.journalKey)
+				}
 				mj.statusCh <- sURLs
 				continue
 			}
@@ -733,7 +861,16 @@ func (mj *mirrorJob) startMirror(ctx context.Context) {
 			// Save totalSize.
 			sURLs.TotalSize = mj.status.Get()
 
-			if sURLs.SourceContent != nil {
+			if mj.opts.journal != nil && sURLs.journalKey != "" &&
+				(sURLs.SourceContent != nil || mj.opts.isRemove) {
+				mj.opts.journal.Queue(sURLs.journalKey)
+			}
+
+			if sURLs.movedFrom != nil {
+				mj.parallel.queueTask(func() URLs {
+					return mj.doMove(ctx, sURLs)
+				}, 0)
+			} else if sURLs.SourceContent != nil {
 				mj.parallel.queueTask(func() URLs {
 					return mj.doMirror(ctx, sURLs)
 				}, sURLs.SourceContent.Size)
@@ -860,7 +997,7 @@ func getEventPathURLWin(srcURL, eventPath string) string {
 }
 
 // runMirror - mirrors all buckets to another S3 server
-func runMirror(ctx context.Context, srcURL, dstURL string, cli *cli.Context, encKeyDB map[string][]prefixSSEPair) bool {
+func runMirror(ctx context.Context, srcURL, dstURL string, cli *cli.Context, encKeyDB map[string][]prefixSSEPair, journal *mirrorJournal) bool {
 	// Parse metadata.
 	userMetadata := make(map[string]string)
 	if cli.String("attr") != "" {
@@ -889,6 +1026,18 @@ func runMirror(ctx context.Context, srcURL, dstURL string, cli *cli.Context, enc
 	isOverwrite = isOverwrite || isMetadata
 	isFake := cli.Bool("fake") || cli.Bool("dry-run")
 
+	checksum, err := parseChecksumAlgo(cli.String("checksum"))
+	fatalIf(err.Trace(cli.String("checksum")), "Unable to parse --checksum.")
+
+	cse, err := getCSEKeys(cli)
+	fatalIf(err, "Unable to parse client-side encryption keys.")
+
+	compress, err := parseCompressCodec(cli.String("compress"))
+	fatalIf(err, "Unable to parse --compress.")
+
+	download, err := parseDownloadOptions(cli)
+	fatalIf(err, "Unable to parse ranged download options.")
+
 	mopts := mirrorOptions{
 		isFake:           isFake,
 		isRemove:         isRemove,
@@ -896,6 +1045,11 @@ func runMirror(ctx context.Context, srcURL, dstURL string, cli *cli.Context, enc
 		isWatch:          isWatch,
 		isMetadata:       isMetadata,
 		md5:              cli.Bool("md5"),
+		checksum:         checksum,
+		cse:              cse,
+		compress:         compress,
+		download:         download,
+		compareContent:   cli.Bool("compare-content"),
 		disableMultipart: cli.Bool("disable-multipart"),
 		excludeOptions:   cli.StringSlice("exclude"),
 		olderThan:        cli.String("older-than"),
@@ -904,11 +1058,24 @@ func runMirror(ctx context.Context, srcURL, dstURL string, cli *cli.Context, enc
 		userMetadata:     userMetadata,
 		encKeyDB:         encKeyDB,
 		activeActive:     isWatch,
+		journal:          journal,
+		detectMoves:      cli.Bool("detect-moves"),
+	}
+
+	if mopts.compareContent || mopts.detectMoves {
+		mopts.checksumCache = loadChecksumCache()
 	}
 
 	// Create a new mirror job and execute it
 	mj := newMirrorJob(srcURL, dstURL, mopts)
 
+	// Moves of local files are detected by their inode while watching.
+	if mopts.detectMoves && isWatch && srcClt.GetURL().Type == fileSystem {
+		mj.moves = newMirrorMoveTracker(mirrorMoveDelay)
+		_, sourceURLFull := mj.watchSourceURL()
+		errorIf(probe.NewError(mj.moves.index(sourceURLFull)), "Unable to index `%s` to detect moves.", srcURL)
+	}
+
 	preserve := cli.Bool("preserve")
 
 	createDstBuckets := dstClt.GetURL().Type == objectStorage && dstClt.GetURL().Path == string(dstClt.GetURL().Separator)
@@ -1015,6 +1182,16 @@ func mainMirror(cliCtx *cli.Context) error {
 	ctx, cancelMirror := context.WithCancel(globalContext)
 	defer cancelMirror()
 
+	// Restore the command line of an interrupted mirror session.
+	var journal *mirrorJournal
+	if sid := cliCtx.String("resume"); sid != "" {
+		var err *probe.Error
+		journal, err = loadMirrorJournal(sid)
+		fatalIf(err.Trace(sid), "Unable to load mirror session `%s`.", sid)
+		cliCtx, err = journal.cliContext(cliCtx)
+		fatalIf(err.Trace(sid), "Unable to resume mirror session `%s`.", sid)
+	}
+
 	// Parse encryption keys per command.
 	encKeyDB, err := getEncKeys(cliCtx)
 	fatalIf(err, "Unable to parse encryption keys.")
@@ -1022,6 +1199,18 @@ func mainMirror(cliCtx *cli.Context) error {
 	// check 'mirror' cli arguments.
 	srcURL, tgtURL := checkMirrorSyntax(ctx, cliCtx, encKeyDB)
 
+	// Create or resume the mirror session of this command.
+	if journal == nil && cliCtx.Bool("checkpoint") {
+		sid := getHash("mirror", os.Args[1:])
+		if isMirrorJournalExists(sid) {
+			journal, err = loadMirrorJournal(sid)
+			fatalIf(err.Trace(sid), "Unable to load mirror session `%s`.", sid)
+		} else {
+			journal, err = newMirrorJournal(sid, cliCtx)
+			fatalIf(err.Trace(sid), "Unable to create mirror session `%s`.", sid)
+		}
+	}
+
 	if prometheusAddress := cliCtx.String("monitoring-address"); prometheusAddress != "" {
 		http.Handle("/metrics", promhttp.Handler())
 		go func() {
@@ -1037,12 +1226,21 @@ func mainMirror(cliCtx *cli.Context) error {
 		case <-ctx.Done():
 			return exitStatus(globalErrorExitStatus)
 		default:
-			errorDetected := runMirror(ctx, srcURL, tgtURL, cliCtx, encKeyDB)
+			errorDetected := runMirror(ctx, srcURL, tgtURL, cliCtx, encKeyDB, journal)
 			if cliCtx.Bool("watch") || cliCtx.Bool("multi-master") || cliCtx.Bool("active-active") {
 				mirrorRestarts.Inc()
 				time.Sleep(time.Duration(r.Float64() * float64(2*time.Second)))
 				continue
 			}
+			if journal != nil {
+				if errorDetected || ctx.Err() != nil {
+					// Keep the session for the next run.
+					errorIf(journal.Close().Trace(journal.SessionID), "Unable to save mirror session.")
+					console.Errorln("Run `mc mirror --resume " + journal.SessionID + "` to resume mirroring.")
+				} else {
+					errorIf(journal.Delete().Trace(journal.SessionID), "Unable to remove mirror session.")
+				}
+			}
 			if errorDetected {
 				return exitStatus(globalErrorExitStatus)
 			}
@@ -0,0 +1,363 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	json "github.com/minio/colorjson"
+	"github.com/trinet2005/oss-mc/pkg/probe"
+	"github.com/trinet2005/oss-pkg/console"
+)
+
+// mirrorMoveDelay - how long the removal of a watched file is held back,
+// for the same file to show up under its new name when it was moved.
+const mirrorMoveDelay = 2 * time.Second
+
+// mirrorMoveMessage container for moves replayed on the target.
+type mirrorMoveMessage struct {
+	Status     string `json:"status"`
+	Type       string `json:"type"`
+	Source     string `json:"source"`
+	From       string `json:"from"`
+	Target     string `json:"target"`
+	Size       int64  `json:"size"`
+	TotalCount int64  `json:"totalCount"`
+	TotalSize  int64  `json:"totalSize"`
+}
+
+// String colorized move message
+func (m mirrorMoveMessage) String() string {
+	return console.Colorize("Mirror", fmt.Sprintf("`%s` -> `%s` (moved)", m.From, m.Target))
+}
+
+// JSON jsonified move message
+func (m mirrorMoveMessage) JSON() string {
+	m.Status = "success"
+	m.Type = "move"
+	msgBytes, e := json.MarshalIndent(m, "", " ")
+	fatalIf(probe.NewError(e), "Unable to marshal into JSON.")
+	return string(msgBytes)
+}
+
+// matchMirrorMoves pairs the objects to be copied with the objects to be
+// removed holding the same data, they were moved or renamed in the source.
+// Moves copy the removed object server-side to the new name instead of
+// uploading it again. An object to be removed is used for one move only.
+func matchMirrorMoves(copies, removals []URLs, same func(src, tgt *ClientContent) bool) (moves, restCopies, restRemovals []URLs) {
+	bySize := make(map[int64][]int)
+	for i, removal := range removals {
+		bySize[removal.TargetContent.Size] = append(bySize[removal.TargetContent.Size], i)
+	}
+	moved := make([]bool, len(removals))
+	for _, c := range copies {
+		found := false
+		for _, i := range bySize[c.SourceContent.Size] {
+			if moved[i] || !same(c.SourceContent, removals[i].TargetContent) {
+				continue
+			}
+			moved[i], found = true, true
+			c.movedFrom = removals[i].TargetContent
+			moves = append(moves, c)
+			break
+		}
+		if !found {
+			restCopies = append(restCopies, c)
+		}
+	}
+	for i, removal := range removals {
+		if !moved[i] {
+			restRemovals = append(restRemovals, removal)
+		}
+	}
+	return moves, restCopies, restRemovals
+}
+
+// doMove - replays a move of the source on the target, the object is
+// copied server-side to its new name and its old name removed. When the
+// old name cannot be copied, e.g. a watched file renamed before it was
+// uploaded, the object is uploaded to its new name instead.
+func (mj *mirrorJob) doMove(ctx context.Context, sURLs URLs) URLs {
+	if sURLs.Error != nil {
+		return sURLs.WithError(sURLs.Error.Trace())
+	}
+
+	targetAlias := sURLs.TargetAlias
+	from := sURLs.movedFrom.URL
+	to := sURLs.TargetContent.URL
+	fromPath := filepath.ToSlash(aliasPath(targetAlias, from.Path))
+	toPath := filepath.ToSlash(aliasPath(targetAlias, to.Path))
+	moveMsg := mirrorMoveMessage{
+		Source:     filepath.ToSlash(aliasPath(sURLs.SourceAlias, sURLs.SourceContent.URL.Path)),
+		From:       fromPath,
+		Target:     toPath,
+		Size:       sURLs.SourceContent.Size,
+		TotalCount: sURLs.TotalCount,
+		TotalSize:  sURLs.TotalSize,
+	}
+
+	if mj.opts.isFake {
+		mj.status.PrintMsg(moveMsg)
+		mj.status.Add(sURLs.SourceContent.Size)
+		mj.status.Update()
+		return sURLs.WithError(nil)
+	}
+
+	mj.status.SetCaption(from.String() + ":")
+	opts := CopyOptions{
+		srcSSE:           getSSE(fromPath, mj.opts.encKeyDB[targetAlias]),
+		tgtSSE:           getSSE(toPath, mj.opts.encKeyDB[targetAlias]),
+		metadata:         make(map[string]string),
+		disableMultipart: mj.opts.disableMultipart,
+		storageClass:     mj.opts.storageClass,
+	}
+	err := copySourceToTargetURL(ctx, targetAlias, to.String(), filepath.ToSlash(from.Path), sURLs.movedFrom.VersionID,
+		"", "", "", sURLs.SourceContent.Size, mj.status, opts)
+	if err != nil {
+		return mj.doMoveUpload(ctx, sURLs)
+	}
+	mj.status.PrintMsg(moveMsg)
+	return mj.removeMovedFrom(ctx, sURLs)
+}
+
+// doMoveUpload - uploads a moved object to its new name, when its old
+// name on the target cannot be copied, and removes the old name.
+func (mj *mirrorJob) doMoveUpload(ctx context.Context, sURLs URLs) URLs {
+	movedFrom := sURLs.movedFrom
+	sURLs.movedFrom = nil
+	sURLs = mj.doMirror(ctx, sURLs)
+	if sURLs.Error != nil {
+		return sURLs
+	}
+	sURLs.movedFrom = movedFrom
+	return mj.removeMovedFrom(ctx, sURLs)
+}
+
+// removeMovedFrom - removes the old name of a moved object on the target.
+func (mj *mirrorJob) removeMovedFrom(ctx context.Context, sURLs URLs) URLs {
+	movedFrom := sURLs.movedFrom
+	sURLs.movedFrom = nil
+	removed := mj.doRemove(ctx, URLs{TargetAlias: sURLs.TargetAlias, TargetContent: movedFrom})
+	if removed.Error != nil {
+		switch removed.Error.ToGoError().(type) {
+		case PathNotFound, ObjectMissing:
+			// The old name was never uploaded.
+			return sURLs.WithError(nil)
+		}
+	}
+	return sURLs.WithError(removed.Error)
+}
+
+// doMoveWatch - replays a move of a watched file on the target.
+func (mj *mirrorJob) doMoveWatch(ctx context.Context, sURLs URLs) URLs {
+	// adjust total, because we want to show progress of
+	// the item still queued to be moved.
+	mj.status.Add(sURLs.SourceContent.Size)
+	mj.status.SetTotal(mj.status.Get()).Update()
+	mj.status.AddCounts(1)
+	sURLs.TotalSize = mj.status.Get()
+	sURLs.TotalCount = mj.status.GetCounts()
+	if !mj.sameMovedContent(ctx, sURLs) {
+		return mj.doMoveUpload(ctx, sURLs)
+	}
+	return mj.doMove(ctx, sURLs)
+}
+
+// sameMovedContent returns true if the old name of a watched file moved
+// holds the same data on the target, an inode reused by a new file is not
+// a move.
+func (mj *mirrorJob) sameMovedContent(ctx context.Context, sURLs URLs) bool {
+	targetAlias := sURLs.TargetAlias
+	from := sURLs.movedFrom.URL
+	clnt, err := newClientFromAlias(targetAlias, from.String())
+	if err != nil {
+		return false
+	}
+	movedFrom, err := clnt.Stat(ctx, StatOptions{
+		sse: getSSE(filepath.ToSlash(aliasPath(targetAlias, from.Path)), mj.opts.encKeyDB[targetAlias]),
+	})
+	if err != nil {
+		return false
+	}
+	comparer := contentComparer{
+		sourceAlias: sURLs.SourceAlias,
+		targetAlias: targetAlias,
+		encKeyDB:    mj.opts.encKeyDB,
+		cache:       mj.opts.checksumCache,
+	}
+	return comparer.sameContent(ctx, sURLs.SourceContent, movedFrom)
+}
+
+type mirrorInode struct {
+	dev, ino uint64
+}
+
+// mirrorTrackedFile is the file last seen with an inode, a file moved
+// keeps its size and modification time.
+type mirrorTrackedFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+func newMirrorTrackedFile(path string, fi os.FileInfo) mirrorTrackedFile {
+	return mirrorTrackedFile{path: path, size: fi.Size(), modTime: fi.ModTime()}
+}
+
+// mirrorMoveTracker detects moves of watched local files by their inode.
+// The removal of a file is held back for a moment, when a file with the
+// same inode, size and modification time shows up under a new name it
+// was moved. Folders moved are
+// seen before their removal, the removal of their files is then skipped.
+type mirrorMoveTracker struct {
+	mutex     sync.Mutex
+	delay     time.Duration
+	inodes    map[string]mirrorInode
+	paths     map[mirrorInode]mirrorTrackedFile
+	pending   map[string]time.Time
+	movedAway map[string]bool
+}
+
+func newMirrorMoveTracker(delay time.Duration) *mirrorMoveTracker {
+	return &mirrorMoveTracker{
+		delay:     delay,
+		inodes:    make(map[string]mirrorInode),
+		paths:     make(map[mirrorInode]mirrorTrackedFile),
+		pending:   make(map[string]time.Time),
+		movedAway: make(map[string]bool),
+	}
+}
+
+// index records the inodes of all files below root.
+func (t *mirrorMoveTracker) index(root string) error {
+	return filepath.Walk(root, func(path string, fi os.FileInfo, e error) error {
+		if e != nil {
+			// Unreadable folders are not tracked.
+			return nil
+		}
+		if fi.Mode().IsRegular() {
+			t.add(path, fi)
+		}
+		return nil
+	})
+}
+
+func (t *mirrorMoveTracker) add(path string, fi os.FileInfo) {
+	dev, ino, ok := fileInode(fi)
+	if !ok {
+		return
+	}
+	inode := mirrorInode{dev: dev, ino: ino}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.inodes[path] = inode
+	t.paths[inode] = newMirrorTrackedFile(path, fi)
+}
+
+// moved returns the old name of a file created at path if it was moved
+// from a file whose removal is held back, the removal is then dropped.
+func (t *mirrorMoveTracker) moved(path string) (string, bool) {
+	fi, e := os.Stat(path)
+	if e != nil || !fi.Mode().IsRegular() {
+		return "", false
+	}
+	dev, ino, ok := fileInode(fi)
+	if !ok {
+		return "", false
+	}
+	inode := mirrorInode{dev: dev, ino: ino}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	tracked, ok := t.paths[inode]
+	t.inodes[path] = inode
+	t.paths[inode] = newMirrorTrackedFile(path, fi)
+	if !ok || tracked.path == path {
+		return "", false
+	}
+	if tracked.size != fi.Size() || !tracked.modTime.Equal(fi.ModTime()) {
+		// A new file given the inode of a removed one.
+		return "", false
+	}
+	old := tracked.path
+	if _, pending := t.pending[old]; pending {
+		delete(t.pending, old)
+	} else if _, e := os.Lstat(old); !os.IsNotExist(e) {
+		// Linked or still there, not a move.
+		return "", false
+	} else {
+		// The removal of the old name is yet to be seen.
+		t.movedAway[old] = true
+	}
+	delete(t.inodes, old)
+	return old, true
+}
+
+// removed holds back the removal of a file, or of the files below a
+// removed folder, until the delay elapsed. Returns the paths to remove
+// right away, the ones not tracked nor moved already.
+func (t *mirrorMoveTracker) removed(path string, now time.Time) []string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	prefix := strings.TrimSuffix(path, string(filepath.Separator)) + string(filepath.Separator)
+	found := false
+	for p := range t.movedAway {
+		if p == path || strings.HasPrefix(p, prefix) {
+			delete(t.movedAway, p)
+			found = true
+		}
+	}
+	for p := range t.inodes {
+		if p == path || strings.HasPrefix(p, prefix) {
+			t.pending[p] = now.Add(t.delay)
+			found = true
+		}
+	}
+	if !found {
+		return []string{path}
+	}
+	return nil
+}
+
+// expired returns the files whose removal was held back until now, they
+// are not tracked anymore.
+func (t *mirrorMoveTracker) expired(now time.Time) []string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	var paths []string
+	for p, deadline := range t.pending {
+		if now.Before(deadline) {
+			continue
+		}
+		delete(t.pending, p)
+		if inode, ok := t.inodes[p]; ok {
+			delete(t.inodes, p)
+			if t.paths[inode].path == p {
+				delete(t.paths, inode)
+			}
+		}
+		paths = append(paths, p)
+	}
+	return paths
+}
@@ -0,0 +1,234 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"time"
+
+	"github.com/trinet2005/oss-mc/pkg/probe"
+	. "gopkg.in/check.v1"
+)
+
+func (s *TestSuite) TestMatchMirrorMoves(c *C) {
+	content := func(path string, size int64, etag string) *ClientContent {
+		return &ClientContent{URL: *newClientURL(path), Size: size, ETag: etag}
+	}
+	copies := []URLs{
+		{SourceContent: content("/src/new/a.bin", 10, "aaa")},
+		{SourceContent: content("/src/new/b.bin", 10, "bbb")},
+		{SourceContent: content("/src/new/c.bin", 20, "ccc")},
+		{SourceContent: content("/src/new/a-copy.bin", 10, "aaa")},
+	}
+	removals := []URLs{
+		{TargetContent: content("/dst/old/a.bin", 10, "aaa")},
+		{TargetContent: content("/dst/old/b.bin", 10, "xxx")},
+		{TargetContent: content("/dst/old/c.bin", 21, "ccc")},
+	}
+	moves, restCopies, restRemovals := matchMirrorMoves(copies, removals, func(src, tgt *ClientContent) bool {
+		return src.ETag == tgt.ETag
+	})
+
+	c.Assert(len(moves), Equals, 1)
+	c.Assert(moves[0].SourceContent.URL.Path, Equals, "/src/new/a.bin")
+	c.Assert(moves[0].movedFrom.URL.Path, Equals, "/dst/old/a.bin")
+
+	// An object is moved once, its duplicates are copied.
+	var copied []string
+	for _, urls := range restCopies {
+		c.Assert(urls.movedFrom, IsNil)
+		copied = append(copied, urls.SourceContent.URL.Path)
+	}
+	c.Assert(copied, DeepEquals, []string{"/src/new/b.bin", "/src/new/c.bin", "/src/new/a-copy.bin"})
+
+	var removed []string
+	for _, urls := range restRemovals {
+		removed = append(removed, urls.TargetContent.URL.Path)
+	}
+	c.Assert(removed, DeepEquals, []string{"/dst/old/b.bin", "/dst/old/c.bin"})
+}
+
+func (s *TestSuite) TestMatchMirrorMovesDigests(c *C) {
+	root := c.MkDir()
+	mtime := time.Unix(1700000000, 0)
+	content := func(name, data string) *ClientContent {
+		path := filepath.Join(root, name)
+		c.Assert(os.WriteFile(path, []byte(data), 0o644), IsNil)
+		c.Assert(os.Chtimes(path, mtime, mtime), IsNil)
+		return &ClientContent{URL: *newClientURL(path), Size: int64(len(data)), Time: mtime}
+	}
+	comparer := &contentComparer{cache: &checksumCache{entries: make(map[string]*checksumCacheEntry)}}
+	same := func(src, tgt *ClientContent) bool {
+		return comparer.sameContent(globalContext, src, tgt)
+	}
+
+	// Same size and modification time is no proof of the same data.
+	copies := []URLs{{SourceContent: content("new-a", "aaaa")}, {SourceContent: content("new-b", "bbbb")}}
+	removals := []URLs{{TargetContent: content("old-c", "cccc")}, {TargetContent: content("old-b", "bbbb")}}
+	moves, restCopies, restRemovals := matchMirrorMoves(copies, removals, same)
+	c.Assert(len(moves), Equals, 1)
+	c.Assert(moves[0].movedFrom.URL.Path, Equals, filepath.Join(root, "old-b"))
+	c.Assert(len(restCopies), Equals, 1)
+	c.Assert(restCopies[0].SourceContent.URL.Path, Equals, filepath.Join(root, "new-a"))
+	c.Assert(len(restRemovals), Equals, 1)
+	c.Assert(restRemovals[0].TargetContent.URL.Path, Equals, filepath.Join(root, "old-c"))
+
+}
+
+func (s *TestSuite) TestMirrorMoveFallback(c *C) {
+	root := c.MkDir()
+	src, dst := filepath.Join(root, "src"), filepath.Join(root, "dst")
+	c.Assert(os.MkdirAll(src, 0o755), IsNil)
+	c.Assert(os.MkdirAll(dst, 0o755), IsNil)
+	c.Assert(os.WriteFile(filepath.Join(src, "new"), []byte("data"), 0o644), IsNil)
+
+	defer func(load func() (*configV10, *probe.Error)) { loadMcConfig = load }(loadMcConfig)
+	loadMcConfig = func() (*configV10, *probe.Error) { return newMcConfig(), nil }
+	defer func(quiet bool) { globalQuiet = quiet }(globalQuiet)
+	globalQuiet = true
+	mj := newMirrorJob(src, dst, mirrorOptions{})
+
+	// The old name was never mirrored, the file is uploaded instead.
+	urls := mj.doMove(globalContext, URLs{
+		SourceContent: &ClientContent{URL: *newClientURL(filepath.Join(src, "new")), Size: 4},
+		TargetContent: &ClientContent{URL: *newClientURL(filepath.Join(dst, "new"))},
+		movedFrom:     &ClientContent{URL: *newClientURL(filepath.Join(dst, "old")), Size: 4},
+	})
+	c.Assert(urls.Error, IsNil)
+	c.Assert(urls.movedFrom, IsNil)
+	data, e := os.ReadFile(filepath.Join(dst, "new"))
+	c.Assert(e, IsNil)
+	c.Assert(string(data), Equals, "data")
+}
+
+func (s *TestSuite) TestMirrorMoveWatchContent(c *C) {
+	root := c.MkDir()
+	src, dst := filepath.Join(root, "src"), filepath.Join(root, "dst")
+	c.Assert(os.MkdirAll(src, 0o755), IsNil)
+	c.Assert(os.MkdirAll(dst, 0o755), IsNil)
+	c.Assert(os.WriteFile(filepath.Join(src, "new"), []byte("new!"), 0o644), IsNil)
+	c.Assert(os.WriteFile(filepath.Join(dst, "old"), []byte("old!"), 0o644), IsNil)
+
+	defer func(load func() (*configV10, *probe.Error)) { loadMcConfig = load }(loadMcConfig)
+	loadMcConfig = func() (*configV10, *probe.Error) { return newMcConfig(), nil }
+	defer func(quiet bool) { globalQuiet = quiet }(globalQuiet)
+	globalQuiet = true
+	mj := newMirrorJob(src, dst, mirrorOptions{
+		checksumCache: &checksumCache{entries: make(map[string]*checksumCacheEntry)},
+	})
+
+	// The old name holds other data, the file is uploaded.
+	urls := mj.doMoveWatch(globalContext, URLs{
+		SourceContent: &ClientContent{URL: *newClientURL(filepath.Join(src, "new")), Size: 4},
+		TargetContent: &ClientContent{URL: *newClientURL(filepath.Join(dst, "new"))},
+		movedFrom:     &ClientContent{URL: *newClientURL(filepath.Join(dst, "old")), Size: 4},
+	})
+	c.Assert(urls.Error, IsNil)
+	data, e := os.ReadFile(filepath.Join(dst, "new"))
+	c.Assert(e, IsNil)
+	c.Assert(string(data), Equals, "new!")
+	_, e = os.Stat(filepath.Join(dst, "old"))
+	c.Assert(os.IsNotExist(e), Equals, true)
+}
+
+func (s *TestSuite) TestMirrorMoveTracker(c *C) {
+	if runtime.GOOS == "windows" {
+		c.Skip("inodes are not available")
+	}
+
+	root, e := os.MkdirTemp(os.TempDir(), "mirror-")
+	c.Assert(e, IsNil)
+	defer os.RemoveAll(root)
+
+	c.Assert(os.MkdirAll(filepath.Join(root, "dir"), 0o755), IsNil)
+	for _, name := range []string{"a", "x", "dir/b", "dir/c"} {
+		c.Assert(os.WriteFile(filepath.Join(root, name), []byte(name), 0o644), IsNil)
+	}
+
+	tracker := newMirrorMoveTracker(time.Second)
+	c.Assert(tracker.index(root), IsNil)
+	now := time.Now()
+
+	// Renamed file.
+	c.Assert(os.Rename(filepath.Join(root, "a"), filepath.Join(root, "a2")), IsNil)
+	c.Assert(tracker.removed(filepath.Join(root, "a"), now), IsNil)
+	old, ok := tracker.moved(filepath.Join(root, "a2"))
+	c.Assert(ok, Equals, true)
+	c.Assert(old, Equals, filepath.Join(root, "a"))
+
+	// Renamed folder, the files below it are moved.
+	c.Assert(os.Rename(filepath.Join(root, "dir"), filepath.Join(root, "dir2")), IsNil)
+	c.Assert(tracker.removed(filepath.Join(root, "dir"), now), IsNil)
+	old, ok = tracker.moved(filepath.Join(root, "dir2", "b"))
+	c.Assert(ok, Equals, true)
+	c.Assert(old, Equals, filepath.Join(root, "dir", "b"))
+
+	// Removed files are removed once the delay elapsed.
+	c.Assert(os.Remove(filepath.Join(root, "x")), IsNil)
+	c.Assert(tracker.removed(filepath.Join(root, "x"), now), IsNil)
+	c.Assert(tracker.expired(now), IsNil)
+	expired := tracker.expired(now.Add(time.Second))
+	sort.Strings(expired)
+	c.Assert(expired, DeepEquals, []string{filepath.Join(root, "dir", "c"), filepath.Join(root, "x")})
+
+	// New files are not moves, unknown files are removed right away.
+	c.Assert(os.WriteFile(filepath.Join(root, "y"), []byte("y"), 0o644), IsNil)
+	_, ok = tracker.moved(filepath.Join(root, "y"))
+	c.Assert(ok, Equals, false)
+	c.Assert(tracker.removed(filepath.Join(root, "z"), now), DeepEquals, []string{filepath.Join(root, "z")})
+
+	// A file of another size under the inode of a removed one is new.
+	c.Assert(os.Rename(filepath.Join(root, "y"), filepath.Join(root, "y2")), IsNil)
+	c.Assert(tracker.removed(filepath.Join(root, "y"), now), IsNil)
+	c.Assert(os.WriteFile(filepath.Join(root, "y2"), []byte("other"), 0o644), IsNil)
+	_, ok = tracker.moved(filepath.Join(root, "y2"))
+	c.Assert(ok, Equals, false)
+	c.Assert(tracker.expired(now.Add(time.Second)), DeepEquals, []string{filepath.Join(root, "y")})
+}
+
+func (s *TestSuite) TestMirrorMoveTrackerMovedFirst(c *C) {
+	if runtime.GOOS == "windows" {
+		c.Skip("inodes are not available")
+	}
+
+	root, e := os.MkdirTemp(os.TempDir(), "mirror-")
+	c.Assert(e, IsNil)
+	defer os.RemoveAll(root)
+
+	c.Assert(os.MkdirAll(filepath.Join(root, "dir"), 0o755), IsNil)
+	c.Assert(os.WriteFile(filepath.Join(root, "dir", "b"), []byte("b"), 0o644), IsNil)
+
+	tracker := newMirrorMoveTracker(time.Second)
+	c.Assert(tracker.index(root), IsNil)
+
+	// The files of a moved folder may show up before its removal.
+	c.Assert(os.Rename(filepath.Join(root, "dir"), filepath.Join(root, "dir2")), IsNil)
+	old, ok := tracker.moved(filepath.Join(root, "dir2", "b"))
+	c.Assert(ok, Equals, true)
+	c.Assert(old, Equals, filepath.Join(root, "dir", "b"))
+	c.Assert(tracker.removed(filepath.Join(root, "dir"), time.Now()), IsNil)
+	c.Assert(tracker.expired(time.Now().Add(time.Second)), IsNil)
+
+	// Hard links are not moves.
+	c.Assert(os.Link(filepath.Join(root, "dir2", "b"), filepath.Join(root, "c")), IsNil)
+	_, ok = tracker.moved(filepath.Join(root, "c"))
+	c.Assert(ok, Equals, false)
+}
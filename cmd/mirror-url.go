@@ -26,6 +26,7 @@ import (
 	"time"
 
 	"github.com/minio/cli"
+	minio "github.com/trinet2005/oss-go-sdk"
 	"github.com/trinet2005/oss-pkg/wildcard"
 )
 
@@ -64,6 +65,26 @@ func checkMirrorSyntax(ctx context.Context, cliCtx *cli.Context, encKeyDB map[st
 		}
 	}
 
+	if cliCtx.Bool("checkpoint") && (cliCtx.Bool("watch") || cliCtx.Bool("active-active") || cliCtx.Bool("multi-master")) {
+		fatalIf(errInvalidArgument().Trace(URLs...), "`--checkpoint` and `--resume` cannot be used with `--watch` or `--active-active`.")
+	}
+	if cliCtx.Bool("checkpoint") && (cliCtx.Bool("fake") || cliCtx.Bool("dry-run")) {
+		fatalIf(errInvalidArgument().Trace(URLs...), "`--checkpoint` and `--resume` cannot be used with `--dry-run`.")
+	}
+
+	if cliCtx.Bool("detect-moves") && !cliCtx.Bool("remove") {
+		fatalIf(errInvalidArgument().Trace(URLs...), "`--detect-moves` requires `--remove`, moved objects are removed from their old name.")
+	}
+	// Moves are mirrored after the listing, out of the order the
+	// journal records the keys done in.
+	if cliCtx.Bool("detect-moves") && cliCtx.Bool("checkpoint") {
+		fatalIf(errInvalidArgument().Trace(URLs...), "`--detect-moves` cannot be used with `--checkpoint` or `--resume`.")
+	}
+
+	if _, err := parseChecksumAlgo(cliCtx.String("checksum")); err != nil {
+		fatalIf(err.Trace(cliCtx.String("checksum")), "Unable to parse --checksum.")
+	}
+
 	/****** Generic rules *******/
 	if !cliCtx.Bool("watch") && !cliCtx.Bool("active-active") && !cliCtx.Bool("multi-master") {
 		_, srcContent, err := url2Stat(ctx, srcURL, "", false, encKeyDB, time.Time{}, false)
@@ -127,8 +148,41 @@ func deltaSourceTarget(ctx context.Context, sourceURL, targetURL string, opts mi
 		return
 	}
 
+	var comparer *contentComparer
+	if opts.compareContent || opts.detectMoves {
+		comparer = &contentComparer{
+			sourceAlias: sourceAlias,
+			targetAlias: targetAlias,
+			encKeyDB:    opts.encKeyDB,
+			cache:       opts.checksumCache,
+		}
+	}
+
+	// Objects only in the source or only in the target are held back
+	// until the end of the listing to find the moved ones.
+	var copies, removals []URLs
+	if opts.detectMoves {
+		defer func() {
+			// Only objects proven to hold the same data by their digests are
+			// moved, the others are copied.
+			moves, restCopies, restRemovals := matchMirrorMoves(copies, removals, func(src, tgt *ClientContent) bool {
+				return comparer.sameContent(ctx, src, tgt)
+			})
+			for _, urls := range append(append(moves, restCopies...), restRemovals...) {
+				URLsCh <- urls
+			}
+		}()
+	}
+
+	// Resume listing after the keys already mirrored.
+	var startAfter string
+	if opts.journal != nil {
+		startAfter = opts.journal.Header.Marker
+	}
+
 	// List both source and target, compare and return values through channel.
-	for diffMsg := range objectDifference(ctx, sourceClnt, targetClnt, opts.isMetadata) {
+	// Copies compressed with --compress are recognized by their metadata.
+	for diffMsg := range objectDifference(ctx, sourceClnt, targetClnt, opts.isMetadata, opts.compress != "", opts.compareContent, startAfter) {
 		if diffMsg.Error != nil {
 			// Send all errors through the channel
 			URLsCh <- URLs{Error: diffMsg.Error, ErrorCond: differInUnknown}
@@ -147,17 +201,44 @@ func deltaSourceTarget(ctx context.Context, sourceURL, targetURL string, opts mi
 			continue
 		}
 
+		// Skip the keys mirrored before the session was interrupted.
+		var journalKey string
+		if opts.journal != nil {
+			journalKey = filepath.ToSlash(srcSuffix)
+			if diffMsg.Diff == differInSecond {
+				journalKey = filepath.ToSlash(tgtSuffix)
+			}
+			if opts.journal.IsDone(journalKey) {
+				continue
+			}
+		}
+
+		if opts.compareContent {
+			diffMsg.Diff = comparer.contentDifference(ctx, diffMsg.Diff, diffMsg.firstContent, diffMsg.secondContent)
+		}
+
+		// Client-side encrypted copies are larger than their plaintext.
+		if diffMsg.Diff == differInSize && opts.cse != nil && cseSameSize(diffMsg.firstContent, diffMsg.secondContent) {
+			diffMsg.Diff = differInNone
+		}
+
+		// Copies compressed client-side record the size of their source.
+		if diffMsg.Diff == differInSize && sameUncompressedSize(diffMsg.firstContent, diffMsg.secondContent) {
+			diffMsg.Diff = differInNone
+		}
+
 		switch diffMsg.Diff {
 		case differInNone:
 			// No difference, continue.
 		case differInType:
-			URLsCh <- URLs{Error: errInvalidTarget(diffMsg.SecondURL)}
-		case differInSize, differInMetadata, differInAASourceMTime:
+			URLsCh <- URLs{Error: errInvalidTarget(diffMsg.SecondURL), journalKey: journalKey}
+		case differInSize, differInMetadata, differInAASourceMTime, differInContent:
 			if !opts.isOverwrite && !opts.isFake && !opts.activeActive {
 				// Size or time or etag differs but --overwrite not set.
 				URLsCh <- URLs{
-					Error:     errOverWriteNotAllowed(diffMsg.SecondURL),
-					ErrorCond: diffMsg.Diff,
+					Error:      errOverWriteNotAllowed(diffMsg.SecondURL),
+					ErrorCond:  diffMsg.Diff,
+					journalKey: journalKey,
 				}
 				continue
 			}
@@ -172,6 +253,7 @@ func deltaSourceTarget(ctx context.Context, sourceURL, targetURL string, opts mi
 				SourceContent: sourceContent,
 				TargetAlias:   targetAlias,
 				TargetContent: targetContent,
+				journalKey:    journalKey,
 			}
 		case differInFirst:
 			// Only in first, always copy.
@@ -179,20 +261,32 @@ func deltaSourceTarget(ctx context.Context, sourceURL, targetURL string, opts mi
 			targetPath := urlJoinPath(targetURL, sourceSuffix)
 			sourceContent := diffMsg.firstContent
 			targetContent := &ClientContent{URL: *newClientURL(targetPath)}
-			URLsCh <- URLs{
+			urls := URLs{
 				SourceAlias:   sourceAlias,
 				SourceContent: sourceContent,
 				TargetAlias:   targetAlias,
 				TargetContent: targetContent,
+				journalKey:    journalKey,
+			}
+			if opts.detectMoves {
+				copies = append(copies, urls)
+				continue
 			}
+			URLsCh <- urls
 		case differInSecond:
 			if !opts.isRemove && !opts.isFake {
 				continue
 			}
-			URLsCh <- URLs{
+			urls := URLs{
 				TargetAlias:   targetAlias,
 				TargetContent: diffMsg.secondContent,
+				journalKey:    journalKey,
+			}
+			if opts.detectMoves {
+				removals = append(removals, urls)
+				continue
 			}
+			URLsCh <- urls
 		default:
 			URLsCh <- URLs{
 				Error:     errUnrecognizedDiffType(diffMsg.Diff).Trace(diffMsg.FirstURL, diffMsg.SecondURL),
@@ -208,9 +302,17 @@ type mirrorOptions struct {
 	excludeOptions                    []string
 	encKeyDB                          map[string][]prefixSSEPair
 	md5, disableMultipart             bool
+	checksum                          minio.ChecksumType
+	cse                               *cseKeys
+	compress                          string
+	download                          downloadOptions
+	compareContent                    bool
+	checksumCache                     *checksumCache
+	journal                           *mirrorJournal
 	olderThan, newerThan              string
 	storageClass                      string
 	userMetadata                      map[string]string
+	detectMoves                       bool
 }
 
 // Prepares urls that need to be copied or removed based on requested options.
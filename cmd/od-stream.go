@@ -119,10 +119,10 @@ func odCopy(ctx context.Context, odURLs URLs, args argKVS, odType string) (odMes
 
 	sourceAlias := odURLs.SourceAlias
 	sourceURL := odURLs.SourceContent.URL
-	sourcePath := filepath.ToSlash(filepath.Join(sourceAlias, sourceURL.Path))
+	sourcePath := filepath.ToSlash(aliasPath(sourceAlias, sourceURL.Path))
 	targetAlias := odURLs.TargetAlias
 	targetURL := odURLs.TargetContent.URL
-	targetPath := filepath.ToSlash(filepath.Join(targetAlias, targetURL.Path))
+	targetPath := filepath.ToSlash(aliasPath(targetAlias, targetURL.Path))
 
 	getOpts := GetOptions{}
 
@@ -221,7 +221,7 @@ func odDownload(ctx context.Context, odURLs URLs, args argKVS) (odMessage, error
 	targetPath := odURLs.TargetContent.URL.Path
 	sourceAlias := odURLs.SourceAlias
 	sourceURL := odURLs.SourceContent.URL
-	sourcePath := filepath.ToSlash(filepath.Join(sourceAlias, sourceURL.Path))
+	sourcePath := filepath.ToSlash(aliasPath(sourceAlias, sourceURL.Path))
 
 	// Get server client.
 	cli, err := newClientFromAlias(sourceAlias, sourceURL.String())
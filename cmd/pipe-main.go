@@ -75,7 +75,7 @@ var pipeCmd = cli.Command{
 	Action:       mainPipe,
 	OnUsageError: onUsageError,
 	Before:       setGlobalsFromContext,
-	Flags:        append(append(pipeFlags, ioFlags...), globalFlags...),
+	Flags:        append(append(append(append(pipeFlags, ioFlags...), cseFlags...), compressFlags...), globalFlags...),
 	CustomHelpTemplate: `NAME:
   {{.HelpName}} - {{.Usage}}
 
@@ -110,6 +110,12 @@ EXAMPLES:
 
   7. Set tags to the uploaded objects
       {{.Prompt}} tar cvf - . | {{.HelpName}} --tags "category=prod&type=backup" play/mybucket/backup.tar
+
+  8. Stream a backup to an object encrypted client-side for an age recipient.
+      {{.Prompt}} tar cvf - . | {{.HelpName}} --cse-recipient age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p play/mybucket/backup.tar
+
+  9. Stream text logs to an object compressed with zstd, 'cat' decompresses it transparently.
+      {{.Prompt}} journalctl -o json | {{.HelpName}} --compress zstd play/mybucket/journal.json
 `,
 }
 
@@ -156,8 +162,31 @@ func pipe(ctx *cli.Context, targetURL string, encKeyDB map[string][]prefixSSEPai
 	}
 
 	pg := newProgressBar(0)
+	var reader io.Reader = io.TeeReader(os.Stdin, pg)
+
+	// Compress and encrypt client-side when uploading to object storage.
+	cse, err := getCSEKeys(ctx)
+	if err != nil {
+		return err.Trace(targetURL)
+	}
+	compress, err := parseCompressCodec(ctx.String("compress"))
+	if err != nil {
+		return err.Trace(targetURL)
+	}
+	if cse != nil || compress != "" {
+		_, urlStrFull, _, err := expandAlias(targetURL)
+		if err != nil {
+			return err.Trace(targetURL)
+		}
+		rc, _, _, err := transformSourceStream(targetURL, reader, -1, meta, URLs{cse: cse, compress: compress}, newClientURL(urlStrFull).Type)
+		if err != nil {
+			return err.Trace(targetURL)
+		}
+		defer rc.Close()
+		reader = rc
+	}
 
-	_, err := putTargetStreamWithURL(targetURL, io.TeeReader(os.Stdin, pg), -1, opts)
+	_, err = putTargetStreamWithURL(targetURL, reader, -1, opts)
 	// TODO: See if this check is necessary.
 	switch e := err.ToGoError().(type) {
 	case *os.PathError:
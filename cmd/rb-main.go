@@ -105,6 +105,10 @@ func checkRbSyntax(cliCtx *cli.Context) {
 	isDangerous := cliCtx.Bool("dangerous")
 
 	for _, url := range cliCtx.Args() {
+		if _, _, aliasCfg, _ := expandAlias(url); isScopedAlias(aliasCfg) {
+			fatalIf(errDummy().Trace(url),
+				"Unable to remove buckets with `"+url+"`, its alias is confined to `"+aliasScope(aliasCfg)+"`.")
+		}
 		if isS3NamespaceRemoval(url) {
 			if isForce && isDangerous {
 				continue
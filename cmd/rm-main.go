@@ -365,7 +365,7 @@ func removeSingle(url, versionID string, opts removeOpts) error {
 				return exitStatus(globalErrorExitStatus)
 			}
 			msg := rmMessage{
-				Key:       path.Join(targetAlias, result.BucketName, result.ObjectName),
+				Key:       filepath.ToSlash(aliasPath(targetAlias, "/"+path.Join(result.BucketName, result.ObjectName))),
 				VersionID: result.ObjectVersionID,
 			}
 			if result.DeleteMarker {
@@ -501,7 +501,7 @@ func listAndRemove(url string, opts removeOpts) error {
 						case contentCh <- content:
 							sent = true
 						case result := <-resultCh:
-							path := path.Join(targetAlias, result.BucketName, result.ObjectName)
+							path := filepath.ToSlash(aliasPath(targetAlias, "/"+path.Join(result.BucketName, result.ObjectName)))
 							if result.Err != nil {
 								errorIf(result.Err.Trace(path),
 									"Failed to remove `"+path+"`.")
@@ -559,7 +559,7 @@ func listAndRemove(url string, opts removeOpts) error {
 				case contentCh <- content:
 					sent = true
 				case result := <-resultCh:
-					path := path.Join(targetAlias, result.BucketName, result.ObjectName)
+					path := filepath.ToSlash(aliasPath(targetAlias, "/"+path.Join(result.BucketName, result.ObjectName)))
 					if result.Err != nil {
 						errorIf(result.Err.Trace(path),
 							"Failed to remove `"+path+"`.")
@@ -622,7 +622,7 @@ func listAndRemove(url string, opts removeOpts) error {
 				case contentCh <- content:
 					sent = true
 				case result := <-resultCh:
-					path := path.Join(targetAlias, result.BucketName, result.ObjectName)
+					path := filepath.ToSlash(aliasPath(targetAlias, "/"+path.Join(result.BucketName, result.ObjectName)))
 					if result.Err != nil {
 						errorIf(result.Err.Trace(path),
 							"Failed to remove `"+path+"`.")
@@ -653,7 +653,7 @@ func listAndRemove(url string, opts removeOpts) error {
 		return nil
 	}
 	for result := range resultCh {
-		path := path.Join(targetAlias, result.BucketName, result.ObjectName)
+		path := filepath.ToSlash(aliasPath(targetAlias, "/"+path.Join(result.BucketName, result.ObjectName)))
 		if result.Err != nil {
 			errorIf(result.Err.Trace(path), "Failed to remove `"+path+"` recursively.")
 			switch result.Err.ToGoError().(type) {
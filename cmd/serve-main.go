@@ -0,0 +1,218 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/minio/cli"
+	json "github.com/minio/colorjson"
+	"github.com/trinet2005/oss-mc/pkg/probe"
+	"github.com/trinet2005/oss-pkg/console"
+)
+
+// serve specific flags.
+var serveFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "addr",
+		Usage: "address to listen on, other than loopback ones require --auth or --token",
+		Value: "localhost:8080",
+	},
+	cli.StringFlag{
+		Name:   "auth",
+		Usage:  "require basic authentication with USER:PASSWORD",
+		EnvVar: "MC_SERVE_AUTH",
+	},
+	cli.StringFlag{
+		Name:   "token",
+		Usage:  "require a bearer token",
+		EnvVar: "MC_SERVE_TOKEN",
+	},
+	cli.BoolFlag{
+		Name:  "webdav",
+		Usage: "answer WebDAV PROPFIND requests",
+	},
+	cli.BoolFlag{
+		Name:  "writable",
+		Usage: "allow uploads with PUT requests, requires --auth or --token",
+	},
+}
+
+// Serve a folder over HTTP.
+var serveCmd = cli.Command{
+	Name:         "serve",
+	Usage:        "serve object(s) over HTTP and WebDAV",
+	Action:       mainServe,
+	OnUsageError: onUsageError,
+	Before:       setGlobalsFromContext,
+	Flags:        append(append(serveFlags, ioFlags...), globalFlags...),
+	CustomHelpTemplate: `NAME:
+  {{.HelpName}} - {{.Usage}}
+
+USAGE:
+  {{.HelpName}} [FLAGS] TARGET
+
+FLAGS:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}
+DESCRIPTION:
+  Serve lists folders as HTML pages and downloads objects over plain HTTP, for tools which do not
+  speak S3, without handing out credentials. Downloads support HEAD, Range, ETag and Last-Modified
+  conditional requests. With --webdav, folders can be mounted as read-only WebDAV shares.
+
+  Serve listens on localhost only by default. Listening on other addresses and accepting uploads with
+  --writable require authentication with --auth or --token.
+
+ENVIRONMENT VARIABLES:
+  MC_SERVE_AUTH:   USER:PASSWORD required with basic authentication
+  MC_SERVE_TOKEN:  bearer token required
+  MC_ENCRYPT_KEY:  list of comma delimited prefix=secret values
+
+EXAMPLES:
+  1. Serve a bucket on localhost, port 8080.
+     {{.Prompt}} {{.HelpName}} play/mybucket
+
+  2. Serve a prefix of a bucket on all interfaces, protected with a password.
+     {{.Prompt}} {{.HelpName}} --addr :9090 --auth "reader:s3cr3t" play/mybucket/reports
+
+  3. Serve a bucket as a WebDAV share, protected with a token.
+     {{.Prompt}} {{.HelpName}} --webdav --token "$(cat token.txt)" play/mybucket
+
+  4. Serve a bucket accepting uploads.
+     {{.Prompt}} {{.HelpName}} --writable --auth "writer:s3cr3t" play/uploads
+`,
+}
+
+// serveMessage container for the address a folder is served on.
+type serveMessage struct {
+	Status string `json:"status"`
+	Target string `json:"target"`
+	URL    string `json:"url"`
+}
+
+// String colorized serve message
+func (s serveMessage) String() string {
+	return console.Colorize("Serve", fmt.Sprintf("Serving `%s` on %s", s.Target, s.URL))
+}
+
+// JSON jsonified serve message
+func (s serveMessage) JSON() string {
+	s.Status = "success"
+	msgBytes, e := json.MarshalIndent(s, "", " ")
+	fatalIf(probe.NewError(e), "Unable to marshal into JSON.")
+	return string(msgBytes)
+}
+
+// checkServeSyntax - validate all the passed arguments
+func checkServeSyntax(cliCtx *cli.Context) {
+	if len(cliCtx.Args()) != 1 {
+		showCommandHelpAndExit(cliCtx, 1) // last argument is exit code
+	}
+	if auth := cliCtx.String("auth"); auth != "" {
+		if user, _, ok := strings.Cut(auth, ":"); !ok || user == "" {
+			fatalIf(errInvalidArgument().Trace(), "--auth must be USER:PASSWORD.")
+		}
+	}
+	if cliCtx.String("auth") != "" || cliCtx.String("token") != "" {
+		return
+	}
+	if cliCtx.Bool("writable") {
+		fatalIf(errInvalidArgument().Trace(), "--writable requires --auth or --token, anyone could upload otherwise.")
+	}
+	addr := cliCtx.String("addr")
+	if !isLoopbackAddr(addr) {
+		fatalIf(errInvalidArgument().Trace(addr), "Listening on `"+addr+"` requires --auth or --token, anyone on the network could read all objects otherwise.")
+	}
+}
+
+// isLoopbackAddr returns true if addr only listens on loopback interfaces,
+// an empty host listens on all of them.
+func isLoopbackAddr(addr string) bool {
+	host, _, e := net.SplitHostPort(addr)
+	if e != nil || host == "" {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// mainServe is the entry point for serve command.
+func mainServe(cliCtx *cli.Context) error {
+	ctx, cancelServe := context.WithCancel(globalContext)
+	defer cancelServe()
+
+	// Parse encryption keys per command.
+	encKeyDB, err := getEncKeys(cliCtx)
+	fatalIf(err, "Unable to parse encryption keys.")
+
+	// check 'serve' cli arguments.
+	checkServeSyntax(cliCtx)
+
+	console.SetColor("Serve", color.New(color.FgGreen, color.Bold))
+
+	target := cliCtx.Args().Get(0)
+	alias, urlStr, _ := mustExpandAlias(target)
+	separator := string(newClientURL(urlStr).Separator)
+	if !strings.HasSuffix(urlStr, separator) {
+		urlStr += separator
+	}
+	handler := &serveHandler{
+		alias:    alias,
+		url:      urlStr,
+		encKeyDB: encKeyDB,
+		token:    cliCtx.String("token"),
+		webdav:   cliCtx.Bool("webdav"),
+		writable: cliCtx.Bool("writable"),
+	}
+	if auth := cliCtx.String("auth"); auth != "" {
+		handler.user, handler.password, _ = strings.Cut(auth, ":")
+	}
+
+	// Verify the folder is accessible.
+	_, err = handler.list(ctx, "")
+	fatalIf(err.Trace(target), "Unable to serve `"+target+"`.")
+
+	listener, e := net.Listen("tcp", cliCtx.String("addr"))
+	fatalIf(probe.NewError(e).Trace(cliCtx.String("addr")), "Unable to listen on `"+cliCtx.String("addr")+"`.")
+
+	server := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+
+	printMsg(serveMessage{Target: target, URL: "http://" + listener.Addr().String()})
+	if e = server.Serve(listener); e != nil && !errors.Is(e, http.ErrServerClosed) {
+		fatalIf(probe.NewError(e), "Unable to serve `"+target+"`.")
+	}
+	return nil
+}
@@ -0,0 +1,508 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/xml"
+	"errors"
+	"html/template"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	humanize "github.com/dustin/go-humanize"
+	"github.com/trinet2005/oss-go-sdk/pkg/encrypt"
+	"github.com/trinet2005/oss-mc/pkg/probe"
+)
+
+// serveHandler serves the objects below a folder over HTTP, folders are
+// listed as HTML pages or as WebDAV collections.
+type serveHandler struct {
+	alias    string
+	url      string
+	encKeyDB map[string][]prefixSSEPair
+
+	user, password string
+	token          string
+	webdav         bool
+	writable       bool
+}
+
+// serveEntry is an object or a folder of a listing.
+type serveEntry struct {
+	Name    string
+	IsDir   bool
+	Size    int64
+	ModTime time.Time
+	ETag    string
+}
+
+// Href returns the link to an entry relative to its folder.
+func (e serveEntry) Href() string {
+	href := (&url.URL{Path: e.Name}).EscapedPath()
+	if e.IsDir {
+		href += "/"
+	}
+	// A name with a colon must not be taken for a scheme.
+	return "./" + href
+}
+
+// HumanSize returns the size of an object in a human readable form.
+func (e serveEntry) HumanSize() string {
+	return humanize.IBytes(uint64(e.Size))
+}
+
+var serveIndexTemplate = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Index of /{{.Path}}</title></head>
+<body>
+<h1>Index of /{{.Path}}</h1>
+<table>
+<tr><th align="left">Name</th><th align="right">Size</th><th align="left">Last modified</th></tr>
+{{if .Path}}<tr><td><a href="../">../</a></td><td></td><td></td></tr>
+{{end}}{{range .Entries}}<tr><td><a href="{{.Href}}">{{.Name}}{{if .IsDir}}/{{end}}</a></td><td align="right">{{if not .IsDir}}{{.HumanSize}}{{end}}</td><td>{{if not .IsDir}}{{.ModTime.UTC.Format "2006-01-02 15:04:05 MST"}}{{end}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+func (h *serveHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.authorized(r) {
+		if h.user != "" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="mc", charset="UTF-8"`)
+		} else {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="mc"`)
+		}
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	key, ok := serveKey(r.URL.Path)
+	if !ok {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	isDir := key == "" || strings.HasSuffix(r.URL.Path, "/")
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		if isDir {
+			h.serveDir(w, r, key)
+		} else {
+			h.serveObject(w, r, key)
+		}
+	case http.MethodOptions:
+		w.Header().Set("Allow", strings.Join(h.methods(), ", "))
+		if h.webdav {
+			w.Header().Set("DAV", "1")
+		}
+	case "PROPFIND":
+		if !h.webdav {
+			h.methodNotAllowed(w)
+			return
+		}
+		h.propfind(w, r, key)
+	case http.MethodPut:
+		if !h.writable || isDir {
+			h.methodNotAllowed(w)
+			return
+		}
+		h.put(w, r, key)
+	default:
+		h.methodNotAllowed(w)
+	}
+}
+
+// serveKey returns the key requested at urlPath below the served folder.
+// Paths with `..` elements or backslashes, the separator of local folders
+// on Windows, are refused as they could escape the served folder.
+func serveKey(urlPath string) (string, bool) {
+	if strings.Contains(urlPath, `\`) || isEscapingPath(urlPath) {
+		return "", false
+	}
+	return strings.TrimPrefix(path.Clean("/"+urlPath), "/"), true
+}
+
+// authorized returns true if the request holds the credentials required,
+// a bearer token or a user and password.
+func (h *serveHandler) authorized(r *http.Request) bool {
+	if h.user == "" && h.token == "" {
+		return true
+	}
+	if h.token != "" {
+		auth := r.Header.Get("Authorization")
+		if strings.HasPrefix(auth, "Bearer ") && hmac.Equal([]byte(strings.TrimPrefix(auth, "Bearer ")), []byte(h.token)) {
+			return true
+		}
+	}
+	if h.user != "" {
+		user, password, ok := r.BasicAuth()
+		// Compare both, not to tell which one is wrong from timing.
+		userMatch := hmac.Equal([]byte(user), []byte(h.user))
+		passwordMatch := hmac.Equal([]byte(password), []byte(h.password))
+		if ok && userMatch && passwordMatch {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *serveHandler) methods() []string {
+	methods := []string{http.MethodGet, http.MethodHead, http.MethodOptions}
+	if h.webdav {
+		methods = append(methods, "PROPFIND")
+	}
+	if h.writable {
+		methods = append(methods, http.MethodPut)
+	}
+	return methods
+}
+
+func (h *serveHandler) methodNotAllowed(w http.ResponseWriter) {
+	w.Header().Set("Allow", strings.Join(h.methods(), ", "))
+	http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+}
+
+// serveError replies with the HTTP status of a client error.
+func serveError(w http.ResponseWriter, err *probe.Error) {
+	status := http.StatusInternalServerError
+	switch err.ToGoError().(type) {
+	case ObjectMissing, PathNotFound, BucketDoesNotExist, ObjectIsDeleteMarker:
+		status = http.StatusNotFound
+	case PathInsufficientPermission:
+		status = http.StatusForbidden
+	case BucketInvalid, BucketNameEmpty, ObjectNameEmpty, PathNotADirectory, ObjectAlreadyExistsAsDirectory:
+		status = http.StatusBadRequest
+	}
+	if status == http.StatusInternalServerError {
+		errorIf(err, "Unable to serve the request.")
+	}
+	http.Error(w, http.StatusText(status), status)
+}
+
+func (h *serveHandler) urlOf(key string) string {
+	if key == "" {
+		return h.url
+	}
+	return urlJoinPath(h.url, key)
+}
+
+func (h *serveHandler) sse(key string) encrypt.ServerSide {
+	return getSSE(filepath.ToSlash(aliasPath(h.alias, newClientURL(h.urlOf(key)).Path)), h.encKeyDB[h.alias])
+}
+
+// stat returns the object or folder at key.
+func (h *serveHandler) stat(ctx context.Context, key string) (Client, *ClientContent, *probe.Error) {
+	clnt, err := newClientFromAlias(h.alias, h.urlOf(key))
+	if err != nil {
+		return nil, nil, err.Trace(key)
+	}
+	content, err := clnt.Stat(ctx, StatOptions{sse: h.sse(key)})
+	if err != nil {
+		return nil, nil, err.Trace(key)
+	}
+	return clnt, content, nil
+}
+
+// list returns the entries of the folder at key sorted by name, folders
+// without entries only exist on local filesystems.
+func (h *serveHandler) list(ctx context.Context, key string) ([]serveEntry, *probe.Error) {
+	dirURL := h.urlOf(key)
+	clnt, err := newClientFromAlias(h.alias, dirURL)
+	if err != nil {
+		return nil, err.Trace(key)
+	}
+	separator := string(clnt.GetURL().Separator)
+	if !strings.HasSuffix(dirURL, separator) {
+		dirURL += separator
+		if clnt, err = newClientFromAlias(h.alias, dirURL); err != nil {
+			return nil, err.Trace(key)
+		}
+	}
+	prefixPath := filepath.ToSlash(clnt.GetURL().Path)
+	if !strings.HasSuffix(prefixPath, "/") {
+		prefixPath += "/"
+	}
+	prefixPath = strings.TrimPrefix(prefixPath, "."+separator)
+
+	var entries []serveEntry
+	for content := range clnt.List(ctx, ListOptions{ShowDir: DirNone}) {
+		if content.Err != nil {
+			return nil, content.Err.Trace(key)
+		}
+		if content.IsDeleteMarker {
+			continue
+		}
+		name := strings.TrimPrefix(filepath.ToSlash(content.URL.Path), prefixPath)
+		name = strings.Trim(name, "/")
+		if name == "" {
+			continue
+		}
+		entries = append(entries, serveEntry{
+			Name:    name,
+			IsDir:   content.Type.IsDir(),
+			Size:    content.Size,
+			ModTime: content.Time,
+			ETag:    strings.Trim(content.ETag, "\""),
+		})
+	}
+	if len(entries) == 0 && key != "" {
+		if _, _, err = h.stat(ctx, key); err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return entries, nil
+}
+
+// serveDir replies with the HTML listing of a folder.
+func (h *serveHandler) serveDir(w http.ResponseWriter, r *http.Request, key string) {
+	entries, err := h.list(r.Context(), key)
+	if err != nil {
+		serveError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if r.Method == http.MethodHead {
+		return
+	}
+	dirPath := key
+	if dirPath != "" {
+		dirPath += "/"
+	}
+	e := serveIndexTemplate.Execute(w, struct {
+		Path    string
+		Entries []serveEntry
+	}{dirPath, entries})
+	errorIf(probe.NewError(e), "Unable to write the listing of `%s`.", key)
+}
+
+// serveObject replies with an object, or the part of it requested with a
+// Range header. Conditional requests are answered by http.ServeContent.
+func (h *serveHandler) serveObject(w http.ResponseWriter, r *http.Request, key string) {
+	clnt, content, err := h.stat(r.Context(), key)
+	if err != nil {
+		serveError(w, err)
+		return
+	}
+	if content.Type.IsDir() {
+		http.Redirect(w, r, "./"+path.Base(key)+"/", http.StatusMovedPermanently)
+		return
+	}
+
+	etag := strings.Trim(content.ETag, "\"")
+	if etag != "" {
+		w.Header().Set("ETag", "\""+etag+"\"")
+	}
+	contentType := content.Metadata["Content-Type"]
+	if contentType == "" {
+		contentType = guessURLContentType(key)
+	}
+	if contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+
+	reader := &serveObjectReader{
+		ctx:  r.Context(),
+		clnt: clnt,
+		size: content.Size,
+		opts: GetOptions{SSE: h.sse(key), MatchETag: etag},
+	}
+	defer reader.Close()
+	http.ServeContent(w, r, path.Base(key), content.Time, reader)
+}
+
+// put uploads the body of a request.
+func (h *serveHandler) put(w http.ResponseWriter, r *http.Request, key string) {
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = guessURLContentType(key)
+	}
+	_, err := putTargetStream(r.Context(), h.alias, h.urlOf(key), "", "", "", r.Body, r.ContentLength, nil, PutOptions{
+		metadata: map[string]string{"Content-Type": contentType},
+		sse:      h.sse(key),
+	})
+	if err != nil {
+		serveError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+// serveObjectReader reads an object from any offset, the object is read
+// again from the offset it is seeked to.
+type serveObjectReader struct {
+	ctx    context.Context
+	clnt   Client
+	opts   GetOptions
+	size   int64
+	offset int64
+	reader io.ReadCloser
+}
+
+func (o *serveObjectReader) Read(p []byte) (int, error) {
+	if o.offset >= o.size {
+		return 0, io.EOF
+	}
+	if o.reader == nil {
+		opts := o.opts
+		opts.RangeStart = o.offset
+		reader, err := o.clnt.Get(o.ctx, opts)
+		if err != nil {
+			return 0, err.ToGoError()
+		}
+		o.reader = reader
+	}
+	n, e := o.reader.Read(p)
+	o.offset += int64(n)
+	return n, e
+}
+
+func (o *serveObjectReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += o.offset
+	case io.SeekEnd:
+		offset += o.size
+	}
+	if offset < 0 {
+		return 0, errors.New("negative offset")
+	}
+	if offset != o.offset {
+		o.Close()
+		o.offset = offset
+	}
+	return offset, nil
+}
+
+func (o *serveObjectReader) Close() error {
+	if o.reader == nil {
+		return nil
+	}
+	e := o.reader.Close()
+	o.reader = nil
+	return e
+}
+
+// WebDAV multistatus response of PROPFIND.
+type davMultistatus struct {
+	XMLName   xml.Name      `xml:"D:multistatus"`
+	Namespace string        `xml:"xmlns:D,attr"`
+	Responses []davResponse `xml:"D:response"`
+}
+
+type davResponse struct {
+	Href     string      `xml:"D:href"`
+	Propstat davPropstat `xml:"D:propstat"`
+}
+
+type davPropstat struct {
+	Prop   davProp `xml:"D:prop"`
+	Status string  `xml:"D:status"`
+}
+
+type davProp struct {
+	DisplayName   string          `xml:"D:displayname"`
+	ResourceType  davResourceType `xml:"D:resourcetype"`
+	ContentLength *int64          `xml:"D:getcontentlength,omitempty"`
+	LastModified  string          `xml:"D:getlastmodified,omitempty"`
+	ETag          string          `xml:"D:getetag,omitempty"`
+	ContentType   string          `xml:"D:getcontenttype,omitempty"`
+}
+
+type davResourceType struct {
+	Collection *struct{} `xml:"D:collection,omitempty"`
+}
+
+func newDAVResponse(href string, entry serveEntry) davResponse {
+	prop := davProp{DisplayName: entry.Name}
+	if entry.IsDir {
+		prop.ResourceType.Collection = &struct{}{}
+	} else {
+		size := entry.Size
+		prop.ContentLength = &size
+		prop.ContentType = guessURLContentType(entry.Name)
+		if entry.ETag != "" {
+			prop.ETag = "\"" + entry.ETag + "\""
+		}
+	}
+	if !entry.ModTime.IsZero() {
+		prop.LastModified = entry.ModTime.UTC().Format(http.TimeFormat)
+	}
+	return davResponse{
+		Href:     (&url.URL{Path: href}).EscapedPath(),
+		Propstat: davPropstat{Prop: prop, Status: "HTTP/1.1 200 OK"},
+	}
+}
+
+// propfind replies with the properties of an object, or of a folder and
+// its entries unless the depth is 0. Infinite depth is served as 1.
+func (h *serveHandler) propfind(w http.ResponseWriter, r *http.Request, key string) {
+	ctx := r.Context()
+	href := "/" + key
+	var entry serveEntry
+	if key == "" {
+		entry = serveEntry{Name: "/", IsDir: true}
+	} else {
+		_, content, err := h.stat(ctx, key)
+		if err != nil {
+			serveError(w, err)
+			return
+		}
+		entry = serveEntry{
+			Name:    path.Base(key),
+			IsDir:   content.Type.IsDir(),
+			Size:    content.Size,
+			ModTime: content.Time,
+			ETag:    strings.Trim(content.ETag, "\""),
+		}
+	}
+	if entry.IsDir {
+		href = strings.TrimSuffix(href, "/") + "/"
+		entry.ModTime = time.Time{}
+	}
+
+	ms := davMultistatus{Namespace: "DAV:", Responses: []davResponse{newDAVResponse(href, entry)}}
+	if entry.IsDir && r.Header.Get("Depth") != "0" {
+		entries, err := h.list(ctx, key)
+		if err != nil {
+			serveError(w, err)
+			return
+		}
+		for _, child := range entries {
+			childHref := href + child.Name
+			if child.IsDir {
+				childHref += "/"
+			}
+			ms.Responses = append(ms.Responses, newDAVResponse(childHref, child))
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	io.WriteString(w, xml.Header)
+	e := xml.NewEncoder(w).Encode(ms)
+	errorIf(probe.NewError(e), "Unable to write the properties of `%s`.", key)
+}
@@ -0,0 +1,207 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/trinet2005/oss-mc/pkg/probe"
+	. "gopkg.in/check.v1"
+)
+
+func (s *TestSuite) TestServeHandler(c *C) {
+	root, e := os.MkdirTemp("", "serve-")
+	c.Assert(e, IsNil)
+	defer os.RemoveAll(root)
+	c.Assert(os.MkdirAll(filepath.Join(root, "dir"), 0o700), IsNil)
+	c.Assert(os.WriteFile(filepath.Join(root, "hello.txt"), []byte("hello world"), 0o600), IsNil)
+	c.Assert(os.WriteFile(filepath.Join(root, "dir", "a b.bin"), []byte("x"), 0o600), IsNil)
+
+	// Local folders are served without a config file.
+	defer func(load func() (*configV10, *probe.Error)) { loadMcConfig = load }(loadMcConfig)
+	loadMcConfig = func() (*configV10, *probe.Error) { return newMcConfig(), nil }
+
+	handler := &serveHandler{url: root + string(filepath.Separator), user: "user", password: "secret"}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	do := func(method, path string, header http.Header, body io.Reader) (*http.Response, string) {
+		req, e := http.NewRequest(method, server.URL+path, body)
+		c.Assert(e, IsNil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		if req.Header.Get("Authorization") == "" {
+			req.SetBasicAuth("user", "secret")
+		}
+		client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+		resp, e := client.Do(req)
+		c.Assert(e, IsNil)
+		defer resp.Body.Close()
+		data, e := io.ReadAll(resp.Body)
+		c.Assert(e, IsNil)
+		return resp, string(data)
+	}
+
+	resp, _ := do(http.MethodGet, "/", http.Header{"Authorization": {"Basic dXNlcjp3cm9uZw=="}}, nil)
+	c.Assert(resp.StatusCode, Equals, http.StatusUnauthorized)
+
+	resp, body := do(http.MethodGet, "/", nil, nil)
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	c.Assert(strings.Contains(body, `href="./hello.txt"`), Equals, true)
+	c.Assert(strings.Contains(body, `href="./dir/"`), Equals, true)
+
+	resp, body = do(http.MethodGet, "/hello.txt", http.Header{"Range": {"bytes=6-"}}, nil)
+	c.Assert(resp.StatusCode, Equals, http.StatusPartialContent)
+	c.Assert(body, Equals, "world")
+	c.Assert(resp.Header.Get("Content-Range"), Equals, "bytes 6-10/11")
+
+	resp, body = do(http.MethodHead, "/hello.txt", nil, nil)
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	c.Assert(resp.ContentLength, Equals, int64(11))
+	c.Assert(body, Equals, "")
+
+	resp, _ = do(http.MethodGet, "/hello.txt", http.Header{"If-Modified-Since": {resp.Header.Get("Last-Modified")}}, nil)
+	c.Assert(resp.StatusCode, Equals, http.StatusNotModified)
+
+	resp, _ = do(http.MethodGet, "/dir", nil, nil)
+	c.Assert(resp.StatusCode, Equals, http.StatusMovedPermanently)
+	c.Assert(resp.Header.Get("Location"), Equals, "/dir/")
+
+	resp, _ = do(http.MethodGet, "/missing.txt", nil, nil)
+	c.Assert(resp.StatusCode, Equals, http.StatusNotFound)
+	resp, _ = do(http.MethodGet, "/missing/", nil, nil)
+	c.Assert(resp.StatusCode, Equals, http.StatusNotFound)
+
+	// WebDAV and uploads are disabled by default.
+	resp, _ = do("PROPFIND", "/dir/", nil, nil)
+	c.Assert(resp.StatusCode, Equals, http.StatusMethodNotAllowed)
+	resp, _ = do(http.MethodPut, "/new.txt", nil, strings.NewReader("new"))
+	c.Assert(resp.StatusCode, Equals, http.StatusMethodNotAllowed)
+
+	handler.webdav, handler.writable = true, true
+	resp, body = do("PROPFIND", "/dir/", http.Header{"Depth": {"1"}}, nil)
+	c.Assert(resp.StatusCode, Equals, http.StatusMultiStatus)
+	c.Assert(strings.Contains(body, "<D:href>/dir/</D:href>"), Equals, true)
+	c.Assert(strings.Contains(body, "<D:href>/dir/a%20b.bin</D:href>"), Equals, true)
+	c.Assert(strings.Contains(body, "<D:getcontentlength>1</D:getcontentlength>"), Equals, true)
+
+	resp, body = do("PROPFIND", "/dir/", http.Header{"Depth": {"0"}}, nil)
+	c.Assert(resp.StatusCode, Equals, http.StatusMultiStatus)
+	c.Assert(strings.Contains(body, "a%20b.bin"), Equals, false)
+
+	resp, _ = do(http.MethodPut, "/up/new.txt", nil, strings.NewReader("new"))
+	c.Assert(resp.StatusCode, Equals, http.StatusCreated)
+	data, e := os.ReadFile(filepath.Join(root, "up", "new.txt"))
+	c.Assert(e, IsNil)
+	c.Assert(string(data), Equals, "new")
+}
+
+func (s *TestSuite) TestServeHandlerETag(c *C) {
+	modTime := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has("location") {
+			w.Write([]byte(`<LocationConstraint xmlns="http://s3.amazonaws.com/doc/2006-03-01/">us-east-1</LocationConstraint>`))
+			return
+		}
+		if r.URL.Path != "/bucket/object.txt" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("ETag", `"5eb63bbbe01eeed093cb22bb8f5acdc3"`)
+		http.ServeContent(w, r, "object.txt", modTime, strings.NewReader("hello world"))
+	}))
+	defer server.Close()
+
+	aliasToConfigMap["serve-test"] = &aliasConfigV10{URL: server.URL, AccessKey: "access", SecretKey: "secret-key", API: "S3v4", Path: "on"}
+	defer delete(aliasToConfigMap, "serve-test")
+
+	handler := &serveHandler{alias: "serve-test", url: server.URL + "/bucket/"}
+	req := httptest.NewRequest(http.MethodGet, "/object.txt", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	c.Assert(rec.Code, Equals, http.StatusOK)
+	c.Assert(rec.Body.String(), Equals, "hello world")
+	etag := rec.Header().Get("ETag")
+	c.Assert(etag, Equals, `"5eb63bbbe01eeed093cb22bb8f5acdc3"`)
+
+	req = httptest.NewRequest(http.MethodGet, "/object.txt", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	c.Assert(rec.Code, Equals, http.StatusNotModified)
+
+	req = httptest.NewRequest(http.MethodGet, "/object.txt", nil)
+	req.Header.Set("Range", "bytes=0-4")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	c.Assert(rec.Code, Equals, http.StatusPartialContent)
+	c.Assert(rec.Body.String(), Equals, "hello")
+}
+
+func (s *TestSuite) TestServeHandlerToken(c *C) {
+	handler := &serveHandler{token: "t0ken"}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	c.Assert(handler.authorized(req), Equals, false)
+	req.Header.Set("Authorization", "Bearer wrong")
+	c.Assert(handler.authorized(req), Equals, false)
+	req.Header.Set("Authorization", "Bearer t0ken")
+	c.Assert(handler.authorized(req), Equals, true)
+}
+
+func (s *TestSuite) TestServeKey(c *C) {
+	for _, testCase := range []struct {
+		urlPath string
+		key     string
+		ok      bool
+	}{
+		{"/", "", true},
+		{"/dir/", "dir", true},
+		{"/dir//a.txt", "dir/a.txt", true},
+		{"/./a.txt", "a.txt", true},
+		{"/../a.txt", "", false},
+		{"/dir/../../a.txt", "", false},
+		{`/..\..\Windows/win.ini`, "", false},
+		{`/dir\a.txt`, "", false},
+	} {
+		key, ok := serveKey(testCase.urlPath)
+		c.Assert(ok, Equals, testCase.ok, Commentf("%s", testCase.urlPath))
+		c.Assert(key, Equals, testCase.key, Commentf("%s", testCase.urlPath))
+	}
+
+	handler := &serveHandler{url: c.MkDir() + string(filepath.Separator)}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/..%5c..%5cWindows/win.ini", nil))
+	c.Assert(rec.Code, Equals, http.StatusBadRequest)
+}
+
+func (s *TestSuite) TestIsLoopbackAddr(c *C) {
+	c.Assert(isLoopbackAddr("localhost:8080"), Equals, true)
+	c.Assert(isLoopbackAddr("127.0.0.1:8080"), Equals, true)
+	c.Assert(isLoopbackAddr("[::1]:8080"), Equals, true)
+	c.Assert(isLoopbackAddr(":8080"), Equals, false)
+	c.Assert(isLoopbackAddr("0.0.0.0:8080"), Equals, false)
+	c.Assert(isLoopbackAddr("192.168.1.10:8080"), Equals, false)
+	c.Assert(isLoopbackAddr("8080"), Equals, false)
+}
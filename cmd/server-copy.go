@@ -0,0 +1,117 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/trinet2005/oss-go-sdk/pkg/encrypt"
+	"github.com/trinet2005/oss-mc/pkg/probe"
+)
+
+// Objects larger than maxCopyObjectSize can only be copied server-side
+// with multipart UploadPartCopy requests.
+const maxCopyObjectSize = 5 * 1024 * 1024 * 1024
+
+// serverCopyCache remembers per alias pair and source bucket whether the
+// target credentials are able to read the source, i.e. copy server-side.
+var serverCopyCache sync.Map
+
+// aliasEndpoint normalizes the endpoint of an alias, aliases with the same
+// endpoint point to the same cluster.
+func aliasEndpoint(aliasCfg *aliasConfigV10) string {
+	if aliasCfg == nil {
+		return ""
+	}
+	u, e := url.Parse(aliasCfg.URL)
+	if e != nil || u.Host == "" {
+		return ""
+	}
+	scheme := strings.ToLower(u.Scheme)
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if scheme == "https" {
+			port = "443"
+		}
+	}
+	return scheme + "://" + host + ":" + port + strings.TrimSuffix(u.Path, "/")
+}
+
+// sameAliasCredentials returns true if both aliases authenticate as the
+// same identity.
+func sameAliasCredentials(first, second *aliasConfigV10) bool {
+	return first.AccessKey == second.AccessKey && first.SecretKey == second.SecretKey &&
+		first.SessionToken == second.SessionToken && credentialsKey(first.Credentials) == credentialsKey(second.Credentials) &&
+		(first.Credentials == nil || first.Credentials.Provider != credentialStore)
+}
+
+// canCopyServerSide returns true if the source of a copy between two
+// different aliases is on the same cluster as its target, and readable
+// with the target credentials. The data is then copied server-side
+// instead of being streamed through the client.
+func canCopyServerSide(ctx context.Context, urls URLs, srcSSE encrypt.ServerSide) bool {
+	_, _, srcCfg, err := expandAlias(urls.SourceAlias)
+	if err != nil || srcCfg == nil {
+		return false
+	}
+	_, _, tgtCfg, err := expandAlias(urls.TargetAlias)
+	if err != nil || tgtCfg == nil {
+		return false
+	}
+	endpoint := aliasEndpoint(srcCfg)
+	if endpoint == "" || endpoint != aliasEndpoint(tgtCfg) {
+		return false
+	}
+	key := serverCopyKey(urls)
+	if ok, found := serverCopyCache.Load(key); found {
+		return ok.(bool)
+	}
+	if sameAliasCredentials(srcCfg, tgtCfg) {
+		return true
+	}
+
+	// Check the target identity is allowed to read the source.
+	ok := statWithAlias(ctx, urls.TargetAlias, urlJoinPath(tgtCfg.URL, urls.SourceContent.URL.Path), urls.SourceContent.VersionID, srcSSE) == nil
+	serverCopyCache.Store(key, ok)
+	return ok
+}
+
+// disableServerCopy falls back to streaming copies between two aliases,
+// after the target credentials were refused to read the source.
+func disableServerCopy(urls URLs) {
+	serverCopyCache.Store(serverCopyKey(urls), false)
+}
+
+func serverCopyKey(urls URLs) string {
+	bucket, _ := url2BucketAndObject(&urls.SourceContent.URL)
+	return urls.SourceAlias + "\x00" + urls.TargetAlias + "\x00" + bucket
+}
+
+func statWithAlias(ctx context.Context, alias, urlStr, versionID string, sse encrypt.ServerSide) *probe.Error {
+	clnt, err := newClientFromAlias(alias, urlStr)
+	if err != nil {
+		return err.Trace(alias, urlStr)
+	}
+	_, err = clnt.Stat(ctx, StatOptions{versionID: versionID, sse: sse})
+	return err
+}
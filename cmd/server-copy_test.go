@@ -0,0 +1,90 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *TestSuite) TestAliasEndpoint(c *C) {
+	testCases := []struct {
+		url      string
+		endpoint string
+	}{
+		{"https://play.min.io", "https://play.min.io:443"},
+		{"https://PLAY.min.io:443/", "https://play.min.io:443"},
+		{"http://localhost:9000", "http://localhost:9000"},
+		{"http://localhost", "http://localhost:80"},
+		{"http://gateway/minio/", "http://gateway:80/minio"},
+		{"localhost:9000", ""},
+	}
+	for _, testCase := range testCases {
+		c.Assert(aliasEndpoint(&aliasConfigV10{URL: testCase.url}), Equals, testCase.endpoint, Commentf("%s", testCase.url))
+	}
+	c.Assert(aliasEndpoint(nil), Equals, "")
+}
+
+func (s *TestSuite) TestCanCopyServerSide(c *C) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has("location") {
+			w.Write([]byte(`<LocationConstraint xmlns="http://s3.amazonaws.com/doc/2006-03-01/">us-east-1</LocationConstraint>`))
+			return
+		}
+		if strings.HasPrefix(r.URL.Path, "/private/") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Header().Set("ETag", `"d41d8cd98f00b204e9800998ecf8427e"`)
+		w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+		w.Header().Set("Content-Length", "0")
+	}))
+	defer server.Close()
+
+	aliasToConfigMap["sc-src"] = &aliasConfigV10{URL: server.URL, AccessKey: "source", SecretKey: "source-secret", API: "S3v4", Path: "on"}
+	aliasToConfigMap["sc-tgt"] = &aliasConfigV10{URL: server.URL + "/", AccessKey: "target", SecretKey: "target-secret", API: "S3v4", Path: "on"}
+	aliasToConfigMap["sc-same"] = &aliasConfigV10{URL: server.URL, AccessKey: "source", SecretKey: "source-secret", API: "S3v4", Path: "on"}
+	aliasToConfigMap["sc-other"] = &aliasConfigV10{URL: "https://play.min.io", AccessKey: "source", SecretKey: "source-secret", API: "S3v4", Path: "on"}
+	defer func() {
+		for _, alias := range []string{"sc-src", "sc-tgt", "sc-same", "sc-other"} {
+			delete(aliasToConfigMap, alias)
+		}
+	}()
+
+	copyURLs := func(targetAlias, object string) URLs {
+		return URLs{
+			SourceAlias:   "sc-src",
+			SourceContent: &ClientContent{URL: *newClientURL(server.URL + object)},
+			TargetAlias:   targetAlias,
+			TargetContent: &ClientContent{URL: *newClientURL(server.URL + "/target/object")},
+		}
+	}
+	ctx := context.Background()
+
+	c.Assert(canCopyServerSide(ctx, copyURLs("sc-same", "/private/object"), nil), Equals, true)
+	c.Assert(canCopyServerSide(ctx, copyURLs("sc-other", "/public/object"), nil), Equals, false)
+	c.Assert(canCopyServerSide(ctx, copyURLs("sc-tgt", "/public/object"), nil), Equals, true)
+	c.Assert(canCopyServerSide(ctx, copyURLs("sc-tgt", "/private/object"), nil), Equals, false)
+
+	disableServerCopy(copyURLs("sc-tgt", "/public/object"))
+	c.Assert(canCopyServerSide(ctx, copyURLs("sc-tgt", "/public/other"), nil), Equals, false)
+}
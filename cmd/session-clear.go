@@ -0,0 +1,117 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"strings"
+
+	"github.com/minio/cli"
+	json "github.com/minio/colorjson"
+	"github.com/trinet2005/oss-mc/pkg/probe"
+	"github.com/trinet2005/oss-pkg/console"
+)
+
+var sessionClearFlags = []cli.Flag{
+	cli.BoolFlag{
+		Name:  "all, a",
+		Usage: "clear all the sessions",
+	},
+}
+
+var sessionClearCmd = cli.Command{
+	Name:            "clear",
+	Usage:           "clear interrupted copy and mirror sessions",
+	Action:          mainSessionClear,
+	Before:          setGlobalsFromContext,
+	Flags:           append(sessionClearFlags, globalFlags...),
+	HideHelpCommand: true,
+	OnUsageError:    onUsageError,
+	CustomHelpTemplate: `NAME:
+  {{.HelpName}} - {{.Usage}}
+
+USAGE:
+  {{.HelpName}} [FLAGS] [SESSION-ID]
+
+FLAGS:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}
+EXAMPLES:
+  1. Clear a session.
+     {{.Prompt}} {{.HelpName}} mirror-ab1b3fe2b5e0d6f0c7d1a3e7d5bb3c2f1e0a5d8c9b7a6f5e4d3c2b1a0f9e8d7c
+
+  2. Clear all the sessions.
+     {{.Prompt}} {{.HelpName}} --all
+
+`,
+}
+
+// clearSessionMessage container for clearing session messages.
+type clearSessionMessage struct {
+	Status    string `json:"status"`
+	SessionID string `json:"sessionId"`
+}
+
+// String colorized clear session message.
+func (c clearSessionMessage) String() string {
+	return console.Colorize("ClearSession", "Session `"+c.SessionID+"` cleared successfully.")
+}
+
+// JSON jsonified clear session message.
+func (c clearSessionMessage) JSON() string {
+	c.Status = "success"
+	clearSessionJSONBytes, e := json.MarshalIndent(c, "", " ")
+	fatalIf(probe.NewError(e), "Unable to marshal into JSON.")
+
+	return string(clearSessionJSONBytes)
+}
+
+// clearSession removes a copy or mirror session.
+func clearSession(sid string) *probe.Error {
+	if isMirrorJournalExists(sid) {
+		return removeMirrorJournal(sid).Trace(sid)
+	}
+	if !isSessionExists(sid) {
+		return errDummy().Trace(sid)
+	}
+	s, err := loadSessionV8(sid)
+	if err != nil {
+		return err.Trace(sid)
+	}
+	return s.Delete().Trace(sid)
+}
+
+// mainSessionClear is the handle for "mc session clear" command.
+func mainSessionClear(ctx *cli.Context) error {
+	setSessionColors()
+
+	var sids []string
+	switch {
+	case ctx.Bool("all") && len(ctx.Args()) == 0:
+		sids = append(getSessionIDs(), getMirrorJournalIDs()...)
+	case !ctx.Bool("all") && len(ctx.Args()) == 1:
+		sids = []string{strings.TrimSpace(ctx.Args().First())}
+	default:
+		showCommandHelpAndExit(ctx, 1) // last argument is exit code.
+	}
+
+	for _, sid := range sids {
+		fatalIf(clearSession(sid), "Unable to clear session `%s`.", sid)
+		printMsg(clearSessionMessage{SessionID: sid})
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"sort"
+	"time"
+
+	"github.com/minio/cli"
+)
+
+var sessionListCmd = cli.Command{
+	Name:            "list",
+	ShortName:       "ls",
+	Usage:           "list interrupted copy and mirror sessions",
+	Action:          mainSessionList,
+	Before:          setGlobalsFromContext,
+	Flags:           globalFlags,
+	HideHelpCommand: true,
+	OnUsageError:    onUsageError,
+	CustomHelpTemplate: `NAME:
+  {{.HelpName}} - {{.Usage}}
+
+USAGE:
+  {{.HelpName}}
+
+FLAGS:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}
+EXAMPLES:
+  1. List all the interrupted sessions.
+     {{.Prompt}} {{.HelpName}}
+
+`,
+}
+
+// listSessions - list the copy and mirror sessions, oldest first.
+func listSessions() {
+	type sessionInfo struct {
+		msg  message
+		when time.Time
+	}
+	var sessions []sessionInfo
+	for _, sid := range getSessionIDs() {
+		s, err := loadSessionV8(sid)
+		if err != nil {
+			continue // Skip sessions which cannot be loaded.
+		}
+		s.DataFP.Close()
+		sessions = append(sessions, sessionInfo{s, s.Header.When})
+	}
+	for _, sid := range getMirrorJournalIDs() {
+		header, err := loadMirrorJournalHeader(sid)
+		if err != nil {
+			continue // Skip sessions which cannot be loaded.
+		}
+		sessions = append(sessions, sessionInfo{&mirrorJournal{Header: header, SessionID: sid}, header.When})
+	}
+
+	sort.SliceStable(sessions, func(i, j int) bool {
+		return sessions[i].when.Before(sessions[j].when)
+	})
+	for _, s := range sessions {
+		printMsg(s.msg)
+	}
+}
+
+// mainSessionList is the handle for "mc session list" command.
+func mainSessionList(ctx *cli.Context) error {
+	if len(ctx.Args()) != 0 {
+		showCommandHelpAndExit(ctx, 1) // last argument is exit code.
+	}
+	setSessionColors()
+	listSessions()
+	return nil
+}
@@ -0,0 +1,55 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"github.com/fatih/color"
+	"github.com/minio/cli"
+	"github.com/trinet2005/oss-pkg/console"
+)
+
+var sessionSubcommands = []cli.Command{
+	sessionListCmd,
+	sessionResumeCmd,
+	sessionClearCmd,
+}
+
+var sessionCmd = cli.Command{
+	Name:            "session",
+	Usage:           "manage interrupted copy and mirror sessions",
+	Action:          mainSession,
+	Before:          setGlobalsFromContext,
+	HideHelpCommand: true,
+	Flags:           globalFlags,
+	Subcommands:     sessionSubcommands,
+}
+
+// mainSession is the handle for "mc session" command.
+func mainSession(ctx *cli.Context) error {
+	commandNotFound(ctx, sessionSubcommands)
+	return nil
+	// Sub-commands like list, resume and clear have their own main.
+}
+
+// setSessionColors - colors of the session messages.
+func setSessionColors() {
+	console.SetColor("SessionID", color.New(color.FgYellow, color.Bold))
+	console.SetColor("SessionTime", color.New(color.FgGreen))
+	console.SetColor("Command", color.New(color.FgWhite, color.Bold))
+	console.SetColor("ClearSession", color.New(color.FgGreen, color.Bold))
+}
@@ -0,0 +1,84 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"flag"
+	"strings"
+
+	"github.com/minio/cli"
+	"github.com/trinet2005/oss-mc/pkg/probe"
+)
+
+var sessionResumeCmd = cli.Command{
+	Name:            "resume",
+	Usage:           "resume an interrupted mirror session",
+	Action:          mainSessionResume,
+	Before:          setGlobalsFromContext,
+	Flags:           globalFlags,
+	HideHelpCommand: true,
+	OnUsageError:    onUsageError,
+	CustomHelpTemplate: `NAME:
+  {{.HelpName}} - {{.Usage}}
+
+USAGE:
+  {{.HelpName}} SESSION-ID
+
+FLAGS:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}
+EXAMPLES:
+  1. Resume an interrupted mirror session, see 'mc session list' for the session IDs.
+     {{.Prompt}} {{.HelpName}} mirror-ab1b3fe2b5e0d6f0c7d1a3e7d5bb3c2f1e0a5d8c9b7a6f5e4d3c2b1a0f9e8d7c
+
+`,
+}
+
+// mainSessionResume is the handle for "mc session resume" command.
+func mainSessionResume(ctx *cli.Context) error {
+	if len(ctx.Args()) != 1 {
+		showCommandHelpAndExit(ctx, 1) // last argument is exit code.
+	}
+	sid := strings.TrimSpace(ctx.Args().First())
+
+	if isSessionExists(sid) {
+		// Copy sessions are identified by their command line.
+		s, err := loadSessionV8(sid)
+		fatalIf(err.Trace(sid), "Unable to load session `%s`.", sid)
+		s.DataFP.Close()
+		fatalIf(errInvalidArgument().Trace(sid),
+			"Run `mc %s --continue %s` from `%s` to resume copy session `%s`.",
+			s.Header.CommandType, strings.Join(s.Header.CommandArgs, " "), s.Header.RootPath, sid)
+	}
+
+	if !isMirrorJournalExists(sid) {
+		fatalIf(errDummy().Trace(sid), "Session `%s` not found.", sid)
+	}
+
+	// Run mirror as `mc mirror --resume SESSION-ID`.
+	set := flag.NewFlagSet(mirrorCmd.Name, flag.ContinueOnError)
+	for _, f := range mirrorCmd.Flags {
+		f.Apply(set)
+	}
+	if e := set.Set("resume", sid); e != nil {
+		fatalIf(probe.NewError(e).Trace(sid), "Unable to resume mirror session `%s`.", sid)
+	}
+	mirrorCtx := cli.NewContext(ctx.App, set, ctx)
+	mirrorCtx.Command = mirrorCmd
+	return mainMirror(mirrorCtx)
+}
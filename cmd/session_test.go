@@ -18,10 +18,12 @@
 package cmd
 
 import (
+	"flag"
 	"math/rand"
 	"os"
 	"regexp"
 
+	"github.com/minio/cli"
 	. "gopkg.in/check.v1"
 )
 
@@ -72,3 +74,39 @@ func (s *TestSuite) TestSession(c *C) {
 	_, e = os.Stat(session.DataFP.Name())
 	c.Assert(e, NotNil)
 }
+
+func (s *TestSuite) TestMirrorJournal(c *C) {
+	err := createSessionDir()
+	c.Assert(err, IsNil)
+
+	set := flag.NewFlagSet("mirror", flag.ContinueOnError)
+	c.Assert(set.Parse([]string{"mybucket", "myminio/mybucket"}), IsNil)
+	sid := getHash("mirror", []string{"mybucket", "myminio/mybucket"})
+	journal, err := newMirrorJournal(sid, cli.NewContext(nil, set, nil))
+	c.Assert(err, IsNil)
+	c.Assert(isMirrorJournalExists(sid), Equals, true)
+
+	for _, key := range []string{"a", "b", "c", "d"} {
+		journal.Queue(key)
+	}
+	// Completed out of order, the marker only moves past "b".
+	c.Assert(journal.Done("b"), IsNil)
+	c.Assert(journal.Done("a"), IsNil)
+	c.Assert(journal.Done("d"), IsNil)
+	c.Assert(journal.Header.Marker, Equals, "b")
+
+	// Simulate a crash in the middle of journaling a key.
+	_, e := journal.journalFP.WriteString(`"c`)
+	c.Assert(e, IsNil)
+	c.Assert(journal.Save(), IsNil)
+
+	saved, err := loadMirrorJournal(sid)
+	c.Assert(err, IsNil)
+	c.Assert(saved.Header.CommandArgs, DeepEquals, []string{"mybucket", "myminio/mybucket"})
+	c.Assert(saved.Header.Marker, Equals, "b")
+	c.Assert(saved.IsDone("c"), Equals, false)
+	c.Assert(saved.IsDone("d"), Equals, true)
+
+	c.Assert(saved.Delete(), IsNil)
+	c.Assert(isMirrorJournalExists(sid), Equals, false)
+}
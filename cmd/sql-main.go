@@ -312,6 +312,18 @@ func getCSVHeader(sourceURL string, encKeyDB map[string][]prefixSSEPair) ([]stri
 		if r, metadata, err = getSourceStreamMetadataFromURL(globalContext, sourceURL, "", time.Time{}, encKeyDB, false); err != nil {
 			return nil, err.Trace(sourceURL)
 		}
+		// Decompress objects compressed client-side.
+		if _, content, err := url2Stat(globalContext, sourceURL, "", false, encKeyDB, time.Time{}, false); err == nil {
+			if codec := compressionCodec(content); codec != "" {
+				dr, e := newDecompressReader(r, codec)
+				if e != nil {
+					r.Close()
+					return nil, probe.NewError(e)
+				}
+				defer r.Close()
+				r = dr
+			}
+		}
 		ctype := metadata["Content-Type"]
 		if strings.Contains(ctype, "gzip") {
 			var e error
@@ -396,6 +408,22 @@ func sqlSelect(targetURL, expression string, encKeyDB map[string][]prefixSSEPair
 	}
 
 	sseKey := getSSE(targetURL, encKeyDB[alias])
+
+	// Objects compressed client-side are decompressed by the server.
+	if selOpts.CompressionType == "" {
+		if content, err := targetClnt.Stat(ctx, StatOptions{sse: sseKey}); err == nil {
+			if isCSEEncrypted(content.Metadata) {
+				return probe.NewError(errors.New("objects encrypted client-side cannot be queried")).Trace(targetURL)
+			}
+			switch compressionCodec(content) {
+			case compressZstd:
+				selOpts.CompressionType = minio.SelectCompressionZSTD
+			case compressGzip:
+				selOpts.CompressionType = minio.SelectCompressionGZIP
+			}
+		}
+	}
+
 	outputer, err := targetClnt.Select(ctx, expression, sseKey, selOpts)
 	if err != nil {
 		return err.Trace(targetURL, expression)
@@ -477,12 +505,12 @@ func mainSQL(cliCtx *cli.Context) error {
 				continue
 			}
 			if writeHdr {
-				query, csvHdrs, selOpts = getAndValidateArgs(cliCtx, encKeyDB, targetAlias+content.URL.Path)
+				query, csvHdrs, selOpts = getAndValidateArgs(cliCtx, encKeyDB, filepath.ToSlash(aliasPath(targetAlias, content.URL.Path)))
 			}
 			contentType := mimedb.TypeByExtension(filepath.Ext(content.URL.Path))
 			for _, cTypeSuffix := range supportedContentTypes {
 				if strings.Contains(contentType, cTypeSuffix) {
-					errorIf(sqlSelect(targetAlias+content.URL.Path, query,
+					errorIf(sqlSelect(filepath.ToSlash(aliasPath(targetAlias, content.URL.Path)), query,
 						encKeyDB, selOpts, csvHdrs, writeHdr).Trace(content.URL.String()), "Unable to run sql")
 				}
 				writeHdr = false
@@ -44,6 +44,8 @@ type statMessage struct {
 	Key               string             `json:"name"`
 	Date              time.Time          `json:"lastModified"`
 	Size              int64              `json:"size"`
+	StoredSize        int64              `json:"storedSize,omitempty"`
+	Compression       string             `json:"compression,omitempty"`
 	ETag              string             `json:"etag"`
 	Type              string             `json:"type,omitempty"`
 	Expires           *time.Time         `json:"expires,omitempty"`
@@ -65,7 +67,11 @@ func (stat statMessage) String() (msg string) {
 		msgBuilder.WriteString(fmt.Sprintf("%-10s: %s ", "Date", stat.Date.Format(printDate)) + "\n")
 	}
 	if stat.Type != "folder" {
-		msgBuilder.WriteString(fmt.Sprintf("%-10s: %-6s ", "Size", humanize.IBytes(uint64(stat.Size))) + "\n")
+		size := humanize.IBytes(uint64(stat.Size))
+		if stat.Compression != "" {
+			size += " " + compressionDescription(stat.Size, stat.StoredSize, stat.Compression)
+		}
+		msgBuilder.WriteString(fmt.Sprintf("%-10s: %-6s ", "Size", size) + "\n")
 	}
 
 	if stat.ETag != "" {
@@ -158,6 +164,13 @@ func parseStat(c *ClientContent) statMessage {
 		return "file"
 	}()
 	content.Size = c.Size
+	if codec := compressionCodec(c); codec != "" {
+		content.Compression = codec
+		content.StoredSize = c.Size
+		if size := uncompressedSize(c); size >= 0 {
+			content.Size = size
+		}
+	}
 	content.VersionID = c.VersionID
 	content.Key = getKey(c)
 	content.Metadata = c.Metadata
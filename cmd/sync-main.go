@@ -0,0 +1,422 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/minio/cli"
+	"github.com/trinet2005/oss-mc/pkg/probe"
+	"github.com/trinet2005/oss-pkg/console"
+)
+
+// sync specific flags.
+var syncFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "conflict",
+		Usage: "resolve object(s) changed on both sides with 'newer-wins', 'keep-both' or 'fail'",
+		Value: syncConflictFail,
+	},
+	cli.BoolFlag{
+		Name:  "dry-run",
+		Usage: "print the changes without syncing",
+	},
+	cli.BoolFlag{
+		Name:  "preserve, a",
+		Usage: "preserve file(s)/object(s) attributes on the other side",
+	},
+	cli.BoolFlag{
+		Name:  "force",
+		Usage: "sync even when a folder synced before is missing or most objects of a folder would be removed",
+	},
+}
+
+// Two-way synchronization of two folders.
+var syncCmd = cli.Command{
+	Name:         "sync",
+	Usage:        "synchronize two folders both ways",
+	Action:       mainSync,
+	OnUsageError: onUsageError,
+	Before:       setGlobalsFromContext,
+	Flags:        append(append(syncFlags, ioFlags...), globalFlags...),
+	CustomHelpTemplate: `NAME:
+  {{.HelpName}} - {{.Usage}}
+
+USAGE:
+  {{.HelpName}} [FLAGS] FIRST SECOND
+
+FLAGS:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}
+DESCRIPTION:
+  Sync copies objects created or modified on either side to the other side, and removes objects on one
+  side which were removed on the other side since the last sync. The versions last synced are kept in a
+  state file per pair of folders in the config folder, the first sync only copies the objects missing
+  on either side.
+
+  Objects changed on both sides since the last sync are conflicts, unless they hold the same data. They
+  are resolved with --conflict:
+
+     fail       - leave both versions as they are and exit with status 2, the default.
+     newer-wins - the most recently modified version is copied over the other one.
+     keep-both  - the older version is kept on both sides as NAME.conflict-YYYYMMDD-HHMMSS.EXT, and
+                  the newer one is copied over it.
+
+  A modification always wins over a removal.
+
+  As protection against unmounted drives, mistyped paths or removed buckets, sync refuses to run when
+  a folder synced before does not exist anymore, or when it would remove more than half of the objects
+  of a folder. Use --force to sync anyway.
+
+ENVIRONMENT VARIABLES:
+  MC_ENCRYPT_KEY:  list of comma delimited prefix=secret values
+
+EXAMPLES:
+  1. Sync a local folder with a bucket on MinIO cloud storage.
+     {{.Prompt}} {{.HelpName}} ~/Documents play/mybucket/documents
+
+  2. Sync two sites, keeping the most recent version of conflicting objects.
+     {{.Prompt}} {{.HelpName}} --conflict newer-wins site1/mybucket site2/mybucket
+
+  3. Sync a laptop with a bucket, keeping both versions of conflicting objects.
+     {{.Prompt}} {{.HelpName}} --conflict keep-both ~/Notes s3/notes
+
+  4. Print the changes a sync would make.
+     {{.Prompt}} {{.HelpName}} --dry-run ~/Documents play/mybucket/documents
+`,
+}
+
+// syncSide is one of the folders of a sync.
+type syncSide struct {
+	alias   string
+	url     string
+	missing bool
+	objects map[string]*ClientContent
+}
+
+// path returns the aliased path of an object.
+func (s syncSide) path(key string) string {
+	return filepath.ToSlash(aliasPath(s.alias, newClientURL(urlJoinPath(s.url, key)).Path))
+}
+
+// syncJob syncs two folders.
+type syncJob struct {
+	sides    [2]*syncSide
+	state    *syncState
+	encKeyDB map[string][]prefixSSEPair
+	preserve bool
+	dryRun   bool
+	status   ProgressReader
+}
+
+// checkSyncSyntax - validate all the passed arguments
+func checkSyncSyntax(cliCtx *cli.Context) {
+	if len(cliCtx.Args()) != 2 {
+		showCommandHelpAndExit(cliCtx, 1) // last argument is exit code
+	}
+	for _, arg := range cliCtx.Args() {
+		if strings.TrimSpace(arg) == "" {
+			fatalIf(errInvalidArgument().Trace(cliCtx.Args()...), "Unable to validate empty argument.")
+		}
+	}
+	switch policy := cliCtx.String("conflict"); policy {
+	case syncConflictFail, syncConflictNewerWins, syncConflictKeepBoth:
+	default:
+		fatalIf(errInvalidArgument().Trace(policy), "Invalid conflict policy `"+policy+"`.")
+	}
+}
+
+// listSyncSide lists the objects of a folder keyed by their name below
+// it, a missing folder has no objects and is flagged as missing.
+func listSyncSide(ctx context.Context, aliasedURL string) (*syncSide, *probe.Error) {
+	separator := string(newClientURL(aliasedURL).Separator)
+	if !strings.HasSuffix(aliasedURL, separator) {
+		aliasedURL += separator
+	}
+	alias, urlStr, _ := mustExpandAlias(aliasedURL)
+	side := &syncSide{alias: alias, url: urlStr, objects: make(map[string]*ClientContent)}
+
+	clnt, err := newClientFromAlias(alias, urlStr)
+	if err != nil {
+		return nil, err.Trace(aliasedURL)
+	}
+	prefixPath := filepath.ToSlash(clnt.GetURL().Path)
+	if !strings.HasSuffix(prefixPath, "/") {
+		prefixPath += "/"
+	}
+	prefixPath = strings.TrimPrefix(prefixPath, "."+separator)
+
+	if _, err = clnt.Stat(ctx, StatOptions{}); err != nil {
+		switch err.ToGoError().(type) {
+		case PathNotFound, ObjectMissing, BucketDoesNotExist:
+			side.missing = true
+			return side, nil
+		}
+		return nil, err.Trace(aliasedURL)
+	}
+
+	for content := range clnt.List(ctx, ListOptions{Recursive: true, ShowDir: DirNone}) {
+		if content.Err != nil {
+			switch content.Err.ToGoError().(type) {
+			case PathNotFound, ObjectMissing:
+				continue
+			}
+			// Objects not listed would be taken for removed ones.
+			return nil, content.Err.Trace(aliasedURL)
+		}
+		if content.IsDeleteMarker || content.Type.IsDir() {
+			continue
+		}
+		key := strings.TrimPrefix(strings.TrimPrefix(filepath.ToSlash(content.URL.Path), prefixPath), "/")
+		side.objects[key] = content
+	}
+	return side, nil
+}
+
+// checkSyncSafety refuses syncs which look like one of the folders went
+// away rather than its objects were removed: a folder synced before is
+// missing, or more than half of the objects of a folder would be removed.
+func checkSyncSafety(sides [2]*syncSide, state *syncState, ops []syncOp) *probe.Error {
+	for _, side := range sides {
+		if side.missing && len(state.entries) > 0 {
+			return errSyncRootMissing(side.path(""))
+		}
+	}
+	var removals [2]int
+	for _, op := range ops {
+		if op.action == syncActionDelete {
+			removals[op.to]++
+		}
+	}
+	for i, side := range sides {
+		if removals[i] > 1 && removals[i]*100 > len(side.objects)*syncMaxRemovalPercent {
+			return errSyncTooManyRemovals(side.path(""), removals[i], len(side.objects))
+		}
+	}
+	return nil
+}
+
+// sameContent compares the objects of both sides by their digests, or
+// byte by byte when there are none.
+func (sj *syncJob) sameContent(ctx context.Context, comparer *contentComparer, key string) (bool, *probe.Error) {
+	first, second := sj.sides[0].objects[key], sj.sides[1].objects[key]
+	if first.Size != second.Size {
+		return false, nil
+	}
+	same, known := comparer.compareDigests(ctx, first, second)
+	if known {
+		return same, nil
+	}
+	return comparer.sameStream(ctx, first, second)
+}
+
+// doCopy copies an object to the other side, or to another name on the
+// same side, and records the version written.
+func (sj *syncJob) doCopy(ctx context.Context, op syncOp) *probe.Error {
+	from, to := sj.sides[op.from], sj.sides[op.to]
+	source := from.objects[op.key]
+	targetURL := urlJoinPath(to.url, op.target)
+
+	if progressReader, ok := sj.status.(*progressBar); ok {
+		progressReader.SetCaption(source.URL.String() + ":")
+	} else {
+		printMsg(syncMessage{
+			Action: syncActionCopy,
+			Source: from.path(op.key),
+			Target: to.path(op.target),
+			Size:   source.Size,
+		})
+	}
+	if sj.dryRun {
+		if progressReader, ok := sj.status.(*progressBar); ok {
+			progressReader.ProgressBar.Add64(source.Size)
+		}
+		return nil
+	}
+
+	urls := uploadSourceToTargetURL(ctx, URLs{
+		SourceAlias:   from.alias,
+		SourceContent: source,
+		TargetAlias:   to.alias,
+		TargetContent: &ClientContent{URL: *newClientURL(targetURL)},
+	}, sj.status, sj.encKeyDB, sj.preserve, false)
+	if urls.Error != nil {
+		return urls.Error.Trace(source.URL.String(), targetURL)
+	}
+
+	clnt, err := newClientFromAlias(to.alias, targetURL)
+	if err != nil {
+		return err.Trace(targetURL)
+	}
+	target, err := clnt.Stat(ctx, StatOptions{sse: getSSE(to.path(op.target), sj.encKeyDB[to.alias])})
+	if err != nil {
+		return err.Trace(targetURL)
+	}
+	sj.state.set(op.target, op.to == 1, target)
+	if op.key == op.target {
+		sj.state.set(op.key, op.from == 1, source)
+	}
+	return nil
+}
+
+// doRemove removes an object removed on the other side.
+func (sj *syncJob) doRemove(ctx context.Context, op syncOp) *probe.Error {
+	to := sj.sides[op.to]
+	target := to.objects[op.key]
+	if _, ok := sj.status.(*progressBar); !ok {
+		printMsg(syncMessage{Action: syncActionDelete, Target: to.path(op.key)})
+	}
+	if sj.dryRun {
+		return nil
+	}
+
+	clnt, err := newClientFromAlias(to.alias, to.url)
+	if err != nil {
+		return err.Trace(to.url)
+	}
+	contentCh := make(chan *ClientContent, 1)
+	contentCh <- &ClientContent{URL: target.URL}
+	close(contentCh)
+	for result := range clnt.Remove(ctx, false, false, false, false, contentCh) {
+		if result.Err != nil {
+			return result.Err.Trace(target.URL.String())
+		}
+	}
+	sj.state.remove(op.key)
+	return nil
+}
+
+// doSync runs the actions planned, it reports whether conflicts were
+// left unresolved and whether actions failed.
+func (sj *syncJob) doSync(ctx context.Context, ops []syncOp) (conflicts, failed bool) {
+	for _, op := range ops {
+		var err *probe.Error
+		switch op.action {
+		case syncActionCopy:
+			err = sj.doCopy(ctx, op)
+		case syncActionDelete:
+			err = sj.doRemove(ctx, op)
+		case syncActionRecord:
+			sj.state.set(op.key, false, sj.sides[0].objects[op.key])
+			sj.state.set(op.key, true, sj.sides[1].objects[op.key])
+		case syncActionForget:
+			sj.state.remove(op.key)
+		case syncActionConflict:
+			first, second := sj.sides[0].path(op.key), sj.sides[1].path(op.key)
+			if op.err != nil {
+				errorIf(op.err, "Unable to compare `"+first+"` and `"+second+"`.")
+				failed = true
+				continue
+			}
+			if globalJSON {
+				printMsg(syncMessage{Action: syncActionConflict, Source: first, Target: second})
+			}
+			errorIf(errSyncConflict(first, second), "Unable to sync `"+first+"`.")
+			conflicts = true
+		}
+		if err != nil {
+			errorIf(err, "Unable to sync `"+sj.sides[op.from].path(op.key)+"`.")
+			failed = true
+		}
+	}
+	return conflicts, failed
+}
+
+// mainSync is the entry point for sync command.
+func mainSync(cliCtx *cli.Context) error {
+	ctx, cancelSync := context.WithCancel(globalContext)
+	defer cancelSync()
+
+	// Parse encryption keys per command.
+	encKeyDB, err := getEncKeys(cliCtx)
+	fatalIf(err, "Unable to parse encryption keys.")
+
+	// check 'sync' cli arguments.
+	checkSyncSyntax(cliCtx)
+
+	console.SetColor("Sync", color.New(color.FgGreen, color.Bold))
+	console.SetColor("SyncDelete", color.New(color.FgRed, color.Bold))
+	console.SetColor("SyncConflict", color.New(color.FgYellow, color.Bold))
+
+	args := cliCtx.Args()
+	sj := &syncJob{
+		encKeyDB: encKeyDB,
+		preserve: cliCtx.Bool("preserve"),
+		dryRun:   cliCtx.Bool("dry-run"),
+	}
+	for i, arg := range args {
+		sj.sides[i], err = listSyncSide(ctx, arg)
+		fatalIf(err.Trace(arg), "Unable to list `"+arg+"`.")
+	}
+
+	sj.state, err = loadSyncState(sj.sides[0].alias+":"+sj.sides[0].url, sj.sides[1].alias+":"+sj.sides[1].url)
+	fatalIf(err.Trace(args...), "Unable to load the sync state.")
+
+	comparer := &contentComparer{
+		sourceAlias: sj.sides[0].alias,
+		targetAlias: sj.sides[1].alias,
+		encKeyDB:    encKeyDB,
+		cache:       loadChecksumCache(),
+	}
+	ops := planSync([2]map[string]*ClientContent{sj.sides[0].objects, sj.sides[1].objects}, sj.state, cliCtx.String("conflict"),
+		func(key string) (bool, *probe.Error) {
+			return sj.sameContent(ctx, comparer, key)
+		})
+	errorIf(comparer.cache.Save(), "Unable to save checksum cache.")
+
+	if !cliCtx.Bool("force") {
+		fatalIf(checkSyncSafety(sj.sides, sj.state, ops).Trace(args...), "Unable to sync `"+args[0]+"` and `"+args[1]+"`.")
+	}
+
+	var totalBytes int64
+	for _, op := range ops {
+		if op.action == syncActionCopy {
+			totalBytes += sj.sides[op.from].objects[op.key].Size
+		}
+	}
+	// Enable progress bar reader only during default mode.
+	if !globalQuiet && !globalJSON {
+		sj.status = newProgressBar(totalBytes)
+	} else {
+		sj.status = newAccounter(totalBytes)
+	}
+
+	conflicts, failed := sj.doSync(ctx, ops)
+	if progressReader, ok := sj.status.(*progressBar); ok {
+		if progressReader.ProgressBar.Get() > 0 {
+			progressReader.ProgressBar.Finish()
+		}
+	} else if accntReader, ok := sj.status.(*accounter); ok && totalBytes > 0 {
+		printMsg(accntReader.Stat())
+	}
+
+	if !sj.dryRun {
+		fatalIf(sj.state.Save().Trace(args...), "Unable to save the sync state.")
+	}
+
+	switch {
+	case failed:
+		return exitStatus(globalErrorExitStatus)
+	case conflicts:
+		return exitStatus(syncConflictExitStatus)
+	}
+	return nil
+}
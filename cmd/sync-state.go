@@ -0,0 +1,188 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/trinet2005/oss-mc/pkg/probe"
+)
+
+const (
+	globalSyncStateDir     = "sync"
+	globalSyncStateVersion = "1"
+)
+
+// syncVersion identifies the version of an object last synced, by its
+// ETag when it has one and by its size and modification time otherwise.
+type syncVersion struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+	ETag    string    `json:"etag,omitempty"`
+}
+
+func newSyncVersion(content *ClientContent) *syncVersion {
+	return &syncVersion{
+		Size:    content.Size,
+		ModTime: content.Time,
+		ETag:    strings.Trim(content.ETag, "\""),
+	}
+}
+
+// changed returns true if content is not the version last synced, nil
+// content means the object does not exist.
+func (v *syncVersion) changed(content *ClientContent) bool {
+	switch {
+	case v == nil:
+		return content != nil
+	case content == nil:
+		return true
+	case v.Size != content.Size:
+		return true
+	}
+	if etag := strings.Trim(content.ETag, "\""); v.ETag != "" && etag != "" {
+		return v.ETag != etag
+	}
+	return !v.ModTime.Equal(content.Time)
+}
+
+// syncStateEntry - versions of an object on both sides of a sync, as
+// they were when last synced.
+type syncStateEntry struct {
+	First  *syncVersion `json:"first,omitempty"`
+	Second *syncVersion `json:"second,omitempty"`
+}
+
+// syncStateV1 - on disk format of the sync state.
+type syncStateV1 struct {
+	Version string                     `json:"version"`
+	First   string                     `json:"first"`
+	Second  string                     `json:"second"`
+	Entries map[string]*syncStateEntry `json:"entries"`
+}
+
+// syncState is the state database of a pair of folders kept in sync,
+// keyed by the object names relative to the folders.
+type syncState struct {
+	file    string
+	state   syncStateV1
+	entries map[string]*syncStateEntry
+}
+
+// getSyncStateFile - get the state file of a pair of folders.
+func getSyncStateFile(first, second string) (string, *probe.Error) {
+	configDir, err := getMcConfigDir()
+	if err != nil {
+		return "", err.Trace()
+	}
+	return filepath.Join(configDir, globalSyncStateDir, getHash("sync", []string{first, second})+".json"), nil
+}
+
+// loadSyncState loads the state of a pair of folders, a missing state
+// file results in an empty state as for a first sync.
+func loadSyncState(first, second string) (*syncState, *probe.Error) {
+	file, err := getSyncStateFile(first, second)
+	if err != nil {
+		return nil, err.Trace(first, second)
+	}
+	s := &syncState{
+		file:    file,
+		entries: make(map[string]*syncStateEntry),
+	}
+	s.state = syncStateV1{Version: globalSyncStateVersion, First: first, Second: second}
+
+	data, e := os.ReadFile(file)
+	if e != nil {
+		if os.IsNotExist(e) {
+			return s, nil
+		}
+		return nil, probe.NewError(e).Trace(file)
+	}
+	var stateV1 syncStateV1
+	if e = json.Unmarshal(data, &stateV1); e != nil {
+		return nil, probe.NewError(e).Trace(file)
+	}
+	if stateV1.Version != globalSyncStateVersion {
+		return nil, errInvalidArgument().Trace(file, stateV1.Version)
+	}
+	for key, entry := range stateV1.Entries {
+		// Entries only recorded for one side were never synced.
+		if entry != nil && entry.First != nil && entry.Second != nil {
+			s.entries[key] = entry
+		}
+	}
+	return s, nil
+}
+
+// get returns the entry of a key, nil if it was never synced.
+func (s *syncState) get(key string) *syncStateEntry {
+	return s.entries[key]
+}
+
+// set records the version of a key on one side, the first side when
+// second is false.
+func (s *syncState) set(key string, second bool, content *ClientContent) {
+	entry, ok := s.entries[key]
+	if !ok {
+		entry = &syncStateEntry{}
+		s.entries[key] = entry
+	}
+	if second {
+		entry.Second = newSyncVersion(content)
+	} else {
+		entry.First = newSyncVersion(content)
+	}
+}
+
+// remove forgets a key, it is gone on both sides.
+func (s *syncState) remove(key string) {
+	delete(s.entries, key)
+}
+
+// Save writes the state, entries only recorded for one side are left
+// out so that they are synced again next time.
+func (s *syncState) Save() *probe.Error {
+	s.state.Entries = make(map[string]*syncStateEntry, len(s.entries))
+	for key, entry := range s.entries {
+		if entry.First != nil && entry.Second != nil {
+			s.state.Entries[key] = entry
+		}
+	}
+	data, e := json.Marshal(s.state)
+	if e != nil {
+		return probe.NewError(e)
+	}
+	if e = os.MkdirAll(filepath.Dir(s.file), 0o700); e != nil {
+		return probe.NewError(e)
+	}
+
+	// Write to a temporary file and rename, so that
+	// the state is never left half written.
+	tmpFile := s.file + ".tmp"
+	if e = os.WriteFile(tmpFile, data, 0o600); e != nil {
+		return probe.NewError(e)
+	}
+	if e = os.Rename(tmpFile, s.file); e != nil {
+		return probe.NewError(e)
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	json "github.com/minio/colorjson"
+	"github.com/trinet2005/oss-mc/pkg/probe"
+	"github.com/trinet2005/oss-pkg/console"
+)
+
+// Exit status of 'sync' when conflicts were left unresolved.
+const syncConflictExitStatus = 2
+
+// Sync refuses to remove more than this percentage of the objects of a
+// folder without --force.
+const syncMaxRemovalPercent = 50
+
+// Policies resolving objects changed on both sides since the last sync.
+const (
+	syncConflictFail      = "fail"
+	syncConflictNewerWins = "newer-wins"
+	syncConflictKeepBoth  = "keep-both"
+)
+
+// Actions of a sync.
+const (
+	syncActionCopy     = "copy"
+	syncActionDelete   = "delete"
+	syncActionConflict = "conflict"
+	syncActionRecord   = "record"
+	syncActionForget   = "forget"
+)
+
+// syncOp is an action on an object of a pair of folders, from the side
+// at index from to the side at index to. Copies write key to target,
+// which differs from key for the conflicting versions kept with
+// keep-both. Records and forgets only update the state.
+type syncOp struct {
+	action string
+	key    string
+	target string
+	from   int
+	to     int
+	err    *probe.Error
+}
+
+// syncMessage container for sync actions.
+type syncMessage struct {
+	Status string `json:"status"`
+	Action string `json:"action"`
+	Source string `json:"source,omitempty"`
+	Target string `json:"target"`
+	Size   int64  `json:"size,omitempty"`
+}
+
+// String colorized sync message
+func (s syncMessage) String() string {
+	switch s.Action {
+	case syncActionDelete:
+		return console.Colorize("SyncDelete", fmt.Sprintf("Removed `%s`.", s.Target))
+	case syncActionConflict:
+		return console.Colorize("SyncConflict", fmt.Sprintf("`%s` <-> `%s` (conflict)", s.Source, s.Target))
+	}
+	return console.Colorize("Sync", fmt.Sprintf("`%s` -> `%s`", s.Source, s.Target))
+}
+
+// JSON jsonified sync message
+func (s syncMessage) JSON() string {
+	s.Status = "success"
+	msgBytes, e := json.MarshalIndent(s, "", " ")
+	fatalIf(probe.NewError(e), "Unable to marshal into JSON.")
+	return string(msgBytes)
+}
+
+// syncConflictName returns the name the older version of a conflicting
+// object is kept as, suffixed with its modification time before the
+// extension, e.g. report.conflict-20060102-150405.txt
+func syncConflictName(key string, older *ClientContent) string {
+	dir, base := path.Split(key)
+	ext := path.Ext(base)
+	if ext == base {
+		ext = ""
+	}
+	suffix := ".conflict-" + older.Time.UTC().Format("20060102-150405")
+	return dir + strings.TrimSuffix(base, ext) + suffix + ext
+}
+
+// planSync returns the actions bringing two folders in sync, from the
+// objects on both sides and the state of the last sync. Objects on
+// one side only are copied to the other one unless they were synced
+// before, they were removed then. Objects changed on both sides are
+// compared with same, the ones which differ are conflicts resolved
+// with policy.
+func planSync(objects [2]map[string]*ClientContent, state *syncState, policy string, same func(key string) (bool, *probe.Error)) []syncOp {
+	keys := make(map[string]bool)
+	for _, side := range objects {
+		for key := range side {
+			keys[key] = true
+		}
+	}
+	for key := range state.entries {
+		keys[key] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	var ops []syncOp
+	for _, key := range sorted {
+		first, second := objects[0][key], objects[1][key]
+		changed := [2]bool{first != nil, second != nil}
+		if entry := state.get(key); entry != nil {
+			changed = [2]bool{entry.First.changed(first), entry.Second.changed(second)}
+		}
+
+		switch {
+		case !changed[0] && !changed[1]:
+			continue
+		case changed[0] != changed[1]:
+			from, to := 0, 1
+			if changed[1] {
+				from, to = 1, 0
+			}
+			switch {
+			case objects[from][key] != nil:
+				ops = append(ops, syncOp{action: syncActionCopy, key: key, target: key, from: from, to: to})
+			case objects[to][key] != nil:
+				ops = append(ops, syncOp{action: syncActionDelete, key: key, from: from, to: to})
+			default:
+				ops = append(ops, syncOp{action: syncActionForget, key: key})
+			}
+			continue
+		case first == nil && second == nil:
+			// Removed on both sides.
+			ops = append(ops, syncOp{action: syncActionForget, key: key})
+			continue
+		case first != nil && second != nil:
+			// Changed the same way on both sides.
+			isSame, err := same(key)
+			if err != nil {
+				ops = append(ops, syncOp{action: syncActionConflict, key: key, from: 0, to: 1, err: err})
+				continue
+			}
+			if isSame {
+				ops = append(ops, syncOp{action: syncActionRecord, key: key})
+				continue
+			}
+		}
+		ops = append(ops, resolveSyncConflict(key, first, second, policy)...)
+	}
+	return ops
+}
+
+// resolveSyncConflict returns the actions resolving an object changed
+// on both sides. A modification always wins over a removal.
+func resolveSyncConflict(key string, first, second *ClientContent, policy string) []syncOp {
+	newer, older := 0, 1
+	if first == nil || (second != nil && second.Time.After(first.Time)) {
+		newer, older = 1, 0
+	}
+	win := syncOp{action: syncActionCopy, key: key, target: key, from: newer, to: older}
+
+	switch {
+	case policy == syncConflictNewerWins:
+		return []syncOp{win}
+	case policy == syncConflictKeepBoth && first != nil && second != nil:
+		olderContent := first
+		if older == 1 {
+			olderContent = second
+		}
+		name := syncConflictName(key, olderContent)
+		return []syncOp{
+			{action: syncActionCopy, key: key, target: name, from: older, to: older},
+			{action: syncActionCopy, key: key, target: name, from: older, to: newer},
+			win,
+		}
+	case policy == syncConflictKeepBoth:
+		// Nothing to keep of the removed side.
+		return []syncOp{win}
+	}
+	return []syncOp{{action: syncActionConflict, key: key, from: 0, to: 1}}
+}
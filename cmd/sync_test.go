@@ -0,0 +1,204 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/trinet2005/oss-mc/pkg/probe"
+	. "gopkg.in/check.v1"
+)
+
+func (s *TestSuite) TestPlanSync(c *C) {
+	t0 := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	content := func(size int64, modTime time.Time) *ClientContent {
+		return &ClientContent{Size: size, Time: modTime}
+	}
+	state := &syncState{entries: make(map[string]*syncStateEntry)}
+	for _, key := range []string{"same", "edited-first", "removed-second", "removed-both", "edited-both", "identical"} {
+		state.set(key, false, content(1, t0))
+		state.set(key, true, content(1, t0))
+	}
+
+	objects := [2]map[string]*ClientContent{
+		{
+			"new-first":      content(1, t0),
+			"same":           content(1, t0),
+			"edited-first":   content(2, t0.Add(time.Hour)),
+			"removed-second": content(1, t0),
+			"edited-both":    content(3, t0.Add(time.Hour)),
+			"identical":      content(4, t0.Add(time.Hour)),
+		},
+		{
+			"new-second":   content(1, t0),
+			"same":         content(1, t0),
+			"edited-first": content(1, t0),
+			"edited-both":  content(5, t0.Add(2*time.Hour)),
+			"identical":    content(4, t0.Add(2*time.Hour)),
+		},
+	}
+
+	same := func(key string) (bool, *probe.Error) {
+		return key == "identical", nil
+	}
+
+	ops := planSync(objects, state, syncConflictFail, same)
+	c.Assert(ops, DeepEquals, []syncOp{
+		{action: syncActionConflict, key: "edited-both", from: 0, to: 1},
+		{action: syncActionCopy, key: "edited-first", target: "edited-first", from: 0, to: 1},
+		{action: syncActionRecord, key: "identical"},
+		{action: syncActionCopy, key: "new-first", target: "new-first", from: 0, to: 1},
+		{action: syncActionCopy, key: "new-second", target: "new-second", from: 1, to: 0},
+		{action: syncActionForget, key: "removed-both"},
+		{action: syncActionDelete, key: "removed-second", from: 1, to: 0},
+	})
+
+	ops = planSync(objects, state, syncConflictNewerWins, same)
+	c.Assert(ops[0], DeepEquals, syncOp{action: syncActionCopy, key: "edited-both", target: "edited-both", from: 1, to: 0})
+
+	ops = planSync(objects, state, syncConflictKeepBoth, same)
+	name := "edited-both.conflict-20230102-040405"
+	c.Assert(ops[:3], DeepEquals, []syncOp{
+		{action: syncActionCopy, key: "edited-both", target: name, from: 0, to: 0},
+		{action: syncActionCopy, key: "edited-both", target: name, from: 0, to: 1},
+		{action: syncActionCopy, key: "edited-both", target: "edited-both", from: 1, to: 0},
+	})
+
+	// A modification wins over a removal.
+	delete(objects[1], "edited-both")
+	ops = planSync(objects, state, syncConflictKeepBoth, same)
+	c.Assert(ops[0], DeepEquals, syncOp{action: syncActionCopy, key: "edited-both", target: "edited-both", from: 0, to: 1})
+}
+
+func (s *TestSuite) TestPlanSyncFirstRun(c *C) {
+	state := &syncState{entries: make(map[string]*syncStateEntry)}
+	objects := [2]map[string]*ClientContent{
+		{"a": {Size: 1}, "b": {Size: 1}},
+		{"b": {Size: 1}},
+	}
+	ops := planSync(objects, state, syncConflictFail, func(string) (bool, *probe.Error) { return true, nil })
+	c.Assert(ops, DeepEquals, []syncOp{
+		{action: syncActionCopy, key: "a", target: "a", from: 0, to: 1},
+		{action: syncActionRecord, key: "b"},
+	})
+}
+
+func (s *TestSuite) TestSyncVersionChanged(c *C) {
+	t0 := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	v := newSyncVersion(&ClientContent{Size: 1, Time: t0, ETag: "\"abc\""})
+	c.Assert(v.changed(&ClientContent{Size: 1, Time: t0.Add(time.Hour), ETag: "abc"}), Equals, false)
+	c.Assert(v.changed(&ClientContent{Size: 1, Time: t0, ETag: "def"}), Equals, true)
+	c.Assert(v.changed(nil), Equals, true)
+
+	v = newSyncVersion(&ClientContent{Size: 1, Time: t0})
+	c.Assert(v.changed(&ClientContent{Size: 1, Time: t0}), Equals, false)
+	c.Assert(v.changed(&ClientContent{Size: 1, Time: t0.Add(time.Second)}), Equals, true)
+	c.Assert(v.changed(&ClientContent{Size: 2, Time: t0}), Equals, true)
+
+	v = nil
+	c.Assert(v.changed(nil), Equals, false)
+	c.Assert(v.changed(&ClientContent{}), Equals, true)
+}
+
+func (s *TestSuite) TestSyncConflictName(c *C) {
+	older := &ClientContent{Time: time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)}
+	c.Assert(syncConflictName("docs/report.txt", older), Equals, "docs/report.conflict-20230102-030405.txt")
+	c.Assert(syncConflictName("Makefile", older), Equals, "Makefile.conflict-20230102-030405")
+	c.Assert(syncConflictName(".bashrc", older), Equals, ".bashrc.conflict-20230102-030405")
+}
+
+func (s *TestSuite) TestSyncStateSave(c *C) {
+	root, e := os.MkdirTemp("", "sync-state-")
+	c.Assert(e, IsNil)
+	defer os.RemoveAll(root)
+
+	state := &syncState{
+		file:    filepath.Join(root, "sync", "state.json"),
+		state:   syncStateV1{Version: globalSyncStateVersion},
+		entries: make(map[string]*syncStateEntry),
+	}
+	state.set("synced", false, &ClientContent{Size: 1})
+	state.set("synced", true, &ClientContent{Size: 1, ETag: "abc"})
+	state.set("half", false, &ClientContent{Size: 2})
+	c.Assert(state.Save(), IsNil)
+
+	data, e := os.ReadFile(state.file)
+	c.Assert(e, IsNil)
+	c.Assert(string(data), Matches, `.*"synced".*`)
+	c.Assert(string(data), Not(Matches), `.*"half".*`)
+}
+
+func (s *TestSuite) TestSyncMissingRoot(c *C) {
+	defer func(load func() (*configV10, *probe.Error)) { loadMcConfig = load }(loadMcConfig)
+	loadMcConfig = func() (*configV10, *probe.Error) { return newMcConfig(), nil }
+
+	root := c.MkDir()
+	first, second := filepath.Join(root, "a"), filepath.Join(root, "b")
+	for _, dir := range []string{first, second} {
+		c.Assert(os.MkdirAll(dir, 0o700), IsNil)
+		for _, name := range []string{"f1", "f2"} {
+			c.Assert(os.WriteFile(filepath.Join(dir, name), []byte(name), 0o600), IsNil)
+		}
+	}
+
+	var sides [2]*syncSide
+	var err *probe.Error
+	state := &syncState{entries: make(map[string]*syncStateEntry)}
+	for i, dir := range []string{first, second} {
+		sides[i], err = listSyncSide(globalContext, dir)
+		c.Assert(err, IsNil)
+		c.Assert(sides[i].missing, Equals, false)
+		for key, content := range sides[i].objects {
+			state.set(key, i == 1, content)
+		}
+	}
+	objects := func() [2]map[string]*ClientContent {
+		return [2]map[string]*ClientContent{sides[0].objects, sides[1].objects}
+	}
+	same := func(string) (bool, *probe.Error) { return true, nil }
+	c.Assert(checkSyncSafety(sides, state, planSync(objects(), state, syncConflictFail, same)), IsNil)
+
+	// The first folder is unmounted, nothing may be removed from the second one.
+	c.Assert(os.Rename(first, first+"-unmounted"), IsNil)
+	sides[0], err = listSyncSide(globalContext, first)
+	c.Assert(err, IsNil)
+	c.Assert(sides[0].missing, Equals, true)
+	ops := planSync(objects(), state, syncConflictFail, same)
+	c.Assert(checkSyncSafety(sides, state, ops), NotNil)
+
+	// Nothing synced before, a missing folder is created by the sync.
+	c.Assert(checkSyncSafety(sides, &syncState{entries: make(map[string]*syncStateEntry)}, nil), IsNil)
+
+	// Objects removed on purpose, but all of them.
+	c.Assert(os.MkdirAll(first, 0o700), IsNil)
+	sides[0], err = listSyncSide(globalContext, first)
+	c.Assert(err, IsNil)
+	ops = planSync(objects(), state, syncConflictFail, same)
+	c.Assert(len(ops), Equals, 2)
+	c.Assert(checkSyncSafety(sides, state, ops), NotNil)
+
+	// Removing one of the two objects is fine.
+	c.Assert(os.WriteFile(filepath.Join(first, "f1"), []byte("f1"), 0o600), IsNil)
+	sides[0], err = listSyncSide(globalContext, first)
+	c.Assert(err, IsNil)
+	ops = planSync(objects(), state, syncConflictFail, same)
+	c.Assert(len(ops), Equals, 2)
+	c.Assert(checkSyncSafety(sides, state, ops), IsNil)
+}
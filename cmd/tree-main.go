@@ -25,6 +25,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/dustin/go-humanize"
 	"github.com/fatih/color"
 	"github.com/minio/cli"
 	"github.com/trinet2005/oss-mc/pkg/probe"
@@ -43,6 +44,12 @@ type treeMessage struct {
 	Entry        string
 	IsDir        bool
 	BranchString string
+	Size         int64
+	Objects      int64
+	IsVersions   bool
+
+	// Print the aggregate size and count.
+	showSize bool
 }
 
 // Colorized message for console printing.
@@ -51,11 +58,23 @@ func (t treeMessage) String() string {
 	if t.IsDir {
 		entryType = "Dir"
 	}
-	return fmt.Sprintf("%s%s", t.BranchString, console.Colorize(entryType, t.Entry))
+	msg := fmt.Sprintf("%s%s", t.BranchString, console.Colorize(entryType, t.Entry))
+	if t.showSize {
+		humanSize := strings.Join(strings.Fields(humanize.IBytes(uint64(t.Size))), "")
+		cnt := fmt.Sprintf("%d object", t.Objects)
+		if t.IsVersions {
+			cnt = fmt.Sprintf("%d version", t.Objects)
+		}
+		if t.Objects != 1 {
+			cnt += "s" // pluralize
+		}
+		msg += " " + console.Colorize("Size", "["+humanSize+", "+cnt+"]")
+	}
+	return msg
 }
 
 // JSON'ified message for scripting.
-// Does No-op. JSON requests are redirected to `ls -r --json`
+// Does No-op. JSON requests are printed as a nested document by doTreeSize.
 func (t treeMessage) JSON() string {
 	fatalIf(probe.NewError(errors.New("JSON() should never be called here")), "Unable to list in tree format. Please report this issue at https://github.com/trinet2005/oss-mc/issues")
 	return ""
@@ -75,6 +94,18 @@ var treeFlags = []cli.Flag{
 		Name:  "rewind",
 		Usage: "display tree no later than specified date",
 	},
+	cli.BoolFlag{
+		Name:  "size, s",
+		Usage: "display the aggregate size and number of objects of each entry",
+	},
+	cli.BoolFlag{
+		Name:  "versions",
+		Usage: "include all object versions in sizes, implies --size",
+	},
+	cli.StringFlag{
+		Name:  "min-size",
+		Usage: "hide entries smaller than specified size in units (e.g. 10MiB), implies --size",
+	},
 }
 
 // trees files and folders.
@@ -109,6 +140,15 @@ EXAMPLES:
 
    5. List all directories upto depth level '2' in tree format.
       {{.Prompt}} {{.HelpName}} --depth 2 myminio/mybucket/
+
+   6. List all directories in "mybucket" with their size and number of objects, hiding those smaller than 1GiB.
+      {{.Prompt}} {{.HelpName}} --min-size 1GiB myminio/mybucket/
+
+   7. List all directories and objects in "mybucket" with the size of all their versions.
+      {{.Prompt}} {{.HelpName}} --files --versions myminio/mybucket/
+
+   8. Export the tree of "mybucket" with sizes as a nested JSON document.
+      {{.Prompt}} {{.HelpName}} --json --depth 2 myminio/mybucket/
 `,
 }
 
@@ -211,7 +251,7 @@ func doTree(ctx context.Context, url string, timeRef time.Time, level int, branc
 		if prev.Type.IsDir() {
 			url := ""
 			if targetAlias != "" {
-				url = targetAlias + "/" + contentURL
+				url = filepath.ToSlash(aliasPath(targetAlias, contentURL))
 			} else {
 				url = contentURL
 			}
@@ -261,6 +301,7 @@ func mainTree(cliCtx *cli.Context) error {
 
 	console.SetColor("File", color.New(color.Bold))
 	console.SetColor("Dir", color.New(color.FgCyan, color.Bold))
+	console.SetColor("Size", color.New(color.FgYellow))
 
 	// parse 'tree' cliCtx arguments.
 	args, depth, includeFiles, timeRef := parseTreeSyntax(ctx, cliCtx)
@@ -270,31 +311,29 @@ func mainTree(cliCtx *cli.Context) error {
 		args = []string{"."}
 	}
 
+	sizeOpts := treeSizeOptions{
+		timeRef:      timeRef,
+		depth:        depth,
+		includeFiles: includeFiles,
+		withVersions: cliCtx.Bool("versions"),
+	}
+	if minSize := cliCtx.String("min-size"); minSize != "" {
+		size, e := humanize.ParseBytes(minSize)
+		fatalIf(probe.NewError(e).Trace(minSize), "Unable to parse --min-size.")
+		sizeOpts.minSize = int64(size)
+	}
+	withSize := cliCtx.Bool("size") || cliCtx.IsSet("versions") || cliCtx.IsSet("min-size")
+
 	var cErr error
 	for _, targetURL := range args {
-		if !globalJSON {
-			if e := doTree(ctx, targetURL, timeRef, 1, "", depth, includeFiles); e != nil {
-				cErr = e
-			}
-		} else {
-			targetAlias, targetURL, _ := mustExpandAlias(targetURL)
-			if !strings.HasSuffix(targetURL, "/") {
-				targetURL += "/"
-			}
-			clnt, err := newClientFromAlias(targetAlias, targetURL)
-			fatalIf(err.Trace(targetURL), "Unable to initialize target `"+targetURL+"`.")
-			opts := doListOptions{
-				timeRef:           timeRef,
-				isRecursive:       true,
-				isIncomplete:      false,
-				isSummary:         false,
-				withOlderVersions: false,
-				listZip:           false,
-				filter:            "*",
-			}
-			if e := doList(ctx, clnt, opts); e != nil {
+		if withSize || globalJSON {
+			if e := doTreeSize(ctx, targetURL, sizeOpts); e != nil {
 				cErr = e
 			}
+			continue
+		}
+		if e := doTree(ctx, targetURL, timeRef, 1, "", depth, includeFiles); e != nil {
+			cErr = e
 		}
 	}
 	return cErr
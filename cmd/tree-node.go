@@ -0,0 +1,205 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	json "github.com/minio/colorjson"
+	"github.com/trinet2005/oss-mc/pkg/probe"
+)
+
+// treeNode is a folder or an object of a tree, with the aggregate size
+// and number of objects below it.
+type treeNode struct {
+	Name     string      `json:"name"`
+	Type     string      `json:"type"`
+	Size     int64       `json:"size"`
+	Objects  int64       `json:"objects"`
+	Children []*treeNode `json:"children,omitempty"`
+
+	children map[string]*treeNode
+}
+
+func newTreeNode(name, nodeType string) *treeNode {
+	return &treeNode{Name: name, Type: nodeType, children: make(map[string]*treeNode)}
+}
+
+func (n *treeNode) isDir() bool {
+	return n.Type == "folder"
+}
+
+// add adds an object to the node and its folders on the way, the object
+// itself is only added as a node with includeFiles.
+func (n *treeNode) add(path string, size int64, includeFiles bool) {
+	n.Size += size
+	n.Objects++
+	name, rest, isDir := strings.Cut(path, "/")
+	if !isDir && !includeFiles {
+		return
+	}
+	child, ok := n.children[name]
+	if !ok {
+		child = newTreeNode(name, "file")
+		if isDir {
+			child.Type = "folder"
+		}
+		n.children[name] = child
+		n.Children = append(n.Children, child)
+	}
+	if !isDir {
+		child.Size += size
+		child.Objects++
+		return
+	}
+	child.add(rest, size, includeFiles)
+}
+
+// prune sorts the children by name, drops the ones smaller than minSize
+// and the folders below depth, -1 keeps all levels.
+func (n *treeNode) prune(minSize int64, depth, level int) {
+	children := n.Children[:0]
+	for _, child := range n.Children {
+		if child.Size < minSize {
+			continue
+		}
+		if child.isDir() {
+			if depth == -1 || level <= depth {
+				child.prune(minSize, depth, level+1)
+			} else {
+				child.Children = nil
+			}
+		}
+		children = append(children, child)
+	}
+	sort.Slice(children, func(i, j int) bool { return children[i].Name < children[j].Name })
+	n.Children = children
+}
+
+// Nested JSON document of a tree.
+type treeNodeMessage struct {
+	Status string `json:"status"`
+	*treeNode
+}
+
+// String is never called, sized trees are printed with treeMessage.
+func (t treeNodeMessage) String() string {
+	return t.Name
+}
+
+// JSON'ified message for scripting.
+func (t treeNodeMessage) JSON() string {
+	t.Status = "success"
+	msgBytes, e := json.MarshalIndent(t, "", " ")
+	fatalIf(probe.NewError(e), "Unable to marshal into JSON.")
+	return string(msgBytes)
+}
+
+type treeSizeOptions struct {
+	timeRef      time.Time
+	depth        int
+	includeFiles bool
+	withVersions bool
+	minSize      int64
+}
+
+// buildTree returns the tree of all objects below url from a recursive
+// listing, the same as du.
+func buildTree(ctx context.Context, url string, o treeSizeOptions) (*treeNode, error) {
+	targetAlias, targetURL, _ := mustExpandAlias(url)
+	if !strings.HasSuffix(targetURL, "/") {
+		targetURL += "/"
+	}
+
+	clnt, err := newClientFromAlias(targetAlias, targetURL)
+	fatalIf(err.Trace(targetURL), "Unable to initialize target `"+targetURL+"`.")
+
+	separator := string(clnt.GetURL().Separator)
+	prefixPath := filepath.ToSlash(clnt.GetURL().Path)
+	if !strings.HasSuffix(prefixPath, "/") {
+		prefixPath += "/"
+	}
+	prefixPath = strings.TrimPrefix(prefixPath, "."+separator)
+
+	var cErr error
+	root := newTreeNode(url, "folder")
+	for content := range clnt.List(ctx, ListOptions{
+		Recursive:         true,
+		TimeRef:           o.timeRef,
+		WithOlderVersions: o.withVersions,
+		ShowDir:           DirNone,
+	}) {
+		if content.Err != nil {
+			errorIf(content.Err.Trace(clnt.GetURL().String()), "Unable to tree.")
+			cErr = exitStatus(globalErrorExitStatus)
+			continue
+		}
+		if content.IsDeleteMarker || content.Type.IsDir() {
+			continue
+		}
+		path := strings.TrimPrefix(filepath.ToSlash(content.URL.Path), prefixPath)
+		root.add(strings.TrimPrefix(path, "/"), content.Size, o.includeFiles)
+	}
+	root.prune(o.minSize, o.depth, 1)
+	return root, cErr
+}
+
+// printTreeNode prints the children of a sized tree, in the same layout
+// as doTree.
+func printTreeNode(node *treeNode, branchString string, withVersions bool) {
+	for i, child := range node.Children {
+		last := i == len(node.Children)-1
+		entry, next := treeEntry, treeNext+treeLevel
+		if last {
+			entry, next = treeLastEntry, " "+treeLevel
+		}
+		printMsg(treeMessage{
+			Entry:        child.Name,
+			IsDir:        child.isDir(),
+			BranchString: branchString + entry,
+			Size:         child.Size,
+			Objects:      child.Objects,
+			IsVersions:   withVersions,
+			showSize:     true,
+		})
+		printTreeNode(child, branchString+next, withVersions)
+	}
+}
+
+// doTreeSize - print the tree of a folder with sizes and counts.
+func doTreeSize(ctx context.Context, url string, o treeSizeOptions) error {
+	root, err := buildTree(ctx, url, o)
+	if globalJSON {
+		printMsg(treeNodeMessage{treeNode: root})
+		return err
+	}
+	printMsg(treeMessage{
+		Entry:      url,
+		IsDir:      true,
+		Size:       root.Size,
+		Objects:    root.Objects,
+		IsVersions: o.withVersions,
+		showSize:   true,
+	})
+	printTreeNode(root, "", o.withVersions)
+	return err
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+func (s *TestSuite) TestTreeNode(c *C) {
+	objects := []struct {
+		path string
+		size int64
+	}{
+		{"photos/2020/a.jpg", 100},
+		{"photos/2020/a.jpg", 50}, // older version
+		{"photos/2021/b.jpg", 10},
+		{"docs/c.txt", 1},
+		{"d.bin", 1000},
+	}
+	build := func(includeFiles bool, minSize int64, depth int) *treeNode {
+		root := newTreeNode("alias/bucket", "folder")
+		for _, object := range objects {
+			root.add(object.path, object.size, includeFiles)
+		}
+		root.prune(minSize, depth, 1)
+		return root
+	}
+	type node struct {
+		name          string
+		size, objects int64
+		children      []node
+	}
+	var flatten func(n *treeNode) node
+	flatten = func(n *treeNode) node {
+		f := node{name: n.Name, size: n.Size, objects: n.Objects}
+		for _, child := range n.Children {
+			f.children = append(f.children, flatten(child))
+		}
+		return f
+	}
+
+	c.Assert(flatten(build(false, 0, -1)), DeepEquals, node{"alias/bucket", 1161, 5, []node{
+		{"docs", 1, 1, nil},
+		{"photos", 160, 3, []node{
+			{"2020", 150, 2, nil},
+			{"2021", 10, 1, nil},
+		}},
+	}})
+	c.Assert(flatten(build(true, 10, -1)), DeepEquals, node{"alias/bucket", 1161, 5, []node{
+		{"d.bin", 1000, 1, nil},
+		{"photos", 160, 3, []node{
+			{"2020", 150, 2, []node{{"a.jpg", 150, 2, nil}}},
+			{"2021", 10, 1, []node{{"b.jpg", 10, 1, nil}}},
+		}},
+	}})
+	c.Assert(flatten(build(true, 0, 1)), DeepEquals, node{"alias/bucket", 1161, 5, []node{
+		{"d.bin", 1000, 1, nil},
+		{"docs", 1, 1, []node{{"c.txt", 1, 1, nil}}},
+		{"photos", 160, 3, []node{
+			{"2020", 150, 2, nil},
+			{"2021", 10, 1, nil},
+		}},
+	}})
+}
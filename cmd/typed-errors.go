@@ -126,3 +126,31 @@ var errConflictSSE = func(sseServer, sseKeys string) *probe.Error {
 	err := fmt.Errorf("SSE alias '%s' overlaps with SSE-C aliases '%s'", sseServer, sseKeys)
 	return probe.NewError(conflictSSEErr(err)).Untrace()
 }
+
+type cseKeyNotFoundErr error
+
+var errCSEKeyNotFound = func(URL string) *probe.Error {
+	msg := "Object `" + URL + "` is encrypted client-side, none of the given keys can decrypt it. Use `--cse-key` or `--cse-identity` to provide the key."
+	return probe.NewError(cseKeyNotFoundErr(errors.New(msg))).Untrace()
+}
+
+type syncConflictErr error
+
+var errSyncConflict = func(first, second string) *probe.Error {
+	msg := "`" + first + "` and `" + second + "` were both changed since the last sync. Use `--conflict` to resolve conflicts."
+	return probe.NewError(syncConflictErr(errors.New(msg))).Untrace()
+}
+
+type syncRootMissingErr error
+
+var errSyncRootMissing = func(root string) *probe.Error {
+	msg := "`" + root + "` does not exist but was synced before, syncing would remove all objects on the other side. Use `--force` to sync anyway."
+	return probe.NewError(syncRootMissingErr(errors.New(msg))).Untrace()
+}
+
+type syncTooManyRemovalsErr error
+
+var errSyncTooManyRemovals = func(root string, removals, total int) *probe.Error {
+	msg := fmt.Sprintf("Syncing would remove %d of the %d objects of `%s`. Use `--force` to sync anyway.", removals, total, root)
+	return probe.NewError(syncTooManyRemovalsErr(errors.New(msg))).Untrace()
+}
@@ -18,6 +18,7 @@
 package cmd
 
 import (
+	minio "github.com/trinet2005/oss-go-sdk"
 	"github.com/trinet2005/oss-mc/pkg/probe"
 )
 
@@ -31,7 +32,13 @@ type URLs struct {
 	TotalSize        int64
 	MD5              bool
 	DisableMultipart bool
+	Checksum         minio.ChecksumType
 	encKeyDB         map[string][]prefixSSEPair
+	cse              *cseKeys
+	compress         string
+	download         downloadOptions
+	journalKey       string
+	movedFrom        *ClientContent
 	Error            *probe.Error `json:"-"`
 	ErrorCond        differType   `json:"-"`
 }
@@ -151,6 +151,10 @@ func NewS3Config(urlStr string, aliasCfg *aliasConfigV10) *Config {
 		s3Config.SessionToken = aliasCfg.SessionToken
 		s3Config.Signature = aliasCfg.API
 		s3Config.Lookup = getLookupType(aliasCfg.Path)
+		s3Config.Limit = aliasCfg.Limit
+		s3Config.Credentials = aliasCfg.Credentials
+		s3Config.AliasTransport = aliasCfg.Transport
+		s3Config.Endpoints = aliasCfg.Endpoints
 	}
 	return s3Config
 }
@@ -29,9 +29,10 @@ require (
 	github.com/prometheus/prom2json v1.3.3
 	github.com/rjeczalik/notify v0.9.3
 	github.com/rs/xid v1.5.0
+	github.com/secure-io/sio-go v0.3.1
 	github.com/shirou/gopsutil/v3 v3.23.8
 	github.com/tidwall/gjson v1.16.0
-	golang.org/x/crypto v0.13.0 // indirect
+	golang.org/x/crypto v0.13.0
 	golang.org/x/net v0.15.0
 	golang.org/x/text v0.13.0
 	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c
@@ -40,14 +41,17 @@ require (
 )
 
 require (
+	filippo.io/age v1.1.1
 	github.com/charmbracelet/bubbles v0.16.1
 	github.com/charmbracelet/lipgloss v0.8.0
+	github.com/fraugster/parquet-go v0.12.0
 	github.com/gdamore/tcell/v2 v2.6.0
 	github.com/golang-jwt/jwt/v4 v4.5.0
 	github.com/juju/ratelimit v1.0.2
 	github.com/muesli/reflow v0.3.0
 	github.com/navidys/tvxwidgets v0.3.0
 	github.com/olekukonko/tablewriter v0.0.5
+	github.com/pkg/sftp v1.13.6
 	github.com/prometheus/client_model v0.4.0
 	github.com/rivo/tview v0.0.0-20230909130259-ba6a2a345459
 	github.com/trinet2005/oss-admin-go v1.6.0
@@ -58,7 +62,10 @@ require (
 
 require (
 	aead.dev/minisign v0.2.0 // indirect
+	github.com/apache/thrift v0.16.0 // indirect
 	github.com/aymanbagabas/go-osc52/v2 v2.0.1 // indirect
+	github.com/golang/snappy v0.0.4 // indirect
+	github.com/kr/fs v0.1.0 // indirect
 	github.com/kr/pretty v0.3.1 // indirect
 	github.com/minio/minio-go/v7 v7.0.63 // indirect
 	github.com/minio/pkg/v2 v2.0.1 // indirect
@@ -107,7 +114,6 @@ require (
 	github.com/prometheus/common v0.44.0 // indirect
 	github.com/prometheus/procfs v0.11.1
 	github.com/rivo/uniseg v0.4.4 // indirect
-	github.com/secure-io/sio-go v0.3.1 // indirect
 	github.com/sirupsen/logrus v1.9.3 // indirect
 	github.com/tidwall/match v1.1.1 // indirect
 	github.com/tidwall/pretty v1.2.1 // indirect
@@ -22,46 +22,101 @@ import (
 	"errors"
 	"io"
 	"net/http"
+	"sync"
+	"sync/atomic"
 
 	"github.com/juju/ratelimit"
 )
 
+// Limits - upload and download limits in bytes per second, zero means unlimited.
+type Limits struct {
+	Upload   int64
+	Download int64
+}
+
+// Dynamic is a ratelimited transport whose limits can be changed while
+// transfers are in progress.
+type Dynamic interface {
+	http.RoundTripper
+	SetLimits(Limits)
+}
+
+// buckets - token buckets of the upload and download limits, nil if unlimited.
+type buckets struct {
+	upload   *ratelimit.Bucket
+	download *ratelimit.Bucket
+}
+
 type limiter struct {
-	upload    *ratelimit.Bucket
-	download  *ratelimit.Bucket
 	transport http.RoundTripper // HTTP transport that needs to be intercepted
+
+	mutex   sync.Mutex // serializes SetLimits
+	current Limits
+	buckets atomic.Value // *buckets, looked up on every read
 }
 
-func (l limiter) limitReader(r io.Reader, b *ratelimit.Bucket) io.Reader {
-	if b == nil {
-		return r
+func newBucket(limit int64) *ratelimit.Bucket {
+	if limit <= 0 {
+		return nil
 	}
-	return ratelimit.Reader(r, b)
+	return ratelimit.NewBucketWithRate(float64(limit), limit)
+}
+
+// SetLimits replaces the token buckets of the limits which changed.
+func (l *limiter) SetLimits(limits Limits) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	b := *l.buckets.Load().(*buckets)
+	if limits.Upload != l.current.Upload {
+		b.upload = newBucket(limits.Upload)
+	}
+	if limits.Download != l.current.Download {
+		b.download = newBucket(limits.Download)
+	}
+	l.current = limits
+	l.buckets.Store(&b)
+}
+
+// limitedReader waits for the tokens of the data read, the bucket is
+// looked up on every read so that new limits apply to ongoing transfers.
+type limitedReader struct {
+	io.ReadCloser
+	bucket func() *ratelimit.Bucket
+}
+
+func (r *limitedReader) Read(p []byte) (n int, err error) {
+	n, err = r.ReadCloser.Read(p)
+	if n > 0 {
+		if b := r.bucket(); b != nil {
+			b.Wait(int64(n))
+		}
+	}
+	return n, err
 }
 
 // RoundTrip executes user provided request and response hooks for each HTTP call.
-func (l limiter) RoundTrip(req *http.Request) (res *http.Response, err error) {
+func (l *limiter) RoundTrip(req *http.Request) (res *http.Response, err error) {
 	if l.transport == nil {
 		return nil, errors.New("Invalid Argument")
 	}
 
-	type readCloser struct {
-		io.Reader
-		io.Closer
-	}
-
 	if req.Body != nil {
-		req.Body = &readCloser{
-			Reader: l.limitReader(req.Body, l.upload),
-			Closer: req.Body,
+		req.Body = &limitedReader{
+			ReadCloser: req.Body,
+			bucket: func() *ratelimit.Bucket {
+				return l.buckets.Load().(*buckets).upload
+			},
 		}
 	}
 
 	res, err = l.transport.RoundTrip(req)
 	if res != nil && res.Body != nil {
-		res.Body = &readCloser{
-			Reader: l.limitReader(res.Body, l.download),
-			Closer: res.Body,
+		res.Body = &limitedReader{
+			ReadCloser: res.Body,
+			bucket: func() *ratelimit.Bucket {
+				return l.buckets.Load().(*buckets).download
+			},
 		}
 	}
 
@@ -73,23 +128,15 @@ func New(uploadLimit, downloadLimit int64, transport http.RoundTripper) http.Rou
 	if uploadLimit == 0 && downloadLimit == 0 {
 		return transport
 	}
+	return NewDynamic(Limits{Upload: uploadLimit, Download: downloadLimit}, transport)
+}
 
-	var (
-		uploadBucket   *ratelimit.Bucket
-		downloadBucket *ratelimit.Bucket
-	)
-
-	if uploadLimit > 0 {
-		uploadBucket = ratelimit.NewBucketWithRate(float64(uploadLimit), uploadLimit)
-	}
-
-	if downloadLimit > 0 {
-		downloadBucket = ratelimit.NewBucketWithRate(float64(downloadLimit), downloadLimit)
-	}
-
-	return &limiter{
-		upload:    uploadBucket,
-		download:  downloadBucket,
-		transport: transport,
-	}
+// NewDynamic returns a ratelimited transport with the given initial limits,
+// which can be changed at any time with SetLimits e.g. on a schedule or
+// after a configuration reload.
+func NewDynamic(limits Limits, transport http.RoundTripper) Dynamic {
+	l := &limiter{transport: transport}
+	l.buckets.Store(&buckets{})
+	l.SetLimits(limits)
+	return l
 }
@@ -0,0 +1,45 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package limiter
+
+import (
+	"testing"
+)
+
+func TestSetLimits(t *testing.T) {
+	l := NewDynamic(Limits{Upload: 1 << 20}, nil).(*limiter)
+	b := l.buckets.Load().(*buckets)
+	if b.upload == nil || b.download != nil {
+		t.Fatalf("Unexpected buckets %+v", b)
+	}
+	upload := b.upload
+
+	// Only the buckets of the limits which changed are replaced,
+	// transfers in progress keep their tokens.
+	l.SetLimits(Limits{Upload: 1 << 20, Download: 2 << 20})
+	b = l.buckets.Load().(*buckets)
+	if b.upload != upload || b.download == nil {
+		t.Fatalf("Unexpected buckets %+v", b)
+	}
+
+	l.SetLimits(Limits{})
+	b = l.buckets.Load().(*buckets)
+	if b.upload != nil || b.download != nil {
+		t.Fatalf("Unexpected buckets %+v", b)
+	}
+}
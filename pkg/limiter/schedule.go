@@ -0,0 +1,111 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package limiter
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Window - limits applied between two times of the day on the given
+// weekdays, a window ending before it starts wraps past midnight.
+type Window struct {
+	Days   [7]bool // indexed by time.Weekday
+	From   time.Duration
+	To     time.Duration
+	Limits Limits
+}
+
+// Schedule - limits by time of day, the first matching window applies,
+// Default applies outside of all the windows.
+type Schedule struct {
+	Default Limits
+	Windows []Window
+}
+
+// At returns the limits in effect at t.
+func (s Schedule) At(t time.Time) Limits {
+	tod := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+	day := t.Weekday()
+	prevDay := (day + 6) % 7
+	for _, w := range s.Windows {
+		switch {
+		case w.From <= w.To:
+			if w.Days[day] && tod >= w.From && tod < w.To {
+				return w.Limits
+			}
+		default:
+			// The part after midnight belongs to the previous day.
+			if (w.Days[day] && tod >= w.From) || (w.Days[prevDay] && tod < w.To) {
+				return w.Limits
+			}
+		}
+	}
+	return s.Default
+}
+
+// ParseTimeOfDay parses a time of the day such as `09:00` or `18:30`.
+func ParseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day `%s`, expected HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// ParseDays parses a comma separated list of weekdays or ranges of
+// weekdays such as `mon-fri` or `sat,sun`, empty means every day.
+func ParseDays(s string) (days [7]bool, err error) {
+	if strings.TrimSpace(s) == "" {
+		for i := range days {
+			days[i] = true
+		}
+		return days, nil
+	}
+	for _, part := range strings.Split(strings.ToLower(s), ",") {
+		from, to, isRange := strings.Cut(strings.TrimSpace(part), "-")
+		first, ok := weekdays[from]
+		if !ok {
+			return days, fmt.Errorf("invalid weekday `%s`", from)
+		}
+		last := first
+		if isRange {
+			if last, ok = weekdays[to]; !ok {
+				return days, fmt.Errorf("invalid weekday `%s`", to)
+			}
+		}
+		for d := first; ; d = (d + 1) % 7 {
+			days[d] = true
+			if d == last {
+				break
+			}
+		}
+	}
+	return days, nil
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2015-2022 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package limiter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSchedule(t *testing.T) {
+	businessDays, err := ParseDays("mon-fri")
+	if err != nil {
+		t.Fatal(err)
+	}
+	everyDay, err := ParseDays("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	weekend, err := ParseDays("sat,sun")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !weekend[time.Saturday] || !weekend[time.Sunday] || weekend[time.Monday] {
+		t.Fatalf("Unexpected weekend days %v", weekend)
+	}
+
+	business := Limits{Upload: 20 << 20, Download: 20 << 20}
+	schedule := Schedule{
+		Default: Limits{Upload: 100 << 20, Download: 100 << 20},
+		Windows: []Window{
+			{Days: businessDays, From: 9 * time.Hour, To: 18 * time.Hour, Limits: business},
+			{Days: everyDay, From: 22 * time.Hour, To: 6 * time.Hour},
+		},
+	}
+
+	testCases := []struct {
+		at       string
+		expected Limits
+	}{
+		{"2023-06-05 10:00", business},         // Monday, business hours
+		{"2023-06-05 18:00", schedule.Default}, // Monday, after business hours
+		{"2023-06-10 10:00", schedule.Default}, // Saturday
+		{"2023-06-05 23:00", Limits{}},         // Night, unlimited
+		{"2023-06-06 05:59", Limits{}},         // Night, past midnight
+		{"2023-06-06 06:00", schedule.Default},
+	}
+	for i, testCase := range testCases {
+		at, err := time.Parse("2006-01-02 15:04", testCase.at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if limits := schedule.At(at); limits != testCase.expected {
+			t.Errorf("Test %d: expected %v at %s, got %v", i+1, testCase.expected, testCase.at, limits)
+		}
+	}
+
+	if _, err := ParseDays("mon-xyz"); err == nil {
+		t.Fatal("Expected invalid weekday to fail")
+	}
+	if _, err := ParseTimeOfDay("25:00"); err == nil {
+		t.Fatal("Expected invalid time of day to fail")
+	}
+}